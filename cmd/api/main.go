@@ -18,7 +18,10 @@ import (
 	"github.com/dppi/dppierp-api/internal/middleware"
 	"github.com/dppi/dppierp-api/internal/repository"
 	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/dppi/dppierp-api/pkg/cache"
 	"github.com/dppi/dppierp-api/pkg/database"
+	"github.com/dppi/dppierp-api/pkg/mailer"
+	"github.com/dppi/dppierp-api/pkg/storage"
 )
 
 var (
@@ -68,29 +71,161 @@ func main() {
 
 	log.Info().Msg("Connected to database successfully")
 
+	// Local disk storage for uploaded files
+	fileStorage, err := storage.NewLocalStorage(cfg.Storage.Path)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize storage")
+	}
+
 	// Initialize repositories
 	fabricRepo := repository.NewFabricRepository(db)
 	rackRepo := repository.NewRackRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	masterRepo := repository.NewMasterRepository(db)
+	destroyRepo := repository.NewDestroyRepository(db, fabricRepo)
+	supplierRatingRepo := repository.NewSupplierRatingRepository(db)
+	buyerRepo := repository.NewBuyerRepository(db)
+	supplierRepo := repository.NewSupplierRepository(db)
+	vendorRepo := repository.NewVendorRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	garmentQCRepo := repository.NewGarmentQCRepository(db)
+	defectReportRepo := repository.NewDefectReportRepository(db)
+	efficiencyRepo := repository.NewEfficiencyRepository(db)
+	requestProcessRepo := repository.NewRequestProcessRepository(db)
+	packingRepo := repository.NewPackingRepository(db)
+	numberingRepo := repository.NewNumberingRepository(db)
+	statusLogRepo := repository.NewStatusLogRepository(db)
+	activityLogRepo := repository.NewActivityLogRepository(db)
+	exceptionRepo := repository.NewExceptionRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	emailRepo := repository.NewEmailRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+
+	// Master data cache, shared through the cache table when running several instances
+	var cacheStore cache.Store = cache.NewMemoryStore()
+	if cfg.Cache.Store == "database" {
+		cacheStore = cache.NewDatabaseStore(db, cfg.Cache.Prefix)
+	}
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret)
 
 	// Initialize services
-	checkpointService := service.NewCheckpointService(fabricRepo, rackRepo)
-	authService := service.NewAuthService(userRepo, authMiddleware)
-	masterService := service.NewMasterService(masterRepo)
+	activityService := service.NewActivityService(activityLogRepo)
+	exceptionService := service.NewExceptionService(exceptionRepo, userRepo)
+	notificationService := service.NewNotificationService(notificationRepo)
+	var mailSender mailer.Sender
+	if cfg.Mail.Host != "" {
+		mailSender = mailer.NewSMTPSender(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password)
+	}
+	emailService := service.NewEmailService(emailRepo, mailSender, service.EmailSettings{
+		AppName:       cfg.Mail.AppName,
+		From:          cfg.Mail.From,
+		AppURL:        cfg.App.FrontendURL,
+		MaxAttempts:   cfg.Mail.MaxAttempts,
+		RetryDelay:    cfg.Mail.RetryDelay,
+		MaxRetryDelay: cfg.Mail.MaxRetryDelay,
+	})
+	movementStream := service.NewMovementStream(0)
+	jobRunner := service.NewJobRunner(jobRepo, service.JobSettings{
+		Queue:        cfg.Jobs.Queue,
+		Workers:      cfg.Jobs.Workers,
+		MaxTries:     cfg.Jobs.MaxTries,
+		Backoff:      cfg.Jobs.Backoff,
+		MaxBackoff:   cfg.Jobs.MaxBackoff,
+		Timeout:      cfg.Jobs.Timeout,
+		RetryAfter:   cfg.Jobs.RetryAfter,
+		PollInterval: cfg.Jobs.PollInterval,
+	})
+	accessService := service.NewAccessService(userRepo, cacheStore, cfg.Cache.PermissionTTL)
+	webhookService := service.NewWebhookService(webhookRepo, accessService, activityService, service.WebhookSettings{
+		MaxAttempts:   cfg.Webhook.MaxAttempts,
+		RetryDelay:    cfg.Webhook.RetryDelay,
+		MaxRetryDelay: cfg.Webhook.MaxRetryDelay,
+		Timeout:       cfg.Webhook.Timeout,
+	})
+	checkpointService := service.NewCheckpointService(fabricRepo, rackRepo, activityService, notificationService, movementStream, accessService)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, authMiddleware, activityService, emailService)
+	masterService := service.NewMasterService(masterRepo, activityService, service.NewMasterCache(cacheStore, cfg.Cache.TTL))
+	destroyService := service.NewDestroyService(destroyRepo, fabricRepo, userRepo, fileStorage, activityService, notificationService)
+	supplierRatingService := service.NewSupplierRatingService(supplierRatingRepo, cfg.Supplier.DeliveryLeadDays, activityService, jobRunner)
+	buyerService := service.NewBuyerService(buyerRepo, activityService)
+	supplierService := service.NewSupplierService(supplierRepo, fileStorage, activityService)
+	vendorService := service.NewVendorService(vendorRepo, activityService)
+	orderService := service.NewOrderService(orderRepo)
+	garmentQCService := service.NewGarmentQCService(garmentQCRepo, activityService, notificationService, movementStream)
+	defectReportService := service.NewDefectReportService(defectReportRepo)
+	efficiencyService := service.NewEfficiencyService(efficiencyRepo)
+	requestProcessService := service.NewRequestProcessService(requestProcessRepo, activityService)
+	packingService := service.NewPackingService(packingRepo, garmentQCRepo, activityService)
+	numberingService := service.NewNumberingService(numberingRepo, activityService)
+	statusLogService := service.NewStatusLogService(statusLogRepo)
+
+	// Moving a roll labels the movement by looking up its stage in movement_types
+	if err := masterService.CheckStageMovementTypes(); err != nil {
+		log.Fatal().Err(err).Msg("Movement types are out of sync with stages")
+	}
 
 	// Initialize handlers
 	checkpointHandler := handler.NewCheckpointHandler(checkpointService)
-	authHandler := handler.NewAuthHandler(authService)
+	movementStreamHandler := handler.NewMovementStreamHandler(movementStream)
+	authHandler := handler.NewAuthHandler(authService, accessService)
 	masterHandler := handler.NewMasterHandler(masterService)
+	destroyHandler := handler.NewDestroyHandler(destroyService)
+	supplierRatingHandler := handler.NewSupplierRatingHandler(supplierRatingService)
+	buyerHandler := handler.NewBuyerHandler(buyerService)
+	supplierHandler := handler.NewSupplierHandler(supplierService)
+	vendorHandler := handler.NewVendorHandler(vendorService)
+	orderHandler := handler.NewOrderHandler(orderService)
+	garmentQCHandler := handler.NewGarmentQCHandler(garmentQCService)
+	defectReportHandler := handler.NewDefectReportHandler(defectReportService)
+	efficiencyHandler := handler.NewEfficiencyHandler(efficiencyService)
+	requestProcessHandler := handler.NewRequestProcessHandler(requestProcessService)
+	packingHandler := handler.NewPackingHandler(packingService)
+	numberingHandler := handler.NewNumberingHandler(numberingService)
+	statusLogHandler := handler.NewStatusLogHandler(statusLogService)
+	activityHandler := handler.NewActivityHandler(activityService)
+	exceptionHandler := handler.NewExceptionHandler(exceptionService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	jobBatchHandler := handler.NewJobBatchHandler(jobRunner)
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if cfg.Supplier.RatingInterval > 0 {
+		go supplierRatingService.RunSchedule(jobsCtx, cfg.Supplier.RatingInterval)
+	}
+
+	if mailSender == nil {
+		log.Warn().Msg("MAIL_HOST is not set, emails are queued but not sent")
+	} else if cfg.Mail.PollInterval > 0 {
+		go emailService.RunOutbox(jobsCtx, cfg.Mail.PollInterval)
+	}
+
+	if cfg.Webhook.PollInterval > 0 {
+		go webhookService.RunOutbox(jobsCtx, cfg.Webhook.PollInterval)
+	}
+
+	// The job runner finishes its running jobs before the process exits
+	jobRunnerDone := make(chan struct{})
+	if cfg.Jobs.Workers > 0 {
+		go func() {
+			jobRunner.Run(jobsCtx)
+			close(jobRunnerDone)
+		}()
+	} else {
+		close(jobRunnerDone)
+		log.Warn().Msg("JOB_WORKERS is 0, jobs are queued but not run")
+	}
 
 	// Setup router
 	router := gin.New()
-	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
+	router.Use(middleware.Recovery(exceptionService))
 	router.Use(middleware.CORSMiddleware(cfg.CORS.AllowedOrigins))
 
 	// Health check
@@ -125,23 +260,231 @@ func main() {
 
 	// Check Point routes (protected)
 	checkpointGroup := router.Group("/check-point/v1")
-	checkpointGroup.Use(authMiddleware.Authenticate())
+	checkpointGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "fabric.qr-system"))
 	{
 		checkpointGroup.GET("/overview", checkpointHandler.GetOverview)
 		checkpointGroup.POST("/scan", checkpointHandler.ScanQR)
 		checkpointGroup.POST("/move", checkpointHandler.MoveStage)
 		checkpointGroup.POST("/scan-rack", checkpointHandler.ScanRack)
 		checkpointGroup.POST("/relocation", checkpointHandler.Relocate)
+		checkpointGroup.GET("/stream", movementStreamHandler.Stream)
+
+		checkpointGroup.GET("/destroy-requests", destroyHandler.List)
+		checkpointGroup.POST("/destroy-requests", destroyHandler.Create)
+		checkpointGroup.GET("/destroy-requests/:id", destroyHandler.Get)
+		checkpointGroup.POST("/destroy-requests/:id/approve", destroyHandler.Approve)
+		checkpointGroup.POST("/destroy-requests/:id/reject", destroyHandler.Reject)
 	}
 
-	// Master Data routes (protected)
+	// Master Data routes (protected). Handhelds load the block, rack and
+	// relaxation lists with just the check point permission.
 	masterGroup := router.Group("/master")
 	masterGroup.Use(authMiddleware.Authenticate())
 	{
-		masterGroup.GET("/blocks", masterHandler.GetBlocks)
-		masterGroup.GET("/racks", masterHandler.GetRacks)
-		masterGroup.GET("/relaxation-blocks", masterHandler.GetRelaxationBlocks)
-		masterGroup.GET("/relaxation-racks", masterHandler.GetRelaxationRacks)
+		blockGroup := masterGroup.Group("/blocks", middleware.RequireModulePermission(accessService, "fabric.master-data.block", "fabric.qr-system.read"))
+		blockGroup.GET("", masterHandler.GetBlocks)
+		blockGroup.POST("", masterHandler.CreateBlock)
+		blockGroup.PUT("/:id", masterHandler.UpdateBlock)
+		blockGroup.DELETE("/:id", masterHandler.Delete(repository.MasterBlocks))
+		blockGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterBlocks))
+
+		rackGroup := masterGroup.Group("/racks", middleware.RequireModulePermission(accessService, "fabric.master-data.rack", "fabric.qr-system.read"))
+		rackGroup.GET("", masterHandler.GetRacks)
+		rackGroup.POST("", masterHandler.CreateRack)
+		rackGroup.PUT("/:id", masterHandler.UpdateRack)
+		rackGroup.DELETE("/:id", masterHandler.Delete(repository.MasterRacks))
+		rackGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterRacks))
+
+		relaxationBlockGroup := masterGroup.Group("/relaxation-blocks", middleware.RequireModulePermission(accessService, "fabric.master-data.relaxation-block", "fabric.qr-system.read"))
+		relaxationBlockGroup.GET("", masterHandler.GetRelaxationBlocks)
+		relaxationBlockGroup.POST("", masterHandler.CreateRelaxationBlock)
+		relaxationBlockGroup.PUT("/:id", masterHandler.UpdateRelaxationBlock)
+		relaxationBlockGroup.DELETE("/:id", masterHandler.Delete(repository.MasterRelaxationBlocks))
+		relaxationBlockGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterRelaxationBlocks))
+
+		relaxationRackGroup := masterGroup.Group("/relaxation-racks", middleware.RequireModulePermission(accessService, "fabric.master-data.relaxation-rack", "fabric.qr-system.read"))
+		relaxationRackGroup.GET("", masterHandler.GetRelaxationRacks)
+		relaxationRackGroup.POST("", masterHandler.CreateRelaxationRack)
+		relaxationRackGroup.PUT("/:id", masterHandler.UpdateRelaxationRack)
+		relaxationRackGroup.DELETE("/:id", masterHandler.Delete(repository.MasterRelaxationRacks))
+		relaxationRackGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterRelaxationRacks))
+
+		unitGroup := masterGroup.Group("/units", middleware.RequireModulePermission(accessService, "accessories.master-data.unit", "fabric.qr-system.read"))
+		unitGroup.GET("", masterHandler.GetUnits)
+		unitGroup.POST("", masterHandler.CreateUnit)
+		unitGroup.PUT("/:id", masterHandler.UpdateUnit)
+		unitGroup.DELETE("/:id", masterHandler.Delete(repository.MasterUnits))
+		unitGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterUnits))
+
+		defectTypeGroup := masterGroup.Group("/defect-types", middleware.RequireModulePermission(accessService, "sewing.master-data.defect-type", "sewing.qc.read"))
+		defectTypeGroup.GET("", masterHandler.GetDefectTypes)
+		defectTypeGroup.POST("", masterHandler.CreateDefectType)
+		defectTypeGroup.PUT("/:id", masterHandler.UpdateDefectType)
+		defectTypeGroup.DELETE("/:id", masterHandler.Delete(repository.MasterDefectTypes))
+		defectTypeGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterDefectTypes))
+
+		movementTypeGroup := masterGroup.Group("/movement-types", middleware.RequireModulePermission(accessService, "setting", "fabric.qr-system.read"))
+		movementTypeGroup.GET("", masterHandler.GetMovementTypes)
+		movementTypeGroup.POST("", masterHandler.CreateMovementType)
+		movementTypeGroup.PUT("/:id", masterHandler.UpdateMovementType)
+		movementTypeGroup.DELETE("/:id", masterHandler.Delete(repository.MasterMovementTypes))
+		movementTypeGroup.POST("/:id/restore", masterHandler.Restore(repository.MasterMovementTypes))
+	}
+
+	// Supplier routes (protected)
+	supplierGroup := router.Group("/suppliers")
+	supplierGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "fabric.master-data.supplier"))
+	{
+		supplierGroup.GET("/ratings", supplierRatingHandler.GetByPeriod)
+		supplierGroup.POST("/ratings/compute", supplierRatingHandler.Compute)
+		supplierGroup.POST("/ratings/recompute", supplierRatingHandler.Recompute)
+		supplierGroup.GET("/:id/ratings", supplierRatingHandler.GetTrend)
+
+		supplierGroup.GET("/categories", supplierHandler.ListCategories)
+		supplierGroup.POST("/categories", supplierHandler.CreateCategory)
+		supplierGroup.PUT("/categories/:id", supplierHandler.UpdateCategory)
+		supplierGroup.DELETE("/categories/:id", supplierHandler.DeleteCategory)
+
+		supplierGroup.GET("", supplierHandler.List)
+		supplierGroup.POST("", supplierHandler.Create)
+		supplierGroup.GET("/:id", supplierHandler.Get)
+		supplierGroup.PUT("/:id", supplierHandler.Update)
+		supplierGroup.DELETE("/:id", supplierHandler.Delete)
+		supplierGroup.POST("/:id/toggle-status", supplierHandler.ToggleStatus)
+		supplierGroup.POST("/:id/documents", supplierHandler.UploadDocument)
+		supplierGroup.DELETE("/:id/documents/:document_id", supplierHandler.DeleteDocument)
+	}
+
+	// Buyer routes (protected)
+	buyerGroup := router.Group("/buyers")
+	buyerGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "order.master-data.buyer"))
+	{
+		buyerGroup.GET("", buyerHandler.List)
+		buyerGroup.POST("", buyerHandler.Create)
+		buyerGroup.GET("/:id", buyerHandler.Get)
+		buyerGroup.PUT("/:id", buyerHandler.Update)
+		buyerGroup.DELETE("/:id", buyerHandler.Delete)
+		buyerGroup.POST("/:id/toggle-status", buyerHandler.ToggleStatus)
+	}
+
+	// Vendor routes (protected)
+	vendorGroup := router.Group("/vendors")
+	vendorGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "order.master-data.vendor"))
+	{
+		vendorGroup.GET("", vendorHandler.List)
+		vendorGroup.POST("", vendorHandler.Create)
+		vendorGroup.GET("/:id", vendorHandler.Get)
+		vendorGroup.PUT("/:id", vendorHandler.Update)
+		vendorGroup.DELETE("/:id", vendorHandler.Delete)
+		vendorGroup.POST("/:id/toggle-status", vendorHandler.ToggleStatus)
+	}
+
+	// Order routes (protected)
+	orderGroup := router.Group("/orders")
+	orderGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "order.order"))
+	{
+		orderGroup.GET("", orderHandler.List)
+		orderGroup.GET("/:id", orderHandler.Get)
+		orderGroup.GET("/:id/fabric-reconciliation", orderHandler.GetFabricReconciliation)
+	}
+
+	// Garment QC routes (protected)
+	garmentQCGroup := router.Group("/garment-qc/v1")
+	garmentQCGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "sewing.qc"))
+	{
+		garmentQCGroup.POST("/scan", garmentQCHandler.Scan)
+		garmentQCGroup.GET("/lines/:line_id/summary", garmentQCHandler.Summary)
+		garmentQCGroup.POST("/items/:id/rework", garmentQCHandler.SendToRework)
+		garmentQCGroup.POST("/items/:id/rework/return", garmentQCHandler.ReturnFromRework)
+		garmentQCGroup.GET("/rework-report", garmentQCHandler.ReworkReport)
+		garmentQCGroup.GET("/defect-pareto", defectReportHandler.Pareto)
+		garmentQCGroup.GET("/defect-pareto/:defect_type_id/processes", defectReportHandler.Processes)
+		garmentQCGroup.GET("/efficiency", efficiencyHandler.Report)
+	}
+
+	// Production routes (protected)
+	productionGroup := router.Group("/production/v1")
+	productionGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "sewing.process"))
+	{
+		productionGroup.GET("/requests/:id/processes", requestProcessHandler.List)
+		productionGroup.GET("/requests/:id/processes/history", requestProcessHandler.History)
+		productionGroup.POST("/requests/:id/processes/copy", requestProcessHandler.Copy)
+		productionGroup.PUT("/requests/:id/processes/:order_process_id", requestProcessHandler.Assign)
+	}
+
+	// Packing check point routes (protected)
+	packingGroup := router.Group("/packing/v1")
+	packingGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "sewing.packing"))
+	{
+		packingGroup.GET("/gates", packingHandler.ListGates)
+		packingGroup.POST("/scan", packingHandler.Scan)
+		packingGroup.GET("/bundles/:code", packingHandler.Summary)
+	}
+
+	// Document numbering routes (protected)
+	numberingGroup := router.Group("/settings/numberings")
+	numberingGroup.Use(authMiddleware.Authenticate(), middleware.RequireModulePermission(accessService, "setting"))
+	{
+		numberingGroup.GET("", numberingHandler.List)
+		numberingGroup.POST("/preview", numberingHandler.PreviewFormat)
+		numberingGroup.POST("/allocate", numberingHandler.Allocate)
+		numberingGroup.GET("/:id/preview", numberingHandler.Preview)
+	}
+
+	// Document status history routes (protected)
+	statusLogGroup := router.Group("/status-logs")
+	statusLogGroup.Use(authMiddleware.Authenticate(), middleware.RequirePermission(accessService, "setting.status-log.read"))
+	{
+		statusLogGroup.GET("/:document/:id", statusLogHandler.History)
+	}
+
+	// Audit trail routes (protected)
+	activityGroup := router.Group("/activity-logs")
+	activityGroup.Use(authMiddleware.Authenticate(), middleware.RequirePermission(accessService, "setting.activity-log.read"))
+	{
+		activityGroup.GET("", activityHandler.List)
+	}
+
+	// Exception log routes (protected)
+	exceptionGroup := router.Group("/exceptions")
+	exceptionGroup.Use(authMiddleware.Authenticate(), middleware.RequirePermission(accessService, service.PermissionViewExceptions))
+	{
+		exceptionGroup.GET("", exceptionHandler.List)
+		exceptionGroup.GET("/:reference", exceptionHandler.Get)
+	}
+
+	// Notification routes (protected)
+	notificationGroup := router.Group("/notifications")
+	notificationGroup.Use(authMiddleware.Authenticate())
+	{
+		notificationGroup.GET("", notificationHandler.List)
+		notificationGroup.GET("/unread-count", notificationHandler.UnreadCount)
+		notificationGroup.POST("/read-all", notificationHandler.MarkAllRead)
+		notificationGroup.POST("/:id/read", notificationHandler.MarkRead)
+		notificationGroup.DELETE("/:id", notificationHandler.Delete)
+	}
+
+	// Webhook routes (protected)
+	webhookGroup := router.Group("/webhooks")
+	webhookGroup.Use(authMiddleware.Authenticate(), middleware.RequirePermission(accessService, service.PermissionManageWebhooks))
+	{
+		webhookGroup.GET("/events", webhookHandler.Events)
+		webhookGroup.GET("/subscriptions", webhookHandler.ListSubscriptions)
+		webhookGroup.POST("/subscriptions", webhookHandler.CreateSubscription)
+		webhookGroup.PUT("/subscriptions/:id", webhookHandler.UpdateSubscription)
+		webhookGroup.DELETE("/subscriptions/:id", webhookHandler.DeleteSubscription)
+		webhookGroup.GET("/deliveries", webhookHandler.ListDeliveries)
+		webhookGroup.GET("/deliveries/:id", webhookHandler.GetDelivery)
+		webhookGroup.POST("/deliveries/:id/redeliver", webhookHandler.Redeliver)
+		webhookGroup.GET("/dead-letters", webhookHandler.DeadLetters)
+	}
+
+	// Background job routes (protected). Whoever recomputes supplier ratings
+	// can follow the batch.
+	jobGroup := router.Group("/jobs")
+	jobGroup.Use(authMiddleware.Authenticate(), middleware.RequirePermission(accessService, "setting.read", "fabric.master-data.supplier.write"))
+	{
+		jobGroup.GET("/batches/:id", jobBatchHandler.Get)
 	}
 
 	// Create server
@@ -153,6 +496,9 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Shutdown does not cancel open requests, so end the movement streams
+	srv.RegisterOnShutdown(movementStream.Close)
+
 	// Start server in goroutine
 	go func() {
 		log.Info().Str("port", cfg.App.Port).Msg("Starting server")
@@ -166,13 +512,20 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info().Msg("Shutting down server...")
+	stopJobs()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal().Err(err).Msg("Server forced to shutdown")
+		log.Error().Err(err).Msg("Server forced to shutdown")
+	}
+
+	select {
+	case <-jobRunnerDone:
+	case <-ctx.Done():
+		log.Warn().Msg("Running jobs did not finish in time, they are retried after JOB_RETRY_AFTER_SECONDS")
 	}
 
 	fmt.Println("Server exited properly")
@@ -0,0 +1,19 @@
+// Package authctx carries the signed in user of a request in its context, so
+// services can read it without depending on the HTTP middleware.
+package authctx
+
+import "context"
+
+type userIDKey struct{}
+
+// WithUserID returns a copy of ctx carrying the id of the signed in user.
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserID returns the id of the signed in user of a request context, or 0
+// outside an authenticated request.
+func UserID(ctx context.Context) int64 {
+	userID, _ := ctx.Value(userIDKey{}).(int64)
+	return userID
+}
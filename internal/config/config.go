@@ -13,11 +13,18 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	CORS     CORSConfig
+	Storage  StorageConfig
+	Supplier SupplierConfig
+	Mail     MailConfig
+	Webhook  WebhookConfig
+	Jobs     JobConfig
+	Cache    CacheConfig
 }
 
 type AppConfig struct {
-	Env  string
-	Port string
+	Env         string
+	Port        string
+	FrontendURL string
 }
 
 type DatabaseConfig struct {
@@ -38,16 +45,94 @@ type CORSConfig struct {
 	AllowedOrigins string
 }
 
+type StorageConfig struct {
+	Path string
+}
+
+type SupplierConfig struct {
+	RatingInterval   time.Duration
+	DeliveryLeadDays int
+}
+
+// MailConfig configures the SMTP server and the email outbox. Emails are
+// queued but not sent while Host is empty.
+type MailConfig struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	From          string
+	AppName       string
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	PollInterval  time.Duration
+}
+
+// WebhookConfig configures the delivery of outgoing webhooks.
+type WebhookConfig struct {
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	Timeout       time.Duration
+	PollInterval  time.Duration
+}
+
+// JobConfig configures the background job runner. Its queue should be one no
+// Laravel worker listens on, since the two run different jobs.
+type JobConfig struct {
+	Queue        string
+	Workers      int
+	MaxTries     int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+	RetryAfter   time.Duration
+	PollInterval time.Duration
+}
+
+// CacheConfig configures the master data and permission caches. The memory
+// store is per instance; the database store shares the cache table between
+// instances.
+type CacheConfig struct {
+	Store         string
+	Prefix        string
+	TTL           time.Duration
+	PermissionTTL time.Duration
+}
+
 func Load() (*Config, error) {
 	// Load .env file if exists
 	_ = godotenv.Load()
 
 	expiryHours, _ := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
+	ratingIntervalHours, _ := strconv.Atoi(getEnv("SUPPLIER_RATING_INTERVAL_HOURS", "24"))
+	deliveryLeadDays, _ := strconv.Atoi(getEnv("SUPPLIER_DELIVERY_LEAD_DAYS", "30"))
+	mailPort, _ := strconv.Atoi(getEnv("MAIL_PORT", "587"))
+	mailMaxAttempts, _ := strconv.Atoi(getEnv("MAIL_MAX_ATTEMPTS", "5"))
+	mailRetrySeconds, _ := strconv.Atoi(getEnv("MAIL_RETRY_SECONDS", "60"))
+	mailMaxRetryMinutes, _ := strconv.Atoi(getEnv("MAIL_MAX_RETRY_MINUTES", "60"))
+	mailPollSeconds, _ := strconv.Atoi(getEnv("MAIL_POLL_SECONDS", "30"))
+	webhookMaxAttempts, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	webhookRetrySeconds, _ := strconv.Atoi(getEnv("WEBHOOK_RETRY_SECONDS", "30"))
+	webhookMaxRetryMinutes, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_RETRY_MINUTES", "360"))
+	webhookTimeoutSeconds, _ := strconv.Atoi(getEnv("WEBHOOK_TIMEOUT_SECONDS", "10"))
+	webhookPollSeconds, _ := strconv.Atoi(getEnv("WEBHOOK_POLL_SECONDS", "10"))
+	jobWorkers, _ := strconv.Atoi(getEnv("JOB_WORKERS", "2"))
+	jobMaxTries, _ := strconv.Atoi(getEnv("JOB_MAX_TRIES", "3"))
+	jobBackoffSeconds, _ := strconv.Atoi(getEnv("JOB_BACKOFF_SECONDS", "10"))
+	jobMaxBackoffMinutes, _ := strconv.Atoi(getEnv("JOB_MAX_BACKOFF_MINUTES", "10"))
+	jobTimeoutSeconds, _ := strconv.Atoi(getEnv("JOB_TIMEOUT_SECONDS", "120"))
+	jobRetryAfterSeconds, _ := strconv.Atoi(getEnv("JOB_RETRY_AFTER_SECONDS", "300"))
+	jobPollSeconds, _ := strconv.Atoi(getEnv("JOB_POLL_SECONDS", "3"))
+	cacheTTLMinutes, _ := strconv.Atoi(getEnv("CACHE_TTL_MINUTES", "10"))
+	cachePermissionTTLMinutes, _ := strconv.Atoi(getEnv("CACHE_PERMISSION_TTL_MINUTES", "5"))
 
 	return &Config{
 		App: AppConfig{
-			Env:  getEnv("APP_ENV", "development"),
-			Port: getEnv("APP_PORT", "8080"),
+			Env:         getEnv("APP_ENV", "development"),
+			Port:        getEnv("APP_PORT", "8080"),
+			FrontendURL: getEnv("APP_FRONTEND_URL", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -64,6 +149,48 @@ func Load() (*Config, error) {
 		CORS: CORSConfig{
 			AllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "*"),
 		},
+		Storage: StorageConfig{
+			Path: getEnv("STORAGE_PATH", "./storage"),
+		},
+		Supplier: SupplierConfig{
+			RatingInterval:   time.Duration(ratingIntervalHours) * time.Hour,
+			DeliveryLeadDays: deliveryLeadDays,
+		},
+		Mail: MailConfig{
+			Host:          getEnv("MAIL_HOST", ""),
+			Port:          mailPort,
+			Username:      getEnv("MAIL_USERNAME", ""),
+			Password:      getEnv("MAIL_PASSWORD", ""),
+			From:          getEnv("MAIL_FROM_ADDRESS", "DPPI ERP <noreply@localhost>"),
+			AppName:       getEnv("MAIL_APP_NAME", "DPPI ERP"),
+			MaxAttempts:   mailMaxAttempts,
+			RetryDelay:    time.Duration(mailRetrySeconds) * time.Second,
+			MaxRetryDelay: time.Duration(mailMaxRetryMinutes) * time.Minute,
+			PollInterval:  time.Duration(mailPollSeconds) * time.Second,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:   webhookMaxAttempts,
+			RetryDelay:    time.Duration(webhookRetrySeconds) * time.Second,
+			MaxRetryDelay: time.Duration(webhookMaxRetryMinutes) * time.Minute,
+			Timeout:       time.Duration(webhookTimeoutSeconds) * time.Second,
+			PollInterval:  time.Duration(webhookPollSeconds) * time.Second,
+		},
+		Jobs: JobConfig{
+			Queue:        getEnv("JOB_QUEUE", "api"),
+			Workers:      jobWorkers,
+			MaxTries:     jobMaxTries,
+			Backoff:      time.Duration(jobBackoffSeconds) * time.Second,
+			MaxBackoff:   time.Duration(jobMaxBackoffMinutes) * time.Minute,
+			Timeout:      time.Duration(jobTimeoutSeconds) * time.Second,
+			RetryAfter:   time.Duration(jobRetryAfterSeconds) * time.Second,
+			PollInterval: time.Duration(jobPollSeconds) * time.Second,
+		},
+		Cache: CacheConfig{
+			Store:         getEnv("CACHE_STORE", "memory"),
+			Prefix:        getEnv("CACHE_PREFIX", "dppierp_api_cache_"),
+			TTL:           time.Duration(cacheTTLMinutes) * time.Minute,
+			PermissionTTL: time.Duration(cachePermissionTTLMinutes) * time.Minute,
+		},
 	}, nil
 }
 
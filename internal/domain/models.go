@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Stage string
 
@@ -22,8 +25,11 @@ type StageInfo struct {
 }
 
 type MovementType struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func GetAllStages() []StageInfo {
@@ -75,6 +81,46 @@ type User struct {
 	DeletedAt            *time.Time `json:"deleted_at,omitempty"`
 }
 
+// SuperAdminRole holds every permission, whether it is assigned or not.
+const SuperAdminRole = "superadmin"
+
+// UserAccess holds a user's roles and every permission they have, directly
+// or through one of the roles.
+type UserAccess struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Can reports whether the user holds the permission.
+func (a *UserAccess) Can(permission string) bool {
+	for _, role := range a.Roles {
+		if role == SuperAdminRole {
+			return true
+		}
+	}
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RefreshToken is a refresh token handed out to a user. Each refresh uses it
+// up and hands out the next token of the same family, so a family is one
+// login session.
+type RefreshToken struct {
+	ID            int64
+	UserID        int64
+	TokenID       string
+	FamilyID      string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	RevokedAt     *time.Time
+	RevokedReason *string
+	CreatedAt     time.Time
+}
+
 // Fabric represents the fabric entity
 type Fabric struct {
 	ID                 int64      `json:"id"`
@@ -101,6 +147,7 @@ type Fabric struct {
 	UpdatedAt          time.Time  `json:"updated_at"`
 	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
 	Buyer              string     `json:"buyer"`
+	BuyerID            *int64     `json:"buyer_id,omitempty"`
 	Style              string     `json:"style"`
 	YardStock          string     `json:"yard_stock,omitempty"`
 	YardRemaining      string     `json:"yard_remaining,omitempty"`
@@ -142,6 +189,127 @@ type Buyer struct {
 	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 }
 
+type Vendor struct {
+	ID            int64      `json:"id"`
+	Code          string     `json:"code"`
+	VendorCode    *string    `json:"vendor_code,omitempty"`
+	Name          string     `json:"name"`
+	ContactPerson *string    `json:"contact_person,omitempty"`
+	Phone         *string    `json:"phone,omitempty"`
+	Address       *string    `json:"address,omitempty"`
+	Email         *string    `json:"email,omitempty"`
+	City          *string    `json:"city,omitempty"`
+	Country       *string    `json:"country,omitempty"`
+	Status        int        `json:"status"`
+	Remarks       *string    `json:"remarks,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Supplier struct {
+	ID                 int64              `json:"id"`
+	Code               string             `json:"code"`
+	Name               string             `json:"name"`
+	SupplierCategoryID *int64             `json:"supplier_category_id,omitempty"`
+	CategoryName       *string            `json:"category_name,omitempty"`
+	ContactPerson      *string            `json:"contact_person,omitempty"`
+	Phone              *string            `json:"phone,omitempty"`
+	Address            *string            `json:"address,omitempty"`
+	Email              *string            `json:"email,omitempty"`
+	City               *string            `json:"city,omitempty"`
+	Country            *string            `json:"country,omitempty"`
+	Status             int                `json:"status"`
+	Remarks            *string            `json:"remarks,omitempty"`
+	Documents          []SupplierDocument `json:"documents,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	DeletedAt          *time.Time         `json:"deleted_at,omitempty"`
+}
+
+type SupplierCategory struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+type SupplierDocument struct {
+	ID           int64     `json:"id"`
+	SupplierID   int64     `json:"supplier_id"`
+	DocumentName *string   `json:"document_name,omitempty"`
+	DocumentType *string   `json:"document_type,omitempty"`
+	FilePath     *string   `json:"file_path,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Order struct {
+	ID           int64                 `json:"id"`
+	Code         string                `json:"code"`
+	DateTime     *time.Time            `json:"date_time,omitempty"`
+	BuyerID      int64                 `json:"buyer_id"`
+	BuyerName    string                `json:"buyer_name"`
+	Style        string                `json:"style"`
+	VendorID     int64                 `json:"vendor_id"`
+	VendorName   string                `json:"vendor_name"`
+	SupplierID   *int64                `json:"supplier_id,omitempty"`
+	SupplierName *string               `json:"supplier_name,omitempty"`
+	Status       *string               `json:"status,omitempty"`
+	Detail       *OrderDetail          `json:"detail,omitempty"`
+	Processes    []OrderProcess        `json:"processes,omitempty"`
+	Fabric       *FabricReconciliation `json:"fabric,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// OrderDetail is the planned manpower of an order.
+type OrderDetail struct {
+	ManOperator    int `json:"man_operator"`
+	ManQC          int `json:"man_qc"`
+	ManHelper      int `json:"man_helper"`
+	ManIron        int `json:"man_iron"`
+	ManQCFinishing int `json:"man_qc_finishing"`
+	ManHangtag     int `json:"man_hangtag"`
+	ManFolding     int `json:"man_folding"`
+	ManTotal       int `json:"man_total"`
+}
+
+type OrderProcess struct {
+	ID           int64    `json:"id"`
+	OrderID      int64    `json:"order_id"`
+	ProcessID    *int64   `json:"process_id,omitempty"`
+	Highlight    *string  `json:"highlight,omitempty"`
+	Name         *string  `json:"name,omitempty"`
+	MachineType  *string  `json:"machine_type,omitempty"`
+	ClassID      *int64   `json:"class_id,omitempty"`
+	Tooling      *string  `json:"tooling,omitempty"`
+	StandardTime *float64 `json:"standard_time,omitempty"`
+	ActualTime   *float64 `json:"actual_time,omitempty"`
+	VideoLink    *string  `json:"video_link,omitempty"`
+}
+
+// FabricReconciliation compares the fabric received for an order with where
+// the rolls are now.
+type FabricReconciliation struct {
+	Incomings     int          `json:"incomings"`
+	RollsReceived int          `json:"rolls_received"`
+	YardsReceived float64      `json:"yards_received"`
+	Stages        []StageYards `json:"stages"`
+}
+
+// StageYards totals the rolls currently on a stage. Rolls that never went
+// through a check point are reported under StageUnassigned.
+type StageYards struct {
+	Stage string  `json:"stage"`
+	Rolls int     `json:"rolls"`
+	Yards float64 `json:"yards"`
+}
+
+const StageUnassigned = "unassigned"
+
 type Inventory struct {
 	ID        int64      `json:"id"`
 	Datetime  string     `json:"datetime"`
@@ -157,6 +325,7 @@ type Inventory struct {
 type Block struct {
 	ID        int64      `json:"id"`
 	Name      string     `json:"name"`
+	Type      *string    `json:"type,omitempty"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
@@ -165,6 +334,8 @@ type Block struct {
 type Rack struct {
 	ID        int64      `json:"id"`
 	Name      string     `json:"name"`
+	Type      *string    `json:"type,omitempty"`
+	Capacity  int        `json:"capacity"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
@@ -185,3 +356,629 @@ type RelaxationRack struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
+
+type Unit struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type DefectType struct {
+	ID        int64      `json:"id"`
+	Key       *string    `json:"key,omitempty"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+const (
+	DestroyStatusPending  = "pending"
+	DestroyStatusApproved = "approved"
+	DestroyStatusRejected = "rejected"
+)
+
+// FabricDestroyRequest is a request to write off a fabric roll. The roll is
+// only moved to the destroy stage once the request has been approved.
+type FabricDestroyRequest struct {
+	ID                  int64      `json:"id"`
+	FabricID            int64      `json:"fabric_id"`
+	FabricCode          string     `json:"fabric_code"`
+	Reason              string     `json:"reason"`
+	Status              string     `json:"status"`
+	RequestedBy         int64      `json:"requested_by"`
+	ReviewedBy          *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt          *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNotes         *string    `json:"review_notes,omitempty"`
+	InventoryMovementID *int64     `json:"inventory_movement_id,omitempty"`
+	Photos              []string   `json:"photos,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// SupplierRating is a supplier scorecard. Computed ratings carry the month
+// they cover in Period ("2006-01"); all scores range from 0 to 100.
+type SupplierRating struct {
+	ID             int64      `json:"id"`
+	SupplierID     int64      `json:"supplier_id"`
+	SupplierName   string     `json:"supplier_name,omitempty"`
+	Period         *string    `json:"period,omitempty"`
+	RatingQuality  int        `json:"rating_quality"`
+	RatingDelivery int        `json:"rating_delivery"`
+	RatingPrice    int        `json:"rating_price"`
+	RatingOverall  int        `json:"rating_overall"`
+	Remarks        *string    `json:"remarks,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Garment QC results as sent by the line QC.
+const (
+	GarmentResultPass   = "pass"
+	GarmentResultFail   = "fail"
+	GarmentResultDefect = "defect"
+)
+
+// Garment stages stored on request_items and request_item_logs. A defect sends
+// the piece back to the sewing process, a checked piece moves on to finishing.
+const (
+	GarmentStageProcess   = "process"
+	GarmentStageFinishing = "finishing"
+	GarmentStagePacking   = "packing"
+)
+
+// RequestStatusApproved marks a line request that is allowed to run.
+const RequestStatusApproved = "approved"
+
+// LineRequest is a production request running on a sewing line.
+type LineRequest struct {
+	ID      int64      `json:"id"`
+	Code    string     `json:"code"`
+	Date    *time.Time `json:"date,omitempty"`
+	OrderID int64      `json:"order_id"`
+	BuyerID *int64     `json:"buyer_id,omitempty"`
+	Style   string     `json:"style"`
+	LineID  int64      `json:"line_id"`
+	Status  *string    `json:"status,omitempty"`
+}
+
+// QRSystem is a garment bundle label. Pieces in the bundle are scanned as
+// "<code>-<piece>".
+type QRSystem struct {
+	ID              int64    `json:"id"`
+	Code            string   `json:"code"`
+	OrderID         int64    `json:"order_id"`
+	PONumber        *string  `json:"po_number,omitempty"`
+	Color           string   `json:"color"`
+	Size            *string  `json:"size,omitempty"`
+	Amount          float64  `json:"amount"`
+	AmountTolerance *float64 `json:"amount_tolerance,omitempty"`
+}
+
+// RequestItem is a garment piece checked by the line QC.
+type RequestItem struct {
+	ID         int64           `json:"id"`
+	QRCode     string          `json:"qr_code"`
+	RequestID  int64           `json:"request_id"`
+	OrderID    int64           `json:"order_id"`
+	QRSystemID int64           `json:"qr_system_id"`
+	Result     string          `json:"result"`
+	ResultedAt time.Time       `json:"resulted_at"`
+	Stage      string          `json:"stage"`
+	IsCNCM     bool            `json:"is_cncm"`
+	IsRework   bool            `json:"is_rework"`
+	PackingID  *int64          `json:"packing_id,omitempty"`
+	Defects    []RequestDefect `json:"defects,omitempty"`
+}
+
+// RequestDefect is a defect found on a piece and the process step that caused it.
+type RequestDefect struct {
+	ID             int64   `json:"id"`
+	DefectTypeID   int64   `json:"defect_type_id"`
+	DefectTypeName string  `json:"defect_type_name,omitempty"`
+	OrderProcessID int64   `json:"order_process_id"`
+	ProcessID      *int64  `json:"process_id,omitempty"`
+	ProcessName    *string `json:"process_name,omitempty"`
+}
+
+// LineQCSummary counts the pieces checked on a line request.
+type LineQCSummary struct {
+	Request *LineRequest `json:"request"`
+	Checked int          `json:"checked"`
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Rework  int          `json:"rework"`
+}
+
+// ReworkFilter narrows down the rework report. Nil times leave the range open.
+type ReworkFilter struct {
+	From   *time.Time
+	To     *time.Time
+	LineID *int64
+}
+
+// ReworkStat is the rework rate and turnaround of a line or a process.
+// Rework rate is the share of checked pieces that went to rework; the
+// turnaround only covers pieces that came back.
+type ReworkStat struct {
+	ID                   int64   `json:"id"`
+	Name                 string  `json:"name"`
+	Checked              int     `json:"checked"`
+	Reworked             int     `json:"reworked"`
+	Returned             int     `json:"returned"`
+	Open                 int     `json:"open"`
+	ReworkRate           float64 `json:"rework_rate"`
+	AvgTurnaroundMinutes float64 `json:"avg_turnaround_minutes"`
+}
+
+type ReworkReport struct {
+	ByLine    []ReworkStat `json:"by_line"`
+	ByProcess []ReworkStat `json:"by_process"`
+}
+
+// DefectFilter narrows down the defect reports. Nil and empty fields are not filtered.
+type DefectFilter struct {
+	From    *time.Time
+	To      *time.Time
+	LineID  *int64
+	OrderID *int64
+	Style   string
+}
+
+// DefectParetoItem is a defect type ranked by how often it was found.
+type DefectParetoItem struct {
+	DefectTypeID         int64   `json:"defect_type_id"`
+	Name                 string  `json:"name"`
+	Count                int     `json:"count"`
+	Percentage           float64 `json:"percentage"`
+	CumulativePercentage float64 `json:"cumulative_percentage"`
+}
+
+// DefectProcessStat counts the defects of a type caused by an order process,
+// with the operators assigned to the process on the line. Defects recorded
+// with only a process have OrderProcessID 0 and are counted per ProcessID.
+type DefectProcessStat struct {
+	OrderProcessID int64            `json:"order_process_id"`
+	ProcessID      *int64           `json:"process_id"`
+	Name           string           `json:"name"`
+	Count          int              `json:"count"`
+	Operators      []DefectOperator `json:"operators"`
+}
+
+// DefectProcessKey identifies a DefectProcessStat: the order process, or the
+// process for defects recorded without one.
+type DefectProcessKey struct {
+	OrderProcessID int64
+	ProcessID      int64
+}
+
+func (s DefectProcessStat) Key() DefectProcessKey {
+	if s.OrderProcessID != 0 || s.ProcessID == nil {
+		return DefectProcessKey{OrderProcessID: s.OrderProcessID}
+	}
+	return DefectProcessKey{ProcessID: *s.ProcessID}
+}
+
+type DefectOperator struct {
+	ManPowerID int64  `json:"man_power_id"`
+	Nickname   string `json:"nickname"`
+	Fullname   string `json:"fullname"`
+	Count      int    `json:"count"`
+}
+
+// EfficiencyFilter narrows down the line efficiency report.
+type EfficiencyFilter struct {
+	From   time.Time
+	To     time.Time
+	LineID *int64
+}
+
+// LineEfficiency is the output of a line request against its targets. Output
+// counts pieces that passed QC, target is the output expected at 100%
+// efficiency for the hours the line worked.
+type LineEfficiency struct {
+	RequestID     int64              `json:"request_id"`
+	RequestCode   string             `json:"request_code"`
+	LineID        int64              `json:"line_id"`
+	LineName      string             `json:"line_name"`
+	Style         string             `json:"style"`
+	ManTotal      int                `json:"man_total"`
+	TargetSMV     float64            `json:"target_smv"`
+	TargetQty     int                `json:"target_qty"`
+	TargetPerHour float64            `json:"target_per_hour"`
+	EfficiencyPeriod
+	Days []EfficiencyPeriod `json:"days"`
+}
+
+// EfficiencyPeriod is the output of a day or an hour.
+type EfficiencyPeriod struct {
+	Period        string             `json:"period,omitempty"`
+	Output        int                `json:"output"`
+	Target        float64            `json:"target"`
+	Variance      float64            `json:"variance"`
+	WorkedHours   int                `json:"worked_hours"`
+	EfficiencyPct float64            `json:"efficiency_pct"`
+	Productivity  float64            `json:"productivity"`
+	Hours         []EfficiencyPeriod `json:"hours,omitempty"`
+}
+
+// ManPower is an operator with the skill class and grade used to match
+// operators to processes.
+type ManPower struct {
+	ID        int64   `json:"id"`
+	Nickname  string  `json:"nickname"`
+	Fullname  string  `json:"fullname"`
+	ClassID   *int64  `json:"class_id,omitempty"`
+	ClassName *string `json:"class_name,omitempty"`
+	GradeID   *int64  `json:"grade_id,omitempty"`
+	GradeName *string `json:"grade_name,omitempty"`
+	IsActive  bool    `json:"is_active"`
+}
+
+// RequestProcess is an operation of a line request and the operator assigned
+// to it. Reassigning soft-deletes the row, so deleted rows are the history.
+type RequestProcess struct {
+	ID             int64      `json:"id"`
+	RequestID      int64      `json:"request_id"`
+	OrderProcessID int64      `json:"order_process_id"`
+	ProcessID      *int64     `json:"process_id,omitempty"`
+	ProcessName    string     `json:"process_name"`
+	ClassID        *int64     `json:"class_id,omitempty"`
+	ClassName      *string    `json:"class_name,omitempty"`
+	ManPower       *ManPower  `json:"man_power,omitempty"`
+	AssignedBy     *int64     `json:"assigned_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// PackingGate is a packing check point of a factory.
+type PackingGate struct {
+	ID          int64   `json:"id"`
+	FactoryID   int64   `json:"factory_id"`
+	FactoryName string  `json:"factory_name"`
+	Gate        string  `json:"gate"`
+	Link        *string `json:"link,omitempty"`
+}
+
+// PackingSummary is the packed quantity of a QR bundle against its amount.
+// The amount tolerance is a percentage on top of the amount.
+type PackingSummary struct {
+	QRSystemID      int64         `json:"qr_system_id"`
+	Code            string        `json:"code"`
+	OrderID         int64         `json:"order_id"`
+	Color           string        `json:"color"`
+	Size            *string       `json:"size,omitempty"`
+	Amount          float64       `json:"amount"`
+	AmountTolerance *float64      `json:"amount_tolerance,omitempty"`
+	MaxAmount       float64       `json:"max_amount"`
+	Packed          int           `json:"packed"`
+	Remaining       float64       `json:"remaining"`
+	OverTolerance   bool          `json:"over_tolerance"`
+	Pieces          []PackedPiece `json:"pieces,omitempty"`
+}
+
+// PackedPiece traces a packed garment to the gate that packed it.
+type PackedPiece struct {
+	ItemID    int64      `json:"item_id"`
+	QRCode    string     `json:"qr_code"`
+	PackingID int64      `json:"packing_id"`
+	Gate      string     `json:"gate"`
+	PackedAt  *time.Time `json:"packed_at,omitempty"`
+}
+
+// PackingScanResult is a packed garment with the state of its bundle. Warning
+// is set when the bundle is packed over its tolerance.
+type PackingScanResult struct {
+	Item    *RequestItem    `json:"item"`
+	Summary *PackingSummary `json:"summary"`
+	Warning string          `json:"warning,omitempty"`
+}
+
+// Numbering resets as stored in setting_numberings.clause.
+const (
+	NumberingResetNever   = "never"
+	NumberingResetDaily   = "daily"
+	NumberingResetMonthly = "monthly"
+	NumberingResetYearly  = "yearly"
+)
+
+// Numbering is a document number definition. Sequence is the last number
+// handed out; UpdatedAt tells the period it was handed out in.
+type Numbering struct {
+	ID          int64      `json:"id"`
+	Module      *string    `json:"module,omitempty"`
+	For         *string    `json:"for,omitempty"`
+	Format      *string    `json:"format,omitempty"`
+	Prefix      *string    `json:"prefix,omitempty"`
+	Clause      *string    `json:"clause,omitempty"`
+	Sequence    int64      `json:"sequence"`
+	IsIncrement bool       `json:"is_increment"`
+	IsActive    bool       `json:"is_active"`
+	Example     *string    `json:"example,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	// LastAllocatedAt is when the last number was handed out. Editing the
+	// numbering does not change it, so it decides when the sequence resets.
+	LastAllocatedAt *time.Time `json:"last_allocated_at,omitempty"`
+}
+
+// NumberingPreview is the number the next allocation would return.
+type NumberingPreview struct {
+	Format  string  `json:"format"`
+	Next    string  `json:"next"`
+	Example *string `json:"example,omitempty"`
+}
+
+// StatusLog is a status change of a document, stored in setting_status_logs
+// against its Laravel model (loggable_type) and id.
+type StatusLog struct {
+	ID            int64      `json:"id"`
+	Module        string     `json:"module"`
+	LoggableType  string     `json:"loggable_type"`
+	LoggableID    int64      `json:"loggable_id"`
+	Status        string     `json:"status"`
+	Action        *string    `json:"action,omitempty"`
+	Description   *string    `json:"description,omitempty"`
+	Notes         *string    `json:"notes,omitempty"`
+	CreatedBy     *int64     `json:"created_by,omitempty"`
+	CreatedByName *string    `json:"created_by_name,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+}
+
+// Activity is an audit trail entry in activity_log. Properties holds the
+// changed fields as {"old": {...}, "attributes": {...}}.
+type Activity struct {
+	ID          int64           `json:"id"`
+	LogName     *string         `json:"log_name,omitempty"`
+	Description string          `json:"description"`
+	SubjectType *string         `json:"subject_type,omitempty"`
+	SubjectID   *int64          `json:"subject_id,omitempty"`
+	Event       *string         `json:"event,omitempty"`
+	CauserType  *string         `json:"causer_type,omitempty"`
+	CauserID    *int64          `json:"causer_id,omitempty"`
+	CauserName  *string         `json:"causer_name,omitempty"`
+	Properties  json.RawMessage `json:"properties,omitempty"`
+	BatchUUID   *string         `json:"batch_uuid,omitempty"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+}
+
+// ActivityFilter narrows down the audit trail. Nil and empty fields are not
+// filtered on.
+type ActivityFilter struct {
+	CauserID    *int64
+	SubjectType string
+	SubjectID   *int64
+	Event       string
+	LogName     string
+	BatchUUID   string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+// Exception is an unhandled error or panic captured from a request.
+type Exception struct {
+	ID             int64           `json:"id"`
+	UserID         *int64          `json:"user_id,omitempty"`
+	UserName       *string         `json:"user_name,omitempty"`
+	URL            string          `json:"url"`
+	Method         string          `json:"method"`
+	IPAddress      *string         `json:"ip_address,omitempty"`
+	RequestData    json.RawMessage `json:"request_data,omitempty"`
+	ExceptionClass string          `json:"exception_class"`
+	StatusCode     int             `json:"status_code"`
+	Message        string          `json:"message"`
+	File           string          `json:"file"`
+	Line           int             `json:"line"`
+	Trace          string          `json:"trace,omitempty"`
+	CallerFile     *string         `json:"caller_file,omitempty"`
+	CallerLine     *int            `json:"caller_line,omitempty"`
+	CallerFunction *string         `json:"caller_function,omitempty"`
+	CreatedAt      *time.Time      `json:"created_at,omitempty"`
+}
+
+// ExceptionFilter narrows down the captured exceptions. Nil and empty fields
+// are not filtered on.
+type ExceptionFilter struct {
+	UserID         *int64
+	StatusCode     int
+	Method         string
+	ExceptionClass string
+	Search         string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// Notification is an in-app notification for a user. Data holds the message
+// and any details of the event as JSON.
+type Notification struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	UserID    int64           `json:"-"`
+}
+
+// NotificationRecipient is a user that a setting_notifications rule sends a
+// module's notifications to, with the body template of that rule.
+type NotificationRecipient struct {
+	UserID int64
+	Body   string
+}
+
+// Email statuses in setting_email_logs, which doubles as the outbox.
+const (
+	EmailStatusQueued  = "queue"
+	EmailStatusSending = "send"
+	EmailStatusRetry   = "retry"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "fail"
+)
+
+// EmailConfig is a setting_email_configs row: the sender, fixed recipients
+// and subject of the emails of a module action.
+type EmailConfig struct {
+	ID            int64   `json:"id"`
+	AppName       *string `json:"app_name,omitempty"`
+	ModuleName    *string `json:"module_name,omitempty"`
+	From          *string `json:"from,omitempty"`
+	To            *string `json:"to,omitempty"`
+	Cc            *string `json:"cc,omitempty"`
+	Bcc           *string `json:"bcc,omitempty"`
+	Action        *string `json:"action,omitempty"`
+	Subject       *string `json:"subject,omitempty"`
+	SupportedBy   *string `json:"supported_by,omitempty"`
+	SupportedLink *string `json:"supported_link,omitempty"`
+}
+
+// EmailLog is an outgoing email in setting_email_logs with its delivery
+// attempts.
+type EmailLog struct {
+	ID         int64      `json:"id"`
+	AppName    *string    `json:"app_name,omitempty"`
+	ModuleName *string    `json:"module_name,omitempty"`
+	ActionName *string    `json:"action_name,omitempty"`
+	DocType    string     `json:"doc_type"`
+	DocID      int64      `json:"doc_id"`
+	Subject    string     `json:"subject"`
+	To         string     `json:"to"`
+	Cc         *string    `json:"cc,omitempty"`
+	Bcc        *string    `json:"bcc,omitempty"`
+	ActionTime *time.Time `json:"action_time,omitempty"`
+	SendTime   *time.Time `json:"send_time,omitempty"`
+	Status     string     `json:"status"`
+	Recipients *string    `json:"recipients,omitempty"`
+	DocURL     *string    `json:"doc_url,omitempty"`
+	DataBody   string     `json:"data_body"`
+	Attempt    int        `json:"attempt"`
+	ErrorMsg   *string    `json:"error_msg,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// Webhook event types sent to subscriptions.
+const (
+	WebhookStageMoved = "fabric.stage_moved"
+	WebhookRelocated  = "fabric.relocated"
+	WebhookReceived   = "fabric.received"
+	WebhookDelivered  = "fabric.delivered"
+	WebhookQCResult   = "fabric.qc_result"
+)
+
+// WebhookEvents lists the event types a subscription can choose from.
+var WebhookEvents = []string{WebhookStageMoved, WebhookRelocated, WebhookReceived, WebhookDelivered, WebhookQCResult}
+
+func IsValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses. Dead deliveries ran out of attempts and are only
+// sent again on request.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySending   = "sending"
+	WebhookDeliveryRetry     = "retry"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription is an endpoint that receives the chosen event types.
+// The secret signs the deliveries and is only shown when it is created.
+type WebhookSubscription struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	URL       string     `json:"url"`
+	Secret    string     `json:"secret,omitempty"`
+	Events    []string   `json:"events"`
+	IsActive  bool       `json:"is_active"`
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// WebhookEvent is an inventory event in the outbox.
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookDelivery is the sending of one event to one subscription.
+type WebhookDelivery struct {
+	ID               int64           `json:"id"`
+	EventID          int64           `json:"webhook_event_id"`
+	SubscriptionID   int64           `json:"webhook_subscription_id"`
+	SubscriptionName string          `json:"subscription_name,omitempty"`
+	URL              string          `json:"url,omitempty"`
+	EventType        string          `json:"event_type"`
+	Payload          json.RawMessage `json:"payload,omitempty"`
+	Status           string          `json:"status"`
+	Attempt          int             `json:"attempt"`
+	NextAttemptAt    *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus   *int            `json:"response_status,omitempty"`
+	ErrorMsg         *string         `json:"error_msg,omitempty"`
+	DeliveredAt      *time.Time      `json:"delivered_at,omitempty"`
+	EventCreatedAt   time.Time       `json:"event_created_at"`
+	CreatedAt        *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time      `json:"updated_at,omitempty"`
+	// Secret signs the delivery; it is only loaded for sending.
+	Secret string `json:"-"`
+}
+
+type WebhookDeliveryFilter struct {
+	Status         string
+	SubscriptionID *int64
+	EventType      string
+	Limit          int
+	Offset         int
+}
+
+// Job is a row of the Laravel-style jobs table. Times are unix seconds there.
+type Job struct {
+	ID          int64
+	Queue       string
+	Payload     string
+	Attempts    int
+	ReservedAt  *int64
+	AvailableAt int64
+	CreatedAt   int64
+}
+
+// JobPayload is the JSON stored in jobs.payload, using Laravel's key names so
+// both apps can read each other's queues and failed jobs.
+type JobPayload struct {
+	UUID        string          `json:"uuid"`
+	DisplayName string          `json:"displayName"`
+	Job         string          `json:"job"`
+	MaxTries    int             `json:"maxTries,omitempty"`
+	Timeout     int             `json:"timeout,omitempty"`
+	BatchID     string          `json:"batchId,omitempty"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// JobBatch tracks a group of jobs in job_batches. A batch is finished once
+// every job has either succeeded or failed for good.
+type JobBatch struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	TotalJobs     int        `json:"total_jobs"`
+	PendingJobs   int        `json:"pending_jobs"`
+	FailedJobs    int        `json:"failed_jobs"`
+	FailedJobIDs  []string   `json:"failed_job_ids"`
+	ProcessedJobs int        `json:"processed_jobs"`
+	Progress      int        `json:"progress"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ActivityHandler struct {
+	service *service.ActivityService
+}
+
+func NewActivityHandler(svc *service.ActivityService) *ActivityHandler {
+	return &ActivityHandler{service: svc}
+}
+
+// List handles GET /activity-logs?causer_id=&subject_type=&subject_id=&event=&log_name=&batch_uuid=&from=YYYY-MM-DD&to=YYYY-MM-DD&limit=&offset=
+func (h *ActivityHandler) List(c *gin.Context) {
+	errs := map[string][]string{}
+	filter := domain.ActivityFilter{
+		CauserID:    parseOptionalID(c, "causer_id", errs),
+		SubjectType: strings.TrimSpace(c.Query("subject_type")),
+		SubjectID:   parseOptionalID(c, "subject_id", errs),
+		Event:       strings.TrimSpace(c.Query("event")),
+		LogName:     strings.TrimSpace(c.Query("log_name")),
+		BatchUUID:   strings.TrimSpace(c.Query("batch_uuid")),
+	}
+	filter.From, filter.To = parseDateRange(c, errs)
+	filter.Limit = parseQueryInt(c, "limit", errs)
+	filter.Offset = parseQueryInt(c, "offset", errs)
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	activities, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch activity log.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched activity log.", activities)
+}
+
+func parseQueryInt(c *gin.Context, param string, errs map[string][]string) int {
+	raw := c.Query(param)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		errs[param] = []string{"The " + param + " must be a positive number."}
+		return 0
+	}
+	return n
+}
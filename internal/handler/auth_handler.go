@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/dppi/dppierp-api/internal/service"
@@ -8,11 +9,12 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService   *service.AuthService
+	accessService *service.AccessService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, accessService *service.AccessService) *AuthHandler {
+	return &AuthHandler{authService: authService, accessService: accessService}
 }
 
 type LoginRequest struct {
@@ -31,8 +33,10 @@ type LoginResponse struct {
 		FullName  string `json:"full_name"`
 	} `json:"user_info"`
 	HasAccess struct {
-		UserID int64  `json:"user_id"`
-		Role   string `json:"role"`
+		UserID      int64    `json:"user_id"`
+		Role        string   `json:"role"`
+		Roles       []string `json:"roles"`
+		Permissions []string `json:"permissions"`
 	} `json:"has_access"`
 }
 
@@ -78,6 +82,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	access, err := h.accessService.Access(c.Request.Context(), user.ID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to load roles and permissions.", err)
+		return
+	}
+
 	response := LoginResponse{
 		TokenType:       "Bearer",
 		AccessToken:     token,
@@ -94,8 +104,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		response.UserInfo.PhotoPath = "http://127.0.0.1:8000/images/avatar.png"
 	}
 
+	// role is the first role the user was given, kept for older clients
 	response.HasAccess.UserID = user.ID
-	response.HasAccess.Role = "superadmin"
+	response.HasAccess.Roles = access.Roles
+	response.HasAccess.Permissions = access.Permissions
+	if len(access.Roles) > 0 {
+		response.HasAccess.Role = access.Roles[0]
+	}
 
 	SuccessResponse(c, http.StatusOK, "Successfully logged.", response)
 }
@@ -106,10 +121,18 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	email, _ := c.Get("email")
 	name, _ := c.Get("name")
 
+	access, err := h.accessService.Access(c.Request.Context(), userID.(int64))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to load roles and permissions.", err)
+		return
+	}
+
 	SuccessResponse(c, http.StatusOK, "User fetched successfully.", gin.H{
-		"id":    userID,
-		"email": email,
-		"name":  name,
+		"id":          userID,
+		"email":       email,
+		"name":        name,
+		"roles":       access.Roles,
+		"permissions": access.Permissions,
 	})
 }
 
@@ -124,13 +147,24 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	newToken, newRefreshToken, err := h.authService.RefreshToken(req.RefreshToken)
-	if err != nil {
+	if errors.Is(err, service.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Refresh token has already been used. Please log in again.",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status":  "error",
 			"message": "Invalid refresh token",
 		})
 		return
 	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token.", err)
+		return
+	}
 
 	SuccessResponse(c, http.StatusOK, "Token refreshed successfully.", gin.H{
 		"access_token":  newToken,
@@ -142,7 +176,10 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 // Logout handles POST /auth/logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	h.authService.Logout()
+	if err := h.authService.Logout(c.GetString("session_id")); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to log out.", err)
+		return
+	}
 	SuccessResponse(c, http.StatusOK, "Successfully logged out.", nil)
 }
 
@@ -157,7 +194,7 @@ func (h *AuthHandler) ForgotPasswordRequest(c *gin.Context) {
 	}
 
 	if err := h.authService.ForgotPasswordRequest(req.Email); err != nil {
-		ErrorResponse(c, http.StatusBadRequest, "Failed to process request", err.Error())
+		passwordErrorResponse(c, "Failed to process request", err, service.ErrEmailNotFound)
 		return
 	}
 
@@ -177,7 +214,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := h.authService.ResetPassword(req.Email, req.Token, req.Password); err != nil {
-		ErrorResponse(c, http.StatusBadRequest, "Failed to reset password", err.Error())
+		passwordErrorResponse(c, "Failed to reset password", err, service.ErrInvalidResetToken, service.ErrUserNotFound)
 		return
 	}
 
@@ -198,9 +235,21 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	if err := h.authService.ChangePassword(userID.(int64), req.CurrentPassword, req.Password); err != nil {
-		ErrorResponse(c, http.StatusBadRequest, "Failed to change password", err.Error())
+		passwordErrorResponse(c, "Failed to change password", err, service.ErrUserNotFound, service.ErrInvalidOldPassword)
 		return
 	}
 
 	SuccessResponse(c, http.StatusOK, "Password changed successfully.", nil)
 }
+
+// passwordErrorResponse answers with 422 when err is one of the expected
+// errors and with a server error otherwise.
+func passwordErrorResponse(c *gin.Context, message string, err error, expected ...error) {
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			ErrorResponse(c, http.StatusUnprocessableEntity, message, err.Error())
+			return
+		}
+	}
+	ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+}
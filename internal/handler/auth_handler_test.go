@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -27,6 +28,50 @@ func (m *mockUserRepo) FindByEmail(email string) (*domain.User, error) {
 	return nil, nil
 }
 
+func (m *mockUserRepo) FindByUsername(username string) (*domain.User, error) {
+	if m.user != nil && m.user.Name == username {
+		return m.user, nil
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) FindByID(id int64) (*domain.User, error) {
+	if m.user != nil && m.user.ID == id {
+		return m.user, nil
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) UpdatePassword(userID int64, newHash string) error { return nil }
+func (m *mockUserRepo) StoreResetToken(email, token string) error         { return nil }
+func (m *mockUserRepo) GetResetToken(email string) (string, error)        { return "", nil }
+func (m *mockUserRepo) DeleteResetToken(email string) error               { return nil }
+func (m *mockUserRepo) HasPermission(userID int64, permission string) (bool, error) {
+	return false, nil
+}
+func (m *mockUserRepo) FindAccess(userID int64) (*domain.UserAccess, error) {
+	return &domain.UserAccess{Roles: []string{"superadmin"}, Permissions: []string{}}, nil
+}
+
+// mockRefreshTokenRepo accepts every refresh token without keeping it.
+type mockRefreshTokenRepo struct{}
+
+func (m *mockRefreshTokenRepo) Create(ctx context.Context, token *domain.RefreshToken) error {
+	return nil
+}
+func (m *mockRefreshTokenRepo) FindByTokenID(ctx context.Context, tokenID string) (*domain.RefreshToken, error) {
+	return nil, nil
+}
+func (m *mockRefreshTokenRepo) Rotate(ctx context.Context, used, next *domain.RefreshToken) error {
+	return nil
+}
+func (m *mockRefreshTokenRepo) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	return nil
+}
+func (m *mockRefreshTokenRepo) RevokeUser(ctx context.Context, userID int64, reason string) error {
+	return nil
+}
+
 func TestAuthHandler_Login_Structure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -44,15 +89,15 @@ func TestAuthHandler_Login_Structure(t *testing.T) {
 		},
 	}
 	authMiddleware := middleware.NewAuthMiddleware("secret-key-secret-key-secret-key-32")
-	authService := service.NewAuthService(mockRepo, authMiddleware)
-	authHandler := NewAuthHandler(authService)
+	authService := service.NewAuthService(mockRepo, &mockRefreshTokenRepo{}, authMiddleware, nil, nil)
+	authHandler := NewAuthHandler(authService, service.NewAccessService(mockRepo, nil, 0))
 
 	// Setup Router
 	r := gin.New()
 	r.POST("/auth/login", authHandler.Login)
 
 	// Create Request
-	reqBody := `{"email": "admin@dppi.com", "password": "password123"}`
+	reqBody := `{"username": "Super Admin", "password": "password123"}`
 	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -108,3 +153,40 @@ func TestAuthHandler_Login_Structure(t *testing.T) {
 		t.Errorf("Expected role 'superadmin', got '%s'", response.Data.HasAccess.Role)
 	}
 }
+
+func TestAuthHandler_PasswordErrorsAreUnprocessable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockRepo := &mockUserRepo{user: &domain.User{ID: 1, Name: "Super Admin", Email: "admin@dppi.com", Password: string(hashedPassword)}}
+	authService := service.NewAuthService(mockRepo, &mockRefreshTokenRepo{}, middleware.NewAuthMiddleware("secret-key-secret-key-secret-key-32"), nil, nil)
+	authHandler := NewAuthHandler(authService, service.NewAccessService(mockRepo, nil, 0))
+
+	r := gin.New()
+	r.POST("/auth/forgot-password/reset", authHandler.ResetPassword)
+	r.POST("/profile/change-password", func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		authHandler.ChangePassword(c)
+	})
+
+	tests := []struct {
+		path string
+		body string
+		want error
+	}{
+		{"/auth/forgot-password/reset", `{"email": "admin@dppi.com", "token": "wrong", "password": "newpassword", "password_confirmation": "newpassword"}`, service.ErrInvalidResetToken},
+		{"/profile/change-password", `{"current_password": "wrong", "password": "newpassword", "password_confirmation": "newpassword"}`, service.ErrInvalidOldPassword},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var response Response
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusUnprocessableEntity || response.Errors != tt.want.Error() {
+			t.Errorf("%s: expected the error with status 422, got %d (%s)", tt.path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type BuyerHandler struct {
+	service *service.BuyerService
+}
+
+func NewBuyerHandler(svc *service.BuyerService) *BuyerHandler {
+	return &BuyerHandler{service: svc}
+}
+
+// List handles GET /buyers?search=&status=
+func (h *BuyerHandler) List(c *gin.Context) {
+	filter, ok := parsePartnerFilter(c)
+	if !ok {
+		return
+	}
+
+	buyers, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch buyers.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched buyers.", buyers)
+}
+
+// Get handles GET /buyers/:id
+func (h *BuyerHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	buyer, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to fetch buyer.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched buyer.", buyer)
+}
+
+// Create handles POST /buyers
+func (h *BuyerHandler) Create(c *gin.Context) {
+	var req PartnerRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	buyer := newBuyer(&req)
+	if err := h.service.Create(c.Request.Context(), buyer); err != nil {
+		partnerErrorResponse(c, "Failed to create buyer.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created buyer.", buyer)
+}
+
+// Update handles PUT /buyers/:id
+func (h *BuyerHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req PartnerRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	buyer, err := h.service.Update(c.Request.Context(), id, newBuyer(&req))
+	if err != nil {
+		partnerErrorResponse(c, "Failed to update buyer.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated buyer.", buyer)
+}
+
+// ToggleStatus handles POST /buyers/:id/toggle-status
+func (h *BuyerHandler) ToggleStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	buyer, err := h.service.ToggleStatus(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to change buyer status.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully changed buyer status.", buyer)
+}
+
+// Delete handles DELETE /buyers/:id
+func (h *BuyerHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		partnerErrorResponse(c, "Failed to delete buyer.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted buyer.", true)
+}
+
+func newBuyer(req *PartnerRequest) *domain.Buyer {
+	return &domain.Buyer{
+		Code:          req.Code,
+		Name:          req.Name,
+		ContactPerson: trimmedOrNil(req.ContactPerson),
+		Phone:         trimmedOrNil(req.Phone),
+		Address:       trimmedOrNil(req.Address),
+		Email:         trimmedOrNil(req.Email),
+		City:          trimmedOrNil(req.City),
+		Country:       trimmedOrNil(req.Country),
+		Status:        req.status(),
+		Remarks:       trimmedOrNil(req.Remarks),
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/dppi/dppierp-api/internal/service"
@@ -39,7 +40,11 @@ func (h *CheckpointHandler) ScanQR(c *gin.Context) {
 
 	result, err := h.service.ScanQR(c.Request.Context(), req.Code)
 	if err != nil {
-		ErrorResponse(c, http.StatusNotFound, "Failed to founded QR.", err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrFabricNotFound) {
+			status = http.StatusNotFound
+		}
+		ErrorResponse(c, status, "Failed to founded QR.", err.Error())
 		return
 	}
 
@@ -109,9 +114,12 @@ func (h *CheckpointHandler) MoveStage(c *gin.Context) {
 		RelaxationRackID:  req.RelaxationRackID,
 		Entries:           req.Entries,
 	}
+	if userID, ok := c.Get("user_id"); ok {
+		svcReq.UserID = userID.(int64)
+	}
 
 	if err := h.service.MoveStage(c.Request.Context(), svcReq); err != nil {
-		ErrorResponse(c, http.StatusUnprocessableEntity, "Failed to moved items.", err.Error())
+		checkpointErrorResponse(c, "Failed to moved items.", err)
 		return
 	}
 
@@ -129,7 +137,11 @@ func (h *CheckpointHandler) ScanRack(c *gin.Context) {
 
 	result, err := h.service.ScanRack(c.Request.Context(), req.Code)
 	if err != nil {
-		ErrorResponse(c, http.StatusNotFound, "Failed to founded Rack QR.", err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrRackNotFound) {
+			status = http.StatusNotFound
+		}
+		ErrorResponse(c, status, "Failed to founded Rack QR.", err.Error())
 		return
 	}
 
@@ -164,9 +176,27 @@ func (h *CheckpointHandler) Relocate(c *gin.Context) {
 	}
 
 	if err := h.service.Relocate(c.Request.Context(), svcReq); err != nil {
-		ErrorResponse(c, http.StatusUnprocessableEntity, "Failed to relocated items.", err.Error())
+		checkpointErrorResponse(c, "Failed to relocated items.", err)
 		return
 	}
 
 	SuccessResponse(c, http.StatusOK, "Successfully relocated items.", true)
 }
+
+// checkpointErrorResponse answers a failed move or relocation. Errors the
+// client can fix are 422; anything else is a server error.
+func checkpointErrorResponse(c *gin.Context, message string, err error) {
+	var forbidden *service.StagePermissionError
+	switch {
+	case errors.As(err, &forbidden):
+		ErrorResponse(c, http.StatusForbidden, message, gin.H{
+			"message":     forbidden.Error(),
+			"permissions": []string{forbidden.Permission},
+		})
+	case errors.Is(err, service.ErrInvalidStage), errors.Is(err, service.ErrDestroyStage), errors.Is(err, service.ErrNoMoveEntries),
+		errors.Is(err, service.ErrFabricNotFound), errors.Is(err, service.ErrRackEmpty):
+		ErrorResponse(c, http.StatusUnprocessableEntity, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DefectReportHandler struct {
+	service *service.DefectReportService
+}
+
+func NewDefectReportHandler(svc *service.DefectReportService) *DefectReportHandler {
+	return &DefectReportHandler{service: svc}
+}
+
+// Pareto handles GET /garment-qc/v1/defect-pareto?from=&to=&line_id=&order_id=&style=
+func (h *DefectReportHandler) Pareto(c *gin.Context) {
+	filter, ok := parseDefectFilter(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.service.Pareto(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch defect pareto.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched defect pareto.", items)
+}
+
+// Processes handles GET /garment-qc/v1/defect-pareto/:defect_type_id/processes
+func (h *DefectReportHandler) Processes(c *gin.Context) {
+	defectTypeID, ok := parseIDParam(c, "defect_type_id")
+	if !ok {
+		return
+	}
+	filter, ok := parseDefectFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.Processes(c.Request.Context(), defectTypeID, filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch defect processes.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched defect processes.", stats)
+}
+
+func parseDefectFilter(c *gin.Context) (domain.DefectFilter, bool) {
+	filter := domain.DefectFilter{Style: c.Query("style")}
+	errs := map[string][]string{}
+
+	filter.From, filter.To = parseDateRange(c, errs)
+	filter.LineID = parseOptionalID(c, "line_id", errs)
+	filter.OrderID = parseOptionalID(c, "order_id", errs)
+
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return filter, false
+	}
+	return filter, true
+}
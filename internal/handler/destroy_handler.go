@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxDestroyPhotos    = 5
+	maxDestroyPhotoSize = 5 << 20 // 5 MB
+)
+
+var allowedPhotoExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+}
+
+type DestroyHandler struct {
+	service *service.DestroyService
+}
+
+func NewDestroyHandler(svc *service.DestroyService) *DestroyHandler {
+	return &DestroyHandler{service: svc}
+}
+
+type ReviewDestroyRequest struct {
+	Notes string `json:"notes"`
+}
+
+// List handles GET /check-point/v1/destroy-requests
+func (h *DestroyHandler) List(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != domain.DestroyStatusPending && status != domain.DestroyStatusApproved && status != domain.DestroyStatusRejected {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"status": {"The status must be pending, approved or rejected."},
+		})
+		return
+	}
+
+	requests, err := h.service.List(c.Request.Context(), status)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch destroy requests.", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully fetched destroy requests.", requests)
+}
+
+// Get handles GET /check-point/v1/destroy-requests/:id
+func (h *DestroyHandler) Get(c *gin.Context) {
+	id, ok := parseDestroyRequestID(c)
+	if !ok {
+		return
+	}
+
+	req, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		h.reviewErrorResponse(c, "Failed to fetch destroy request.", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully fetched destroy request.", req)
+}
+
+// Create handles POST /check-point/v1/destroy-requests (multipart/form-data)
+func (h *DestroyHandler) Create(c *gin.Context) {
+	code := strings.TrimSpace(c.PostForm("code"))
+	reason := strings.TrimSpace(c.PostForm("reason"))
+
+	errs := map[string][]string{}
+	if code == "" {
+		errs["code"] = []string{"The QR code is required."}
+	}
+	if reason == "" {
+		errs["reason"] = []string{"The reason field is required."}
+	}
+
+	var req service.CreateDestroyRequest
+	form, err := c.MultipartForm()
+	if err == nil {
+		req.Photos = form.File["photos"]
+	}
+
+	switch {
+	case len(req.Photos) == 0:
+		errs["photos"] = []string{"At least one photo is required."}
+	case len(req.Photos) > maxDestroyPhotos:
+		errs["photos"] = []string{"A maximum of 5 photos is allowed."}
+	default:
+		for _, photo := range req.Photos {
+			if !allowedPhotoExtensions[strings.ToLower(filepath.Ext(photo.Filename))] {
+				errs["photos"] = []string{"Photos must be jpg, jpeg or png files."}
+				break
+			}
+			if photo.Size > maxDestroyPhotoSize {
+				errs["photos"] = []string{"Each photo may not be greater than 5 MB."}
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	req.Code = code
+	req.Reason = reason
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.Create(c.Request.Context(), userID.(int64), &req)
+	if err != nil {
+		h.createErrorResponse(c, "Failed to request destroy.", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, "Successfully requested destroy.", result)
+}
+
+// Approve handles POST /check-point/v1/destroy-requests/:id/approve
+func (h *DestroyHandler) Approve(c *gin.Context) {
+	id, ok := parseDestroyRequestID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewDestroyRequest
+	_ = c.ShouldBindJSON(&req)
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.Approve(c.Request.Context(), userID.(int64), id, strings.TrimSpace(req.Notes)); err != nil {
+		h.reviewErrorResponse(c, "Failed to approve destroy request.", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully approved destroy request.", true)
+}
+
+// Reject handles POST /check-point/v1/destroy-requests/:id/reject
+func (h *DestroyHandler) Reject(c *gin.Context) {
+	id, ok := parseDestroyRequestID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewDestroyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Notes) == "" {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"notes": {"The notes field is required when rejecting."},
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.Reject(c.Request.Context(), userID.(int64), id, strings.TrimSpace(req.Notes)); err != nil {
+		h.reviewErrorResponse(c, "Failed to reject destroy request.", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully rejected destroy request.", true)
+}
+
+func (h *DestroyHandler) reviewErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrDestroyRequestNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrDestroyForbidden), errors.Is(err, service.ErrDestroySelfApproval):
+		ErrorResponse(c, http.StatusForbidden, message, err.Error())
+	case errors.Is(err, service.ErrDestroyRequestNotPending):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+func (h *DestroyHandler) createErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrFabricNotFound), errors.Is(err, service.ErrFabricDestroyed), errors.Is(err, service.ErrDestroyRequestPending):
+		ErrorResponse(c, http.StatusUnprocessableEntity, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+func parseDestroyRequestID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"id": {"The destroy request id is invalid."},
+		})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type EfficiencyHandler struct {
+	service *service.EfficiencyService
+}
+
+func NewEfficiencyHandler(svc *service.EfficiencyService) *EfficiencyHandler {
+	return &EfficiencyHandler{service: svc}
+}
+
+// Report handles GET /garment-qc/v1/efficiency?from=YYYY-MM-DD&to=YYYY-MM-DD&line_id=
+// Without dates it covers today; a single date covers that day.
+func (h *EfficiencyHandler) Report(c *gin.Context) {
+	errs := map[string][]string{}
+	from, to := parseDateRange(c, errs)
+	lineID := parseOptionalID(c, "line_id", errs)
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	filter := domain.EfficiencyFilter{LineID: lineID}
+	if from != nil {
+		filter.From = *from
+	} else {
+		now := time.Now()
+		filter.From = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	}
+	if to != nil {
+		filter.To = *to
+	} else {
+		filter.To = filter.From.AddDate(0, 0, 1)
+	}
+
+	report, err := h.service.Report(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrEfficiencyRange) {
+			ValidationErrorResponse(c, "Validation error.", map[string][]string{
+				"to": {"The report range must be between 1 and 31 days."},
+			})
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch line efficiency.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched line efficiency.", report)
+}
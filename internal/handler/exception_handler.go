@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ExceptionHandler struct {
+	service *service.ExceptionService
+}
+
+func NewExceptionHandler(svc *service.ExceptionService) *ExceptionHandler {
+	return &ExceptionHandler{service: svc}
+}
+
+// List handles GET /exceptions?user_id=&status_code=&method=&exception_class=&search=&from=YYYY-MM-DD&to=YYYY-MM-DD&limit=&offset=
+func (h *ExceptionHandler) List(c *gin.Context) {
+	errs := map[string][]string{}
+	filter := domain.ExceptionFilter{
+		UserID:         parseOptionalID(c, "user_id", errs),
+		StatusCode:     parseQueryInt(c, "status_code", errs),
+		Method:         strings.ToUpper(strings.TrimSpace(c.Query("method"))),
+		ExceptionClass: strings.TrimSpace(c.Query("exception_class")),
+		Search:         strings.TrimSpace(c.Query("search")),
+	}
+	filter.From, filter.To = parseDateRange(c, errs)
+	filter.Limit = parseQueryInt(c, "limit", errs)
+	filter.Offset = parseQueryInt(c, "offset", errs)
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	exceptions, err := h.service.List(c.Request.Context(), userID.(int64), filter)
+	if err != nil {
+		exceptionErrorResponse(c, "Failed to fetch exceptions.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched exceptions.", exceptions)
+}
+
+// Get handles GET /exceptions/:reference, accepting "EXC-42" or 42.
+func (h *ExceptionHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	exception, err := h.service.Get(c.Request.Context(), userID.(int64), c.Param("reference"))
+	if err != nil {
+		exceptionErrorResponse(c, "Failed to fetch exception.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched exception.", exception)
+}
+
+func exceptionErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrExceptionNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrExceptionForbidden):
+		ErrorResponse(c, http.StatusForbidden, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
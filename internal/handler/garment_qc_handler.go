@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type GarmentQCHandler struct {
+	service *service.GarmentQCService
+}
+
+func NewGarmentQCHandler(svc *service.GarmentQCService) *GarmentQCHandler {
+	return &GarmentQCHandler{service: svc}
+}
+
+type GarmentDefectRequest struct {
+	DefectTypeID   int64 `json:"defect_type_id"`
+	OrderProcessID int64 `json:"order_process_id"`
+}
+
+type GarmentScanRequest struct {
+	LineID  int64                  `json:"line_id"`
+	QRCode  string                 `json:"qr_code"`
+	Result  string                 `json:"result"`
+	Defects []GarmentDefectRequest `json:"defects"`
+}
+
+func (r *GarmentScanRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	if r.LineID <= 0 {
+		errs["line_id"] = []string{"The line id is required."}
+	}
+	if strings.TrimSpace(r.QRCode) == "" {
+		errs["qr_code"] = []string{"The QR code is required."}
+	}
+
+	validateGarmentResult(r.Result, r.Defects, errs)
+	return errs
+}
+
+type ReworkRequest struct {
+	Defects []GarmentDefectRequest `json:"defects"`
+}
+
+func (r *ReworkRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	validateGarmentResult(domain.GarmentResultDefect, r.Defects, errs)
+	return errs
+}
+
+type ReturnReworkRequest struct {
+	Result  string                 `json:"result"`
+	Defects []GarmentDefectRequest `json:"defects"`
+}
+
+func (r *ReturnReworkRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	validateGarmentResult(r.Result, r.Defects, errs)
+	return errs
+}
+
+// validateGarmentResult checks that defects are sent with, and only with, the
+// defect result.
+func validateGarmentResult(result string, defects []GarmentDefectRequest, errs map[string][]string) {
+	switch result {
+	case domain.GarmentResultPass, domain.GarmentResultFail:
+		if len(defects) > 0 {
+			errs["defects"] = []string{"Defects can only be sent with the defect result."}
+		}
+	case domain.GarmentResultDefect:
+		if len(defects) == 0 {
+			errs["defects"] = []string{"At least one defect is required."}
+		}
+		for i, d := range defects {
+			if d.DefectTypeID <= 0 {
+				errs[fmt.Sprintf("defects.%d.defect_type_id", i)] = []string{"The defect type is required."}
+			}
+			if d.OrderProcessID <= 0 {
+				errs[fmt.Sprintf("defects.%d.order_process_id", i)] = []string{"The process is required."}
+			}
+		}
+	default:
+		errs["result"] = []string{"The result must be pass, fail or defect."}
+	}
+}
+
+func defectInputs(defects []GarmentDefectRequest) []service.GarmentDefectInput {
+	inputs := make([]service.GarmentDefectInput, 0, len(defects))
+	for _, d := range defects {
+		inputs = append(inputs, service.GarmentDefectInput{
+			DefectTypeID:   d.DefectTypeID,
+			OrderProcessID: d.OrderProcessID,
+		})
+	}
+	return inputs
+}
+
+// Scan handles POST /garment-qc/v1/scan
+func (h *GarmentQCHandler) Scan(c *gin.Context) {
+	var req GarmentScanRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	input := service.GarmentScanInput{
+		LineID:  req.LineID,
+		QRCode:  req.QRCode,
+		Result:  req.Result,
+		Defects: defectInputs(req.Defects),
+	}
+
+	item, err := h.service.Scan(c.Request.Context(), input)
+	if err != nil {
+		garmentQCErrorResponse(c, "Failed to record QC result.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully recorded QC result.", item)
+}
+
+// SendToRework handles POST /garment-qc/v1/items/:id/rework
+func (h *GarmentQCHandler) SendToRework(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ReworkRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	item, err := h.service.SendToRework(c.Request.Context(), id, defectInputs(req.Defects))
+	if err != nil {
+		garmentQCErrorResponse(c, "Failed to send piece to rework.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully sent piece to rework.", item)
+}
+
+// ReturnFromRework handles POST /garment-qc/v1/items/:id/rework/return
+func (h *GarmentQCHandler) ReturnFromRework(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ReturnReworkRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	item, err := h.service.ReturnFromRework(c.Request.Context(), id, req.Result, defectInputs(req.Defects))
+	if err != nil {
+		garmentQCErrorResponse(c, "Failed to record rework return.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully recorded rework return.", item)
+}
+
+// ReworkReport handles GET /garment-qc/v1/rework-report?from=YYYY-MM-DD&to=YYYY-MM-DD&line_id=
+func (h *GarmentQCHandler) ReworkReport(c *gin.Context) {
+	filter, ok := parseReworkFilter(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.ReworkReport(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch rework report.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched rework report.", report)
+}
+
+// parseReworkFilter reads the date range and line of a report.
+func parseReworkFilter(c *gin.Context) (domain.ReworkFilter, bool) {
+	var filter domain.ReworkFilter
+	errs := map[string][]string{}
+
+	filter.From, filter.To = parseDateRange(c, errs)
+	filter.LineID = parseOptionalID(c, "line_id", errs)
+
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseDateRange reads the from and to query dates. The to date is inclusive,
+// so the returned end is the start of the following day.
+func parseDateRange(c *gin.Context, errs map[string][]string) (*time.Time, *time.Time) {
+	var from, to *time.Time
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			errs["from"] = []string{"The from date must use the YYYY-MM-DD format."}
+		} else {
+			from = &t
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			errs["to"] = []string{"The to date must use the YYYY-MM-DD format."}
+		} else {
+			t = t.AddDate(0, 0, 1)
+			to = &t
+		}
+	}
+	return from, to
+}
+
+func parseOptionalID(c *gin.Context, param string, errs map[string][]string) *int64 {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		errs[param] = []string{fmt.Sprintf("The %s is invalid.", strings.ReplaceAll(param, "_", " "))}
+		return nil
+	}
+	return &id
+}
+
+// Summary handles GET /garment-qc/v1/lines/:line_id/summary
+func (h *GarmentQCHandler) Summary(c *gin.Context) {
+	lineID, ok := parseIDParam(c, "line_id")
+	if !ok {
+		return
+	}
+
+	summary, err := h.service.Summary(c.Request.Context(), lineID)
+	if err != nil {
+		garmentQCErrorResponse(c, "Failed to fetch line QC summary.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched line QC summary.", summary)
+}
+
+func garmentQCErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidGarmentCode):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"qr_code": {"The QR code must look like <bundle>-<piece>."},
+		})
+	case errors.Is(err, service.ErrNoActiveRequest), errors.Is(err, service.ErrQRSystemNotFound), errors.Is(err, service.ErrGarmentItemNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrDefectTypeNotFound), errors.Is(err, service.ErrOrderProcessNotFound):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"defects": {err.Error()},
+		})
+	case errors.Is(err, service.ErrGarmentOrderMismatch), errors.Is(err, service.ErrGarmentAlreadyChecked),
+		errors.Is(err, service.ErrGarmentPacked), errors.Is(err, service.ErrGarmentInRework), errors.Is(err, service.ErrGarmentNotInRework):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -3,9 +3,11 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -90,6 +92,39 @@ func TestValidationErrorResponse(t *testing.T) {
 	}
 }
 
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no validators", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": `W/"old", "abc"`}, true},
+		{"stale etag wins over date", map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/master/blocks", nil)
+		for key, value := range tc.headers {
+			c.Request.Header.Set(key, value)
+		}
+
+		if got := NotModified(c, `"abc"`, modified); got != tc.want {
+			t.Errorf("%s: NotModified = %v, want %v", tc.name, got, tc.want)
+		}
+		if tc.want && w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected status 304, got %d", tc.name, w.Code)
+		}
+		if w.Header().Get("ETag") != `"abc"` || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+			t.Errorf("%s: expected the validators to be set, got %v", tc.name, w.Header())
+		}
+	}
+}
+
 func TestScanQRHandler_ValidationError(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -122,3 +157,69 @@ func TestMoveStageHandler_MissingStageQuery(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
 	}
 }
+
+func TestDestroyHandler_CreateRequiresPhotos(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("code", "F24120001")
+	_ = writer.WriteField("reason", "Water damage")
+	writer.Close()
+
+	c.Request, _ = http.NewRequest("POST", "/check-point/v1/destroy-requests", body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	handler := &DestroyHandler{service: nil}
+	handler.Create(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestDestroyHandler_RejectRequiresNotes(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/check-point/v1/destroy-requests/1/reject", bytes.NewBuffer([]byte(`{}`)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler := &DestroyHandler{service: nil}
+	handler.Reject(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestBuyerHandler_CreateValidation(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := `{"code": "", "name": "GAP", "email": "not-an-email", "status": 3}`
+	c.Request, _ = http.NewRequest("POST", "/buyers", bytes.NewBuffer([]byte(body)))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler := &BuyerHandler{service: nil}
+	handler.Create(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var response struct {
+		Errors map[string][]string `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	for _, field := range []string{"code", "email", "status"} {
+		if _, ok := response.Errors[field]; !ok {
+			t.Errorf("Expected a validation error for %s", field)
+		}
+	}
+	if _, ok := response.Errors["name"]; ok {
+		t.Error("Expected no validation error for name")
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type JobBatchHandler struct {
+	jobs *service.JobRunner
+}
+
+func NewJobBatchHandler(jobs *service.JobRunner) *JobBatchHandler {
+	return &JobBatchHandler{jobs: jobs}
+}
+
+// Get handles GET /jobs/batches/:id
+func (h *JobBatchHandler) Get(c *gin.Context) {
+	batch, err := h.jobs.Batch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrJobBatchNotFound) {
+			ErrorResponse(c, http.StatusNotFound, "Failed to fetch job batch.", err.Error())
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch job batch.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched job batch.", batch)
+}
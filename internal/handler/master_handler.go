@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
 	"github.com/dppi/dppierp-api/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -15,38 +21,448 @@ func NewMasterHandler(service *service.MasterService) *MasterHandler {
 	return &MasterHandler{service: service}
 }
 
+// GetBlocks handles GET /master/blocks
 func (h *MasterHandler) GetBlocks(c *gin.Context) {
-	blocks, err := h.service.GetAllBlocks()
+	h.list(c, repository.MasterBlocks, "blocks")
+}
+
+// GetRacks handles GET /master/racks
+func (h *MasterHandler) GetRacks(c *gin.Context) {
+	h.list(c, repository.MasterRacks, "racks")
+}
+
+// GetRelaxationBlocks handles GET /master/relaxation-blocks
+func (h *MasterHandler) GetRelaxationBlocks(c *gin.Context) {
+	h.list(c, repository.MasterRelaxationBlocks, "relaxation blocks")
+}
+
+// GetRelaxationRacks handles GET /master/relaxation-racks
+func (h *MasterHandler) GetRelaxationRacks(c *gin.Context) {
+	h.list(c, repository.MasterRelaxationRacks, "relaxation racks")
+}
+
+// list writes a master data list, or 304 when the client's copy is current.
+func (h *MasterHandler) list(c *gin.Context, table repository.MasterTable, label string) {
+	list, err := h.service.List(c.Request.Context(), table)
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch blocks", err.Error())
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch "+label, err)
 		return
 	}
-	SuccessResponse(c, http.StatusOK, "Successfully fetched blocks", blocks)
+	if NotModified(c, list.ETag, list.LastModified) {
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched "+label, list.Data)
 }
 
-func (h *MasterHandler) GetRacks(c *gin.Context) {
-	racks, err := h.service.GetAllRacks()
+type BlockRequest struct {
+	Name string  `json:"name"`
+	Type *string `json:"type"`
+}
+
+type RackRequest struct {
+	Name     string  `json:"name"`
+	Type     *string `json:"type"`
+	Capacity int     `json:"capacity"`
+}
+
+type RelaxationRequest struct {
+	Name string `json:"name"`
+}
+
+type UnitRequest struct {
+	Name string `json:"name"`
+}
+
+type DefectTypeRequest struct {
+	Key  *string `json:"key"`
+	Name string  `json:"name"`
+}
+
+type MovementTypeRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateBlock handles POST /master/blocks
+func (h *MasterHandler) CreateBlock(c *gin.Context) {
+	var req BlockRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	block := &domain.Block{Name: req.Name, Type: req.Type}
+	if err := h.service.CreateBlock(c.Request.Context(), block); err != nil {
+		masterErrorResponse(c, "Failed to create block.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created block.", block)
+}
+
+// UpdateBlock handles PUT /master/blocks/:id
+func (h *MasterHandler) UpdateBlock(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req BlockRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	block, err := h.service.UpdateBlock(c.Request.Context(), id, &domain.Block{Name: req.Name, Type: req.Type})
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch racks", err.Error())
+		masterErrorResponse(c, "Failed to update block.", err)
 		return
 	}
-	SuccessResponse(c, http.StatusOK, "Successfully fetched racks", racks)
+	SuccessResponse(c, http.StatusOK, "Successfully updated block.", block)
 }
 
-func (h *MasterHandler) GetRelaxationBlocks(c *gin.Context) {
-	blocks, err := h.service.GetAllRelaxationBlocks()
+// CreateRack handles POST /master/racks
+func (h *MasterHandler) CreateRack(c *gin.Context) {
+	var req RackRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	rack := &domain.Rack{Name: req.Name, Type: req.Type, Capacity: req.Capacity}
+	if err := h.service.CreateRack(c.Request.Context(), rack); err != nil {
+		masterErrorResponse(c, "Failed to create rack.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created rack.", rack)
+}
+
+// UpdateRack handles PUT /master/racks/:id
+func (h *MasterHandler) UpdateRack(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RackRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	rack, err := h.service.UpdateRack(c.Request.Context(), id, &domain.Rack{Name: req.Name, Type: req.Type, Capacity: req.Capacity})
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch relaxation blocks", err.Error())
+		masterErrorResponse(c, "Failed to update rack.", err)
 		return
 	}
-	SuccessResponse(c, http.StatusOK, "Successfully fetched relaxation blocks", blocks)
+	SuccessResponse(c, http.StatusOK, "Successfully updated rack.", rack)
 }
 
-func (h *MasterHandler) GetRelaxationRacks(c *gin.Context) {
-	racks, err := h.service.GetAllRelaxationRacks()
+// CreateRelaxationBlock handles POST /master/relaxation-blocks
+func (h *MasterHandler) CreateRelaxationBlock(c *gin.Context) {
+	var req RelaxationRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	block := &domain.RelaxationBlock{Name: req.Name}
+	if err := h.service.CreateRelaxationBlock(c.Request.Context(), block); err != nil {
+		masterErrorResponse(c, "Failed to create relaxation block.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created relaxation block.", block)
+}
+
+// UpdateRelaxationBlock handles PUT /master/relaxation-blocks/:id
+func (h *MasterHandler) UpdateRelaxationBlock(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RelaxationRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	block, err := h.service.UpdateRelaxationBlock(c.Request.Context(), id, &domain.RelaxationBlock{Name: req.Name})
+	if err != nil {
+		masterErrorResponse(c, "Failed to update relaxation block.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated relaxation block.", block)
+}
+
+// CreateRelaxationRack handles POST /master/relaxation-racks
+func (h *MasterHandler) CreateRelaxationRack(c *gin.Context) {
+	var req RelaxationRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	rack := &domain.RelaxationRack{Name: req.Name}
+	if err := h.service.CreateRelaxationRack(c.Request.Context(), rack); err != nil {
+		masterErrorResponse(c, "Failed to create relaxation rack.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created relaxation rack.", rack)
+}
+
+// UpdateRelaxationRack handles PUT /master/relaxation-racks/:id
+func (h *MasterHandler) UpdateRelaxationRack(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req RelaxationRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	rack, err := h.service.UpdateRelaxationRack(c.Request.Context(), id, &domain.RelaxationRack{Name: req.Name})
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch relaxation racks", err.Error())
+		masterErrorResponse(c, "Failed to update relaxation rack.", err)
 		return
 	}
-	SuccessResponse(c, http.StatusOK, "Successfully fetched relaxation racks", racks)
+	SuccessResponse(c, http.StatusOK, "Successfully updated relaxation rack.", rack)
+}
+
+// GetUnits handles GET /master/units
+func (h *MasterHandler) GetUnits(c *gin.Context) {
+	h.list(c, repository.MasterUnits, "units")
+}
+
+// GetDefectTypes handles GET /master/defect-types
+func (h *MasterHandler) GetDefectTypes(c *gin.Context) {
+	h.list(c, repository.MasterDefectTypes, "defect types")
+}
+
+// GetMovementTypes handles GET /master/movement-types
+func (h *MasterHandler) GetMovementTypes(c *gin.Context) {
+	h.list(c, repository.MasterMovementTypes, "movement types")
+}
+
+// CreateUnit handles POST /master/units
+func (h *MasterHandler) CreateUnit(c *gin.Context) {
+	var req UnitRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	unit := &domain.Unit{Name: req.Name}
+	if err := h.service.CreateUnit(c.Request.Context(), unit); err != nil {
+		masterErrorResponse(c, "Failed to create unit.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created unit.", unit)
+}
+
+// UpdateUnit handles PUT /master/units/:id
+func (h *MasterHandler) UpdateUnit(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UnitRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	unit, err := h.service.UpdateUnit(c.Request.Context(), id, &domain.Unit{Name: req.Name})
+	if err != nil {
+		masterErrorResponse(c, "Failed to update unit.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated unit.", unit)
+}
+
+// CreateDefectType handles POST /master/defect-types
+func (h *MasterHandler) CreateDefectType(c *gin.Context) {
+	var req DefectTypeRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	defectType := &domain.DefectType{Key: req.Key, Name: req.Name}
+	if err := h.service.CreateDefectType(c.Request.Context(), defectType); err != nil {
+		masterErrorResponse(c, "Failed to create defect type.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created defect type.", defectType)
+}
+
+// UpdateDefectType handles PUT /master/defect-types/:id
+func (h *MasterHandler) UpdateDefectType(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req DefectTypeRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	defectType, err := h.service.UpdateDefectType(c.Request.Context(), id, &domain.DefectType{Key: req.Key, Name: req.Name})
+	if err != nil {
+		masterErrorResponse(c, "Failed to update defect type.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated defect type.", defectType)
+}
+
+// CreateMovementType handles POST /master/movement-types
+func (h *MasterHandler) CreateMovementType(c *gin.Context) {
+	var req MovementTypeRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	movementType := &domain.MovementType{Name: req.Name}
+	if err := h.service.CreateMovementType(c.Request.Context(), movementType); err != nil {
+		masterErrorResponse(c, "Failed to create movement type.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created movement type.", movementType)
+}
+
+// UpdateMovementType handles PUT /master/movement-types/:id
+func (h *MasterHandler) UpdateMovementType(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req MovementTypeRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	movementType, err := h.service.UpdateMovementType(c.Request.Context(), id, &domain.MovementType{Name: req.Name})
+	if err != nil {
+		masterErrorResponse(c, "Failed to update movement type.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated movement type.", movementType)
+}
+
+// Delete returns a handler for DELETE /master/<table>/:id
+func (h *MasterHandler) Delete(table repository.MasterTable) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := h.service.Delete(c.Request.Context(), table, id); err != nil {
+			masterErrorResponse(c, "Failed to delete master data.", err)
+			return
+		}
+		SuccessResponse(c, http.StatusOK, "Successfully deleted master data.", true)
+	}
+}
+
+// Restore returns a handler for POST /master/<table>/:id/restore
+func (h *MasterHandler) Restore(table repository.MasterTable) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := h.service.Restore(c.Request.Context(), table, id); err != nil {
+			masterErrorResponse(c, "Failed to restore master data.", err)
+			return
+		}
+		SuccessResponse(c, http.StatusOK, "Successfully restored master data.", true)
+	}
+}
+
+func (r *BlockRequest) validate() map[string][]string {
+	errs := validateMasterName(r.Name, 100)
+	if r.Type != nil && !validStorageType(*r.Type) {
+		errs["type"] = []string{"The type must be fabric or accessories."}
+	}
+	return errs
+}
+
+func (r *RackRequest) validate() map[string][]string {
+	errs := validateMasterName(r.Name, 100)
+	if r.Type != nil && !validStorageType(*r.Type) {
+		errs["type"] = []string{"The type must be fabric or accessories."}
+	}
+	if r.Capacity < 0 {
+		errs["capacity"] = []string{"The capacity must be at least 0."}
+	}
+	return errs
+}
+
+func (r *RelaxationRequest) validate() map[string][]string {
+	return validateMasterName(r.Name, 100)
+}
+
+func (r *UnitRequest) validate() map[string][]string {
+	return validateMasterName(r.Name, 100)
+}
+
+func (r *DefectTypeRequest) validate() map[string][]string {
+	errs := validateMasterName(r.Name, 50)
+	if r.Key != nil && len(strings.TrimSpace(*r.Key)) > 100 {
+		errs["key"] = []string{"The key may not be greater than 100 characters."}
+	}
+	return errs
+}
+
+func (r *MovementTypeRequest) validate() map[string][]string {
+	return validateMasterName(r.Name, 100)
+}
+
+func validateMasterName(name string, max int) map[string][]string {
+	errs := map[string][]string{}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		errs["name"] = []string{"The name field is required."}
+	} else if len(name) > max {
+		errs["name"] = []string{fmt.Sprintf("The name may not be greater than %d characters.", max)}
+	}
+	return errs
+}
+
+func validStorageType(t string) bool {
+	return t == "fabric" || t == "accessories"
+}
+
+// bindJSONRequest binds the JSON body and runs validate on it, writing the
+// validation response itself when anything is wrong.
+func bindJSONRequest(c *gin.Context, req interface{}, validate func() map[string][]string) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"body": {"The request body must be valid JSON."},
+		})
+		return false
+	}
+	if errs := validate(); len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return false
+	}
+	return true
+}
+
+func masterErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrMasterNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrMasterNameTaken):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"name": {"The name has already been taken."},
+		})
+	case errors.Is(err, service.ErrDefectKeyTaken):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"key": {"The key has already been taken."},
+		})
+	case errors.Is(err, service.ErrMasterInUse), errors.Is(err, service.ErrMasterNotDeleted), errors.Is(err, service.ErrStageMovement):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+// parseIDParam reads a positive numeric route parameter, writing the
+// validation response itself when it is invalid.
+func parseIDParam(c *gin.Context, param string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
+	if err != nil || id <= 0 {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			param: {fmt.Sprintf("The %s is invalid.", strings.ReplaceAll(param, "_", " "))},
+		})
+		return 0, false
+	}
+	return id, true
 }
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	movementHeartbeat = 25 * time.Second
+	// movementRetry tells EventSource clients how soon to reconnect.
+	movementRetry = 3 * time.Second
+)
+
+type MovementStreamHandler struct {
+	stream *service.MovementStream
+}
+
+func NewMovementStreamHandler(stream *service.MovementStream) *MovementStreamHandler {
+	return &MovementStreamHandler{stream: stream}
+}
+
+// Stream handles GET /check-point/v1/stream?stage=&block_id=&buyer_id=
+//
+// It is a server-sent events stream of committed moves, relocations and QC
+// results. Reconnecting clients send the Last-Event-ID header (or
+// last_event_id) to receive what they missed; a "reset" event means some of
+// it is no longer available and the dashboard should reload.
+func (h *MovementStreamHandler) Stream(c *gin.Context) {
+	errs := map[string][]string{}
+	filter := service.MovementFilter{
+		Stage:   c.Query("stage"),
+		BlockID: parseOptionalID(c, "block_id", errs),
+		BuyerID: parseOptionalID(c, "buyer_id", errs),
+	}
+	if filter.Stage != "" && !domain.IsValidStage(filter.Stage) && !isGarmentStage(filter.Stage) {
+		errs["stage"] = []string{"The selected stage is invalid."}
+	}
+
+	var lastEventID uint64
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			errs["last_event_id"] = []string{"The last event id is invalid."}
+		}
+		lastEventID = id
+	}
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	// The server's write timeout would cut the stream off
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn().Err(err).Msg("Failed to clear write deadline for movement stream")
+	}
+
+	sub, replay, complete := h.stream.Subscribe(lastEventID, filter)
+	defer h.stream.Unsubscribe(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", movementRetry.Milliseconds())
+	if !complete {
+		fmt.Fprint(c.Writer, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		writeMovementEvent(c, e)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(movementHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind, or the server is shutting down;
+				// the client resumes from its last ID
+				return
+			}
+			writeMovementEvent(c, e)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeMovementEvent(c *gin.Context, e service.MovementEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Error().Err(err).Uint64("event_id", e.ID).Msg("Failed to encode movement event")
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+func isGarmentStage(stage string) bool {
+	switch stage {
+	case domain.GarmentStageProcess, domain.GarmentStageFinishing, domain.GarmentStagePacking:
+		return true
+	}
+	return false
+}
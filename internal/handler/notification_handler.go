@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	service *service.NotificationService
+}
+
+func NewNotificationHandler(svc *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: svc}
+}
+
+// List handles GET /notifications?unread=true&limit=&offset=
+func (h *NotificationHandler) List(c *gin.Context) {
+	errs := map[string][]string{}
+	unreadOnly := false
+	if raw := c.Query("unread"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs["unread"] = []string{"The unread must be true or false."}
+		}
+		unreadOnly = parsed
+	}
+	limit := parseQueryInt(c, "limit", errs)
+	offset := parseQueryInt(c, "offset", errs)
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	list, err := h.service.List(c.Request.Context(), userID.(int64), unreadOnly, limit, offset)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch notifications.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched notifications.", list)
+}
+
+// UnreadCount handles GET /notifications/unread-count
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	count, err := h.service.UnreadCount(c.Request.Context(), userID.(int64))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to count notifications.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully counted notifications.", gin.H{"unread_count": count})
+}
+
+// MarkRead handles POST /notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	notification, err := h.service.MarkRead(c.Request.Context(), userID.(int64), c.Param("id"))
+	if err != nil {
+		notificationErrorResponse(c, "Failed to mark notification read.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully marked notification read.", notification)
+}
+
+// MarkAllRead handles POST /notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	updated, err := h.service.MarkAllRead(c.Request.Context(), userID.(int64))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to mark notifications read.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully marked notifications read.", gin.H{"updated": updated})
+}
+
+// Delete handles DELETE /notifications/:id
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.Delete(c.Request.Context(), userID.(int64), c.Param("id")); err != nil {
+		notificationErrorResponse(c, "Failed to delete notification.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted notification.", true)
+}
+
+func notificationErrorResponse(c *gin.Context, message string, err error) {
+	if errors.Is(err, service.ErrNotificationNotFound) {
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+		return
+	}
+	ErrorResponse(c, http.StatusInternalServerError, message, err)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type NumberingHandler struct {
+	service *service.NumberingService
+}
+
+func NewNumberingHandler(svc *service.NumberingService) *NumberingHandler {
+	return &NumberingHandler{service: svc}
+}
+
+type NumberingPreviewRequest struct {
+	Format string `json:"format"`
+	Prefix string `json:"prefix"`
+}
+
+func (r *NumberingPreviewRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	if strings.TrimSpace(r.Format) == "" {
+		errs["format"] = []string{"The format is required."}
+	}
+	return errs
+}
+
+type NumberingAllocateRequest struct {
+	Module string `json:"module"`
+	For    string `json:"for"`
+}
+
+func (r *NumberingAllocateRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	if strings.TrimSpace(r.Module) == "" {
+		errs["module"] = []string{"The module is required."}
+	}
+	if strings.TrimSpace(r.For) == "" {
+		errs["for"] = []string{"The for field is required."}
+	}
+	return errs
+}
+
+// List handles GET /settings/numberings
+func (h *NumberingHandler) List(c *gin.Context) {
+	numberings, err := h.service.List(c.Request.Context())
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch numberings.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched numberings.", numberings)
+}
+
+// Preview handles GET /settings/numberings/:id/preview
+func (h *NumberingHandler) Preview(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	preview, err := h.service.Preview(c.Request.Context(), id)
+	if err != nil {
+		numberingErrorResponse(c, "Failed to preview numbering.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully previewed numbering.", preview)
+}
+
+// PreviewFormat handles POST /settings/numberings/preview
+func (h *NumberingHandler) PreviewFormat(c *gin.Context) {
+	var req NumberingPreviewRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	preview, err := h.service.PreviewFormat(req.Format, req.Prefix)
+	if err != nil {
+		numberingErrorResponse(c, "Failed to preview numbering.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully previewed numbering.", preview)
+}
+
+// Allocate handles POST /settings/numberings/allocate
+func (h *NumberingHandler) Allocate(c *gin.Context) {
+	var req NumberingAllocateRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	code, err := h.service.Next(c.Request.Context(), req.Module, req.For)
+	if err != nil {
+		numberingErrorResponse(c, "Failed to allocate number.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully allocated number.", gin.H{"number": code})
+}
+
+func numberingErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidNumberFormat):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"format": {err.Error()},
+		})
+	case errors.Is(err, service.ErrNumberingNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	service *service.OrderService
+}
+
+func NewOrderHandler(svc *service.OrderService) *OrderHandler {
+	return &OrderHandler{service: svc}
+}
+
+// List handles GET /orders?search=&buyer_id=&status=
+func (h *OrderHandler) List(c *gin.Context) {
+	filter := repository.OrderFilter{
+		Search: c.Query("search"),
+		Status: c.Query("status"),
+	}
+	if raw := c.Query("buyer_id"); raw != "" {
+		buyerID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || buyerID <= 0 {
+			ValidationErrorResponse(c, "Validation error.", map[string][]string{
+				"buyer_id": {"The buyer id is invalid."},
+			})
+			return
+		}
+		filter.BuyerID = &buyerID
+	}
+
+	orders, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch orders.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched orders.", orders)
+}
+
+// Get handles GET /orders/:id
+func (h *OrderHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	order, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		orderErrorResponse(c, "Failed to fetch order.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched order.", order)
+}
+
+// GetFabricReconciliation handles GET /orders/:id/fabric-reconciliation
+func (h *OrderHandler) GetFabricReconciliation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	rec, err := h.service.GetFabricReconciliation(c.Request.Context(), id)
+	if err != nil {
+		orderErrorResponse(c, "Failed to fetch fabric reconciliation.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched fabric reconciliation.", rec)
+}
+
+func orderErrorResponse(c *gin.Context, message string, err error) {
+	if errors.Is(err, service.ErrOrderNotFound) {
+		ErrorResponse(c, http.StatusNotFound, "Order not found.", err.Error())
+		return
+	}
+	ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PackingHandler struct {
+	service *service.PackingService
+}
+
+func NewPackingHandler(svc *service.PackingService) *PackingHandler {
+	return &PackingHandler{service: svc}
+}
+
+type PackingScanRequest struct {
+	PackingID int64  `json:"packing_id"`
+	QRCode    string `json:"qr_code"`
+}
+
+func (r *PackingScanRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	if r.PackingID <= 0 {
+		errs["packing_id"] = []string{"The packing gate is required."}
+	}
+	if strings.TrimSpace(r.QRCode) == "" {
+		errs["qr_code"] = []string{"The QR code is required."}
+	}
+	return errs
+}
+
+// ListGates handles GET /packing/v1/gates
+func (h *PackingHandler) ListGates(c *gin.Context) {
+	gates, err := h.service.ListGates(c.Request.Context())
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch packing gates.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched packing gates.", gates)
+}
+
+// Scan handles POST /packing/v1/scan
+func (h *PackingHandler) Scan(c *gin.Context) {
+	var req PackingScanRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	result, err := h.service.Scan(c.Request.Context(), req.PackingID, req.QRCode)
+	if err != nil {
+		packingErrorResponse(c, "Failed to pack piece.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully packed piece.", result)
+}
+
+// Summary handles GET /packing/v1/bundles/:code
+func (h *PackingHandler) Summary(c *gin.Context) {
+	summary, err := h.service.Summary(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		packingErrorResponse(c, "Failed to fetch packing summary.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched packing summary.", summary)
+}
+
+func packingErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidGarmentCode):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"qr_code": {"The QR code must look like <bundle>-<piece>."},
+		})
+	case errors.Is(err, service.ErrPackingGateNotFound), errors.Is(err, service.ErrQRSystemNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrGarmentNotChecked), errors.Is(err, service.ErrGarmentNotPassed),
+		errors.Is(err, service.ErrGarmentInRework), errors.Is(err, service.ErrGarmentPacked):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PartnerRequest holds the contact fields shared by buyers, suppliers and vendors.
+type PartnerRequest struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	ContactPerson *string `json:"contact_person"`
+	Phone         *string `json:"phone"`
+	Address       *string `json:"address"`
+	Email         *string `json:"email"`
+	City          *string `json:"city"`
+	Country       *string `json:"country"`
+	Status        *int    `json:"status"`
+	Remarks       *string `json:"remarks"`
+}
+
+func (r *PartnerRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+
+	required := func(field, value string, max int) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			errs[field] = []string{fmt.Sprintf("The %s field is required.", field)}
+		} else if len(value) > max {
+			errs[field] = []string{fmt.Sprintf("The %s may not be greater than %d characters.", field, max)}
+		}
+	}
+	optional := func(field string, value *string, max int) {
+		if value != nil && len(strings.TrimSpace(*value)) > max {
+			errs[field] = []string{fmt.Sprintf("The %s may not be greater than %d characters.", strings.ReplaceAll(field, "_", " "), max)}
+		}
+	}
+
+	required("code", r.Code, 25)
+	required("name", r.Name, 200)
+	optional("contact_person", r.ContactPerson, 50)
+	optional("phone", r.Phone, 15)
+	optional("email", r.Email, 25)
+	optional("city", r.City, 100)
+	optional("country", r.Country, 100)
+
+	if email := trimmedOrNil(r.Email); email != nil && errs["email"] == nil {
+		if _, err := mail.ParseAddress(*email); err != nil {
+			errs["email"] = []string{"The email must be a valid email address."}
+		}
+	}
+	if r.Status != nil && *r.Status != 0 && *r.Status != 1 {
+		errs["status"] = []string{"The status must be 0 or 1."}
+	}
+	return errs
+}
+
+// status returns the requested status, defaulting new records to active.
+func (r *PartnerRequest) status() int {
+	if r.Status == nil {
+		return 1
+	}
+	return *r.Status
+}
+
+// parsePartnerFilter reads ?search= and ?status= from the query string.
+func parsePartnerFilter(c *gin.Context) (repository.PartnerFilter, bool) {
+	filter := repository.PartnerFilter{Search: c.Query("search")}
+
+	if raw := c.Query("status"); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil || (status != 0 && status != 1) {
+			ValidationErrorResponse(c, "Validation error.", map[string][]string{
+				"status": {"The status must be 0 or 1."},
+			})
+			return filter, false
+		}
+		filter.Status = &status
+	}
+	return filter, true
+}
+
+func partnerErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrBuyerNotFound), errors.Is(err, service.ErrVendorNotFound),
+		errors.Is(err, service.ErrSupplierNotFound), errors.Is(err, service.ErrSupplierDocumentNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrSupplierCategoryNotFound):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"supplier_category_id": {"The selected supplier category is invalid."},
+		})
+	case errors.Is(err, service.ErrPartnerCodeTaken):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"code": {"The code has already been taken."},
+		})
+	case errors.Is(err, service.ErrSupplierCategoryNameTaken):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"name": {"The name has already been taken."},
+		})
+	case errors.Is(err, service.ErrPartnerInUse), errors.Is(err, service.ErrSupplierCategoryInUse):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
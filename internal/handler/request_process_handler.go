@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RequestProcessHandler struct {
+	service *service.RequestProcessService
+}
+
+func NewRequestProcessHandler(svc *service.RequestProcessService) *RequestProcessHandler {
+	return &RequestProcessHandler{service: svc}
+}
+
+type AssignOperatorRequest struct {
+	ManPowerID int64 `json:"man_power_id"`
+}
+
+func (r *AssignOperatorRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+	if r.ManPowerID <= 0 {
+		errs["man_power_id"] = []string{"The operator is required."}
+	}
+	return errs
+}
+
+// List handles GET /production/v1/requests/:id/processes
+func (h *RequestProcessHandler) List(c *gin.Context) {
+	requestID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	processes, err := h.service.List(c.Request.Context(), requestID)
+	if err != nil {
+		requestProcessErrorResponse(c, "Failed to fetch request processes.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched request processes.", processes)
+}
+
+// History handles GET /production/v1/requests/:id/processes/history
+func (h *RequestProcessHandler) History(c *gin.Context) {
+	requestID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	processes, err := h.service.History(c.Request.Context(), requestID)
+	if err != nil {
+		requestProcessErrorResponse(c, "Failed to fetch assignment history.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched assignment history.", processes)
+}
+
+// Copy handles POST /production/v1/requests/:id/processes/copy
+func (h *RequestProcessHandler) Copy(c *gin.Context) {
+	requestID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	processes, err := h.service.CopyFromOrder(c.Request.Context(), requestID)
+	if err != nil {
+		requestProcessErrorResponse(c, "Failed to copy order processes.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully copied order processes.", processes)
+}
+
+// Assign handles PUT /production/v1/requests/:id/processes/:order_process_id
+func (h *RequestProcessHandler) Assign(c *gin.Context) {
+	requestID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	orderProcessID, ok := parseIDParam(c, "order_process_id")
+	if !ok {
+		return
+	}
+	var req AssignOperatorRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	process, err := h.service.Assign(c.Request.Context(), userID.(int64), requestID, orderProcessID, req.ManPowerID)
+	if err != nil {
+		requestProcessErrorResponse(c, "Failed to assign operator.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully assigned operator.", process)
+}
+
+func requestProcessErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrRequestNotFound), errors.Is(err, service.ErrRequestProcessNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrManPowerNotFound), errors.Is(err, service.ErrManPowerInactive), errors.Is(err, service.ErrSkillMismatch):
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"man_power_id": {err.Error()},
+		})
+	case errors.Is(err, service.ErrRequestProcessChanged):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/dppi/dppierp-api/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,8 +29,16 @@ func SuccessResponse(c *gin.Context, statusCode int, message string, data interf
 	})
 }
 
-// ErrorResponse sends an error response
+// ErrorResponse sends an error response. Server errors are stored in the
+// exceptions table and the client only gets a reference to them.
 func ErrorResponse(c *gin.Context, statusCode int, message string, errors interface{}) {
+	if statusCode >= http.StatusInternalServerError {
+		reference := middleware.CaptureError(c, statusCode, errors)
+		errors = nil
+		if reference != "" {
+			errors = gin.H{"reference": reference}
+		}
+	}
 	c.JSON(statusCode, Response{
 		Status:  "error",
 		Error:   true,
@@ -43,3 +54,36 @@ func ValidationErrorResponse(c *gin.Context, message string, errors map[string][
 		"errors":  errors,
 	})
 }
+
+// NotModified sets the validators of a cacheable response and writes 304 when
+// the client's copy is still current. If-None-Match takes precedence over
+// If-Modified-Since.
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, no-cache")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares the entity tags of an If-None-Match header weakly.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type StatusLogHandler struct {
+	service *service.StatusLogService
+}
+
+func NewStatusLogHandler(svc *service.StatusLogService) *StatusLogHandler {
+	return &StatusLogHandler{service: svc}
+}
+
+// History handles GET /status-logs/:document/:id
+func (h *StatusLogHandler) History(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	logs, err := h.service.History(c.Request.Context(), c.Param("document"), id)
+	if errors.Is(err, service.ErrUnknownDocument) {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"document": {"The document must be one of " + strings.Join(service.StatusLogDocuments(), ", ") + "."},
+		})
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch status history.", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully fetched status history.", logs)
+}
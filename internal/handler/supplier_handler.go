@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const maxSupplierDocumentSize = 10 << 20 // 10 MB
+
+var allowedDocumentExtensions = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".doc":  true,
+	".docx": true,
+	".xls":  true,
+	".xlsx": true,
+}
+
+type SupplierHandler struct {
+	service *service.SupplierService
+}
+
+func NewSupplierHandler(svc *service.SupplierService) *SupplierHandler {
+	return &SupplierHandler{service: svc}
+}
+
+type SupplierRequest struct {
+	PartnerRequest
+	SupplierCategoryID *int64 `json:"supplier_category_id"`
+}
+
+type SupplierCategoryRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (r *SupplierCategoryRequest) validate() map[string][]string {
+	return validateMasterName(r.Name, 100)
+}
+
+// List handles GET /suppliers?search=&status=&category_id=
+func (h *SupplierHandler) List(c *gin.Context) {
+	filter, ok := parsePartnerFilter(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("category_id"); raw != "" {
+		categoryID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || categoryID <= 0 {
+			ValidationErrorResponse(c, "Validation error.", map[string][]string{
+				"category_id": {"The category id is invalid."},
+			})
+			return
+		}
+		filter.CategoryID = &categoryID
+	}
+
+	suppliers, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch suppliers.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched suppliers.", suppliers)
+}
+
+// Get handles GET /suppliers/:id
+func (h *SupplierHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	supplier, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to fetch supplier.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched supplier.", supplier)
+}
+
+// Create handles POST /suppliers
+func (h *SupplierHandler) Create(c *gin.Context) {
+	var req SupplierRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	supplier := newSupplier(&req)
+	if err := h.service.Create(c.Request.Context(), supplier); err != nil {
+		partnerErrorResponse(c, "Failed to create supplier.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created supplier.", supplier)
+}
+
+// Update handles PUT /suppliers/:id
+func (h *SupplierHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req SupplierRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	supplier, err := h.service.Update(c.Request.Context(), id, newSupplier(&req))
+	if err != nil {
+		partnerErrorResponse(c, "Failed to update supplier.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated supplier.", supplier)
+}
+
+// ToggleStatus handles POST /suppliers/:id/toggle-status
+func (h *SupplierHandler) ToggleStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	supplier, err := h.service.ToggleStatus(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to change supplier status.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully changed supplier status.", supplier)
+}
+
+// Delete handles DELETE /suppliers/:id
+func (h *SupplierHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		partnerErrorResponse(c, "Failed to delete supplier.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted supplier.", true)
+}
+
+// UploadDocument handles POST /suppliers/:id/documents (multipart/form-data)
+func (h *SupplierHandler) UploadDocument(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	errs := map[string][]string{}
+	file, err := c.FormFile("file")
+	switch {
+	case err != nil:
+		errs["file"] = []string{"The file field is required."}
+	case !allowedDocumentExtensions[strings.ToLower(filepath.Ext(file.Filename))]:
+		errs["file"] = []string{"The file must be a pdf, image, Word or Excel file."}
+	case file.Size > maxSupplierDocumentSize:
+		errs["file"] = []string{"The file may not be greater than 10 MB."}
+	}
+
+	name := strings.TrimSpace(c.PostForm("document_name"))
+	docType := strings.TrimSpace(c.PostForm("document_type"))
+	if len(name) > 150 {
+		errs["document_name"] = []string{"The document name may not be greater than 150 characters."}
+	}
+	if len(docType) > 100 {
+		errs["document_type"] = []string{"The document type may not be greater than 100 characters."}
+	}
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	document, err := h.service.UploadDocument(c.Request.Context(), id, &service.UploadSupplierDocument{
+		DocumentName: name,
+		DocumentType: docType,
+		File:         file,
+	})
+	if err != nil {
+		partnerErrorResponse(c, "Failed to upload supplier document.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully uploaded supplier document.", document)
+}
+
+// DeleteDocument handles DELETE /suppliers/:id/documents/:document_id
+func (h *SupplierHandler) DeleteDocument(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	documentID, ok := parseIDParam(c, "document_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDocument(c.Request.Context(), id, documentID); err != nil {
+		partnerErrorResponse(c, "Failed to delete supplier document.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted supplier document.", true)
+}
+
+// ListCategories handles GET /suppliers/categories
+func (h *SupplierHandler) ListCategories(c *gin.Context) {
+	categories, err := h.service.ListCategories(c.Request.Context())
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch supplier categories.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched supplier categories.", categories)
+}
+
+// CreateCategory handles POST /suppliers/categories
+func (h *SupplierHandler) CreateCategory(c *gin.Context) {
+	var req SupplierCategoryRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	category := &domain.SupplierCategory{Name: req.Name, Description: trimmedOrNil(req.Description)}
+	if err := h.service.CreateCategory(c.Request.Context(), category); err != nil {
+		partnerErrorResponse(c, "Failed to create supplier category.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created supplier category.", category)
+}
+
+// UpdateCategory handles PUT /suppliers/categories/:id
+func (h *SupplierHandler) UpdateCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req SupplierCategoryRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	category, err := h.service.UpdateCategory(c.Request.Context(), id, &domain.SupplierCategory{Name: req.Name, Description: trimmedOrNil(req.Description)})
+	if err != nil {
+		if errors.Is(err, service.ErrSupplierCategoryNotFound) {
+			ErrorResponse(c, http.StatusNotFound, "Failed to update supplier category.", err.Error())
+			return
+		}
+		partnerErrorResponse(c, "Failed to update supplier category.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated supplier category.", category)
+}
+
+// DeleteCategory handles DELETE /suppliers/categories/:id
+func (h *SupplierHandler) DeleteCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCategory(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrSupplierCategoryNotFound) {
+			ErrorResponse(c, http.StatusNotFound, "Failed to delete supplier category.", err.Error())
+			return
+		}
+		partnerErrorResponse(c, "Failed to delete supplier category.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted supplier category.", true)
+}
+
+func newSupplier(req *SupplierRequest) *domain.Supplier {
+	return &domain.Supplier{
+		Code:               req.Code,
+		Name:               req.Name,
+		SupplierCategoryID: req.SupplierCategoryID,
+		ContactPerson:      trimmedOrNil(req.ContactPerson),
+		Phone:              trimmedOrNil(req.Phone),
+		Address:            trimmedOrNil(req.Address),
+		Email:              trimmedOrNil(req.Email),
+		City:               trimmedOrNil(req.City),
+		Country:            trimmedOrNil(req.Country),
+		Status:             req.status(),
+		Remarks:            trimmedOrNil(req.Remarks),
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SupplierRatingHandler struct {
+	service *service.SupplierRatingService
+}
+
+func NewSupplierRatingHandler(svc *service.SupplierRatingService) *SupplierRatingHandler {
+	return &SupplierRatingHandler{service: svc}
+}
+
+type ComputeRatingsRequest struct {
+	Period string `json:"period"`
+}
+
+// Compute handles POST /suppliers/ratings/compute
+func (h *SupplierRatingHandler) Compute(c *gin.Context) {
+	var req ComputeRatingsRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if req.Period == "" {
+		req.Period = time.Now().Format("2006-01")
+	}
+	if !validPeriod(req.Period) {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"period": {"The period must use the YYYY-MM format."},
+		})
+		return
+	}
+
+	ratings, err := h.service.ComputePeriod(c.Request.Context(), req.Period)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to compute supplier ratings.", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully computed supplier ratings.", ratings)
+}
+
+type RecomputeRatingsRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Recompute handles POST /suppliers/ratings/recompute. Each month of the range
+// is computed by a background job; the response is the batch to follow.
+func (h *SupplierRatingHandler) Recompute(c *gin.Context) {
+	var req RecomputeRatingsRequest
+	_ = c.ShouldBindJSON(&req)
+
+	errs := map[string][]string{}
+	for field, period := range map[string]string{"from": req.From, "to": req.To} {
+		if !validPeriod(period) {
+			errs[field] = []string{"The " + field + " must use the YYYY-MM format."}
+		}
+	}
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	batch, err := h.service.Recompute(c.Request.Context(), req.From, req.To)
+	if err != nil {
+		if errors.Is(err, service.ErrRatingPeriodRange) {
+			ValidationErrorResponse(c, "Validation error.", map[string][]string{"to": {err.Error()}})
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to queue supplier rating recompute.", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusAccepted, "Supplier ratings are being recomputed.", batch)
+}
+
+// GetByPeriod handles GET /suppliers/ratings?period=YYYY-MM
+func (h *SupplierRatingHandler) GetByPeriod(c *gin.Context) {
+	period := c.DefaultQuery("period", time.Now().Format("2006-01"))
+	if !validPeriod(period) {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"period": {"The period must use the YYYY-MM format."},
+		})
+		return
+	}
+
+	ratings, err := h.service.GetByPeriod(c.Request.Context(), period)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch supplier ratings.", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully fetched supplier ratings.", ratings)
+}
+
+// GetTrend handles GET /suppliers/:id/ratings?from=YYYY-MM&to=YYYY-MM
+func (h *SupplierRatingHandler) GetTrend(c *gin.Context) {
+	supplierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || supplierID <= 0 {
+		ValidationErrorResponse(c, "Validation error.", map[string][]string{
+			"id": {"The supplier id is invalid."},
+		})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	errs := map[string][]string{}
+	if from != "" && !validPeriod(from) {
+		errs["from"] = []string{"The from period must use the YYYY-MM format."}
+	}
+	if to != "" && !validPeriod(to) {
+		errs["to"] = []string{"The to period must use the YYYY-MM format."}
+	}
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	ratings, err := h.service.GetTrend(c.Request.Context(), supplierID, from, to)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch supplier rating trend.", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, "Successfully fetched supplier rating trend.", ratings)
+}
+
+func validPeriod(period string) bool {
+	_, err := time.Parse("2006-01", period)
+	return err == nil
+}
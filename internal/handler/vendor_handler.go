@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type VendorHandler struct {
+	service *service.VendorService
+}
+
+func NewVendorHandler(svc *service.VendorService) *VendorHandler {
+	return &VendorHandler{service: svc}
+}
+
+type VendorRequest struct {
+	PartnerRequest
+	VendorCode *string `json:"vendor_code"`
+}
+
+func (r *VendorRequest) validate() map[string][]string {
+	errs := r.PartnerRequest.validate()
+	if r.VendorCode != nil && len(*r.VendorCode) > 255 {
+		errs["vendor_code"] = []string{"The vendor code may not be greater than 255 characters."}
+	}
+	return errs
+}
+
+// List handles GET /vendors?search=&status=
+func (h *VendorHandler) List(c *gin.Context) {
+	filter, ok := parsePartnerFilter(c)
+	if !ok {
+		return
+	}
+
+	vendors, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch vendors.", err.Error())
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched vendors.", vendors)
+}
+
+// Get handles GET /vendors/:id
+func (h *VendorHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	vendor, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to fetch vendor.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched vendor.", vendor)
+}
+
+// Create handles POST /vendors
+func (h *VendorHandler) Create(c *gin.Context) {
+	var req VendorRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	vendor := newVendor(&req)
+	if err := h.service.Create(c.Request.Context(), vendor); err != nil {
+		partnerErrorResponse(c, "Failed to create vendor.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created vendor.", vendor)
+}
+
+// Update handles PUT /vendors/:id
+func (h *VendorHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req VendorRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	vendor, err := h.service.Update(c.Request.Context(), id, newVendor(&req))
+	if err != nil {
+		partnerErrorResponse(c, "Failed to update vendor.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated vendor.", vendor)
+}
+
+// ToggleStatus handles POST /vendors/:id/toggle-status
+func (h *VendorHandler) ToggleStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	vendor, err := h.service.ToggleStatus(c.Request.Context(), id)
+	if err != nil {
+		partnerErrorResponse(c, "Failed to change vendor status.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully changed vendor status.", vendor)
+}
+
+// Delete handles DELETE /vendors/:id
+func (h *VendorHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		partnerErrorResponse(c, "Failed to delete vendor.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted vendor.", true)
+}
+
+func newVendor(req *VendorRequest) *domain.Vendor {
+	return &domain.Vendor{
+		Code:          req.Code,
+		VendorCode:    trimmedOrNil(req.VendorCode),
+		Name:          req.Name,
+		ContactPerson: trimmedOrNil(req.ContactPerson),
+		Phone:         trimmedOrNil(req.Phone),
+		Address:       trimmedOrNil(req.Address),
+		Email:         trimmedOrNil(req.Email),
+		City:          trimmedOrNil(req.City),
+		Country:       trimmedOrNil(req.Country),
+		Status:        req.status(),
+		Remarks:       trimmedOrNil(req.Remarks),
+	}
+}
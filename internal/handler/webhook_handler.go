@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+func NewWebhookHandler(svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: svc}
+}
+
+type WebhookSubscriptionRequest struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+func (r *WebhookSubscriptionRequest) validate() map[string][]string {
+	errs := map[string][]string{}
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		errs["name"] = []string{"The name field is required."}
+	} else if len(r.Name) > 100 {
+		errs["name"] = []string{"The name may not be greater than 100 characters."}
+	}
+
+	r.URL = strings.TrimSpace(r.URL)
+	if r.URL == "" {
+		errs["url"] = []string{"The url field is required."}
+	} else if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs["url"] = []string{"The url must be a valid http or https URL."}
+	} else if len(r.URL) > 500 {
+		errs["url"] = []string{"The url may not be greater than 500 characters."}
+	}
+
+	if len(r.Events) == 0 {
+		errs["events"] = []string{"The events field is required."}
+	}
+	seen := map[string]bool{}
+	events := []string{}
+	for _, event := range r.Events {
+		event = strings.TrimSpace(event)
+		if !domain.IsValidWebhookEvent(event) {
+			errs["events"] = []string{fmt.Sprintf("The event %q is invalid. Valid events: %s.", event, strings.Join(domain.WebhookEvents, ", "))}
+			break
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	r.Events = events
+	return errs
+}
+
+func (r *WebhookSubscriptionRequest) subscription() *domain.WebhookSubscription {
+	return &domain.WebhookSubscription{
+		Name:     r.Name,
+		URL:      r.URL,
+		Events:   r.Events,
+		IsActive: r.IsActive == nil || *r.IsActive,
+	}
+}
+
+// Events handles GET /webhooks/events
+func (h *WebhookHandler) Events(c *gin.Context) {
+	SuccessResponse(c, http.StatusOK, "Successfully fetched webhook events.", domain.WebhookEvents)
+}
+
+// ListSubscriptions handles GET /webhooks/subscriptions
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	subs, err := h.service.ListSubscriptions(c.Request.Context(), userID.(int64))
+	if err != nil {
+		webhookErrorResponse(c, "Failed to fetch webhook subscriptions.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched webhook subscriptions.", subs)
+}
+
+// CreateSubscription handles POST /webhooks/subscriptions. The response holds
+// the signing secret, which is not shown again.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req WebhookSubscriptionRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	sub := req.subscription()
+	if err := h.service.CreateSubscription(c.Request.Context(), userID.(int64), sub); err != nil {
+		webhookErrorResponse(c, "Failed to create webhook subscription.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, "Successfully created webhook subscription.", sub)
+}
+
+// UpdateSubscription handles PUT /webhooks/subscriptions/:id
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req WebhookSubscriptionRequest
+	if !bindJSONRequest(c, &req, req.validate) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	sub, err := h.service.UpdateSubscription(c.Request.Context(), userID.(int64), id, req.subscription())
+	if err != nil {
+		webhookErrorResponse(c, "Failed to update webhook subscription.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully updated webhook subscription.", sub)
+}
+
+// DeleteSubscription handles DELETE /webhooks/subscriptions/:id
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.DeleteSubscription(c.Request.Context(), userID.(int64), id); err != nil {
+		webhookErrorResponse(c, "Failed to delete webhook subscription.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully deleted webhook subscription.", true)
+}
+
+// ListDeliveries handles GET /webhooks/deliveries?status=&subscription_id=&event_type=&limit=&offset=
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	h.listDeliveries(c, c.Query("status"))
+}
+
+// DeadLetters handles GET /webhooks/dead-letters?subscription_id=&event_type=&limit=&offset=
+func (h *WebhookHandler) DeadLetters(c *gin.Context) {
+	h.listDeliveries(c, domain.WebhookDeliveryDead)
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, status string) {
+	errs := map[string][]string{}
+	filter := domain.WebhookDeliveryFilter{
+		Status:         status,
+		SubscriptionID: parseOptionalID(c, "subscription_id", errs),
+		EventType:      strings.TrimSpace(c.Query("event_type")),
+		Limit:          parseQueryInt(c, "limit", errs),
+		Offset:         parseQueryInt(c, "offset", errs),
+	}
+	switch status {
+	case "", domain.WebhookDeliveryPending, domain.WebhookDeliverySending, domain.WebhookDeliveryRetry, domain.WebhookDeliveryDelivered, domain.WebhookDeliveryDead:
+	default:
+		errs["status"] = []string{"The selected status is invalid."}
+	}
+	if filter.EventType != "" && !domain.IsValidWebhookEvent(filter.EventType) {
+		errs["event_type"] = []string{"The selected event type is invalid."}
+	}
+	if len(errs) > 0 {
+		ValidationErrorResponse(c, "Validation error.", errs)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	deliveries, err := h.service.Deliveries(c.Request.Context(), userID.(int64), filter)
+	if err != nil {
+		webhookErrorResponse(c, "Failed to fetch webhook deliveries.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched webhook deliveries.", deliveries)
+}
+
+// GetDelivery handles GET /webhooks/deliveries/:id
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	delivery, err := h.service.Delivery(c.Request.Context(), userID.(int64), id)
+	if err != nil {
+		webhookErrorResponse(c, "Failed to fetch webhook delivery.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Successfully fetched webhook delivery.", delivery)
+}
+
+// Redeliver handles POST /webhooks/deliveries/:id/redeliver
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	delivery, err := h.service.Redeliver(c.Request.Context(), userID.(int64), id)
+	if err != nil {
+		webhookErrorResponse(c, "Failed to redeliver webhook.", err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, "Webhook delivery is queued.", delivery)
+}
+
+func webhookErrorResponse(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		ErrorResponse(c, http.StatusNotFound, message, err.Error())
+	case errors.Is(err, service.ErrWebhookForbidden):
+		ErrorResponse(c, http.StatusForbidden, message, err.Error())
+	case errors.Is(err, service.ErrWebhookDeliveryInProgress):
+		ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -1,14 +1,19 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/dppi/dppierp-api/internal/authctx"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// RefreshTokenTTL is how long a refresh token can be used.
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
 type AuthMiddleware struct {
 	secret string
 }
@@ -22,15 +27,18 @@ type Claims struct {
 	UserID int64  `json:"user_id"`
 	Email  string `json:"email"`
 	Name   string `json:"name"`
+	// SessionID is the refresh token family the token was issued for.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token
-func (m *AuthMiddleware) GenerateToken(userID int64, email, name string) (string, error) {
+// GenerateToken generates a new JWT token for a login session
+func (m *AuthMiddleware) GenerateToken(userID int64, email, name, sessionID string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
+		UserID:    userID,
+		Email:     email,
+		Name:      name,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -41,12 +49,14 @@ func (m *AuthMiddleware) GenerateToken(userID int64, email, name string) (string
 	return token.SignedString([]byte(m.secret))
 }
 
-// GenerateRefreshToken generates a new JWT refresh token
-func (m *AuthMiddleware) GenerateRefreshToken(userID int64) (string, error) {
+// GenerateRefreshToken generates a new JWT refresh token. tokenID is the
+// token_id of its refresh_tokens row.
+func (m *AuthMiddleware) GenerateRefreshToken(userID int64, tokenID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"type":    "refresh",
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // 7 days
+		"jti":     tokenID,
+		"exp":     time.Now().Add(RefreshTokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
@@ -72,25 +82,30 @@ func (m *AuthMiddleware) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// ValidateRefreshToken validates a refresh token and returns the user ID
-func (m *AuthMiddleware) ValidateRefreshToken(tokenString string) (int64, error) {
+// ValidateRefreshToken validates a refresh token and returns the user ID and
+// the token ID. Refresh tokens issued without a token ID are rejected.
+func (m *AuthMiddleware) ValidateRefreshToken(tokenString string) (int64, string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(m.secret), nil
 	})
 
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		if claims["type"] != "refresh" {
-			return 0, jwt.ErrSignatureInvalid
+			return 0, "", jwt.ErrSignatureInvalid
+		}
+		userID, ok := claims["user_id"].(float64)
+		tokenID, _ := claims["jti"].(string)
+		if !ok || tokenID == "" {
+			return 0, "", jwt.ErrTokenInvalidClaims
 		}
-		userID := int64(claims["user_id"].(float64))
-		return userID, nil
+		return int64(userID), tokenID, nil
 	}
 
-	return 0, jwt.ErrSignatureInvalid
+	return 0, "", jwt.ErrSignatureInvalid
 }
 
 // Authenticate is a Gin middleware for JWT authentication
@@ -128,7 +143,64 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("name", claims.Name)
+		c.Set("session_id", claims.SessionID)
+		c.Request = c.Request.WithContext(authctx.WithUserID(c.Request.Context(), claims.UserID))
 
 		c.Next()
 	}
 }
+
+// PermissionChecker reports whether a user holds a permission.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+}
+
+// RequirePermission lets a request through when the signed in user holds any
+// of the permissions. It runs after Authenticate.
+func RequirePermission(checker PermissionChecker, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetInt64("user_id")
+		for _, permission := range permissions {
+			allowed, err := checker.HasPermission(c.Request.Context(), userID, permission)
+			if err != nil {
+				var errs interface{}
+				if reference := CaptureError(c, http.StatusInternalServerError, err); reference != "" {
+					errs = gin.H{"reference": reference}
+				}
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"status":  "error",
+					"error":   true,
+					"message": "Failed to check permissions.",
+					"errors":  errs,
+				})
+				return
+			}
+			if allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"error":   true,
+			"message": "You do not have permission to access this resource.",
+			"errors":  gin.H{"permissions": permissions},
+		})
+	}
+}
+
+// RequireModulePermission requires <module>.read for GET and HEAD requests and
+// <module>.write for the others, following the naming of the permissions
+// table. readers are further permissions that allow GET and HEAD requests.
+func RequireModulePermission(checker PermissionChecker, module string, readers ...string) gin.HandlerFunc {
+	read := RequirePermission(checker, append([]string{module + ".read"}, readers...)...)
+	write := RequirePermission(checker, module+".write")
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			read(c)
+		} else {
+			write(c)
+		}
+	}
+}
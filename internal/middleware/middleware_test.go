@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dppi/dppierp-api/internal/authctx"
+	"github.com/dppi/dppierp-api/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,7 +24,7 @@ func TestAuthMiddleware_GenerateAndValidateToken(t *testing.T) {
 	auth := NewAuthMiddleware("test-secret-key-12345")
 
 	// Generate token
-	token, err := auth.GenerateToken(1, "test@example.com", "Test User")
+	token, err := auth.GenerateToken(1, "test@example.com", "Test User", "session-1")
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -43,6 +50,28 @@ func TestAuthMiddleware_GenerateAndValidateToken(t *testing.T) {
 	if claims.Name != "Test User" {
 		t.Errorf("Expected name 'Test User', got '%s'", claims.Name)
 	}
+
+	if claims.SessionID != "session-1" {
+		t.Errorf("Expected session 'session-1', got '%s'", claims.SessionID)
+	}
+}
+
+func TestAuthMiddleware_RefreshToken(t *testing.T) {
+	auth := NewAuthMiddleware("test-secret-key-12345")
+
+	token, err := auth.GenerateRefreshToken(1, "token-1")
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	userID, tokenID, err := auth.ValidateRefreshToken(token)
+	if err != nil || userID != 1 || tokenID != "token-1" {
+		t.Errorf("Expected user 1 and token-1, got %d, %q, %v", userID, tokenID, err)
+	}
+
+	access, _ := auth.GenerateToken(1, "test@example.com", "Test User", "session-1")
+	if _, _, err := auth.ValidateRefreshToken(access); err == nil {
+		t.Error("Expected an access token to be rejected as refresh token")
+	}
 }
 
 func TestAuthMiddleware_InvalidToken(t *testing.T) {
@@ -99,7 +128,7 @@ func TestAuthMiddleware_ValidAuth(t *testing.T) {
 	c, router := gin.CreateTestContext(w)
 
 	// Generate a valid token
-	token, _ := auth.GenerateToken(1, "test@example.com", "Test User")
+	token, _ := auth.GenerateToken(1, "test@example.com", "Test User", "")
 
 	c.Request, _ = http.NewRequest("GET", "/test", nil)
 	c.Request.Header.Set("Authorization", "Bearer "+token)
@@ -107,6 +136,9 @@ func TestAuthMiddleware_ValidAuth(t *testing.T) {
 	router.Use(auth.Authenticate())
 	router.GET("/test", func(c *gin.Context) {
 		userID, _ := c.Get("user_id")
+		if authctx.UserID(c.Request.Context()) != userID {
+			t.Errorf("Expected user %v in the request context", userID)
+		}
 		c.JSON(http.StatusOK, gin.H{"user_id": userID})
 	})
 
@@ -117,6 +149,38 @@ func TestAuthMiddleware_ValidAuth(t *testing.T) {
 	}
 }
 
+// permissionSet grants the listed permissions to every user.
+type permissionSet map[string]bool
+
+func (p permissionSet) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	return p[permission], nil
+}
+
+func TestRequireModulePermission(t *testing.T) {
+	auth := NewAuthMiddleware("test-secret-key-12345")
+	token, _ := auth.GenerateToken(1, "test@example.com", "Test User", "")
+	checker := permissionSet{"fabric.qr-system.read": true}
+
+	router := gin.New()
+	group := router.Group("/master/blocks", auth.Authenticate(), RequireModulePermission(checker, "fabric.master-data.block", "fabric.qr-system.read"))
+	group.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.POST("", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	for method, want := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/master/blocks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", method, want, w.Code)
+		}
+		if want == http.StatusForbidden && !strings.Contains(w.Body.String(), "fabric.master-data.block.write") {
+			t.Errorf("Expected the missing permission in the response, got %s", w.Body.String())
+		}
+	}
+}
+
 func TestCORSMiddleware(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -153,3 +217,125 @@ func TestLogger(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 }
+
+type mockExceptionRecorder struct {
+	exceptions []*domain.Exception
+}
+
+func (m *mockExceptionRecorder) Record(ctx context.Context, e *domain.Exception) (string, error) {
+	m.exceptions = append(m.exceptions, e)
+	return "EXC-" + strconv.Itoa(len(m.exceptions)), nil
+}
+
+func TestRecovery_StoresPanic(t *testing.T) {
+	recorder := &mockExceptionRecorder{}
+	router := gin.New()
+	router.Use(Recovery(recorder))
+	router.POST("/boom", func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name != "roll" {
+			t.Errorf("Expected handler to read the full body, got %q (%v)", req.Name, err)
+		}
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	body := `{"name":"roll","password":"secret","nested":{"refresh_token":"abc"}}`
+	req, _ := http.NewRequest("POST", "/boom?page=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	var resp struct {
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Errors["reference"] != "EXC-1" {
+		t.Errorf("Expected reference EXC-1, got %v", resp.Errors)
+	}
+	if strings.Contains(w.Body.String(), "boom") {
+		t.Errorf("Expected panic message to stay out of the response, got %s", w.Body.String())
+	}
+
+	if len(recorder.exceptions) != 1 {
+		t.Fatalf("Expected 1 stored exception, got %d", len(recorder.exceptions))
+	}
+	e := recorder.exceptions[0]
+	if e.Message != "boom" || e.ExceptionClass != "string" || e.Method != "POST" || e.URL != "/boom?page=2" {
+		t.Errorf("Unexpected exception: %+v", e)
+	}
+	if e.Trace == "" {
+		t.Error("Expected a stack trace")
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(e.RequestData, &data); err != nil {
+		t.Fatalf("Failed to decode request data: %v", err)
+	}
+	if data["name"] != "roll" || data["page"] != "2" {
+		t.Errorf("Expected body and query in request data, got %v", data)
+	}
+	if data["password"] != hiddenValue {
+		t.Errorf("Expected password to be hidden, got %v", data["password"])
+	}
+	if nested, _ := data["nested"].(map[string]interface{}); nested["refresh_token"] != hiddenValue {
+		t.Errorf("Expected nested token to be hidden, got %v", data["nested"])
+	}
+}
+
+func TestCaptureError_ReturnsReference(t *testing.T) {
+	recorder := &mockExceptionRecorder{}
+	router := gin.New()
+	router.Use(Recovery(recorder))
+	router.GET("/fail", func(c *gin.Context) {
+		reference := CaptureError(c, http.StatusInternalServerError, errors.New("Error 1054: Unknown column 'x'"))
+		c.JSON(http.StatusInternalServerError, gin.H{"reference": reference})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	router.ServeHTTP(w, req)
+
+	if len(recorder.exceptions) != 1 {
+		t.Fatalf("Expected the error to be stored once, got %d", len(recorder.exceptions))
+	}
+	e := recorder.exceptions[0]
+	if e.Message != "Error 1054: Unknown column 'x'" || e.ExceptionClass != "*errors.errorString" {
+		t.Errorf("Unexpected exception: %+v", e)
+	}
+	if !strings.Contains(w.Body.String(), "EXC-1") {
+		t.Errorf("Expected reference in response, got %s", w.Body.String())
+	}
+}
+
+func TestRecovery_StoresUncapturedServerError(t *testing.T) {
+	recorder := &mockExceptionRecorder{}
+	router := gin.New()
+	router.Use(Recovery(recorder))
+	router.GET("/ok", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/unavailable", func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	for _, path := range []string{"/ok", "/unavailable"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	if len(recorder.exceptions) != 1 {
+		t.Fatalf("Expected only the 5xx to be stored, got %d", len(recorder.exceptions))
+	}
+	if e := recorder.exceptions[0]; e.StatusCode != http.StatusServiceUnavailable || e.URL != "/unavailable" {
+		t.Errorf("Unexpected exception: %+v", e)
+	}
+}
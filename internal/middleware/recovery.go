@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/authctx"
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ExceptionRecorder stores a captured exception and returns the reference
+// handed to the client.
+type ExceptionRecorder interface {
+	Record(ctx context.Context, e *domain.Exception) (string, error)
+}
+
+const (
+	exceptionCaptureKey = "exception_capture"
+	// maxCapturedBody caps how much of a request body is kept for the
+	// exception log. The handler still reads the whole body.
+	maxCapturedBody  = 64 << 10
+	recordTimeout    = 5 * time.Second
+	hiddenValue      = "[hidden]"
+	internalErrorMsg = "Internal server error."
+)
+
+// sensitiveKeys are masked in stored request data when a key contains one of
+// them.
+var sensitiveKeys = []string{"password", "token", "secret", "otp", "authorization"}
+
+// skippedFrames are not reported as the file or caller of an exception.
+var skippedFrames = []string{"runtime.", "github.com/gin-gonic/", "/internal/middleware.", "/internal/handler.ErrorResponse"}
+
+type exceptionCapture struct {
+	recorder ExceptionRecorder
+	body     []byte
+	captured bool
+}
+
+// Recovery is a Gin middleware that turns panics into an opaque 500 response
+// and stores them, together with any other 5xx response, in the exceptions
+// table.
+func Recovery(recorder ExceptionRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		capture := &exceptionCapture{recorder: recorder, body: peekBody(c)}
+		c.Set(exceptionCaptureKey, capture)
+
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			if err, ok := r.(error); ok && isBrokenPipe(err) {
+				log.Warn().Err(err).Str("path", c.Request.URL.Path).Msg("Client connection closed")
+				c.Abort()
+				return
+			}
+
+			e := newException(c, http.StatusInternalServerError)
+			e.ExceptionClass = fmt.Sprintf("%T", r)
+			if err, ok := r.(error); ok {
+				e.ExceptionClass = errorClass(err)
+			}
+			e.Message = fmt.Sprint(r)
+			reference := capture.record(c, e)
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"error":   true,
+				"message": internalErrorMsg,
+				"errors":  gin.H{"reference": reference},
+			})
+		}()
+
+		c.Next()
+
+		// A 5xx written directly, without CaptureError, is still logged; the
+		// response itself has already gone out.
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError && !capture.captured {
+			e := newException(c, status)
+			e.ExceptionClass = "error"
+			e.Message = http.StatusText(status)
+			if last := c.Errors.Last(); last != nil {
+				e.ExceptionClass = errorClass(last.Err)
+				e.Message = last.Error()
+			}
+			e.File = c.HandlerName()
+			e.Trace = ""
+			e.CallerFile, e.CallerLine, e.CallerFunction = nil, nil, nil
+			capture.record(c, e)
+		}
+	}
+}
+
+// CaptureError stores err as an exception of the current request and returns
+// the reference to show the client instead of the error text. It returns an
+// empty reference when Recovery is not installed or the exception could not
+// be stored.
+func CaptureError(c *gin.Context, statusCode int, err interface{}) string {
+	value, ok := c.Get(exceptionCaptureKey)
+	if !ok {
+		log.Error().Interface("error", err).Str("path", c.Request.URL.Path).Msg("Request failed")
+		return ""
+	}
+	capture := value.(*exceptionCapture)
+
+	e := newException(c, statusCode)
+	switch v := err.(type) {
+	case error:
+		e.ExceptionClass = errorClass(v)
+		e.Message = v.Error()
+	case string:
+		e.ExceptionClass = "error"
+		e.Message = v
+	default:
+		e.ExceptionClass = fmt.Sprintf("%T", v)
+		e.Message = fmt.Sprint(v)
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(statusCode)
+	}
+	return capture.record(c, e)
+}
+
+// record stores e and logs it. The request context may already be cancelled
+// by the time a handler fails, so the insert runs without its cancellation.
+func (capture *exceptionCapture) record(c *gin.Context, e *domain.Exception) string {
+	capture.captured = true
+	e.RequestData = sanitizeRequestData(c, capture.body)
+
+	var reference string
+	if capture.recorder != nil {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), recordTimeout)
+		defer cancel()
+
+		var err error
+		reference, err = capture.recorder.Record(ctx, e)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to store exception")
+		}
+	}
+
+	log.Error().
+		Str("reference", reference).
+		Str("method", e.Method).
+		Str("url", e.URL).
+		Int("status", e.StatusCode).
+		Str("class", e.ExceptionClass).
+		Str("file", fmt.Sprintf("%s:%d", e.File, e.Line)).
+		Msg(e.Message)
+	return reference
+}
+
+// newException fills in the request details and the location of the failure
+// from the current call stack.
+func newException(c *gin.Context, statusCode int) *domain.Exception {
+	e := &domain.Exception{
+		URL:        c.Request.URL.String(),
+		Method:     c.Request.Method,
+		StatusCode: statusCode,
+		Trace:      string(debug.Stack()),
+	}
+	if userID := authctx.UserID(c.Request.Context()); userID > 0 {
+		e.UserID = &userID
+	}
+	if ip := c.ClientIP(); ip != "" {
+		e.IPAddress = &ip
+	}
+
+	frames := appFrames()
+	if len(frames) > 0 {
+		e.File, e.Line = frames[0].File, frames[0].Line
+	}
+	if len(frames) > 1 {
+		caller := frames[1]
+		e.CallerFile, e.CallerLine, e.CallerFunction = &caller.File, &caller.Line, &caller.Function
+	}
+	return e
+}
+
+// appFrames returns the current call stack without runtime, gin and error
+// capture frames, innermost first.
+func appFrames() []runtime.Frame {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	iter := runtime.CallersFrames(pcs[:n])
+
+	var frames []runtime.Frame
+	for {
+		frame, more := iter.Next()
+		if !isSkippedFrame(frame.Function) {
+			frames = append(frames, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func isSkippedFrame(function string) bool {
+	for _, skipped := range skippedFrames {
+		if strings.Contains(function, skipped) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorClass names the type of the innermost wrapped error, e.g.
+// *mysql.MySQLError rather than *fmt.wrapError.
+func errorClass(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
+
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// peekBody reads up to maxCapturedBody bytes of a JSON or form body and puts
+// them back in front of the rest so the handler sees the full body.
+func peekBody(c *gin.Context) []byte {
+	if c.Request.Body == nil {
+		return nil
+	}
+	switch c.ContentType() {
+	case gin.MIMEJSON, gin.MIMEPOSTForm:
+	default:
+		return nil
+	}
+
+	body := c.Request.Body
+	buf, _ := io.ReadAll(io.LimitReader(body, maxCapturedBody+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), body), body}
+	return buf
+}
+
+// sanitizeRequestData merges the query string and body of the request into one
+// object with sensitive values masked.
+func sanitizeRequestData(c *gin.Context, body []byte) json.RawMessage {
+	data := map[string]interface{}{}
+	for key, values := range c.Request.URL.Query() {
+		data[key] = formValue(values)
+	}
+
+	switch {
+	case len(body) > maxCapturedBody:
+		data["_body"] = "[truncated]"
+	case len(body) == 0:
+	case c.ContentType() == gin.MIMEJSON:
+		var parsed interface{}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			data["_body"] = "[invalid json]"
+		} else if fields, ok := parsed.(map[string]interface{}); ok {
+			for key, value := range fields {
+				data[key] = value
+			}
+		} else {
+			data["_body"] = parsed
+		}
+	case c.ContentType() == gin.MIMEPOSTForm:
+		values, _ := url.ParseQuery(string(body))
+		for key, v := range values {
+			data[key] = formValue(v)
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(maskSensitive(data))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func formValue(values []string) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// maskSensitive replaces the values of sensitive keys, at any depth.
+func maskSensitive(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSensitiveKey(key) {
+				v[key] = hiddenValue
+			} else {
+				v[key] = maskSensitive(inner)
+			}
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = maskSensitive(inner)
+		}
+	}
+	return value
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// Subject types of the audit trail. They match the Laravel models so both apps
+// share the same activity_log.
+const (
+	SubjectUser                = "App\\Models\\User"
+	SubjectFabric              = LoggableFabric
+	SubjectDestroyRequest      = LoggableFabricDestroyRequest
+	SubjectRequestItem         = LoggableRequestItem
+	SubjectRequest             = "App\\Models\\Request"
+	SubjectRequestProcess      = "App\\Models\\RequestProcess"
+	SubjectBlock               = "App\\Models\\Block"
+	SubjectRack                = "App\\Models\\Rack"
+	SubjectRelaxationBlock     = "App\\Models\\RelaxationBlock"
+	SubjectRelaxationRack      = "App\\Models\\RelaxationRack"
+	SubjectUnit                = "App\\Models\\Unit"
+	SubjectDefectType          = "App\\Models\\DefectType"
+	SubjectMovementType        = "App\\Models\\MovementType"
+	SubjectBuyer               = "App\\Models\\Buyer"
+	SubjectSupplier            = "App\\Models\\Supplier"
+	SubjectSupplierCategory    = "App\\Models\\SupplierCategory"
+	SubjectSupplierDocument    = "App\\Models\\SupplierDocument"
+	SubjectSupplierRating      = "App\\Models\\SupplierRating"
+	SubjectVendor              = "App\\Models\\Vendor"
+	SubjectNumbering           = "App\\Models\\SettingNumbering"
+	SubjectWebhookSubscription = "App\\Models\\WebhookSubscription"
+	SubjectWebhookDelivery     = "App\\Models\\WebhookDelivery"
+)
+
+// MasterSubjects maps the master tables to their subject types.
+var MasterSubjects = map[MasterTable]string{
+	MasterBlocks:           SubjectBlock,
+	MasterRacks:            SubjectRack,
+	MasterRelaxationBlocks: SubjectRelaxationBlock,
+	MasterRelaxationRacks:  SubjectRelaxationRack,
+	MasterUnits:            SubjectUnit,
+	MasterDefectTypes:      SubjectDefectType,
+	MasterMovementTypes:    SubjectMovementType,
+}
+
+type ActivityLogRepository interface {
+	Create(ctx context.Context, activity *domain.Activity) error
+	List(ctx context.Context, filter domain.ActivityFilter) ([]domain.Activity, error)
+}
+
+type mysqlActivityLogRepository struct {
+	db *sql.DB
+}
+
+func NewActivityLogRepository(db *sql.DB) ActivityLogRepository {
+	return &mysqlActivityLogRepository{db: db}
+}
+
+func (r *mysqlActivityLogRepository) Create(ctx context.Context, a *domain.Activity) error {
+	now := time.Now()
+
+	var properties *string
+	if len(a.Properties) > 0 {
+		p := string(a.Properties)
+		properties = &p
+	}
+
+	query := `INSERT INTO activity_log (log_name, description, subject_type, event, subject_id, causer_type, causer_id, properties, batch_uuid, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, a.LogName, a.Description, a.SubjectType, a.Event, a.SubjectID, a.CauserType, a.CauserID, properties, a.BatchUUID, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert activity: %w", err)
+	}
+	a.ID, _ = result.LastInsertId()
+	a.CreatedAt = &now
+	return nil
+}
+
+// List returns the matching activities, newest first.
+func (r *mysqlActivityLogRepository) List(ctx context.Context, filter domain.ActivityFilter) ([]domain.Activity, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if filter.CauserID != nil {
+		conditions = append(conditions, "a.causer_type = ? AND a.causer_id = ?")
+		args = append(args, SubjectUser, *filter.CauserID)
+	}
+	if filter.SubjectType != "" {
+		conditions = append(conditions, "a.subject_type = ?")
+		args = append(args, filter.SubjectType)
+	}
+	if filter.SubjectID != nil {
+		conditions = append(conditions, "a.subject_id = ?")
+		args = append(args, *filter.SubjectID)
+	}
+	if filter.Event != "" {
+		conditions = append(conditions, "a.event = ?")
+		args = append(args, filter.Event)
+	}
+	if filter.LogName != "" {
+		conditions = append(conditions, "a.log_name = ?")
+		args = append(args, filter.LogName)
+	}
+	if filter.BatchUUID != "" {
+		conditions = append(conditions, "a.batch_uuid = ?")
+		args = append(args, filter.BatchUUID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "a.created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "a.created_at < ?")
+		args = append(args, *filter.To)
+	}
+
+	query := `
+		SELECT a.id, a.log_name, a.description, a.subject_type, a.subject_id, a.event, a.causer_type, a.causer_id, u.name, a.properties, a.batch_uuid, a.created_at
+		FROM activity_log a
+		LEFT JOIN users u ON a.causer_type = ? AND u.id = a.causer_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY a.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append([]interface{}{SubjectUser}, args...)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activities: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []domain.Activity{}
+	for rows.Next() {
+		var a domain.Activity
+		var properties sql.NullString
+		if err := rows.Scan(&a.ID, &a.LogName, &a.Description, &a.SubjectType, &a.SubjectID, &a.Event, &a.CauserType, &a.CauserID, &a.CauserName, &properties, &a.BatchUUID, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if properties.Valid && properties.String != "" {
+			a.Properties = []byte(properties.String)
+		}
+		activities = append(activities, a)
+	}
+	return activities, nil
+}
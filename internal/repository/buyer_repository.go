@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type BuyerRepository interface {
+	List(ctx context.Context, filter PartnerFilter) ([]domain.Buyer, error)
+	FindByID(ctx context.Context, id int64) (*domain.Buyer, error)
+	Create(ctx context.Context, buyer *domain.Buyer) error
+	Update(ctx context.Context, buyer *domain.Buyer) error
+	CodeExists(ctx context.Context, code string, excludeID int64) (bool, error)
+	SetStatus(ctx context.Context, id int64, status int) error
+	CountOrders(ctx context.Context, id int64) (int, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type mysqlBuyerRepository struct {
+	db *sql.DB
+}
+
+func NewBuyerRepository(db *sql.DB) BuyerRepository {
+	return &mysqlBuyerRepository{db: db}
+}
+
+const buyerColumns = `b.id, b.code, b.name, b.contact_person, b.phone, b.address, b.email, b.city, b.country, b.status, b.remarks, b.created_at, b.updated_at, b.deleted_at`
+
+func scanBuyer(row interface{ Scan(...interface{}) error }, b *domain.Buyer) error {
+	return row.Scan(
+		&b.ID, &b.Code, &b.Name, &b.ContactPerson, &b.Phone, &b.Address, &b.Email,
+		&b.City, &b.Country, &b.Status, &b.Remarks, &b.CreatedAt, &b.UpdatedAt, &b.DeletedAt,
+	)
+}
+
+func (r *mysqlBuyerRepository) List(ctx context.Context, filter PartnerFilter) ([]domain.Buyer, error) {
+	where, args := partnerConditions("b", filter)
+	query := "SELECT " + buyerColumns + " FROM buyers b WHERE " + where + " ORDER BY b.name"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get buyers: %w", err)
+	}
+	defer rows.Close()
+
+	var buyers []domain.Buyer
+	for rows.Next() {
+		var b domain.Buyer
+		if err := scanBuyer(rows, &b); err != nil {
+			return nil, fmt.Errorf("failed to scan buyer: %w", err)
+		}
+		buyers = append(buyers, b)
+	}
+	return buyers, nil
+}
+
+func (r *mysqlBuyerRepository) FindByID(ctx context.Context, id int64) (*domain.Buyer, error) {
+	query := "SELECT " + buyerColumns + " FROM buyers b WHERE b.id = ? AND b.deleted_at IS NULL"
+
+	var b domain.Buyer
+	err := scanBuyer(r.db.QueryRowContext(ctx, query, id), &b)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find buyer: %w", err)
+	}
+	return &b, nil
+}
+
+func (r *mysqlBuyerRepository) Create(ctx context.Context, b *domain.Buyer) error {
+	now := time.Now()
+	query := `INSERT INTO buyers (code, name, contact_person, phone, address, email, city, country, status, remarks, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, b.Code, b.Name, b.ContactPerson, b.Phone, b.Address, b.Email, b.City, b.Country, b.Status, b.Remarks, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert buyer: %w", err)
+	}
+	b.ID, _ = result.LastInsertId()
+	b.CreatedAt, b.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlBuyerRepository) Update(ctx context.Context, b *domain.Buyer) error {
+	now := time.Now()
+	query := `UPDATE buyers SET code = ?, name = ?, contact_person = ?, phone = ?, address = ?, email = ?, city = ?, country = ?, status = ?, remarks = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, b.Code, b.Name, b.ContactPerson, b.Phone, b.Address, b.Email, b.City, b.Country, b.Status, b.Remarks, now, b.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update buyer: %w", err)
+	}
+	b.UpdatedAt = now
+	return nil
+}
+
+func (r *mysqlBuyerRepository) CodeExists(ctx context.Context, code string, excludeID int64) (bool, error) {
+	return partnerCodeExists(ctx, r.db, "buyers", code, excludeID)
+}
+
+func (r *mysqlBuyerRepository) SetStatus(ctx context.Context, id int64, status int) error {
+	return setPartnerStatus(ctx, r.db, "buyers", id, status)
+}
+
+func (r *mysqlBuyerRepository) CountOrders(ctx context.Context, id int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE buyer_id = ? AND deleted_at IS NULL`, id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count buyer orders: %w", err)
+	}
+	return count, nil
+}
+
+func (r *mysqlBuyerRepository) Delete(ctx context.Context, id int64) error {
+	return softDeleteRow(ctx, r.db, "buyers", id)
+}
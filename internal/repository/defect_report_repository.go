@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type DefectReportRepository interface {
+	CountByDefectType(ctx context.Context, filter domain.DefectFilter) ([]domain.DefectParetoItem, error)
+	CountByProcess(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) ([]domain.DefectProcessStat, error)
+	CountByOperator(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) (map[domain.DefectProcessKey][]domain.DefectOperator, error)
+}
+
+type mysqlDefectReportRepository struct {
+	db *sql.DB
+}
+
+func NewDefectReportRepository(db *sql.DB) DefectReportRepository {
+	return &mysqlDefectReportRepository{db: db}
+}
+
+// defectConditions filters request_defects rd joined with requests r and orders o.
+func defectConditions(filter domain.DefectFilter) (string, []interface{}) {
+	conditions := " AND rd.deleted_at IS NULL"
+	var args []interface{}
+	if filter.From != nil {
+		conditions += " AND rd.created_at >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions += " AND rd.created_at < ?"
+		args = append(args, *filter.To)
+	}
+	if filter.LineID != nil {
+		conditions += " AND r.line_id = ?"
+		args = append(args, *filter.LineID)
+	}
+	if filter.OrderID != nil {
+		conditions += " AND r.order_id = ?"
+		args = append(args, *filter.OrderID)
+	}
+	if filter.Style != "" {
+		conditions += " AND o.style = ?"
+		args = append(args, filter.Style)
+	}
+	return conditions, args
+}
+
+// CountByDefectType returns the defect types found, most frequent first.
+func (r *mysqlDefectReportRepository) CountByDefectType(ctx context.Context, filter domain.DefectFilter) ([]domain.DefectParetoItem, error) {
+	conditions, args := defectConditions(filter)
+	query := `
+		SELECT dt.id, dt.name, COUNT(*) AS total
+		FROM request_defects rd
+		JOIN m_defect_types dt ON dt.id = rd.defect_type_id
+		JOIN requests r ON r.id = rd.request_id
+		JOIN orders o ON o.id = r.order_id
+		WHERE 1 = 1` + conditions + `
+		GROUP BY dt.id, dt.name
+		ORDER BY total DESC, dt.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count defects by type: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.DefectParetoItem
+	for rows.Next() {
+		var item domain.DefectParetoItem
+		if err := rows.Scan(&item.DefectTypeID, &item.Name, &item.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan defect count: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// CountByProcess returns the order processes blamed for a defect type, most
+// frequent first. Defects recorded with only a process are counted under that
+// process, so the counts add up to the defects of the type.
+func (r *mysqlDefectReportRepository) CountByProcess(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) ([]domain.DefectProcessStat, error) {
+	conditions, args := defectConditions(filter)
+	query := `
+		SELECT COALESCE(op.id, 0), COALESCE(op.process_id, rdp.process_id), COALESCE(op.name, p.name, '-'), COUNT(DISTINCT rd.id) AS total
+		FROM request_defects rd
+		JOIN request_defect_processes rdp ON rdp.request_defect_id = rd.id AND rdp.deleted_at IS NULL
+		LEFT JOIN order_processes op ON op.id = rdp.order_process_id
+		LEFT JOIN processes p ON p.id = COALESCE(op.process_id, rdp.process_id)
+		JOIN requests r ON r.id = rd.request_id
+		JOIN orders o ON o.id = r.order_id
+		WHERE rd.defect_type_id = ?` + conditions + `
+		GROUP BY COALESCE(op.id, 0), COALESCE(op.process_id, rdp.process_id), op.name, p.name
+		ORDER BY total DESC, COALESCE(op.id, 0)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{defectTypeID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count defects by process: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []domain.DefectProcessStat
+	for rows.Next() {
+		var s domain.DefectProcessStat
+		if err := rows.Scan(&s.OrderProcessID, &s.ProcessID, &s.Name, &s.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan process defect count: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// CountByOperator returns, per order process, the operators that were assigned
+// to it on the request the defect was found on. Defects recorded with only a
+// process are matched to the operators assigned to that process.
+func (r *mysqlDefectReportRepository) CountByOperator(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) (map[domain.DefectProcessKey][]domain.DefectOperator, error) {
+	conditions, args := defectConditions(filter)
+	query := `
+		SELECT COALESCE(op.id, 0), CASE WHEN op.id IS NULL THEN COALESCE(rdp.process_id, 0) ELSE 0 END,
+			mp.id, mp.nickname, mp.fullname, COUNT(DISTINCT rd.id) AS total
+		FROM request_defects rd
+		JOIN request_defect_processes rdp ON rdp.request_defect_id = rd.id AND rdp.deleted_at IS NULL
+		LEFT JOIN order_processes op ON op.id = rdp.order_process_id
+		JOIN request_processes rp ON rp.request_id = rd.request_id AND rp.deleted_at IS NULL
+			AND (rp.order_process_id = op.id OR (op.id IS NULL AND rp.process_id = rdp.process_id))
+		JOIN man_powers mp ON mp.id = rp.man_power_id
+		JOIN requests r ON r.id = rd.request_id
+		JOIN orders o ON o.id = r.order_id
+		WHERE rd.defect_type_id = ?` + conditions + `
+		GROUP BY COALESCE(op.id, 0), CASE WHEN op.id IS NULL THEN COALESCE(rdp.process_id, 0) ELSE 0 END, mp.id, mp.nickname, mp.fullname
+		ORDER BY total DESC, mp.nickname
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{defectTypeID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count defects by operator: %w", err)
+	}
+	defer rows.Close()
+
+	operators := map[domain.DefectProcessKey][]domain.DefectOperator{}
+	for rows.Next() {
+		var key domain.DefectProcessKey
+		var op domain.DefectOperator
+		if err := rows.Scan(&key.OrderProcessID, &key.ProcessID, &op.ManPowerID, &op.Nickname, &op.Fullname, &op.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan operator defect count: %w", err)
+		}
+		operators[key] = append(operators[key], op)
+	}
+	return operators, nil
+}
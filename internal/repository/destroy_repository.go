@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+var ErrDestroyRequestNotPending = errors.New("destroy request is no longer pending")
+
+// ErrDestroyRequestPending is returned by Create when the roll already has a
+// pending destroy request.
+var ErrDestroyRequestPending = errors.New("roll already has a pending destroy request")
+
+type DestroyRepository interface {
+	Create(ctx context.Context, req *domain.FabricDestroyRequest) (int64, error)
+	FindByID(ctx context.Context, id int64) (*domain.FabricDestroyRequest, error)
+	List(ctx context.Context, status string) ([]domain.FabricDestroyRequest, error)
+	HasPending(ctx context.Context, fabricID int64) (bool, error)
+	Approve(ctx context.Context, id, reviewerID int64, notes string) error
+	Reject(ctx context.Context, id, reviewerID int64, notes string) error
+}
+
+type mysqlDestroyRepository struct {
+	db         *sql.DB
+	fabricRepo *FabricRepository
+}
+
+func NewDestroyRepository(db *sql.DB, fabricRepo *FabricRepository) DestroyRepository {
+	return &mysqlDestroyRepository{db: db, fabricRepo: fabricRepo}
+}
+
+// Create stores a pending destroy request together with its photos. The roll
+// is locked while its pending requests are checked, so two concurrent requests
+// for the same roll cannot both be stored.
+func (r *mysqlDestroyRepository) Create(ctx context.Context, req *domain.FabricDestroyRequest) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fabricID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM fabrics WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, req.FabricID).Scan(&fabricID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("fabric %d is not found", req.FabricID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock fabric: %w", err)
+	}
+	pending, err := hasPendingDestroyRequest(ctx, tx, req.FabricID)
+	if err != nil {
+		return 0, err
+	}
+	if pending {
+		return 0, ErrDestroyRequestPending
+	}
+
+	now := time.Now()
+
+	query := `INSERT INTO fabric_destroy_requests (fabric_id, reason, status, requested_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, req.FabricID, req.Reason, domain.DestroyStatusPending, req.RequestedBy, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert destroy request: %w", err)
+	}
+	id, _ := result.LastInsertId()
+
+	for _, path := range req.Photos {
+		_, err = tx.ExecContext(ctx, `INSERT INTO fabric_destroy_photos (fabric_destroy_request_id, file_path, created_at, updated_at) VALUES (?, ?, ?, ?)`, id, path, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert destroy photo: %w", err)
+		}
+	}
+
+	if err := logStatus(ctx, tx, StatusLogEntry{
+		Module:       "fabric",
+		LoggableType: LoggableFabricDestroyRequest,
+		LoggableID:   id,
+		Status:       domain.DestroyStatusPending,
+		Action:       "submitted",
+		Notes:        req.Reason,
+		CreatedBy:    req.RequestedBy,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit destroy request: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *mysqlDestroyRepository) FindByID(ctx context.Context, id int64) (*domain.FabricDestroyRequest, error) {
+	query := `
+		SELECT
+			d.id, d.fabric_id, f.code, d.reason, d.status, d.requested_by,
+			d.reviewed_by, d.reviewed_at, d.review_notes, d.inventory_movement_id,
+			d.created_at, d.updated_at
+		FROM fabric_destroy_requests d
+		JOIN fabrics f ON f.id = d.fabric_id
+		WHERE d.id = ? AND d.deleted_at IS NULL
+		LIMIT 1
+	`
+
+	var req domain.FabricDestroyRequest
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&req.ID, &req.FabricID, &req.FabricCode, &req.Reason, &req.Status, &req.RequestedBy,
+		&req.ReviewedBy, &req.ReviewedAt, &req.ReviewNotes, &req.InventoryMovementID,
+		&req.CreatedAt, &req.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find destroy request: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT file_path FROM fabric_destroy_photos WHERE fabric_destroy_request_id = ? AND deleted_at IS NULL ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destroy photos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan destroy photo: %w", err)
+		}
+		req.Photos = append(req.Photos, path)
+	}
+
+	return &req, nil
+}
+
+// List returns destroy requests, newest first. An empty status returns all of them.
+func (r *mysqlDestroyRepository) List(ctx context.Context, status string) ([]domain.FabricDestroyRequest, error) {
+	query := `
+		SELECT
+			d.id, d.fabric_id, f.code, d.reason, d.status, d.requested_by,
+			d.reviewed_by, d.reviewed_at, d.review_notes, d.inventory_movement_id,
+			d.created_at, d.updated_at
+		FROM fabric_destroy_requests d
+		JOIN fabrics f ON f.id = d.fabric_id
+		WHERE d.deleted_at IS NULL AND (? = '' OR d.status = ?)
+		ORDER BY d.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list destroy requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []domain.FabricDestroyRequest
+	for rows.Next() {
+		var req domain.FabricDestroyRequest
+		if err := rows.Scan(
+			&req.ID, &req.FabricID, &req.FabricCode, &req.Reason, &req.Status, &req.RequestedBy,
+			&req.ReviewedBy, &req.ReviewedAt, &req.ReviewNotes, &req.InventoryMovementID,
+			&req.CreatedAt, &req.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan destroy request: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+func (r *mysqlDestroyRepository) HasPending(ctx context.Context, fabricID int64) (bool, error) {
+	return hasPendingDestroyRequest(ctx, r.db, fabricID)
+}
+
+func hasPendingDestroyRequest(ctx context.Context, db queryRower, fabricID int64) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM fabric_destroy_requests WHERE fabric_id = ? AND status = ? AND deleted_at IS NULL`, fabricID, domain.DestroyStatusPending).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pending destroy requests: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Approve marks the request approved and moves the roll to the destroy stage
+// in the same transaction.
+func (r *mysqlDestroyRepository) Approve(ctx context.Context, id, reviewerID int64, notes string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fabricID, err := lockPendingDestroyRequest(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	var onStage sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT stage FROM inventories WHERE fabric_id = ? AND deleted_at IS NULL`, fabricID).Scan(&onStage)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	toStage := string(domain.StageDestroy)
+	remarks := "From " + onStage.String
+	if onStage.String == toStage {
+		remarks = "Return " + onStage.String
+	}
+
+	invMovementID, err := r.fabricRepo.handleStage(ctx, tx, fabricID, toStage, remarks, onStage.String, reviewerID)
+	if err != nil {
+		return fmt.Errorf("failed to handle stage: %w", err)
+	}
+
+	now := time.Now()
+	query := `UPDATE fabric_destroy_requests SET status = ?, reviewed_by = ?, reviewed_at = ?, review_notes = ?, inventory_movement_id = ?, updated_at = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, domain.DestroyStatusApproved, reviewerID, now, nullableString(notes), invMovementID, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to approve destroy request: %w", err)
+	}
+
+	if err := logStatus(ctx, tx, StatusLogEntry{
+		Module:       "fabric",
+		LoggableType: LoggableFabricDestroyRequest,
+		LoggableID:   id,
+		Status:       domain.DestroyStatusApproved,
+		Action:       "approved",
+		Notes:        notes,
+		CreatedBy:    reviewerID,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *mysqlDestroyRepository) Reject(ctx context.Context, id, reviewerID int64, notes string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPendingDestroyRequest(ctx, tx, id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `UPDATE fabric_destroy_requests SET status = ?, reviewed_by = ?, reviewed_at = ?, review_notes = ?, updated_at = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, domain.DestroyStatusRejected, reviewerID, now, nullableString(notes), now, id)
+	if err != nil {
+		return fmt.Errorf("failed to reject destroy request: %w", err)
+	}
+
+	if err := logStatus(ctx, tx, StatusLogEntry{
+		Module:       "fabric",
+		LoggableType: LoggableFabricDestroyRequest,
+		LoggableID:   id,
+		Status:       domain.DestroyStatusRejected,
+		Action:       "rejected",
+		Notes:        notes,
+		CreatedBy:    reviewerID,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockPendingDestroyRequest locks the request row for the rest of the
+// transaction so two reviewers cannot decide on the same request at once.
+func lockPendingDestroyRequest(ctx context.Context, tx *sql.Tx, id int64) (int64, error) {
+	var fabricID int64
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT fabric_id, status FROM fabric_destroy_requests WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, id).Scan(&fabricID, &status)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("destroy request %d is not found", id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock destroy request: %w", err)
+	}
+	if status != domain.DestroyStatusPending {
+		return 0, ErrDestroyRequestNotPending
+	}
+	return fabricID, nil
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
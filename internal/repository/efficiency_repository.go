@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// HourlyOutput counts the pieces checked on a request within an hour.
+type HourlyOutput struct {
+	RequestID int64
+	Hour      time.Time
+	Checked   int
+	Output    int
+}
+
+type EfficiencyRepository interface {
+	GetRequestTargets(ctx context.Context, filter domain.EfficiencyFilter) ([]domain.LineEfficiency, error)
+	GetHourlyOutput(ctx context.Context, filter domain.EfficiencyFilter) ([]HourlyOutput, error)
+}
+
+type mysqlEfficiencyRepository struct {
+	db *sql.DB
+}
+
+func NewEfficiencyRepository(db *sql.DB) EfficiencyRepository {
+	return &mysqlEfficiencyRepository{db: db}
+}
+
+func efficiencyConditions(filter domain.EfficiencyFilter) (string, []interface{}) {
+	conditions := " AND ri.resulted_at >= ? AND ri.resulted_at < ?"
+	args := []interface{}{filter.From, filter.To}
+	if filter.LineID != nil {
+		conditions += " AND r.line_id = ?"
+		args = append(args, *filter.LineID)
+	}
+	return conditions, args
+}
+
+// GetRequestTargets returns the targets of every request with pieces checked
+// in the range, taken from its latest request_details row.
+func (r *mysqlEfficiencyRepository) GetRequestTargets(ctx context.Context, filter domain.EfficiencyFilter) ([]domain.LineEfficiency, error) {
+	conditions, args := efficiencyConditions(filter)
+	query := `
+		SELECT
+			r.id, r.code, r.line_id, COALESCE(l.name, '-'), COALESCE(o.style, '-'),
+			COALESCE(rd.man_total, 0), COALESCE(rd.target_smv, 0), COALESCE(rd.target_qty, 0)
+		FROM requests r
+		LEFT JOIN ` + linesTable + ` l ON l.id = r.line_id
+		LEFT JOIN orders o ON o.id = r.order_id
+		LEFT JOIN request_details rd ON rd.id = (
+			SELECT MAX(d.id) FROM request_details d WHERE d.request_id = r.id AND d.deleted_at IS NULL
+		)
+		WHERE r.deleted_at IS NULL AND r.id IN (
+			SELECT ri.request_id FROM request_items ri JOIN requests r ON r.id = ri.request_id
+			WHERE ri.deleted_at IS NULL` + conditions + `
+		)
+		ORDER BY l.name, r.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request targets: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []domain.LineEfficiency
+	for rows.Next() {
+		var t domain.LineEfficiency
+		if err := rows.Scan(&t.RequestID, &t.RequestCode, &t.LineID, &t.LineName, &t.Style, &t.ManTotal, &t.TargetSMV, &t.TargetQty); err != nil {
+			return nil, fmt.Errorf("failed to scan request target: %w", err)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// GetHourlyOutput counts the checked and passed pieces per request and hour.
+func (r *mysqlEfficiencyRepository) GetHourlyOutput(ctx context.Context, filter domain.EfficiencyFilter) ([]HourlyOutput, error) {
+	conditions, args := efficiencyConditions(filter)
+	query := `
+		SELECT ri.request_id, DATE_FORMAT(ri.resulted_at, '%Y-%m-%d %H:00:00') AS hour, COUNT(*), COALESCE(SUM(ri.result = 1), 0)
+		FROM request_items ri
+		JOIN requests r ON r.id = ri.request_id
+		WHERE ri.deleted_at IS NULL` + conditions + `
+		GROUP BY ri.request_id, hour
+		ORDER BY ri.request_id, hour
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly output: %w", err)
+	}
+	defer rows.Close()
+
+	var output []HourlyOutput
+	for rows.Next() {
+		var o HourlyOutput
+		var hour string
+		if err := rows.Scan(&o.RequestID, &hour, &o.Checked, &o.Output); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly output: %w", err)
+		}
+		if o.Hour, err = time.ParseInLocation("2006-01-02 15:04:05", hour, time.Local); err != nil {
+			return nil, fmt.Errorf("failed to parse output hour: %w", err)
+		}
+		output = append(output, o)
+	}
+	return output, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type EmailRepository interface {
+	FindConfig(ctx context.Context, module, action string) (*domain.EmailConfig, error)
+	CreateLog(ctx context.Context, email *domain.EmailLog) error
+	Pending(ctx context.Context, staleBefore time.Time, limit int) ([]domain.EmailLog, error)
+	Claim(ctx context.Context, email *domain.EmailLog) (bool, error)
+	UpdateDelivery(ctx context.Context, email *domain.EmailLog) error
+}
+
+type mysqlEmailRepository struct {
+	db *sql.DB
+}
+
+func NewEmailRepository(db *sql.DB) EmailRepository {
+	return &mysqlEmailRepository{db: db}
+}
+
+// FindConfig returns the email setting of a module action, preferring the
+// default one. It returns nil when none is set up.
+func (r *mysqlEmailRepository) FindConfig(ctx context.Context, module, action string) (*domain.EmailConfig, error) {
+	query := `
+		SELECT id, app_name, module_name, ` + "`from`, `to`" + `, cc, bcc, action, subject, supported_by, supported_link
+		FROM setting_email_configs
+		WHERE module_name = ? AND action = ? AND deleted_at IS NULL
+		ORDER BY is_default DESC, id
+		LIMIT 1
+	`
+	var c domain.EmailConfig
+	err := r.db.QueryRowContext(ctx, query, module, action).Scan(&c.ID, &c.AppName, &c.ModuleName, &c.From, &c.To, &c.Cc, &c.Bcc, &c.Action, &c.Subject, &c.SupportedBy, &c.SupportedLink)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email config: %w", err)
+	}
+	return &c, nil
+}
+
+// CreateLog queues an email in the outbox.
+func (r *mysqlEmailRepository) CreateLog(ctx context.Context, e *domain.EmailLog) error {
+	now := time.Now()
+	query := `
+		INSERT INTO setting_email_logs (app_name, module_name, action_name, doc_type, doc_id, subject, ` + "`to`" + `, cc, bcc, action_time, status, recipients, doc_url, data_body, attempt, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`
+	result, err := r.db.ExecContext(ctx, query, e.AppName, e.ModuleName, e.ActionName, e.DocType, e.DocID, e.Subject, e.To, e.Cc, e.Bcc, now, domain.EmailStatusQueued, e.Recipients, e.DocURL, e.DataBody, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	e.ID, _ = result.LastInsertId()
+	e.ActionTime, e.UpdatedAt = &now, &now
+	e.Status = domain.EmailStatusQueued
+	return nil
+}
+
+// Pending returns the emails waiting to be sent, oldest first: queued ones,
+// ones waiting for a retry and ones whose sender stopped before finishing,
+// i.e. still sending since before staleBefore.
+func (r *mysqlEmailRepository) Pending(ctx context.Context, staleBefore time.Time, limit int) ([]domain.EmailLog, error) {
+	query := `
+		SELECT id, app_name, module_name, action_name, doc_type, doc_id, subject, ` + "`to`" + `, cc, bcc, action_time, send_time, status, recipients, doc_url, data_body, attempt, error_msg, updated_at
+		FROM setting_email_logs
+		WHERE deleted_at IS NULL
+		AND (status IN (?, ?) OR (status = ? AND updated_at < ?))
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, domain.EmailStatusQueued, domain.EmailStatusRetry, domain.EmailStatusSending, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []domain.EmailLog
+	for rows.Next() {
+		var e domain.EmailLog
+		var subject, to, body sql.NullString
+		var attempt sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.AppName, &e.ModuleName, &e.ActionName, &e.DocType, &e.DocID, &subject, &to, &e.Cc, &e.Bcc, &e.ActionTime, &e.SendTime, &e.Status, &e.Recipients, &e.DocURL, &body, &attempt, &e.ErrorMsg, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		e.Subject, e.To, e.DataBody, e.Attempt = subject.String, to.String, body.String, int(attempt.Int64)
+		emails = append(emails, e)
+	}
+	return emails, nil
+}
+
+// Claim marks the email as being sent, unless another worker got to it first
+// since it was read.
+func (r *mysqlEmailRepository) Claim(ctx context.Context, e *domain.EmailLog) (bool, error) {
+	now := time.Now()
+	query := `UPDATE setting_email_logs SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND attempt = ?`
+	result, err := r.db.ExecContext(ctx, query, domain.EmailStatusSending, now, e.ID, e.Status, e.Attempt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim email: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	e.Status, e.UpdatedAt = domain.EmailStatusSending, &now
+	return true, nil
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt.
+func (r *mysqlEmailRepository) UpdateDelivery(ctx context.Context, e *domain.EmailLog) error {
+	now := time.Now()
+	query := `UPDATE setting_email_logs SET status = ?, attempt = ?, send_time = ?, error_msg = ?, updated_at = ? WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, e.Status, e.Attempt, e.SendTime, e.ErrorMsg, now, e.ID); err != nil {
+		return fmt.Errorf("failed to update email delivery: %w", err)
+	}
+	e.UpdatedAt = &now
+	return nil
+}
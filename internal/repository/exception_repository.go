@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type ExceptionRepository interface {
+	Create(ctx context.Context, e *domain.Exception) error
+	List(ctx context.Context, filter domain.ExceptionFilter) ([]domain.Exception, error)
+	FindByID(ctx context.Context, id int64) (*domain.Exception, error)
+}
+
+type mysqlExceptionRepository struct {
+	db *sql.DB
+}
+
+func NewExceptionRepository(db *sql.DB) ExceptionRepository {
+	return &mysqlExceptionRepository{db: db}
+}
+
+func (r *mysqlExceptionRepository) Create(ctx context.Context, e *domain.Exception) error {
+	now := time.Now()
+
+	var requestData *string
+	if len(e.RequestData) > 0 {
+		d := string(e.RequestData)
+		requestData = &d
+	}
+
+	query := `INSERT INTO exceptions (user_id, url, method, ip_address, request_data, exception_class, status_code, message, file, line, trace, caller_file, caller_line, caller_function, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, e.UserID, e.URL, e.Method, e.IPAddress, requestData, e.ExceptionClass, e.StatusCode, e.Message, e.File, e.Line, e.Trace, e.CallerFile, e.CallerLine, e.CallerFunction, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert exception: %w", err)
+	}
+	e.ID, _ = result.LastInsertId()
+	e.CreatedAt = &now
+	return nil
+}
+
+// List returns the matching exceptions, newest first. The stack trace is
+// left out; fetch a single exception to see it.
+func (r *mysqlExceptionRepository) List(ctx context.Context, filter domain.ExceptionFilter) ([]domain.Exception, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "e.user_id = ?")
+		args = append(args, *filter.UserID)
+	}
+	if filter.StatusCode != 0 {
+		conditions = append(conditions, "e.status_code = ?")
+		args = append(args, filter.StatusCode)
+	}
+	if filter.Method != "" {
+		conditions = append(conditions, "e.method = ?")
+		args = append(args, filter.Method)
+	}
+	if filter.ExceptionClass != "" {
+		conditions = append(conditions, "e.exception_class = ?")
+		args = append(args, filter.ExceptionClass)
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, "(e.message LIKE ? OR e.url LIKE ?)")
+		like := "%" + filter.Search + "%"
+		args = append(args, like, like)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "e.created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "e.created_at < ?")
+		args = append(args, *filter.To)
+	}
+
+	query := `
+		SELECT e.id, e.user_id, u.name, e.url, e.method, e.ip_address, e.request_data, e.exception_class, e.status_code, e.message, e.file, e.line, e.caller_file, e.caller_line, e.caller_function, e.created_at
+		FROM exceptions e
+		LEFT JOIN users u ON u.id = e.user_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY e.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	exceptions := []domain.Exception{}
+	for rows.Next() {
+		var e domain.Exception
+		var requestData sql.NullString
+		if err := rows.Scan(&e.ID, &e.UserID, &e.UserName, &e.URL, &e.Method, &e.IPAddress, &requestData, &e.ExceptionClass, &e.StatusCode, &e.Message, &e.File, &e.Line, &e.CallerFile, &e.CallerLine, &e.CallerFunction, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan exception: %w", err)
+		}
+		if requestData.Valid && requestData.String != "" {
+			e.RequestData = []byte(requestData.String)
+		}
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, nil
+}
+
+func (r *mysqlExceptionRepository) FindByID(ctx context.Context, id int64) (*domain.Exception, error) {
+	query := `
+		SELECT e.id, e.user_id, u.name, e.url, e.method, e.ip_address, e.request_data, e.exception_class, e.status_code, e.message, e.file, e.line, e.trace, e.caller_file, e.caller_line, e.caller_function, e.created_at
+		FROM exceptions e
+		LEFT JOIN users u ON u.id = e.user_id
+		WHERE e.id = ?
+	`
+
+	var e domain.Exception
+	var requestData sql.NullString
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&e.ID, &e.UserID, &e.UserName, &e.URL, &e.Method, &e.IPAddress, &requestData, &e.ExceptionClass, &e.StatusCode, &e.Message, &e.File, &e.Line, &e.Trace, &e.CallerFile, &e.CallerLine, &e.CallerFunction, &e.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get exception: %w", err)
+	}
+	if requestData.Valid && requestData.String != "" {
+		e.RequestData = []byte(requestData.String)
+	}
+	return &e, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -10,6 +11,13 @@ import (
 	"github.com/dppi/dppierp-api/internal/domain"
 )
 
+var (
+	// ErrFabricNotFound is returned when a moved QR code has no fabric.
+	ErrFabricNotFound = errors.New("QR code is not found")
+	// ErrRackEmpty is returned when relocating a rack without rolls.
+	ErrRackEmpty = errors.New("no fabric found in the selected current rack")
+)
+
 type FabricRepository struct {
 	db *sql.DB
 }
@@ -19,7 +27,7 @@ func NewFabricRepository(db *sql.DB) *FabricRepository {
 }
 
 func (r *FabricRepository) GetMovementTypes(ctx context.Context) ([]domain.MovementType, error) {
-	query := `SELECT id, name FROM movement_types ORDER BY id`
+	query := `SELECT id, name FROM movement_types WHERE deleted_at IS NULL ORDER BY id`
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -93,7 +101,7 @@ func (r *FabricRepository) FindByCodeWithInventory(ctx context.Context, code str
 			f.weight, f.width, f.yard, f.unit_id, f.fabric_type, f.fabric_contain,
 			f.rack_id, f.block_id, f.relaxation_rack_id, f.relaxation_block_id,
 			f.finish_date, f.qc_result, f.status, f.created_at, f.updated_at,
-			COALESCE(b.name, '-') as buyer,
+			COALESCE(b.name, '-') as buyer, o.buyer_id,
 			COALESCE(o.style, '-') as style,
 			i.id as inv_id, i.stage as inv_stage
 		FROM fabrics f
@@ -116,7 +124,7 @@ func (r *FabricRepository) FindByCodeWithInventory(ctx context.Context, code str
 		&fabric.Yard, &fabric.UnitID, &fabric.FabricType, &fabric.FabricContain,
 		&fabric.RackID, &fabric.BlockID, &fabric.RelaxationRackID, &fabric.RelaxationBlockID,
 		&finishDate, &qcResult, &fabric.Status, &fabric.CreatedAt, &fabric.UpdatedAt,
-		&fabric.Buyer, &fabric.Style,
+		&fabric.Buyer, &fabric.BuyerID, &fabric.Style,
 		&invID, &invStage,
 	)
 
@@ -215,6 +223,7 @@ type MoveRequestData struct {
 	RelaxationBlockID *int64
 	RelaxationRackID  *int64
 	Entries           []MoveEntryData
+	UserID            int64
 }
 
 type MoveEntryData struct {
@@ -240,7 +249,7 @@ func (r *FabricRepository) UpdateBlockRack(ctx context.Context, req *MoveRequest
 			return fmt.Errorf("error finding fabric %s: %w", code, err)
 		}
 		if fabric == nil {
-			return fmt.Errorf("QR code %s is not found", code)
+			return fmt.Errorf("%w: %s", ErrFabricNotFound, code)
 		}
 
 		onStage := ""
@@ -271,7 +280,7 @@ func (r *FabricRepository) UpdateBlockRack(ctx context.Context, req *MoveRequest
 			remarks = "Return " + onStage
 		}
 
-		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage)
+		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage, req.UserID)
 		if err != nil {
 			return fmt.Errorf("failed to handle stage: %w", err)
 		}
@@ -301,7 +310,7 @@ func (r *FabricRepository) UpdateRelaxationBlockRack(ctx context.Context, req *M
 			return fmt.Errorf("error finding fabric %s: %w", code, err)
 		}
 		if fabric == nil {
-			return fmt.Errorf("QR code %s is not found", code)
+			return fmt.Errorf("%w: %s", ErrFabricNotFound, code)
 		}
 
 		onStage := ""
@@ -327,7 +336,7 @@ func (r *FabricRepository) UpdateRelaxationBlockRack(ctx context.Context, req *M
 			remarks = "Return " + onStage
 		}
 
-		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage)
+		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage, req.UserID)
 		if err != nil {
 			return fmt.Errorf("failed to handle stage: %w", err)
 		}
@@ -360,7 +369,7 @@ func (r *FabricRepository) UpdateStageWithQC(ctx context.Context, req *MoveReque
 			return fmt.Errorf("error finding fabric %s: %w", code, err)
 		}
 		if fabric == nil {
-			return fmt.Errorf("QR code %s is not found", code)
+			return fmt.Errorf("%w: %s", ErrFabricNotFound, code)
 		}
 
 		onStage := ""
@@ -386,7 +395,7 @@ func (r *FabricRepository) UpdateStageWithQC(ctx context.Context, req *MoveReque
 			remarks = "Return " + onStage
 		}
 
-		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage)
+		invMovementID, err := r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage, req.UserID)
 		if err != nil {
 			return fmt.Errorf("failed to handle stage: %w", err)
 		}
@@ -395,6 +404,17 @@ func (r *FabricRepository) UpdateStageWithQC(ctx context.Context, req *MoveReque
 		if err != nil {
 			return fmt.Errorf("failed to update control log: %w", err)
 		}
+
+		err = enqueueWebhookEvents(ctx, tx, FabricWebhookPayload{
+			FabricID:            fabric.ID,
+			Code:                fabric.Code,
+			InventoryMovementID: invMovementID,
+			QCResult:            qcResult,
+			UserID:              req.UserID,
+		}, domain.WebhookQCResult)
+		if err != nil {
+			return err
+		}
 	}
 
 	return tx.Commit()
@@ -415,7 +435,7 @@ func (r *FabricRepository) UpdateStage(ctx context.Context, req *MoveRequestData
 			return fmt.Errorf("error finding fabric %s: %w", code, err)
 		}
 		if fabric == nil {
-			return fmt.Errorf("QR code %s is not found", code)
+			return fmt.Errorf("%w: %s", ErrFabricNotFound, code)
 		}
 
 		onStage := ""
@@ -428,7 +448,7 @@ func (r *FabricRepository) UpdateStage(ctx context.Context, req *MoveRequestData
 			remarks = "Return " + onStage
 		}
 
-		_, err = r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage)
+		_, err = r.handleStage(ctx, tx, fabric.ID, req.Stage, remarks, onStage, req.UserID)
 		if err != nil {
 			return fmt.Errorf("failed to handle stage: %w", err)
 		}
@@ -437,7 +457,9 @@ func (r *FabricRepository) UpdateStage(ctx context.Context, req *MoveRequestData
 	return tx.Commit()
 }
 
-func (r *FabricRepository) handleStage(ctx context.Context, tx *sql.Tx, fabricID int64, toStage, remarks, onStage string) (int64, error) {
+// handleStage moves a fabric to toStage: it finishes the running movement,
+// starts a new one and logs the stage change against the fabric.
+func (r *FabricRepository) handleStage(ctx context.Context, tx *sql.Tx, fabricID int64, toStage, remarks, onStage string, userID int64) (int64, error) {
 	now := time.Now()
 	currentDate := now.Format("2006-01-02")
 	currentTime := now.Format("15:04:05")
@@ -458,9 +480,9 @@ func (r *FabricRepository) handleStage(ctx context.Context, tx *sql.Tx, fabricID
 		inventoryID, _ = invResult.LastInsertId()
 	}
 
-	// Get movement_type_id based on stage
+	// Get movement_type_id based on stage (checked at startup, see MasterService.CheckStageMovementTypes)
 	var movementTypeID int64 = 1 // default
-	_ = tx.QueryRowContext(ctx, `SELECT id FROM movement_types WHERE name = ? LIMIT 1`, toStage).Scan(&movementTypeID)
+	_ = tx.QueryRowContext(ctx, `SELECT id FROM movement_types WHERE name = ? AND deleted_at IS NULL LIMIT 1`, toStage).Scan(&movementTypeID)
 
 	// Get existing movement ID to update its time record
 	var existingMovementID int64
@@ -480,9 +502,14 @@ func (r *FabricRepository) handleStage(ctx context.Context, tx *sql.Tx, fabricID
 		}
 	}
 
+	events, err := stageWebhookEvents(ctx, tx, fabricID, onStage, toStage)
+	if err != nil {
+		return 0, err
+	}
+
 	// Insert new movement
-	movementQuery := `INSERT INTO inventory_movements (datetime, inventory_id, movement_type_id, fabric_id, remarks, status, action_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, 'starting', 1, ?, ?)`
-	result, err := tx.ExecContext(ctx, movementQuery, now, inventoryID, movementTypeID, fabricID, remarks, now, now)
+	movementQuery := `INSERT INTO inventory_movements (datetime, inventory_id, movement_type_id, fabric_id, remarks, status, action_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, 'starting', ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, movementQuery, now, inventoryID, movementTypeID, fabricID, remarks, userID, now, now)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert inventory movement: %w", err)
 	}
@@ -512,20 +539,50 @@ func (r *FabricRepository) handleStage(ctx context.Context, tx *sql.Tx, fabricID
 		return 0, fmt.Errorf("failed to update inventory stage: %w", err)
 	}
 
+	action := "moved"
+	if onStage == toStage {
+		action = "returned"
+	}
+	err = logStatus(ctx, tx, StatusLogEntry{
+		Module:       "fabric",
+		LoggableType: LoggableFabric,
+		LoggableID:   fabricID,
+		Status:       toStage,
+		Action:       action,
+		Notes:        remarks,
+		CreatedBy:    userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = enqueueWebhookEvents(ctx, tx, FabricWebhookPayload{
+		FabricID:            fabricID,
+		FromStage:           onStage,
+		ToStage:             toStage,
+		InventoryMovementID: invMovementID,
+		UserID:              userID,
+	}, events...)
+	if err != nil {
+		return 0, err
+	}
+
 	return invMovementID, nil
 }
 
-func (r *FabricRepository) RelocateFabricsWithLog(ctx context.Context, currentRackID, newRackID int64) error {
+// RelocateFabricsWithLog moves every roll of a rack to another rack on behalf
+// of userID and returns the ids of the moved rolls.
+func (r *FabricRepository) RelocateFabricsWithLog(ctx context.Context, currentRackID, newRackID, userID int64) ([]int64, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	fabricsQuery := `SELECT id FROM fabrics WHERE rack_id = ? AND deleted_at IS NULL`
 	rows, err := tx.QueryContext(ctx, fabricsQuery, currentRackID)
 	if err != nil {
-		return fmt.Errorf("failed to get fabrics: %w", err)
+		return nil, fmt.Errorf("failed to get fabrics: %w", err)
 	}
 
 	var fabricIDs []int64
@@ -533,14 +590,14 @@ func (r *FabricRepository) RelocateFabricsWithLog(ctx context.Context, currentRa
 		var id int64
 		if err := rows.Scan(&id); err != nil {
 			rows.Close()
-			return fmt.Errorf("failed to scan fabric id: %w", err)
+			return nil, fmt.Errorf("failed to scan fabric id: %w", err)
 		}
 		fabricIDs = append(fabricIDs, id)
 	}
 	rows.Close()
 
 	if len(fabricIDs) == 0 {
-		return fmt.Errorf("no fabric found in the selected current rack")
+		return nil, ErrRackEmpty
 	}
 
 	now := time.Now()
@@ -548,22 +605,35 @@ func (r *FabricRepository) RelocateFabricsWithLog(ctx context.Context, currentRa
 	for _, fabricID := range fabricIDs {
 		_, err = tx.ExecContext(ctx, `UPDATE fabrics SET rack_id = ?, updated_at = ? WHERE id = ?`, newRackID, now, fabricID)
 		if err != nil {
-			return fmt.Errorf("failed to update fabric rack: %w", err)
+			return nil, fmt.Errorf("failed to update fabric rack: %w", err)
 		}
 
 		_, err = tx.ExecContext(ctx, `UPDATE fabric_rack_relocations SET is_archived = 1, updated_at = ? WHERE fabric_id = ? AND is_archived IS NULL`, now, fabricID)
 		if err != nil {
-			return fmt.Errorf("failed to archive relocation: %w", err)
+			return nil, fmt.Errorf("failed to archive relocation: %w", err)
 		}
 
 		relocationQuery := `INSERT INTO fabric_rack_relocations (fabric_id, current_rack_id, new_rack_id, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
-		_, err = tx.ExecContext(ctx, relocationQuery, fabricID, currentRackID, newRackID, 1, now, now) // TODO: get auth user id
+		_, err = tx.ExecContext(ctx, relocationQuery, fabricID, currentRackID, newRackID, userID, now, now)
 		if err != nil {
-			return fmt.Errorf("failed to insert relocation log: %w", err)
+			return nil, fmt.Errorf("failed to insert relocation log: %w", err)
+		}
+
+		err = enqueueWebhookEvents(ctx, tx, FabricWebhookPayload{
+			FabricID:      fabricID,
+			CurrentRackID: currentRackID,
+			NewRackID:     newRackID,
+			UserID:        userID,
+		}, domain.WebhookRelocated)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit relocation: %w", err)
+	}
+	return fabricIDs, nil
 }
 
 func (r *FabricRepository) UpdateFabricsForMove(ctx context.Context, codes []string, stage string, updates map[string]interface{}) error {
@@ -608,7 +678,7 @@ func (r *FabricRepository) RelocateFabrics(ctx context.Context, currentRackID, n
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("no fabric found in the selected current rack")
+		return ErrRackEmpty
 	}
 
 	return nil
@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// ErrItemAlreadyChecked is returned by CreateItem when the piece was checked
+// since it was looked up, e.g. by a concurrent scan of the same QR code.
+var ErrItemAlreadyChecked = errors.New("request item has already been checked")
+
+// ReworkEvent is one trip of a piece through rework. ReturnedAt is nil while
+// the piece is still being repaired. Processes lists the order processes
+// blamed for the defects on the piece.
+type ReworkEvent struct {
+	ItemID     int64
+	LineID     int64
+	LineName   string
+	ReworkAt   time.Time
+	ReturnedAt *time.Time
+	Processes  []ReworkProcess
+}
+
+type ReworkProcess struct {
+	ID   int64
+	Name string
+}
+
+type GarmentQCRepository interface {
+	FindActiveRequest(ctx context.Context, lineID int64) (*domain.LineRequest, error)
+	FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error)
+	FindQRSystemByCode(ctx context.Context, code string) (*domain.QRSystem, error)
+	FindItemByCode(ctx context.Context, qrCode string) (*domain.RequestItem, error)
+	FindItemByID(ctx context.Context, id int64) (*domain.RequestItem, error)
+	DefectTypeExists(ctx context.Context, id int64) (bool, error)
+	FindOrderProcess(ctx context.Context, orderID, id int64) (*domain.OrderProcess, error)
+	CreateItem(ctx context.Context, item *domain.RequestItem) error
+	UpdateItemStage(ctx context.Context, item *domain.RequestItem) error
+	GetCheckedByLine(ctx context.Context, filter domain.ReworkFilter) ([]domain.ReworkStat, error)
+	GetReworkEvents(ctx context.Context, filter domain.ReworkFilter) ([]ReworkEvent, error)
+	GetRequestCounts(ctx context.Context, requestID int64) (*domain.LineQCSummary, error)
+}
+
+type mysqlGarmentQCRepository struct {
+	db *sql.DB
+}
+
+func NewGarmentQCRepository(db *sql.DB) GarmentQCRepository {
+	return &mysqlGarmentQCRepository{db: db}
+}
+
+// FindActiveRequest returns the latest approved request of the line.
+func (r *mysqlGarmentQCRepository) FindActiveRequest(ctx context.Context, lineID int64) (*domain.LineRequest, error) {
+	query := lineRequestSelect + " WHERE r.line_id = ? AND r.status = ? AND r.deleted_at IS NULL ORDER BY r.date DESC, r.id DESC LIMIT 1"
+	return findLineRequest(ctx, r.db, query, lineID, domain.RequestStatusApproved)
+}
+
+// FindRequest returns a request of any status, e.g. the one a piece was
+// checked for.
+func (r *mysqlGarmentQCRepository) FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error) {
+	return findLineRequest(ctx, r.db, lineRequestSelect+" WHERE r.id = ? AND r.deleted_at IS NULL", id)
+}
+
+const lineRequestSelect = `
+	SELECT r.id, r.code, r.date, r.order_id, o.buyer_id, COALESCE(o.style, '-'), r.line_id, r.status
+	FROM requests r
+	LEFT JOIN orders o ON o.id = r.order_id
+`
+
+func findLineRequest(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*domain.LineRequest, error) {
+	var req domain.LineRequest
+	err := db.QueryRowContext(ctx, query, args...).Scan(
+		&req.ID, &req.Code, &req.Date, &req.OrderID, &req.BuyerID, &req.Style, &req.LineID, &req.Status,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find request: %w", err)
+	}
+	return &req, nil
+}
+
+func (r *mysqlGarmentQCRepository) FindQRSystemByCode(ctx context.Context, code string) (*domain.QRSystem, error) {
+	query := `
+		SELECT id, code, order_id, po_number, color, size, amount, amount_tolerance
+		FROM qr_systems
+		WHERE code = ? AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var qr domain.QRSystem
+	err := r.db.QueryRowContext(ctx, query, code).Scan(
+		&qr.ID, &qr.Code, &qr.OrderID, &qr.PONumber, &qr.Color, &qr.Size, &qr.Amount, &qr.AmountTolerance,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find QR system: %w", err)
+	}
+	return &qr, nil
+}
+
+// linesTable is quoted because LINES is a reserved word in MySQL.
+const linesTable = "`lines`"
+
+const requestItemSelect = `
+	SELECT ri.id, ri.qr_code, ri.request_id, r.order_id, COALESCE(ri.qr_system_id, 0), COALESCE(ri.result, 0), ri.resulted_at,
+		COALESCE(ri.stage, ''), COALESCE(ri.is_cncm, 0), COALESCE(ri.is_rework, 0), ri.packing_id
+	FROM request_items ri
+	JOIN requests r ON r.id = ri.request_id
+`
+
+func (r *mysqlGarmentQCRepository) FindItemByCode(ctx context.Context, qrCode string) (*domain.RequestItem, error) {
+	query := requestItemSelect + " WHERE ri.qr_code = ? AND ri.deleted_at IS NULL ORDER BY ri.id DESC LIMIT 1"
+	return r.findItem(ctx, query, qrCode)
+}
+
+func (r *mysqlGarmentQCRepository) FindItemByID(ctx context.Context, id int64) (*domain.RequestItem, error) {
+	query := requestItemSelect + " WHERE ri.id = ? AND ri.deleted_at IS NULL"
+	return r.findItem(ctx, query, id)
+}
+
+func (r *mysqlGarmentQCRepository) findItem(ctx context.Context, query string, args ...interface{}) (*domain.RequestItem, error) {
+	var item domain.RequestItem
+	var passed bool
+	var resultedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&item.ID, &item.QRCode, &item.RequestID, &item.OrderID, &item.QRSystemID, &passed, &resultedAt,
+		&item.Stage, &item.IsCNCM, &item.IsRework, &item.PackingID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find request item: %w", err)
+	}
+	item.ResultedAt = resultedAt.Time
+	item.Result = garmentResult(passed, item.IsCNCM)
+	return &item, nil
+}
+
+// garmentResult maps the stored result flags back to the QC result. A piece
+// that did not pass is either rejected (CNCM) or has defects to rework.
+func garmentResult(passed, cncm bool) string {
+	switch {
+	case passed:
+		return domain.GarmentResultPass
+	case cncm:
+		return domain.GarmentResultFail
+	default:
+		return domain.GarmentResultDefect
+	}
+}
+
+func (r *mysqlGarmentQCRepository) DefectTypeExists(ctx context.Context, id int64) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM m_defect_types WHERE id = ? AND deleted_at IS NULL", id).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check defect type: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *mysqlGarmentQCRepository) FindOrderProcess(ctx context.Context, orderID, id int64) (*domain.OrderProcess, error) {
+	query := `
+		SELECT id, order_id, process_id, name
+		FROM order_processes
+		WHERE id = ? AND order_id = ? AND deleted_at IS NULL
+	`
+
+	var p domain.OrderProcess
+	err := r.db.QueryRowContext(ctx, query, id, orderID).Scan(&p.ID, &p.OrderID, &p.ProcessID, &p.Name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find order process: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateItem stores the checked piece, its stage log and its defects. The
+// bundle is locked while the piece is checked for an earlier scan, so two
+// concurrent scans of the same piece cannot both be stored.
+func (r *mysqlGarmentQCRepository) CreateItem(ctx context.Context, item *domain.RequestItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var qrSystemID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM qr_systems WHERE id = ? FOR UPDATE`, item.QRSystemID).Scan(&qrSystemID)
+	if err != nil {
+		return fmt.Errorf("failed to lock QR system: %w", err)
+	}
+	var checked bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM request_items WHERE qr_code = ? AND deleted_at IS NULL)`, item.QRCode).Scan(&checked)
+	if err != nil {
+		return fmt.Errorf("failed to check request item: %w", err)
+	}
+	if checked {
+		return ErrItemAlreadyChecked
+	}
+
+	now := time.Now()
+
+	query := `INSERT INTO request_items (qr_code, request_id, qr_system_id, result, resulted_at, stage, is_cncm, is_rework, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query,
+		item.QRCode, item.RequestID, item.QRSystemID, item.Result == domain.GarmentResultPass, item.ResultedAt,
+		item.Stage, item.IsCNCM, item.IsRework, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert request item: %w", err)
+	}
+	item.ID, _ = result.LastInsertId()
+
+	if err := insertItemLog(ctx, tx, item, "checked", now); err != nil {
+		return err
+	}
+	if err := insertDefects(ctx, tx, item, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit request item: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlGarmentQCRepository) GetRequestCounts(ctx context.Context, requestID int64) (*domain.LineQCSummary, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(result = 1), 0), COALESCE(SUM(is_cncm = 1), 0), COALESCE(SUM(is_rework = 1), 0)
+		FROM request_items
+		WHERE request_id = ? AND deleted_at IS NULL
+	`
+
+	var summary domain.LineQCSummary
+	err := r.db.QueryRowContext(ctx, query, requestID).Scan(&summary.Checked, &summary.Passed, &summary.Failed, &summary.Rework)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count request items: %w", err)
+	}
+	return &summary, nil
+}
+
+// insertItemLog logs the stage the piece moved to, both in request_item_logs
+// and as a status change. Moves into rework carry rework_at so the time spent
+// in rework can be measured.
+func insertItemLog(ctx context.Context, tx *sql.Tx, item *domain.RequestItem, action string, now time.Time) error {
+	var reworkAt *time.Time
+	if item.IsRework {
+		reworkAt = &now
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO request_item_logs (request_item_id, stage, rework_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`, item.ID, item.Stage, reworkAt, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert request item log: %w", err)
+	}
+	return logStatus(ctx, tx, StatusLogEntry{
+		Module:       "garment",
+		LoggableType: LoggableRequestItem,
+		LoggableID:   item.ID,
+		Status:       item.Stage,
+		Action:       action,
+		Description:  item.Result,
+	})
+}
+
+func insertDefects(ctx context.Context, tx *sql.Tx, item *domain.RequestItem, now time.Time) error {
+	for i := range item.Defects {
+		defect := &item.Defects[i]
+
+		result, err := tx.ExecContext(ctx, `INSERT INTO request_defects (request_item_id, request_id, defect_type_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`, item.ID, item.RequestID, defect.DefectTypeID, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert request defect: %w", err)
+		}
+		defect.ID, _ = result.LastInsertId()
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO request_defect_processes (request_defect_id, order_process_id, process_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`, defect.ID, defect.OrderProcessID, defect.ProcessID, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert request defect process: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpdateItemStage saves the new result and stage of a piece, logs the move and
+// stores any new defects.
+func (r *mysqlGarmentQCRepository) UpdateItemStage(ctx context.Context, item *domain.RequestItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	query := `UPDATE request_items SET result = ?, resulted_at = ?, stage = ?, is_cncm = ?, is_rework = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err = tx.ExecContext(ctx, query, item.Result == domain.GarmentResultPass, item.ResultedAt, item.Stage, item.IsCNCM, item.IsRework, now, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update request item: %w", err)
+	}
+
+	action := "returned"
+	if item.IsRework {
+		action = "rework"
+	}
+	if err := insertItemLog(ctx, tx, item, action, now); err != nil {
+		return err
+	}
+	if err := insertDefects(ctx, tx, item, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit request item: %w", err)
+	}
+	return nil
+}
+
+func reworkConditions(column string, filter domain.ReworkFilter) (string, []interface{}) {
+	var conditions string
+	var args []interface{}
+	if filter.From != nil {
+		conditions += " AND " + column + " >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions += " AND " + column + " < ?"
+		args = append(args, *filter.To)
+	}
+	if filter.LineID != nil {
+		conditions += " AND r.line_id = ?"
+		args = append(args, *filter.LineID)
+	}
+	return conditions, args
+}
+
+// GetCheckedByLine counts the pieces checked on each line.
+func (r *mysqlGarmentQCRepository) GetCheckedByLine(ctx context.Context, filter domain.ReworkFilter) ([]domain.ReworkStat, error) {
+	conditions, args := reworkConditions("ri.resulted_at", filter)
+	query := `
+		SELECT r.line_id, COALESCE(l.name, '-'), COUNT(*)
+		FROM request_items ri
+		JOIN requests r ON r.id = ri.request_id
+		LEFT JOIN ` + linesTable + ` l ON l.id = r.line_id
+		WHERE ri.deleted_at IS NULL AND ri.resulted_at IS NOT NULL` + conditions + `
+		GROUP BY r.line_id, l.name
+		ORDER BY l.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count checked items: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []domain.ReworkStat
+	for rows.Next() {
+		var s domain.ReworkStat
+		if err := rows.Scan(&s.ID, &s.Name, &s.Checked); err != nil {
+			return nil, fmt.Errorf("failed to scan checked items: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetReworkEvents returns every move into rework in the range. A piece is back
+// from rework at its next logged move.
+func (r *mysqlGarmentQCRepository) GetReworkEvents(ctx context.Context, filter domain.ReworkFilter) ([]ReworkEvent, error) {
+	conditions, args := reworkConditions("lg.rework_at", filter)
+	query := `
+		SELECT
+			lg.request_item_id, r.line_id, COALESCE(l.name, '-'), lg.rework_at,
+			(SELECT MIN(nx.created_at) FROM request_item_logs nx WHERE nx.request_item_id = lg.request_item_id AND nx.id > lg.id)
+		FROM request_item_logs lg
+		JOIN request_items ri ON ri.id = lg.request_item_id AND ri.deleted_at IS NULL
+		JOIN requests r ON r.id = ri.request_id
+		LEFT JOIN ` + linesTable + ` l ON l.id = r.line_id
+		WHERE lg.rework_at IS NOT NULL` + conditions + `
+		ORDER BY lg.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rework events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ReworkEvent
+	itemIDs := map[int64]bool{}
+	for rows.Next() {
+		var e ReworkEvent
+		var returnedAt sql.NullTime
+		if err := rows.Scan(&e.ItemID, &e.LineID, &e.LineName, &e.ReworkAt, &returnedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rework event: %w", err)
+		}
+		if returnedAt.Valid {
+			e.ReturnedAt = &returnedAt.Time
+		}
+		events = append(events, e)
+		itemIDs[e.ItemID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rework events: %w", err)
+	}
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	processes, err := r.getItemProcesses(ctx, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Processes = processes[events[i].ItemID]
+	}
+	return events, nil
+}
+
+// getItemProcesses returns the distinct order processes blamed for the defects
+// of each piece.
+func (r *mysqlGarmentQCRepository) getItemProcesses(ctx context.Context, itemIDs map[int64]bool) (map[int64][]ReworkProcess, error) {
+	placeholders := make([]string, 0, len(itemIDs))
+	args := make([]interface{}, 0, len(itemIDs))
+	for id := range itemIDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT DISTINCT rd.request_item_id, op.id, COALESCE(op.name, p.name, '-')
+		FROM request_defects rd
+		JOIN request_defect_processes rdp ON rdp.request_defect_id = rd.id AND rdp.deleted_at IS NULL
+		JOIN order_processes op ON op.id = rdp.order_process_id
+		LEFT JOIN processes p ON p.id = op.process_id
+		WHERE rd.deleted_at IS NULL AND rd.request_item_id IN (` + strings.Join(placeholders, ", ") + `)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get defect processes: %w", err)
+	}
+	defer rows.Close()
+
+	processes := map[int64][]ReworkProcess{}
+	for rows.Next() {
+		var itemID int64
+		var p ReworkProcess
+		if err := rows.Scan(&itemID, &p.ID, &p.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan defect process: %w", err)
+		}
+		processes[itemID] = append(processes[itemID], p)
+	}
+	return processes, nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// jobConnection is the queue connection name recorded with failed jobs.
+const jobConnection = "database"
+
+type JobRepository interface {
+	Push(ctx context.Context, job *domain.Job) error
+	CreateBatch(ctx context.Context, batch *domain.JobBatch, jobs []*domain.Job) error
+	Reserve(ctx context.Context, queue string, now time.Time, retryAfter time.Duration) (*domain.Job, error)
+	Complete(ctx context.Context, job *domain.Job, batchID string) error
+	Release(ctx context.Context, job *domain.Job, availableAt time.Time) error
+	Fail(ctx context.Context, job *domain.Job, payload *domain.JobPayload, exception string) error
+	FindBatch(ctx context.Context, id string) (*domain.JobBatch, error)
+}
+
+type mysqlJobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) JobRepository {
+	return &mysqlJobRepository{db: db}
+}
+
+func (r *mysqlJobRepository) Push(ctx context.Context, job *domain.Job) error {
+	return pushJob(ctx, r.db, job)
+}
+
+func pushJob(ctx context.Context, db execer, job *domain.Job) error {
+	query := `INSERT INTO jobs (queue, payload, attempts, reserved_at, available_at, created_at) VALUES (?, ?, 0, NULL, ?, ?)`
+	result, err := db.ExecContext(ctx, query, job.Queue, job.Payload, job.AvailableAt, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to push job: %w", err)
+	}
+	job.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// CreateBatch stores the batch and its jobs together, so workers never see a
+// job of a batch that does not exist yet.
+func (r *mysqlJobRepository) CreateBatch(ctx context.Context, batch *domain.JobBatch, jobs []*domain.Job) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO job_batches (id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at) VALUES (?, ?, ?, ?, 0, '[]', ?, ?)`
+	if _, err := tx.ExecContext(ctx, query, batch.ID, batch.Name, batch.TotalJobs, batch.PendingJobs, "a:0:{}", batch.CreatedAt.Unix()); err != nil {
+		return fmt.Errorf("failed to create job batch: %w", err)
+	}
+	for _, job := range jobs {
+		if err := pushJob(ctx, tx, job); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Reserve takes the next available job of the queue and counts the attempt.
+// Jobs reserved longer than retryAfter ago belong to a worker that died and
+// are taken again.
+func (r *mysqlJobRepository) Reserve(ctx context.Context, queue string, now time.Time, retryAfter time.Duration) (*domain.Job, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, queue, payload, attempts, reserved_at, available_at, created_at
+		FROM jobs
+		WHERE queue = ?
+		AND ((reserved_at IS NULL AND available_at <= ?) OR reserved_at <= ?)
+		ORDER BY id
+		LIMIT 1
+		FOR UPDATE
+	`
+	var job domain.Job
+	err = tx.QueryRowContext(ctx, query, queue, now.Unix(), now.Add(-retryAfter).Unix()).Scan(
+		&job.ID, &job.Queue, &job.Payload, &job.Attempts, &job.ReservedAt, &job.AvailableAt, &job.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve job: %w", err)
+	}
+
+	reservedAt := now.Unix()
+	if _, err := tx.ExecContext(ctx, `UPDATE jobs SET reserved_at = ?, attempts = attempts + 1 WHERE id = ?`, reservedAt, job.ID); err != nil {
+		return nil, fmt.Errorf("failed to reserve job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to reserve job: %w", err)
+	}
+	job.ReservedAt = &reservedAt
+	job.Attempts++
+	return &job, nil
+}
+
+// Complete removes a finished job and counts it as done in its batch.
+func (r *mysqlJobRepository) Complete(ctx context.Context, job *domain.Job, batchID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, job.ID); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if batchID != "" {
+		if err := updateBatch(ctx, tx, batchID, 1, ""); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Release puts a failed job back on the queue for another attempt.
+func (r *mysqlJobRepository) Release(ctx context.Context, job *domain.Job, availableAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE jobs SET reserved_at = NULL, available_at = ? WHERE id = ?`, availableAt.Unix(), job.ID); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	return nil
+}
+
+// Fail moves a job that will not be tried again to failed_jobs and records
+// the failure in its batch.
+func (r *mysqlJobRepository) Fail(ctx context.Context, job *domain.Job, payload *domain.JobPayload, exception string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO failed_jobs (uuid, connection, queue, payload, exception, failed_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, query, payload.UUID, jobConnection, job.Queue, job.Payload, exception, time.Now()); err != nil {
+		return fmt.Errorf("failed to store failed job: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, job.ID); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if payload.BatchID != "" {
+		if err := updateBatch(ctx, tx, payload.BatchID, 0, payload.UUID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// updateBatch counts a job of the batch as done, or as failed when failedUUID
+// is set, and marks the batch finished once no job is left to run.
+func updateBatch(ctx context.Context, tx *sql.Tx, batchID string, done int, failedUUID string) error {
+	var pending, failed int
+	var failedIDs string
+	err := tx.QueryRowContext(ctx, `SELECT pending_jobs, failed_jobs, failed_job_ids FROM job_batches WHERE id = ? FOR UPDATE`, batchID).Scan(&pending, &failed, &failedIDs)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get job batch: %w", err)
+	}
+
+	pending -= done
+	if failedUUID != "" {
+		failed++
+		ids := decodeFailedJobIDs(failedIDs)
+		data, err := json.Marshal(append(ids, failedUUID))
+		if err != nil {
+			return fmt.Errorf("failed to encode failed job ids: %w", err)
+		}
+		failedIDs = string(data)
+	}
+
+	var finishedAt *int64
+	if pending-failed <= 0 {
+		now := time.Now().Unix()
+		finishedAt = &now
+	}
+	query := `UPDATE job_batches SET pending_jobs = ?, failed_jobs = ?, failed_job_ids = ?, finished_at = COALESCE(finished_at, ?) WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, pending, failed, failedIDs, finishedAt, batchID); err != nil {
+		return fmt.Errorf("failed to update job batch: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlJobRepository) FindBatch(ctx context.Context, id string) (*domain.JobBatch, error) {
+	query := `SELECT id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, cancelled_at, created_at, finished_at FROM job_batches WHERE id = ?`
+	var b domain.JobBatch
+	var failedIDs string
+	var cancelledAt, finishedAt sql.NullInt64
+	var createdAt int64
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&b.ID, &b.Name, &b.TotalJobs, &b.PendingJobs, &b.FailedJobs, &failedIDs, &cancelledAt, &createdAt, &finishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job batch: %w", err)
+	}
+
+	b.FailedJobIDs = decodeFailedJobIDs(failedIDs)
+	b.CreatedAt = time.Unix(createdAt, 0)
+	if cancelledAt.Valid {
+		t := time.Unix(cancelledAt.Int64, 0)
+		b.CancelledAt = &t
+	}
+	if finishedAt.Valid {
+		t := time.Unix(finishedAt.Int64, 0)
+		b.FinishedAt = &t
+	}
+	return &b, nil
+}
+
+func decodeFailedJobIDs(raw string) []string {
+	ids := []string{}
+	if strings.TrimSpace(raw) != "" {
+		_ = json.Unmarshal([]byte(raw), &ids)
+	}
+	return ids
+}
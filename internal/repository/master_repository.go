@@ -2,15 +2,73 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/dppi/dppierp-api/internal/domain"
 )
 
+// MasterTable names a warehouse master data table.
+type MasterTable string
+
+const (
+	MasterBlocks           MasterTable = "m_blocks"
+	MasterRacks            MasterTable = "m_racks"
+	MasterRelaxationBlocks MasterTable = "m_relaxation_blocks"
+	MasterRelaxationRacks  MasterTable = "m_relaxation_racks"
+	MasterUnits            MasterTable = "m_units"
+	MasterDefectTypes      MasterTable = "m_defect_types"
+	MasterMovementTypes    MasterTable = "movement_types"
+)
+
+// usageQueries count the active rows still referencing a master row.
+var usageQueries = map[MasterTable]string{
+	MasterBlocks:           "SELECT COUNT(*) FROM fabrics WHERE block_id = ? AND deleted_at IS NULL",
+	MasterRacks:            "SELECT COUNT(*) FROM fabrics WHERE rack_id = ? AND deleted_at IS NULL",
+	MasterRelaxationBlocks: "SELECT COUNT(*) FROM fabrics WHERE relaxation_block_id = ? AND deleted_at IS NULL",
+	MasterRelaxationRacks:  "SELECT COUNT(*) FROM fabrics WHERE relaxation_rack_id = ? AND deleted_at IS NULL",
+	MasterUnits:            "SELECT COUNT(*) FROM fabrics WHERE unit_id = ? AND deleted_at IS NULL",
+	MasterDefectTypes:      "SELECT COUNT(*) FROM request_defects WHERE defect_type_id = ? AND deleted_at IS NULL",
+	MasterMovementTypes:    "SELECT COUNT(*) FROM inventory_movements WHERE movement_type_id = ? AND deleted_at IS NULL",
+}
+
 type MasterRepository interface {
 	GetAllBlocks() ([]domain.Block, error)
 	GetAllRacks() ([]domain.Rack, error)
 	GetAllRelaxationBlocks() ([]domain.RelaxationBlock, error)
 	GetAllRelaxationRacks() ([]domain.RelaxationRack, error)
+	GetAllUnits() ([]domain.Unit, error)
+	GetAllDefectTypes() ([]domain.DefectType, error)
+	GetAllMovementTypes() ([]domain.MovementType, error)
+
+	FindBlockByID(id int64) (*domain.Block, error)
+	FindRackByID(id int64) (*domain.Rack, error)
+	FindRelaxationBlockByID(id int64) (*domain.RelaxationBlock, error)
+	FindRelaxationRackByID(id int64) (*domain.RelaxationRack, error)
+	FindUnitByID(id int64) (*domain.Unit, error)
+	FindDefectTypeByID(id int64) (*domain.DefectType, error)
+	FindMovementTypeByID(id int64) (*domain.MovementType, error)
+
+	CreateBlock(block *domain.Block) error
+	UpdateBlock(block *domain.Block) error
+	CreateRack(rack *domain.Rack) error
+	UpdateRack(rack *domain.Rack) error
+	CreateRelaxationBlock(block *domain.RelaxationBlock) error
+	UpdateRelaxationBlock(block *domain.RelaxationBlock) error
+	CreateRelaxationRack(rack *domain.RelaxationRack) error
+	UpdateRelaxationRack(rack *domain.RelaxationRack) error
+	CreateUnit(unit *domain.Unit) error
+	UpdateUnit(unit *domain.Unit) error
+	CreateDefectType(defectType *domain.DefectType) error
+	UpdateDefectType(defectType *domain.DefectType) error
+	CreateMovementType(movementType *domain.MovementType) error
+	UpdateMovementType(movementType *domain.MovementType) error
+
+	NameExists(table MasterTable, name string, excludeID int64) (bool, error)
+	DefectKeyExists(key string, excludeID int64) (bool, error)
+	CountUsage(table MasterTable, id int64) (int, error)
+	SoftDelete(table MasterTable, id int64) error
+	Restore(table MasterTable, id int64) error
 }
 
 type mysqlMasterRepository struct {
@@ -22,7 +80,7 @@ func NewMasterRepository(db *sql.DB) MasterRepository {
 }
 
 func (r *mysqlMasterRepository) GetAllBlocks() ([]domain.Block, error) {
-	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_blocks WHERE deleted_at IS NULL"
+	query := "SELECT id, name, type, created_at, updated_at, deleted_at FROM m_blocks WHERE deleted_at IS NULL"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -32,7 +90,7 @@ func (r *mysqlMasterRepository) GetAllBlocks() ([]domain.Block, error) {
 	var blocks []domain.Block
 	for rows.Next() {
 		var b domain.Block
-		if err := rows.Scan(&b.ID, &b.Name, &b.CreatedAt, &b.UpdatedAt, &b.DeletedAt); err != nil {
+		if err := rows.Scan(&b.ID, &b.Name, &b.Type, &b.CreatedAt, &b.UpdatedAt, &b.DeletedAt); err != nil {
 			return nil, err
 		}
 		blocks = append(blocks, b)
@@ -41,7 +99,7 @@ func (r *mysqlMasterRepository) GetAllBlocks() ([]domain.Block, error) {
 }
 
 func (r *mysqlMasterRepository) GetAllRacks() ([]domain.Rack, error) {
-	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_racks WHERE deleted_at IS NULL"
+	query := "SELECT id, name, type, capacity, created_at, updated_at, deleted_at FROM m_racks WHERE deleted_at IS NULL"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -51,7 +109,7 @@ func (r *mysqlMasterRepository) GetAllRacks() ([]domain.Rack, error) {
 	var racks []domain.Rack
 	for rows.Next() {
 		var ra domain.Rack
-		if err := rows.Scan(&ra.ID, &ra.Name, &ra.CreatedAt, &ra.UpdatedAt, &ra.DeletedAt); err != nil {
+		if err := rows.Scan(&ra.ID, &ra.Name, &ra.Type, &ra.Capacity, &ra.CreatedAt, &ra.UpdatedAt, &ra.DeletedAt); err != nil {
 			return nil, err
 		}
 		racks = append(racks, ra)
@@ -96,3 +154,324 @@ func (r *mysqlMasterRepository) GetAllRelaxationRacks() ([]domain.RelaxationRack
 	}
 	return racks, nil
 }
+
+// The Find*ByID methods also return soft-deleted rows so they can be restored.
+
+func (r *mysqlMasterRepository) FindBlockByID(id int64) (*domain.Block, error) {
+	query := "SELECT id, name, type, created_at, updated_at, deleted_at FROM m_blocks WHERE id = ?"
+	var b domain.Block
+	err := r.db.QueryRow(query, id).Scan(&b.ID, &b.Name, &b.Type, &b.CreatedAt, &b.UpdatedAt, &b.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *mysqlMasterRepository) FindRackByID(id int64) (*domain.Rack, error) {
+	query := "SELECT id, name, type, capacity, created_at, updated_at, deleted_at FROM m_racks WHERE id = ?"
+	var ra domain.Rack
+	err := r.db.QueryRow(query, id).Scan(&ra.ID, &ra.Name, &ra.Type, &ra.Capacity, &ra.CreatedAt, &ra.UpdatedAt, &ra.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ra, nil
+}
+
+func (r *mysqlMasterRepository) FindRelaxationBlockByID(id int64) (*domain.RelaxationBlock, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_relaxation_blocks WHERE id = ?"
+	var b domain.RelaxationBlock
+	err := r.db.QueryRow(query, id).Scan(&b.ID, &b.Name, &b.CreatedAt, &b.UpdatedAt, &b.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *mysqlMasterRepository) FindRelaxationRackByID(id int64) (*domain.RelaxationRack, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_relaxation_racks WHERE id = ?"
+	var ra domain.RelaxationRack
+	err := r.db.QueryRow(query, id).Scan(&ra.ID, &ra.Name, &ra.CreatedAt, &ra.UpdatedAt, &ra.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ra, nil
+}
+
+func (r *mysqlMasterRepository) CreateBlock(block *domain.Block) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_blocks (name, type, created_at, updated_at) VALUES (?, ?, ?, ?)", block.Name, block.Type, now, now)
+	if err != nil {
+		return err
+	}
+	block.ID, _ = result.LastInsertId()
+	block.CreatedAt, block.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateBlock(block *domain.Block) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_blocks SET name = ?, type = ?, updated_at = ? WHERE id = ?", block.Name, block.Type, now, block.ID)
+	block.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) CreateRack(rack *domain.Rack) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_racks (name, type, capacity, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", rack.Name, rack.Type, rack.Capacity, now, now)
+	if err != nil {
+		return err
+	}
+	rack.ID, _ = result.LastInsertId()
+	rack.CreatedAt, rack.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateRack(rack *domain.Rack) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_racks SET name = ?, type = ?, capacity = ?, updated_at = ? WHERE id = ?", rack.Name, rack.Type, rack.Capacity, now, rack.ID)
+	rack.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) CreateRelaxationBlock(block *domain.RelaxationBlock) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_relaxation_blocks (name, created_at, updated_at) VALUES (?, ?, ?)", block.Name, now, now)
+	if err != nil {
+		return err
+	}
+	block.ID, _ = result.LastInsertId()
+	block.CreatedAt, block.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateRelaxationBlock(block *domain.RelaxationBlock) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_relaxation_blocks SET name = ?, updated_at = ? WHERE id = ?", block.Name, now, block.ID)
+	block.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) CreateRelaxationRack(rack *domain.RelaxationRack) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_relaxation_racks (name, created_at, updated_at) VALUES (?, ?, ?)", rack.Name, now, now)
+	if err != nil {
+		return err
+	}
+	rack.ID, _ = result.LastInsertId()
+	rack.CreatedAt, rack.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateRelaxationRack(rack *domain.RelaxationRack) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_relaxation_racks SET name = ?, updated_at = ? WHERE id = ?", rack.Name, now, rack.ID)
+	rack.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) GetAllUnits() ([]domain.Unit, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_units WHERE deleted_at IS NULL"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []domain.Unit
+	for rows.Next() {
+		var u domain.Unit
+		if err := rows.Scan(&u.ID, &u.Name, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+func (r *mysqlMasterRepository) GetAllDefectTypes() ([]domain.DefectType, error) {
+	query := "SELECT id, `key`, name, created_at, updated_at, deleted_at FROM m_defect_types WHERE deleted_at IS NULL"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []domain.DefectType
+	for rows.Next() {
+		var d domain.DefectType
+		if err := rows.Scan(&d.ID, &d.Key, &d.Name, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt); err != nil {
+			return nil, err
+		}
+		types = append(types, d)
+	}
+	return types, nil
+}
+
+func (r *mysqlMasterRepository) GetAllMovementTypes() ([]domain.MovementType, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM movement_types WHERE deleted_at IS NULL ORDER BY id"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []domain.MovementType
+	for rows.Next() {
+		var mt domain.MovementType
+		if err := rows.Scan(&mt.ID, &mt.Name, &mt.CreatedAt, &mt.UpdatedAt, &mt.DeletedAt); err != nil {
+			return nil, err
+		}
+		types = append(types, mt)
+	}
+	return types, nil
+}
+
+func (r *mysqlMasterRepository) FindUnitByID(id int64) (*domain.Unit, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM m_units WHERE id = ?"
+	var u domain.Unit
+	err := r.db.QueryRow(query, id).Scan(&u.ID, &u.Name, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *mysqlMasterRepository) FindDefectTypeByID(id int64) (*domain.DefectType, error) {
+	query := "SELECT id, `key`, name, created_at, updated_at, deleted_at FROM m_defect_types WHERE id = ?"
+	var d domain.DefectType
+	err := r.db.QueryRow(query, id).Scan(&d.ID, &d.Key, &d.Name, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *mysqlMasterRepository) FindMovementTypeByID(id int64) (*domain.MovementType, error) {
+	query := "SELECT id, name, created_at, updated_at, deleted_at FROM movement_types WHERE id = ?"
+	var mt domain.MovementType
+	err := r.db.QueryRow(query, id).Scan(&mt.ID, &mt.Name, &mt.CreatedAt, &mt.UpdatedAt, &mt.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mt, nil
+}
+
+func (r *mysqlMasterRepository) CreateUnit(unit *domain.Unit) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_units (name, created_at, updated_at) VALUES (?, ?, ?)", unit.Name, now, now)
+	if err != nil {
+		return err
+	}
+	unit.ID, _ = result.LastInsertId()
+	unit.CreatedAt, unit.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateUnit(unit *domain.Unit) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_units SET name = ?, updated_at = ? WHERE id = ?", unit.Name, now, unit.ID)
+	unit.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) CreateDefectType(defectType *domain.DefectType) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO m_defect_types (`key`, name, created_at, updated_at) VALUES (?, ?, ?, ?)", defectType.Key, defectType.Name, now, now)
+	if err != nil {
+		return err
+	}
+	defectType.ID, _ = result.LastInsertId()
+	defectType.CreatedAt, defectType.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateDefectType(defectType *domain.DefectType) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE m_defect_types SET `key` = ?, name = ?, updated_at = ? WHERE id = ?", defectType.Key, defectType.Name, now, defectType.ID)
+	defectType.UpdatedAt = now
+	return err
+}
+
+func (r *mysqlMasterRepository) CreateMovementType(movementType *domain.MovementType) error {
+	now := time.Now()
+	result, err := r.db.Exec("INSERT INTO movement_types (name, created_at, updated_at) VALUES (?, ?, ?)", movementType.Name, now, now)
+	if err != nil {
+		return err
+	}
+	movementType.ID, _ = result.LastInsertId()
+	movementType.CreatedAt, movementType.UpdatedAt = &now, &now
+	return nil
+}
+
+func (r *mysqlMasterRepository) UpdateMovementType(movementType *domain.MovementType) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE movement_types SET name = ?, updated_at = ? WHERE id = ?", movementType.Name, now, movementType.ID)
+	movementType.UpdatedAt = &now
+	return err
+}
+
+// NameExists checks the name against the active rows of the table, ignoring excludeID.
+func (r *mysqlMasterRepository) NameExists(table MasterTable, name string, excludeID int64) (bool, error) {
+	query := "SELECT COUNT(*) FROM " + string(table) + " WHERE name = ? AND id <> ? AND deleted_at IS NULL"
+	var count int
+	if err := r.db.QueryRow(query, name, excludeID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DefectKeyExists checks the key against the active defect types, ignoring excludeID.
+func (r *mysqlMasterRepository) DefectKeyExists(key string, excludeID int64) (bool, error) {
+	query := "SELECT COUNT(*) FROM m_defect_types WHERE `key` = ? AND id <> ? AND deleted_at IS NULL"
+	var count int
+	if err := r.db.QueryRow(query, key, excludeID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountUsage counts the active rows (fabric rolls, defects, movements) that
+// still reference the master row.
+func (r *mysqlMasterRepository) CountUsage(table MasterTable, id int64) (int, error) {
+	query, ok := usageQueries[table]
+	if !ok {
+		return 0, errors.New("unknown master table " + string(table))
+	}
+	var count int
+	if err := r.db.QueryRow(query, id).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *mysqlMasterRepository) SoftDelete(table MasterTable, id int64) error {
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE "+string(table)+" SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL", now, now, id)
+	return err
+}
+
+func (r *mysqlMasterRepository) Restore(table MasterTable, id int64) error {
+	_, err := r.db.Exec("UPDATE "+string(table)+" SET deleted_at = NULL, updated_at = ? WHERE id = ?", time.Now(), id)
+	return err
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// Recipient types of setting_notifications rules.
+const (
+	NotifiableUser = SubjectUser
+	NotifiableRole = "App\\Models\\Role"
+)
+
+// NotificationChannelDatabase is the setting_notifications type of rules that
+// deliver in-app notifications.
+const NotificationChannelDatabase = "database"
+
+type NotificationRepository interface {
+	List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]domain.Notification, error)
+	CountUnread(ctx context.Context, userID int64) (int, error)
+	FindByID(ctx context.Context, userID int64, id string) (*domain.Notification, error)
+	MarkRead(ctx context.Context, userID int64, id string) error
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+	Delete(ctx context.Context, userID int64, id string) (bool, error)
+	Create(ctx context.Context, notifications []domain.Notification) error
+	Recipients(ctx context.Context, module, channel string) ([]domain.NotificationRecipient, error)
+}
+
+type mysqlNotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &mysqlNotificationRepository{db: db}
+}
+
+// List returns the user's notifications, newest first.
+func (r *mysqlNotificationRepository) List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]domain.Notification, error) {
+	query := `
+		SELECT id, type, data, read_at, created_at
+		FROM notifications
+		WHERE notifiable_type = ? AND notifiable_id = ?
+	`
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC, id LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, NotifiableUser, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []domain.Notification{}
+	for rows.Next() {
+		n := domain.Notification{UserID: userID}
+		var data string
+		if err := rows.Scan(&n.ID, &n.Type, &data, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		n.Data = []byte(data)
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+func (r *mysqlNotificationRepository) CountUnread(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE notifiable_type = ? AND notifiable_id = ? AND read_at IS NULL`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, NotifiableUser, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *mysqlNotificationRepository) FindByID(ctx context.Context, userID int64, id string) (*domain.Notification, error) {
+	query := `
+		SELECT id, type, data, read_at, created_at
+		FROM notifications
+		WHERE id = ? AND notifiable_type = ? AND notifiable_id = ?
+	`
+	n := domain.Notification{UserID: userID}
+	var data string
+	err := r.db.QueryRowContext(ctx, query, id, NotifiableUser, userID).Scan(&n.ID, &n.Type, &data, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	n.Data = []byte(data)
+	return &n, nil
+}
+
+// MarkRead marks one notification read. A notification that is already read
+// keeps its original read_at.
+func (r *mysqlNotificationRepository) MarkRead(ctx context.Context, userID int64, id string) error {
+	now := time.Now()
+	query := `UPDATE notifications SET read_at = ?, updated_at = ? WHERE id = ? AND notifiable_type = ? AND notifiable_id = ? AND read_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, now, now, id, NotifiableUser, userID); err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read and returns
+// how many there were.
+func (r *mysqlNotificationRepository) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	now := time.Now()
+	query := `UPDATE notifications SET read_at = ?, updated_at = ? WHERE notifiable_type = ? AND notifiable_id = ? AND read_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, now, now, NotifiableUser, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *mysqlNotificationRepository) Delete(ctx context.Context, userID int64, id string) (bool, error) {
+	query := `DELETE FROM notifications WHERE id = ? AND notifiable_type = ? AND notifiable_id = ?`
+	result, err := r.db.ExecContext(ctx, query, id, NotifiableUser, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete notification: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Create inserts the notifications in one transaction.
+func (r *mysqlNotificationRepository) Create(ctx context.Context, notifications []domain.Notification) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `INSERT INTO notifications (id, type, notifiable_type, notifiable_id, data, read_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, NULL, ?, ?)`
+	for i := range notifications {
+		n := &notifications[i]
+		if _, err := tx.ExecContext(ctx, query, n.ID, n.Type, NotifiableUser, n.UserID, string(n.Data), now, now); err != nil {
+			return fmt.Errorf("failed to insert notification: %w", err)
+		}
+		n.CreatedAt = &now
+	}
+
+	return tx.Commit()
+}
+
+// Recipients resolves the active setting_notifications rules of a module and
+// channel to users. Role rules expand to every active user holding the role.
+// A user matched by several rules is returned once per rule, in rule order.
+func (r *mysqlNotificationRepository) Recipients(ctx context.Context, module, channel string) ([]domain.NotificationRecipient, error) {
+	query := `
+		SELECT u.id, sn.body
+		FROM setting_notifications sn
+		JOIN users u ON u.deleted_at IS NULL AND (
+			(sn.notifiable_type = ? AND u.id = sn.notifiable_id)
+			OR (sn.notifiable_type = ? AND u.id IN (SELECT uhr.user_id FROM user_has_roles uhr WHERE uhr.role_id = sn.notifiable_id))
+		)
+		WHERE sn.module = ? AND sn.type = ? AND sn.deleted_at IS NULL
+		ORDER BY sn.id, u.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, NotifiableUser, NotifiableRole, module, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []domain.NotificationRecipient
+	for rows.Next() {
+		var recipient domain.NotificationRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Body); err != nil {
+			return nil, fmt.Errorf("failed to scan notification recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// ErrNumberingNotFound is returned when no active numbering matches.
+var ErrNumberingNotFound = errors.New("numbering is not found")
+
+// NumberingAllocator picks the next sequence and code of a locked numbering.
+type NumberingAllocator func(n *domain.Numbering, now time.Time) (int64, string, error)
+
+type NumberingRepository interface {
+	List(ctx context.Context) ([]domain.Numbering, error)
+	FindByID(ctx context.Context, id int64) (*domain.Numbering, error)
+	Allocate(ctx context.Context, module, forKey string, allocate NumberingAllocator) (string, error)
+}
+
+type mysqlNumberingRepository struct {
+	db *sql.DB
+}
+
+func NewNumberingRepository(db *sql.DB) NumberingRepository {
+	return &mysqlNumberingRepository{db: db}
+}
+
+const numberingSelect = "SELECT id, module, `for`, format, prefix, clause, sequence, is_increment, is_active, example, updated_at, last_allocated_at FROM setting_numberings"
+
+func scanNumbering(row interface{ Scan(...interface{}) error }) (*domain.Numbering, error) {
+	var n domain.Numbering
+	err := row.Scan(&n.ID, &n.Module, &n.For, &n.Format, &n.Prefix, &n.Clause, &n.Sequence, &n.IsIncrement, &n.IsActive, &n.Example, &n.UpdatedAt, &n.LastAllocatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *mysqlNumberingRepository) List(ctx context.Context) ([]domain.Numbering, error) {
+	rows, err := r.db.QueryContext(ctx, numberingSelect+" WHERE deleted_at IS NULL ORDER BY module, `for`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get numberings: %w", err)
+	}
+	defer rows.Close()
+
+	numberings := []domain.Numbering{}
+	for rows.Next() {
+		n, err := scanNumbering(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan numbering: %w", err)
+		}
+		numberings = append(numberings, *n)
+	}
+	return numberings, nil
+}
+
+func (r *mysqlNumberingRepository) FindByID(ctx context.Context, id int64) (*domain.Numbering, error) {
+	n, err := scanNumbering(r.db.QueryRowContext(ctx, numberingSelect+" WHERE id = ? AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find numbering: %w", err)
+	}
+	return n, nil
+}
+
+// Allocate hands out the next number. The numbering row stays locked until
+// the number is stored, so concurrent callers wait for each other and never
+// get the same number.
+func (r *mysqlNumberingRepository) Allocate(ctx context.Context, module, forKey string, allocate NumberingAllocator) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := numberingSelect + " WHERE module = ? AND `for` = ? AND is_active = 1 AND deleted_at IS NULL ORDER BY id LIMIT 1 FOR UPDATE"
+	n, err := scanNumbering(tx.QueryRowContext(ctx, query, module, forKey))
+	if err == sql.ErrNoRows {
+		return "", ErrNumberingNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lock numbering: %w", err)
+	}
+
+	now := time.Now()
+	sequence, code, err := allocate(n, now)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE setting_numberings SET sequence = ?, last_allocated_at = ?, updated_at = ? WHERE id = ?`, sequence, now, now, n.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update numbering: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit numbering: %w", err)
+	}
+	return code, nil
+}
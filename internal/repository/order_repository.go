@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// OrderFilter narrows down the order listing.
+type OrderFilter struct {
+	Search  string
+	BuyerID *int64
+	Status  string
+}
+
+type OrderRepository interface {
+	List(ctx context.Context, filter OrderFilter) ([]domain.Order, error)
+	FindByID(ctx context.Context, id int64) (*domain.Order, error)
+	GetDetail(ctx context.Context, orderID int64) (*domain.OrderDetail, error)
+	GetProcesses(ctx context.Context, orderID int64) ([]domain.OrderProcess, error)
+	GetFabricReceived(ctx context.Context, orderID int64) (*domain.FabricReconciliation, error)
+	GetFabricByStage(ctx context.Context, orderID int64) ([]domain.StageYards, error)
+}
+
+type mysqlOrderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) OrderRepository {
+	return &mysqlOrderRepository{db: db}
+}
+
+const orderSelect = `
+	SELECT
+		o.id, o.code, o.dateTime, o.buyer_id, COALESCE(b.name, '-'), o.style,
+		o.vendor_id, COALESCE(v.name, '-'), o.supplier_id, s.name, o.status,
+		o.created_at, o.updated_at
+	FROM orders o
+	LEFT JOIN buyers b ON b.id = o.buyer_id
+	LEFT JOIN vendors v ON v.id = o.vendor_id
+	LEFT JOIN suppliers s ON s.id = o.supplier_id
+`
+
+func scanOrder(row interface{ Scan(...interface{}) error }, o *domain.Order) error {
+	return row.Scan(
+		&o.ID, &o.Code, &o.DateTime, &o.BuyerID, &o.BuyerName, &o.Style,
+		&o.VendorID, &o.VendorName, &o.SupplierID, &o.SupplierName, &o.Status,
+		&o.CreatedAt, &o.UpdatedAt,
+	)
+}
+
+func (r *mysqlOrderRepository) List(ctx context.Context, filter OrderFilter) ([]domain.Order, error) {
+	conditions := []string{"o.deleted_at IS NULL"}
+	var args []interface{}
+
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		conditions = append(conditions, "(o.code LIKE ? OR o.style LIKE ?)")
+		args = append(args, like, like)
+	}
+	if filter.BuyerID != nil {
+		conditions = append(conditions, "o.buyer_id = ?")
+		args = append(args, *filter.BuyerID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "o.status = ?")
+		args = append(args, filter.Status)
+	}
+
+	query := orderSelect + " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY o.dateTime DESC, o.id DESC"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		var o domain.Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+func (r *mysqlOrderRepository) FindByID(ctx context.Context, id int64) (*domain.Order, error) {
+	var o domain.Order
+	err := scanOrder(r.db.QueryRowContext(ctx, orderSelect+" WHERE o.id = ? AND o.deleted_at IS NULL", id), &o)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+	return &o, nil
+}
+
+// GetDetail returns the latest manpower plan of the order.
+func (r *mysqlOrderRepository) GetDetail(ctx context.Context, orderID int64) (*domain.OrderDetail, error) {
+	query := `
+		SELECT
+			COALESCE(man_operator, 0), COALESCE(man_qc, 0), COALESCE(man_helper, 0), COALESCE(man_iron, 0),
+			COALESCE(man_qc_finishing, 0), COALESCE(man_hangtag, 0), COALESCE(man_folding, 0), COALESCE(man_total, 0)
+		FROM order_details
+		WHERE order_id = ? AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var d domain.OrderDetail
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&d.ManOperator, &d.ManQC, &d.ManHelper, &d.ManIron,
+		&d.ManQCFinishing, &d.ManHangtag, &d.ManFolding, &d.ManTotal,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order detail: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *mysqlOrderRepository) GetProcesses(ctx context.Context, orderID int64) ([]domain.OrderProcess, error) {
+	query := `
+		SELECT id, order_id, process_id, highlight, name, machine_type, class_id, tooling, standard_time, actual_time, video_link
+		FROM order_processes
+		WHERE order_id = ? AND deleted_at IS NULL
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order processes: %w", err)
+	}
+	defer rows.Close()
+
+	var processes []domain.OrderProcess
+	for rows.Next() {
+		var p domain.OrderProcess
+		if err := rows.Scan(
+			&p.ID, &p.OrderID, &p.ProcessID, &p.Highlight, &p.Name, &p.MachineType,
+			&p.ClassID, &p.Tooling, &p.StandardTime, &p.ActualTime, &p.VideoLink,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan order process: %w", err)
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+// GetFabricReceived totals the incomings of the order and the rolls they brought in.
+func (r *mysqlOrderRepository) GetFabricReceived(ctx context.Context, orderID int64) (*domain.FabricReconciliation, error) {
+	query := `
+		SELECT COUNT(DISTINCT fi.id), COUNT(f.id), COALESCE(SUM(f.yard), 0)
+		FROM fabric_incomings fi
+		LEFT JOIN fabrics f ON f.fabric_incoming_id = fi.id AND f.deleted_at IS NULL
+		WHERE fi.order_id = ? AND fi.deleted_at IS NULL
+	`
+
+	var rec domain.FabricReconciliation
+	if err := r.db.QueryRowContext(ctx, query, orderID).Scan(&rec.Incomings, &rec.RollsReceived, &rec.YardsReceived); err != nil {
+		return nil, fmt.Errorf("failed to get fabric received: %w", err)
+	}
+	return &rec, nil
+}
+
+// GetFabricByStage totals the received rolls by their current check point
+// stage. Rolls without an inventory row and rolls with an empty stage are
+// grouped together as unassigned.
+func (r *mysqlOrderRepository) GetFabricByStage(ctx context.Context, orderID int64) ([]domain.StageYards, error) {
+	query := `
+		SELECT COALESCE(NULLIF(i.stage, ''), ?) AS stage, COUNT(f.id), COALESCE(SUM(f.yard), 0)
+		FROM fabric_incomings fi
+		JOIN fabrics f ON f.fabric_incoming_id = fi.id AND f.deleted_at IS NULL
+		LEFT JOIN inventories i ON i.fabric_id = f.id AND i.deleted_at IS NULL
+		WHERE fi.order_id = ? AND fi.deleted_at IS NULL
+		GROUP BY COALESCE(NULLIF(i.stage, ''), ?)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.StageUnassigned, orderID, domain.StageUnassigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fabric by stage: %w", err)
+	}
+	defer rows.Close()
+
+	var stages []domain.StageYards
+	for rows.Next() {
+		var s domain.StageYards
+		if err := rows.Scan(&s.Stage, &s.Rolls, &s.Yards); err != nil {
+			return nil, fmt.Errorf("failed to scan fabric stage: %w", err)
+		}
+		stages = append(stages, s)
+	}
+	return stages, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// ErrItemNotPackable is returned when the piece was packed, sent to rework or
+// failed in the meantime.
+var ErrItemNotPackable = errors.New("request item can no longer be packed")
+
+type PackingRepository interface {
+	ListGates(ctx context.Context) ([]domain.PackingGate, error)
+	FindGate(ctx context.Context, id int64) (*domain.PackingGate, error)
+	Pack(ctx context.Context, itemID, packingID int64) error
+	CountPacked(ctx context.Context, qrSystemID int64) (int, error)
+	ListPacked(ctx context.Context, qrSystemID int64) ([]domain.PackedPiece, error)
+}
+
+type mysqlPackingRepository struct {
+	db *sql.DB
+}
+
+func NewPackingRepository(db *sql.DB) PackingRepository {
+	return &mysqlPackingRepository{db: db}
+}
+
+const packingGateSelect = `
+	SELECT p.id, p.factory_id, COALESCE(f.name, '-'), p.gate, p.link
+	FROM packings p
+	LEFT JOIN factories f ON f.id = p.factory_id
+`
+
+func (r *mysqlPackingRepository) ListGates(ctx context.Context) ([]domain.PackingGate, error) {
+	rows, err := r.db.QueryContext(ctx, packingGateSelect+" WHERE p.deleted_at IS NULL ORDER BY f.name, p.gate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get packing gates: %w", err)
+	}
+	defer rows.Close()
+
+	gates := []domain.PackingGate{}
+	for rows.Next() {
+		var g domain.PackingGate
+		if err := rows.Scan(&g.ID, &g.FactoryID, &g.FactoryName, &g.Gate, &g.Link); err != nil {
+			return nil, fmt.Errorf("failed to scan packing gate: %w", err)
+		}
+		gates = append(gates, g)
+	}
+	return gates, nil
+}
+
+func (r *mysqlPackingRepository) FindGate(ctx context.Context, id int64) (*domain.PackingGate, error) {
+	var g domain.PackingGate
+	err := r.db.QueryRowContext(ctx, packingGateSelect+" WHERE p.id = ? AND p.deleted_at IS NULL", id).Scan(
+		&g.ID, &g.FactoryID, &g.FactoryName, &g.Gate, &g.Link,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find packing gate: %w", err)
+	}
+	return &g, nil
+}
+
+// Pack moves a piece that passed QC and is not in rework to the packing stage
+// of a gate and logs the move.
+func (r *mysqlPackingRepository) Pack(ctx context.Context, itemID, packingID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	query := `UPDATE request_items SET stage = ?, packing_id = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND COALESCE(stage, '') <> ? AND COALESCE(is_rework, 0) = 0 AND result = 1`
+	result, err := tx.ExecContext(ctx, query, domain.GarmentStagePacking, packingID, now, itemID, domain.GarmentStagePacking)
+	if err != nil {
+		return fmt.Errorf("failed to pack request item: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrItemNotPackable
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO request_item_logs (request_item_id, stage, created_at, updated_at) VALUES (?, ?, ?, ?)`, itemID, domain.GarmentStagePacking, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert request item log: %w", err)
+	}
+
+	err = logStatus(ctx, tx, StatusLogEntry{
+		Module:       "garment",
+		LoggableType: LoggableRequestItem,
+		LoggableID:   itemID,
+		Status:       domain.GarmentStagePacking,
+		Action:       "packed",
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit packing: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlPackingRepository) CountPacked(ctx context.Context, qrSystemID int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM request_items WHERE qr_system_id = ? AND stage = ? AND deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query, qrSystemID, domain.GarmentStagePacking).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count packed items: %w", err)
+	}
+	return count, nil
+}
+
+// ListPacked returns the packed pieces of a bundle with the gate and time
+// they were packed.
+func (r *mysqlPackingRepository) ListPacked(ctx context.Context, qrSystemID int64) ([]domain.PackedPiece, error) {
+	query := `
+		SELECT
+			ri.id, ri.qr_code, ri.packing_id, COALESCE(p.gate, '-'),
+			(SELECT MAX(lg.created_at) FROM request_item_logs lg WHERE lg.request_item_id = ri.id AND lg.stage = ?)
+		FROM request_items ri
+		LEFT JOIN packings p ON p.id = ri.packing_id
+		WHERE ri.qr_system_id = ? AND ri.stage = ? AND ri.deleted_at IS NULL
+		ORDER BY ri.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.GarmentStagePacking, qrSystemID, domain.GarmentStagePacking)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get packed items: %w", err)
+	}
+	defer rows.Close()
+
+	pieces := []domain.PackedPiece{}
+	for rows.Next() {
+		var piece domain.PackedPiece
+		var packingID sql.NullInt64
+		var packedAt sql.NullTime
+		if err := rows.Scan(&piece.ItemID, &piece.QRCode, &packingID, &piece.Gate, &packedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan packed item: %w", err)
+		}
+		piece.PackingID = packingID.Int64
+		if packedAt.Valid {
+			piece.PackedAt = &packedAt.Time
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PartnerFilter narrows down buyer, supplier and vendor listings.
+type PartnerFilter struct {
+	Search     string
+	Status     *int
+	CategoryID *int64
+}
+
+// partnerConditions builds the WHERE clause shared by the buyers, suppliers
+// and vendors listings. Search matches the code, name and contact person.
+func partnerConditions(alias string, filter PartnerFilter) (string, []interface{}) {
+	conditions := []string{alias + ".deleted_at IS NULL"}
+	var args []interface{}
+
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		conditions = append(conditions, fmt.Sprintf("(%[1]s.code LIKE ? OR %[1]s.name LIKE ? OR %[1]s.contact_person LIKE ?)", alias))
+		args = append(args, like, like, like)
+	}
+	if filter.Status != nil {
+		conditions = append(conditions, alias+".status = ?")
+		args = append(args, *filter.Status)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// partnerCodeExists checks the code against the active rows of the table, ignoring excludeID.
+func partnerCodeExists(ctx context.Context, db *sql.DB, table, code string, excludeID int64) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM " + table + " WHERE code = ? AND id <> ? AND deleted_at IS NULL"
+	if err := db.QueryRowContext(ctx, query, code, excludeID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check %s code: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+func setPartnerStatus(ctx context.Context, db *sql.DB, table string, id int64, status int) error {
+	query := "UPDATE " + table + " SET status = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL"
+	if _, err := db.ExecContext(ctx, query, status, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update %s status: %w", table, err)
+	}
+	return nil
+}
+
+func softDeleteRow(ctx context.Context, db *sql.DB, table string, id int64) error {
+	now := time.Now()
+	query := "UPDATE " + table + " SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL"
+	if _, err := db.ExecContext(ctx, query, now, now, id); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", table, err)
+	}
+	return nil
+}
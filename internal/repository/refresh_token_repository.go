@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// ErrRefreshTokenUsed is returned by Rotate when the token was used or
+// revoked since it was read, e.g. by a concurrent refresh with the same token.
+var ErrRefreshTokenUsed = errors.New("refresh token has already been used")
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *domain.RefreshToken) error
+	FindByTokenID(ctx context.Context, tokenID string) (*domain.RefreshToken, error)
+	Rotate(ctx context.Context, used *domain.RefreshToken, next *domain.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID, reason string) error
+	RevokeUser(ctx context.Context, userID int64, reason string) error
+}
+
+type mysqlRefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &mysqlRefreshTokenRepository{db: db}
+}
+
+func (r *mysqlRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	return createRefreshToken(ctx, r.db, token)
+}
+
+func createRefreshToken(ctx context.Context, db execer, token *domain.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, token_id, family_id, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.ExecContext(ctx, query, token.UserID, token.TokenID, token.FamilyID, token.ExpiresAt, token.CreatedAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	token.ID, _ = result.LastInsertId()
+	return nil
+}
+
+func (r *mysqlRefreshTokenRepository) FindByTokenID(ctx context.Context, tokenID string) (*domain.RefreshToken, error) {
+	query := `SELECT id, user_id, token_id, family_id, expires_at, used_at, revoked_at, revoked_reason, created_at FROM refresh_tokens WHERE token_id = ?`
+	var token domain.RefreshToken
+	err := r.db.QueryRowContext(ctx, query, tokenID).Scan(
+		&token.ID, &token.UserID, &token.TokenID, &token.FamilyID, &token.ExpiresAt,
+		&token.UsedAt, &token.RevokedAt, &token.RevokedReason, &token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// Rotate marks used as used and stores next in one transaction. Only one of
+// several concurrent refreshes with the same token gets to mark it; the
+// others get ErrRefreshTokenUsed.
+func (r *mysqlRefreshTokenRepository) Rotate(ctx context.Context, used *domain.RefreshToken, next *domain.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ?, updated_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`, now, now, used.ID)
+	if err != nil {
+		return fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrRefreshTokenUsed
+	}
+	if err := createRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeFamily revokes every token of a login session that is not revoked yet.
+func (r *mysqlRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	now := time.Now()
+	query := `UPDATE refresh_tokens SET revoked_at = ?, revoked_reason = ?, updated_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, now, reason, now, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeUser revokes every token of every login session of the user.
+func (r *mysqlRefreshTokenRepository) RevokeUser(ctx context.Context, userID int64, reason string) error {
+	now := time.Now()
+	query := `UPDATE refresh_tokens SET revoked_at = ?, revoked_reason = ?, updated_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, now, reason, now, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// ErrRequestProcessChanged is returned when the assignment was changed by
+// someone else while it was being reassigned.
+var ErrRequestProcessChanged = errors.New("the assignment has been changed in the meantime")
+
+type RequestProcessRepository interface {
+	FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error)
+	List(ctx context.Context, requestID int64, withDeleted bool) ([]domain.RequestProcess, error)
+	FindCurrent(ctx context.Context, requestID, orderProcessID int64) (*domain.RequestProcess, error)
+	CopyFromOrder(ctx context.Context, requestID, orderID int64) (int, error)
+	FindManPower(ctx context.Context, id int64) (*domain.ManPower, error)
+	Reassign(ctx context.Context, current *domain.RequestProcess, manPowerID, userID int64) (*domain.RequestProcess, error)
+}
+
+type mysqlRequestProcessRepository struct {
+	db *sql.DB
+}
+
+func NewRequestProcessRepository(db *sql.DB) RequestProcessRepository {
+	return &mysqlRequestProcessRepository{db: db}
+}
+
+const requestProcessSelect = `
+	SELECT
+		rp.id, rp.request_id, COALESCE(rp.order_process_id, 0), rp.process_id, COALESCE(op.name, p.name, '-'),
+		COALESCE(op.class_id, p.class_id), c.name,
+		mp.id, mp.nickname, mp.fullname, mp.class_id, mc.name, mp.grade_id, g.name, mp.is_active,
+		rp.assigned_by, rp.created_at, rp.deleted_at
+	FROM request_processes rp
+	LEFT JOIN order_processes op ON op.id = rp.order_process_id
+	LEFT JOIN processes p ON p.id = rp.process_id
+	LEFT JOIN classes c ON c.id = COALESCE(op.class_id, p.class_id)
+	LEFT JOIN man_powers mp ON mp.id = rp.man_power_id
+	LEFT JOIN classes mc ON mc.id = mp.class_id
+	LEFT JOIN grades g ON g.id = mp.grade_id
+`
+
+func scanRequestProcess(row interface{ Scan(...interface{}) error }) (*domain.RequestProcess, error) {
+	var rp domain.RequestProcess
+	var mpID sql.NullInt64
+	var mpNickname, mpFullname sql.NullString
+	var mpActive sql.NullBool
+	mp := domain.ManPower{}
+	err := row.Scan(
+		&rp.ID, &rp.RequestID, &rp.OrderProcessID, &rp.ProcessID, &rp.ProcessName,
+		&rp.ClassID, &rp.ClassName,
+		&mpID, &mpNickname, &mpFullname, &mp.ClassID, &mp.ClassName, &mp.GradeID, &mp.GradeName, &mpActive,
+		&rp.AssignedBy, &rp.CreatedAt, &rp.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if mpID.Valid {
+		mp.ID, mp.Nickname, mp.Fullname, mp.IsActive = mpID.Int64, mpNickname.String, mpFullname.String, mpActive.Bool
+		rp.ManPower = &mp
+	}
+	return &rp, nil
+}
+
+func (r *mysqlRequestProcessRepository) FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error) {
+	return findLineRequest(ctx, r.db, lineRequestSelect+" WHERE r.id = ? AND r.deleted_at IS NULL", id)
+}
+
+// List returns the current assignments of a request, or the full history
+// including replaced assignments when withDeleted is set.
+func (r *mysqlRequestProcessRepository) List(ctx context.Context, requestID int64, withDeleted bool) ([]domain.RequestProcess, error) {
+	query := requestProcessSelect + " WHERE rp.request_id = ?"
+	if !withDeleted {
+		query += " AND rp.deleted_at IS NULL"
+	}
+	query += " ORDER BY rp.order_process_id, rp.id"
+
+	rows, err := r.db.QueryContext(ctx, query, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request processes: %w", err)
+	}
+	defer rows.Close()
+
+	processes := []domain.RequestProcess{}
+	for rows.Next() {
+		rp, err := scanRequestProcess(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request process: %w", err)
+		}
+		processes = append(processes, *rp)
+	}
+	return processes, nil
+}
+
+func (r *mysqlRequestProcessRepository) FindCurrent(ctx context.Context, requestID, orderProcessID int64) (*domain.RequestProcess, error) {
+	query := requestProcessSelect + " WHERE rp.request_id = ? AND rp.order_process_id = ? AND rp.deleted_at IS NULL ORDER BY rp.id DESC LIMIT 1"
+	rp, err := scanRequestProcess(r.db.QueryRowContext(ctx, query, requestID, orderProcessID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find request process: %w", err)
+	}
+	return rp, nil
+}
+
+// CopyFromOrder adds the order's processes the request does not have yet,
+// without operators. It returns the number of processes added.
+func (r *mysqlRequestProcessRepository) CopyFromOrder(ctx context.Context, requestID, orderID int64) (int, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO request_processes (request_id, order_process_id, process_id, created_at, updated_at)
+		SELECT ?, op.id, op.process_id, ?, ?
+		FROM order_processes op
+		WHERE op.order_id = ? AND op.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM request_processes rp
+			WHERE rp.request_id = ? AND rp.order_process_id = op.id AND rp.deleted_at IS NULL
+		)
+		ORDER BY op.id
+	`
+
+	result, err := r.db.ExecContext(ctx, query, requestID, now, now, orderID, requestID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy order processes: %w", err)
+	}
+	copied, _ := result.RowsAffected()
+	return int(copied), nil
+}
+
+func (r *mysqlRequestProcessRepository) FindManPower(ctx context.Context, id int64) (*domain.ManPower, error) {
+	query := `
+		SELECT mp.id, mp.nickname, mp.fullname, mp.class_id, c.name, mp.grade_id, g.name, mp.is_active
+		FROM man_powers mp
+		LEFT JOIN classes c ON c.id = mp.class_id
+		LEFT JOIN grades g ON g.id = mp.grade_id
+		WHERE mp.id = ? AND mp.deleted_at IS NULL
+	`
+
+	var mp domain.ManPower
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&mp.ID, &mp.Nickname, &mp.Fullname, &mp.ClassID, &mp.ClassName, &mp.GradeID, &mp.GradeName, &mp.IsActive,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find man power: %w", err)
+	}
+	return &mp, nil
+}
+
+// Reassign replaces the current assignment with one for the new operator. The
+// replaced row is soft-deleted and stays as history.
+func (r *mysqlRequestProcessRepository) Reassign(ctx context.Context, current *domain.RequestProcess, manPowerID, userID int64) (*domain.RequestProcess, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	result, err := tx.ExecContext(ctx, `UPDATE request_processes SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`, now, now, current.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace request process: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, ErrRequestProcessChanged
+	}
+
+	query := `INSERT INTO request_processes (request_id, order_process_id, process_id, man_power_id, assigned_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err = tx.ExecContext(ctx, query, current.RequestID, current.OrderProcessID, current.ProcessID, manPowerID, userID, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert request process: %w", err)
+	}
+	id, _ := result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit request process: %w", err)
+	}
+
+	rp, err := scanRequestProcess(r.db.QueryRowContext(ctx, requestProcessSelect+" WHERE rp.id = ?", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find request process: %w", err)
+	}
+	return rp, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// Loggable types of the documents whose status changes are logged. They match
+// the Laravel models so both apps share the same history.
+const (
+	LoggableFabric               = "App\\Models\\Fabric"
+	LoggableFabricDestroyRequest = "App\\Models\\FabricDestroyRequest"
+	LoggableRequestItem          = "App\\Models\\RequestItem"
+)
+
+// StatusLogEntry is a status change to record. A zero CreatedBy is stored as
+// NULL for changes without a signed in user.
+type StatusLogEntry struct {
+	Module       string
+	LoggableType string
+	LoggableID   int64
+	Status       string
+	Action       string
+	Description  string
+	Notes        string
+	CreatedBy    int64
+}
+
+type StatusLogRepository interface {
+	Log(ctx context.Context, entry StatusLogEntry) error
+	History(ctx context.Context, loggableType string, loggableID int64) ([]domain.StatusLog, error)
+}
+
+type mysqlStatusLogRepository struct {
+	db *sql.DB
+}
+
+func NewStatusLogRepository(db *sql.DB) StatusLogRepository {
+	return &mysqlStatusLogRepository{db: db}
+}
+
+// Log records a status change outside a transaction. Workflows that change a
+// status in a transaction call logStatus with it instead, so the log is only
+// kept when the change is.
+func (r *mysqlStatusLogRepository) Log(ctx context.Context, entry StatusLogEntry) error {
+	return logStatus(ctx, r.db, entry)
+}
+
+// History returns the status changes of a document, oldest first.
+func (r *mysqlStatusLogRepository) History(ctx context.Context, loggableType string, loggableID int64) ([]domain.StatusLog, error) {
+	query := `
+		SELECT sl.id, sl.module, sl.loggable_type, sl.loggable_id, sl.status, sl.action, sl.description, sl.notes, sl.created_by, u.name, sl.created_at
+		FROM setting_status_logs sl
+		LEFT JOIN users u ON u.id = sl.created_by
+		WHERE sl.loggable_type = ? AND sl.loggable_id = ? AND sl.deleted_at IS NULL
+		ORDER BY sl.created_at, sl.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, loggableType, loggableID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []domain.StatusLog{}
+	for rows.Next() {
+		var l domain.StatusLog
+		if err := rows.Scan(&l.ID, &l.Module, &l.LoggableType, &l.LoggableID, &l.Status, &l.Action, &l.Description, &l.Notes, &l.CreatedBy, &l.CreatedByName, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func logStatus(ctx context.Context, db execer, entry StatusLogEntry) error {
+	var createdBy *int64
+	if entry.CreatedBy > 0 {
+		createdBy = &entry.CreatedBy
+	}
+
+	now := time.Now()
+	query := `INSERT INTO setting_status_logs (module, loggable_type, loggable_id, status, action, description, notes, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.ExecContext(ctx, query,
+		entry.Module, entry.LoggableType, entry.LoggableID, entry.Status, nullableString(entry.Action),
+		nullableString(entry.Description), nullableString(entry.Notes), createdBy, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert status log: %w", err)
+	}
+	return nil
+}
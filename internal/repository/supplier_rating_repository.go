@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// SupplierQualityStats aggregates inspection and QC results of a supplier's rolls.
+type SupplierQualityStats struct {
+	SupplierID        int64
+	Inspections       int
+	InspectionsPassed int
+	AvgYsdPoints      float64
+	Controls          int
+	ControlsPassed    int
+}
+
+// SupplierDeliveryStats aggregates how fast a supplier's incomings arrived
+// after the order date.
+type SupplierDeliveryStats struct {
+	SupplierID  int64
+	Deliveries  int
+	OnTime      int
+	AvgLeadDays float64
+}
+
+type SupplierRatingRepository interface {
+	GetQualityStats(ctx context.Context, from, to time.Time) ([]SupplierQualityStats, error)
+	GetDeliveryStats(ctx context.Context, from, to time.Time, leadDays int) ([]SupplierDeliveryStats, error)
+	FindByPeriod(ctx context.Context, supplierID int64, period string) (*domain.SupplierRating, error)
+	Save(ctx context.Context, rating *domain.SupplierRating) error
+	ListByPeriod(ctx context.Context, period string) ([]domain.SupplierRating, error)
+	ListBySupplier(ctx context.Context, supplierID int64, fromPeriod, toPeriod string) ([]domain.SupplierRating, error)
+}
+
+type mysqlSupplierRatingRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRatingRepository(db *sql.DB) SupplierRatingRepository {
+	return &mysqlSupplierRatingRepository{db: db}
+}
+
+func (r *mysqlSupplierRatingRepository) GetQualityStats(ctx context.Context, from, to time.Time) ([]SupplierQualityStats, error) {
+	stats := map[int64]*SupplierQualityStats{}
+	var order []int64
+
+	get := func(supplierID int64) *SupplierQualityStats {
+		s, ok := stats[supplierID]
+		if !ok {
+			s = &SupplierQualityStats{SupplierID: supplierID}
+			stats[supplierID] = s
+			order = append(order, supplierID)
+		}
+		return s
+	}
+
+	inspectionQuery := `
+		SELECT f.supplier_id, COUNT(*), COALESCE(SUM(UPPER(fi.status) = 'PASS'), 0), COALESCE(AVG(fi.ysd_points), 0)
+		FROM fabric_inspections fi
+		JOIN fabrics f ON f.id = fi.fabric_id
+		WHERE fi.deleted_at IS NULL AND f.supplier_id IS NOT NULL
+		AND COALESCE(fi.date_time, fi.created_at) >= ? AND COALESCE(fi.date_time, fi.created_at) < ?
+		GROUP BY f.supplier_id
+	`
+	rows, err := r.db.QueryContext(ctx, inspectionQuery, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspection stats: %w", err)
+	}
+	for rows.Next() {
+		var supplierID int64
+		var total, passed int
+		var avgPoints float64
+		if err := rows.Scan(&supplierID, &total, &passed, &avgPoints); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan inspection stats: %w", err)
+		}
+		s := get(supplierID)
+		s.Inspections, s.InspectionsPassed, s.AvgYsdPoints = total, passed, avgPoints
+	}
+	rows.Close()
+
+	controlQuery := `
+		SELECT f.supplier_id, COUNT(*), COALESCE(SUM(LOWER(fc.result) = 'pass'), 0)
+		FROM fabric_controls fc
+		JOIN fabrics f ON f.id = fc.fabric_id
+		WHERE fc.deleted_at IS NULL AND f.supplier_id IS NOT NULL
+		AND fc.created_at >= ? AND fc.created_at < ?
+		GROUP BY f.supplier_id
+	`
+	rows, err = r.db.QueryContext(ctx, controlQuery, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get control stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var supplierID int64
+		var total, passed int
+		if err := rows.Scan(&supplierID, &total, &passed); err != nil {
+			return nil, fmt.Errorf("failed to scan control stats: %w", err)
+		}
+		s := get(supplierID)
+		s.Controls, s.ControlsPassed = total, passed
+	}
+
+	result := make([]SupplierQualityStats, 0, len(order))
+	for _, id := range order {
+		result = append(result, *stats[id])
+	}
+	return result, nil
+}
+
+func (r *mysqlSupplierRatingRepository) GetDeliveryStats(ctx context.Context, from, to time.Time, leadDays int) ([]SupplierDeliveryStats, error) {
+	query := `
+		SELECT
+			s.supplier_id,
+			COUNT(*),
+			COALESCE(SUM(DATEDIFF(fi.delivery_date, DATE(o.dateTime)) <= ?), 0),
+			COALESCE(AVG(DATEDIFF(fi.delivery_date, DATE(o.dateTime))), 0)
+		FROM fabric_incomings fi
+		JOIN orders o ON o.id = fi.order_id
+		JOIN (
+			SELECT DISTINCT fabric_incoming_id, supplier_id
+			FROM fabrics
+			WHERE supplier_id IS NOT NULL AND deleted_at IS NULL
+		) s ON s.fabric_incoming_id = fi.id
+		WHERE fi.deleted_at IS NULL AND fi.delivery_date IS NOT NULL AND o.dateTime IS NOT NULL
+		AND fi.delivery_date >= ? AND fi.delivery_date < ?
+		GROUP BY s.supplier_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, leadDays, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []SupplierDeliveryStats
+	for rows.Next() {
+		var s SupplierDeliveryStats
+		if err := rows.Scan(&s.SupplierID, &s.Deliveries, &s.OnTime, &s.AvgLeadDays); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+func (r *mysqlSupplierRatingRepository) FindByPeriod(ctx context.Context, supplierID int64, period string) (*domain.SupplierRating, error) {
+	query := `
+		SELECT id, supplier_id, period, rating_quality, rating_delivery, rating_price, rating_overall, remarks, created_at, updated_at
+		FROM supplier_ratings
+		WHERE supplier_id = ? AND period = ? AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var rating domain.SupplierRating
+	err := r.db.QueryRowContext(ctx, query, supplierID, period).Scan(
+		&rating.ID, &rating.SupplierID, &rating.Period, &rating.RatingQuality, &rating.RatingDelivery,
+		&rating.RatingPrice, &rating.RatingOverall, &rating.Remarks, &rating.CreatedAt, &rating.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find supplier rating: %w", err)
+	}
+
+	return &rating, nil
+}
+
+// Save inserts the rating, or updates it when it already has an ID.
+func (r *mysqlSupplierRatingRepository) Save(ctx context.Context, rating *domain.SupplierRating) error {
+	now := time.Now()
+
+	// A rating stored for the period since it was looked up, e.g. by a
+	// concurrent run, is updated instead. Its price is entered by hand and
+	// kept. A soft-deleted rating of the period is restored.
+	if rating.ID == 0 {
+		query := `
+			INSERT INTO supplier_ratings (supplier_id, period, rating_quality, rating_delivery, rating_price, rating_overall, remarks, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				id = LAST_INSERT_ID(id),
+				rating_quality = VALUES(rating_quality),
+				rating_delivery = VALUES(rating_delivery),
+				rating_overall = VALUES(rating_overall),
+				remarks = VALUES(remarks),
+				updated_at = VALUES(updated_at),
+				deleted_at = NULL
+		`
+		result, err := r.db.ExecContext(ctx, query, rating.SupplierID, rating.Period, rating.RatingQuality, rating.RatingDelivery, rating.RatingPrice, rating.RatingOverall, rating.Remarks, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert supplier rating: %w", err)
+		}
+		rating.ID, _ = result.LastInsertId()
+		rating.CreatedAt = now
+		rating.UpdatedAt = now
+		return nil
+	}
+
+	query := `UPDATE supplier_ratings SET rating_quality = ?, rating_delivery = ?, rating_price = ?, rating_overall = ?, remarks = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, rating.RatingQuality, rating.RatingDelivery, rating.RatingPrice, rating.RatingOverall, rating.Remarks, now, rating.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update supplier rating: %w", err)
+	}
+	rating.UpdatedAt = now
+	return nil
+}
+
+func (r *mysqlSupplierRatingRepository) ListByPeriod(ctx context.Context, period string) ([]domain.SupplierRating, error) {
+	query := `
+		SELECT sr.id, sr.supplier_id, s.name, sr.period, sr.rating_quality, sr.rating_delivery, sr.rating_price, sr.rating_overall, sr.remarks, sr.created_at, sr.updated_at
+		FROM supplier_ratings sr
+		JOIN suppliers s ON s.id = sr.supplier_id
+		WHERE sr.period = ? AND sr.deleted_at IS NULL
+		ORDER BY sr.rating_overall DESC, s.name
+	`
+	return r.queryRatings(ctx, query, period)
+}
+
+func (r *mysqlSupplierRatingRepository) ListBySupplier(ctx context.Context, supplierID int64, fromPeriod, toPeriod string) ([]domain.SupplierRating, error) {
+	query := `
+		SELECT sr.id, sr.supplier_id, s.name, sr.period, sr.rating_quality, sr.rating_delivery, sr.rating_price, sr.rating_overall, sr.remarks, sr.created_at, sr.updated_at
+		FROM supplier_ratings sr
+		JOIN suppliers s ON s.id = sr.supplier_id
+		WHERE sr.supplier_id = ? AND sr.period IS NOT NULL AND sr.deleted_at IS NULL
+		AND sr.period >= ? AND sr.period <= ?
+		ORDER BY sr.period
+	`
+	return r.queryRatings(ctx, query, supplierID, fromPeriod, toPeriod)
+}
+
+func (r *mysqlSupplierRatingRepository) queryRatings(ctx context.Context, query string, args ...interface{}) ([]domain.SupplierRating, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var ratings []domain.SupplierRating
+	for rows.Next() {
+		var rating domain.SupplierRating
+		if err := rows.Scan(
+			&rating.ID, &rating.SupplierID, &rating.SupplierName, &rating.Period, &rating.RatingQuality, &rating.RatingDelivery,
+			&rating.RatingPrice, &rating.RatingOverall, &rating.Remarks, &rating.CreatedAt, &rating.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier rating: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+
+	return ratings, nil
+}
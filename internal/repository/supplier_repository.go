@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type SupplierRepository interface {
+	List(ctx context.Context, filter PartnerFilter) ([]domain.Supplier, error)
+	FindByID(ctx context.Context, id int64) (*domain.Supplier, error)
+	Create(ctx context.Context, supplier *domain.Supplier) error
+	Update(ctx context.Context, supplier *domain.Supplier) error
+	CodeExists(ctx context.Context, code string, excludeID int64) (bool, error)
+	SetStatus(ctx context.Context, id int64, status int) error
+	CountUsage(ctx context.Context, id int64) (int, error)
+	Delete(ctx context.Context, id int64) error
+
+	ListCategories(ctx context.Context) ([]domain.SupplierCategory, error)
+	FindCategoryByID(ctx context.Context, id int64) (*domain.SupplierCategory, error)
+	CreateCategory(ctx context.Context, category *domain.SupplierCategory) error
+	UpdateCategory(ctx context.Context, category *domain.SupplierCategory) error
+	CategoryNameExists(ctx context.Context, name string, excludeID int64) (bool, error)
+	CountCategorySuppliers(ctx context.Context, id int64) (int, error)
+	DeleteCategory(ctx context.Context, id int64) error
+
+	ListDocuments(ctx context.Context, supplierID int64) ([]domain.SupplierDocument, error)
+	FindDocumentByID(ctx context.Context, supplierID, id int64) (*domain.SupplierDocument, error)
+	CreateDocument(ctx context.Context, document *domain.SupplierDocument) error
+	DeleteDocument(ctx context.Context, id int64) error
+}
+
+type mysqlSupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) SupplierRepository {
+	return &mysqlSupplierRepository{db: db}
+}
+
+const supplierColumns = `s.id, s.code, s.name, s.supplier_category_id, sc.name, s.contact_person, s.phone, s.address, s.email, s.city, s.country, s.status, s.remarks, s.created_at, s.updated_at, s.deleted_at`
+
+func scanSupplier(row interface{ Scan(...interface{}) error }, s *domain.Supplier) error {
+	return row.Scan(
+		&s.ID, &s.Code, &s.Name, &s.SupplierCategoryID, &s.CategoryName, &s.ContactPerson, &s.Phone, &s.Address,
+		&s.Email, &s.City, &s.Country, &s.Status, &s.Remarks, &s.CreatedAt, &s.UpdatedAt, &s.DeletedAt,
+	)
+}
+
+func (r *mysqlSupplierRepository) List(ctx context.Context, filter PartnerFilter) ([]domain.Supplier, error) {
+	where, args := partnerConditions("s", filter)
+	if filter.CategoryID != nil {
+		where += " AND s.supplier_category_id = ?"
+		args = append(args, *filter.CategoryID)
+	}
+	query := "SELECT " + supplierColumns + " FROM suppliers s LEFT JOIN supplier_categories sc ON sc.id = s.supplier_category_id WHERE " + where + " ORDER BY s.name"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	var suppliers []domain.Supplier
+	for rows.Next() {
+		var s domain.Supplier
+		if err := scanSupplier(rows, &s); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, nil
+}
+
+func (r *mysqlSupplierRepository) FindByID(ctx context.Context, id int64) (*domain.Supplier, error) {
+	query := "SELECT " + supplierColumns + " FROM suppliers s LEFT JOIN supplier_categories sc ON sc.id = s.supplier_category_id WHERE s.id = ? AND s.deleted_at IS NULL"
+
+	var s domain.Supplier
+	err := scanSupplier(r.db.QueryRowContext(ctx, query, id), &s)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find supplier: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *mysqlSupplierRepository) Create(ctx context.Context, s *domain.Supplier) error {
+	now := time.Now()
+	query := `INSERT INTO suppliers (code, name, supplier_category_id, contact_person, phone, address, email, city, country, status, remarks, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, s.Code, s.Name, s.SupplierCategoryID, s.ContactPerson, s.Phone, s.Address, s.Email, s.City, s.Country, s.Status, s.Remarks, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert supplier: %w", err)
+	}
+	s.ID, _ = result.LastInsertId()
+	s.CreatedAt, s.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlSupplierRepository) Update(ctx context.Context, s *domain.Supplier) error {
+	now := time.Now()
+	query := `UPDATE suppliers SET code = ?, name = ?, supplier_category_id = ?, contact_person = ?, phone = ?, address = ?, email = ?, city = ?, country = ?, status = ?, remarks = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, s.Code, s.Name, s.SupplierCategoryID, s.ContactPerson, s.Phone, s.Address, s.Email, s.City, s.Country, s.Status, s.Remarks, now, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update supplier: %w", err)
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+func (r *mysqlSupplierRepository) CodeExists(ctx context.Context, code string, excludeID int64) (bool, error) {
+	return partnerCodeExists(ctx, r.db, "suppliers", code, excludeID)
+}
+
+func (r *mysqlSupplierRepository) SetStatus(ctx context.Context, id int64, status int) error {
+	return setPartnerStatus(ctx, r.db, "suppliers", id, status)
+}
+
+// CountUsage counts the fabric rolls and orders that still reference the supplier.
+func (r *mysqlSupplierRepository) CountUsage(ctx context.Context, id int64) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM fabrics WHERE supplier_id = ? AND deleted_at IS NULL) +
+			(SELECT COUNT(*) FROM orders WHERE supplier_id = ? AND deleted_at IS NULL)
+	`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, id, id).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count supplier usage: %w", err)
+	}
+	return count, nil
+}
+
+func (r *mysqlSupplierRepository) Delete(ctx context.Context, id int64) error {
+	return softDeleteRow(ctx, r.db, "suppliers", id)
+}
+
+func (r *mysqlSupplierRepository) ListCategories(ctx context.Context) ([]domain.SupplierCategory, error) {
+	query := `SELECT id, name, description, created_at, updated_at, deleted_at FROM supplier_categories WHERE deleted_at IS NULL ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []domain.SupplierCategory
+	for rows.Next() {
+		var c domain.SupplierCategory
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+func (r *mysqlSupplierRepository) FindCategoryByID(ctx context.Context, id int64) (*domain.SupplierCategory, error) {
+	query := `SELECT id, name, description, created_at, updated_at, deleted_at FROM supplier_categories WHERE id = ? AND deleted_at IS NULL`
+
+	var c domain.SupplierCategory
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find supplier category: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *mysqlSupplierRepository) CreateCategory(ctx context.Context, c *domain.SupplierCategory) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `INSERT INTO supplier_categories (name, description, created_at, updated_at) VALUES (?, ?, ?, ?)`, c.Name, c.Description, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert supplier category: %w", err)
+	}
+	c.ID, _ = result.LastInsertId()
+	c.CreatedAt, c.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlSupplierRepository) UpdateCategory(ctx context.Context, c *domain.SupplierCategory) error {
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, `UPDATE supplier_categories SET name = ?, description = ?, updated_at = ? WHERE id = ?`, c.Name, c.Description, now, c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update supplier category: %w", err)
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+func (r *mysqlSupplierRepository) CategoryNameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM supplier_categories WHERE name = ? AND id <> ? AND deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query, name, excludeID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check supplier category name: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *mysqlSupplierRepository) CountCategorySuppliers(ctx context.Context, id int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM suppliers WHERE supplier_category_id = ? AND deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count category suppliers: %w", err)
+	}
+	return count, nil
+}
+
+func (r *mysqlSupplierRepository) DeleteCategory(ctx context.Context, id int64) error {
+	return softDeleteRow(ctx, r.db, "supplier_categories", id)
+}
+
+func (r *mysqlSupplierRepository) ListDocuments(ctx context.Context, supplierID int64) ([]domain.SupplierDocument, error) {
+	query := `SELECT id, supplier_id, document_name, document_type, file_path, created_at, updated_at FROM supplier_documents WHERE supplier_id = ? AND deleted_at IS NULL ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []domain.SupplierDocument
+	for rows.Next() {
+		var d domain.SupplierDocument
+		if err := rows.Scan(&d.ID, &d.SupplierID, &d.DocumentName, &d.DocumentType, &d.FilePath, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	return documents, nil
+}
+
+func (r *mysqlSupplierRepository) FindDocumentByID(ctx context.Context, supplierID, id int64) (*domain.SupplierDocument, error) {
+	query := `SELECT id, supplier_id, document_name, document_type, file_path, created_at, updated_at FROM supplier_documents WHERE id = ? AND supplier_id = ? AND deleted_at IS NULL`
+
+	var d domain.SupplierDocument
+	err := r.db.QueryRowContext(ctx, query, id, supplierID).Scan(&d.ID, &d.SupplierID, &d.DocumentName, &d.DocumentType, &d.FilePath, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find supplier document: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *mysqlSupplierRepository) CreateDocument(ctx context.Context, d *domain.SupplierDocument) error {
+	now := time.Now()
+	query := `INSERT INTO supplier_documents (supplier_id, document_name, document_type, file_path, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, d.SupplierID, d.DocumentName, d.DocumentType, d.FilePath, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert supplier document: %w", err)
+	}
+	d.ID, _ = result.LastInsertId()
+	d.CreatedAt, d.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlSupplierRepository) DeleteDocument(ctx context.Context, id int64) error {
+	return softDeleteRow(ctx, r.db, "supplier_documents", id)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/dppi/dppierp-api/internal/domain"
 )
@@ -15,6 +16,8 @@ type UserRepository interface {
 	StoreResetToken(email, token string) error
 	GetResetToken(email string) (string, error)
 	DeleteResetToken(email string) error
+	HasPermission(userID int64, permission string) (bool, error)
+	FindAccess(userID int64) (*domain.UserAccess, error)
 }
 
 type mysqlUserRepository struct {
@@ -141,3 +144,82 @@ func (r *mysqlUserRepository) DeleteResetToken(email string) error {
 	_, err := r.db.Exec("DELETE FROM password_reset_tokens WHERE email = ?", email)
 	return err
 }
+
+// HasPermission reports whether the user holds the permission, either directly
+// or through one of their roles.
+func (r *mysqlUserRepository) HasPermission(userID int64, permission string) (bool, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM permissions p
+		WHERE p.name = ?
+		AND (
+			EXISTS (
+				SELECT 1 FROM user_has_permissions uhp
+				WHERE uhp.permission_id = p.id AND uhp.user_id = ?
+			)
+			OR EXISTS (
+				SELECT 1 FROM role_has_permissions rhp
+				JOIN user_has_roles uhr ON uhr.role_id = rhp.role_id
+				WHERE rhp.permission_id = p.id AND uhr.user_id = ?
+			)
+		)
+	`
+	var count int
+	if err := r.db.QueryRow(query, permission, userID, userID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindAccess returns the user's roles, oldest first, and the names of every
+// permission they hold directly or through a role.
+func (r *mysqlUserRepository) FindAccess(userID int64) (*domain.UserAccess, error) {
+	access := &domain.UserAccess{Roles: []string{}, Permissions: []string{}}
+
+	roleQuery := `
+		SELECT r.name
+		FROM roles r
+		JOIN user_has_roles uhr ON uhr.role_id = r.id
+		WHERE uhr.user_id = ?
+		ORDER BY r.id
+	`
+	if err := r.scanNames(roleQuery, &access.Roles, userID); err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	permissionQuery := `
+		SELECT DISTINCT p.name
+		FROM permissions p
+		WHERE EXISTS (
+			SELECT 1 FROM user_has_permissions uhp
+			WHERE uhp.permission_id = p.id AND uhp.user_id = ?
+		)
+		OR EXISTS (
+			SELECT 1 FROM role_has_permissions rhp
+			JOIN user_has_roles uhr ON uhr.role_id = rhp.role_id
+			WHERE rhp.permission_id = p.id AND uhr.user_id = ?
+		)
+		ORDER BY p.name
+	`
+	if err := r.scanNames(permissionQuery, &access.Permissions, userID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	return access, nil
+}
+
+func (r *mysqlUserRepository) scanNames(query string, names *[]string, args ...interface{}) error {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		*names = append(*names, name)
+	}
+	return rows.Err()
+}
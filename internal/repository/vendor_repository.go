@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type VendorRepository interface {
+	List(ctx context.Context, filter PartnerFilter) ([]domain.Vendor, error)
+	FindByID(ctx context.Context, id int64) (*domain.Vendor, error)
+	Create(ctx context.Context, vendor *domain.Vendor) error
+	Update(ctx context.Context, vendor *domain.Vendor) error
+	CodeExists(ctx context.Context, code string, excludeID int64) (bool, error)
+	SetStatus(ctx context.Context, id int64, status int) error
+	CountOrders(ctx context.Context, id int64) (int, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type mysqlVendorRepository struct {
+	db *sql.DB
+}
+
+func NewVendorRepository(db *sql.DB) VendorRepository {
+	return &mysqlVendorRepository{db: db}
+}
+
+const vendorColumns = `v.id, v.code, v.vendor_code, v.name, v.contact_person, v.phone, v.address, v.email, v.city, v.country, v.status, v.remarks, v.created_at, v.updated_at, v.deleted_at`
+
+func scanVendor(row interface{ Scan(...interface{}) error }, v *domain.Vendor) error {
+	return row.Scan(
+		&v.ID, &v.Code, &v.VendorCode, &v.Name, &v.ContactPerson, &v.Phone, &v.Address, &v.Email,
+		&v.City, &v.Country, &v.Status, &v.Remarks, &v.CreatedAt, &v.UpdatedAt, &v.DeletedAt,
+	)
+}
+
+func (r *mysqlVendorRepository) List(ctx context.Context, filter PartnerFilter) ([]domain.Vendor, error) {
+	where, args := partnerConditions("v", filter)
+	query := "SELECT " + vendorColumns + " FROM vendors v WHERE " + where + " ORDER BY v.name"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendors: %w", err)
+	}
+	defer rows.Close()
+
+	var vendors []domain.Vendor
+	for rows.Next() {
+		var v domain.Vendor
+		if err := scanVendor(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan vendor: %w", err)
+		}
+		vendors = append(vendors, v)
+	}
+	return vendors, nil
+}
+
+func (r *mysqlVendorRepository) FindByID(ctx context.Context, id int64) (*domain.Vendor, error) {
+	query := "SELECT " + vendorColumns + " FROM vendors v WHERE v.id = ? AND v.deleted_at IS NULL"
+
+	var v domain.Vendor
+	err := scanVendor(r.db.QueryRowContext(ctx, query, id), &v)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vendor: %w", err)
+	}
+	return &v, nil
+}
+
+func (r *mysqlVendorRepository) Create(ctx context.Context, v *domain.Vendor) error {
+	now := time.Now()
+	query := `INSERT INTO vendors (code, vendor_code, name, contact_person, phone, address, email, city, country, status, remarks, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, v.Code, v.VendorCode, v.Name, v.ContactPerson, v.Phone, v.Address, v.Email, v.City, v.Country, v.Status, v.Remarks, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert vendor: %w", err)
+	}
+	v.ID, _ = result.LastInsertId()
+	v.CreatedAt, v.UpdatedAt = now, now
+	return nil
+}
+
+func (r *mysqlVendorRepository) Update(ctx context.Context, v *domain.Vendor) error {
+	now := time.Now()
+	query := `UPDATE vendors SET code = ?, vendor_code = ?, name = ?, contact_person = ?, phone = ?, address = ?, email = ?, city = ?, country = ?, status = ?, remarks = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, v.Code, v.VendorCode, v.Name, v.ContactPerson, v.Phone, v.Address, v.Email, v.City, v.Country, v.Status, v.Remarks, now, v.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update vendor: %w", err)
+	}
+	v.UpdatedAt = now
+	return nil
+}
+
+func (r *mysqlVendorRepository) CodeExists(ctx context.Context, code string, excludeID int64) (bool, error) {
+	return partnerCodeExists(ctx, r.db, "vendors", code, excludeID)
+}
+
+func (r *mysqlVendorRepository) SetStatus(ctx context.Context, id int64, status int) error {
+	return setPartnerStatus(ctx, r.db, "vendors", id, status)
+}
+
+func (r *mysqlVendorRepository) CountOrders(ctx context.Context, id int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE vendor_id = ? AND deleted_at IS NULL`, id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count vendor orders: %w", err)
+	}
+	return count, nil
+}
+
+func (r *mysqlVendorRepository) Delete(ctx context.Context, id int64) error {
+	return softDeleteRow(ctx, r.db, "vendors", id)
+}
@@ -0,0 +1,395 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type WebhookRepository interface {
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	FindSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error)
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) (bool, error)
+	ActiveSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+
+	PendingEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error)
+	DispatchEvent(ctx context.Context, eventID int64, subscriptionIDs []int64) error
+
+	DueDeliveries(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error)
+	FindDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+}
+
+type mysqlWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &mysqlWebhookRepository{db: db}
+}
+
+const webhookSubscriptionSelect = `
+	SELECT id, name, url, events, is_active, created_by, created_at, updated_at
+	FROM webhook_subscriptions
+`
+
+func (r *mysqlWebhookRepository) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, webhookSubscriptionSelect+" WHERE deleted_at IS NULL ORDER BY id")
+}
+
+// ActiveSubscriptions returns the subscriptions that receive new events.
+func (r *mysqlWebhookRepository) ActiveSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, webhookSubscriptionSelect+" WHERE is_active = 1 AND deleted_at IS NULL ORDER BY id")
+}
+
+func (r *mysqlWebhookRepository) querySubscriptions(ctx context.Context, query string) ([]domain.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []domain.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
+
+func (r *mysqlWebhookRepository) FindSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	row := r.db.QueryRowContext(ctx, webhookSubscriptionSelect+" WHERE id = ? AND deleted_at IS NULL", id)
+	sub, err := scanWebhookSubscription(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sub, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhookSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	var events string
+	if err := row.Scan(&sub.ID, &sub.Name, &sub.URL, &events, &sub.IsActive, &sub.CreatedBy, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+	}
+	sub.Events = splitWebhookEvents(events)
+	return &sub, nil
+}
+
+func (r *mysqlWebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	now := time.Now()
+	query := `INSERT INTO webhook_subscriptions (name, url, secret, events, is_active, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, sub.Name, sub.URL, sub.Secret, strings.Join(sub.Events, ","), sub.IsActive, sub.CreatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	sub.ID, _ = result.LastInsertId()
+	sub.CreatedAt, sub.UpdatedAt = &now, &now
+	return nil
+}
+
+// UpdateSubscription saves the name, url, events and active flag. The secret
+// is kept.
+func (r *mysqlWebhookRepository) UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	now := time.Now()
+	query := `UPDATE webhook_subscriptions SET name = ?, url = ?, events = ?, is_active = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, sub.Name, sub.URL, strings.Join(sub.Events, ","), sub.IsActive, now, sub.ID); err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	sub.UpdatedAt = &now
+	return nil
+}
+
+func (r *mysqlWebhookRepository) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE webhook_subscriptions SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// PendingEvents returns the outbox events not yet fanned out, oldest first.
+func (r *mysqlWebhookRepository) PendingEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+	query := `SELECT id, event_type, payload, created_at FROM webhook_events WHERE dispatched_at IS NULL ORDER BY id LIMIT ?`
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.WebhookEvent
+	for rows.Next() {
+		var e domain.WebhookEvent
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Type, &payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		e.Payload = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// DispatchEvent creates a delivery of the event for each subscription and
+// marks the event dispatched. Running it twice for an event creates no
+// duplicate deliveries.
+func (r *mysqlWebhookRepository) DispatchEvent(ctx context.Context, eventID int64, subscriptionIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, subscriptionID := range subscriptionIDs {
+		query := `INSERT IGNORE INTO webhook_deliveries (webhook_event_id, webhook_subscription_id, status, attempt, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, eventID, subscriptionID, domain.WebhookDeliveryPending, now, now, now); err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_events SET dispatched_at = ? WHERE id = ? AND dispatched_at IS NULL`, now, eventID); err != nil {
+		return fmt.Errorf("failed to mark webhook event dispatched: %w", err)
+	}
+	return tx.Commit()
+}
+
+const (
+	webhookDeliveryColumns = `
+	d.id, d.webhook_event_id, d.webhook_subscription_id, s.name, s.url, e.event_type, e.payload,
+	d.status, d.attempt, d.next_attempt_at, d.response_status, d.error_msg, d.delivered_at,
+	e.created_at, d.created_at, d.updated_at`
+	webhookDeliveryFrom = `
+	FROM webhook_deliveries d
+	JOIN webhook_events e ON e.id = d.webhook_event_id
+	JOIN webhook_subscriptions s ON s.id = d.webhook_subscription_id`
+	webhookDeliverySelect = "SELECT" + webhookDeliveryColumns + webhookDeliveryFrom
+)
+
+// DueDeliveries returns the deliveries to attempt now, with the secret to sign
+// them: pending ones, retries whose wait is over and ones whose sender
+// stopped before finishing, i.e. still sending since before staleBefore.
+// Deliveries of inactive or deleted subscriptions are held back.
+func (r *mysqlWebhookRepository) DueDeliveries(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	query := "SELECT s.secret," + webhookDeliveryColumns + webhookDeliveryFrom + `
+		WHERE s.is_active = 1 AND s.deleted_at IS NULL
+		AND ((d.status IN (?, ?) AND d.next_attempt_at <= ?) OR (d.status = ? AND d.updated_at < ?))
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, domain.WebhookDeliveryPending, domain.WebhookDeliveryRetry, now, domain.WebhookDeliverySending, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var secret string
+		d, err := scanWebhookDelivery(rows, &secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Secret = secret
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery marks the delivery as being sent, unless another worker got
+// to it first since it was read.
+func (r *mysqlWebhookRepository) ClaimDelivery(ctx context.Context, d *domain.WebhookDelivery) (bool, error) {
+	now := time.Now()
+	query := `UPDATE webhook_deliveries SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND attempt = ?`
+	result, err := r.db.ExecContext(ctx, query, domain.WebhookDeliverySending, now, d.ID, d.Status, d.Attempt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	d.Status, d.UpdatedAt = domain.WebhookDeliverySending, &now
+	return true, nil
+}
+
+// UpdateDelivery stores the outcome of an attempt or a manual redelivery.
+func (r *mysqlWebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	now := time.Now()
+	query := `UPDATE webhook_deliveries SET status = ?, attempt = ?, next_attempt_at = ?, response_status = ?, error_msg = ?, delivered_at = ?, updated_at = ? WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, d.Status, d.Attempt, d.NextAttemptAt, d.ResponseStatus, d.ErrorMsg, d.DeliveredAt, now, d.ID); err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	d.UpdatedAt = &now
+	return nil
+}
+
+// ListDeliveries returns deliveries newest first, without their payload.
+func (r *mysqlWebhookRepository) ListDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error) {
+	query := webhookDeliverySelect + " WHERE 1 = 1"
+	var args []interface{}
+	if filter.Status != "" {
+		query += " AND d.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.SubscriptionID != nil {
+		query += " AND d.webhook_subscription_id = ?"
+		args = append(args, *filter.SubscriptionID)
+	}
+	if filter.EventType != "" {
+		query += " AND e.event_type = ?"
+		args = append(args, filter.EventType)
+	}
+	query += " ORDER BY d.id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = nil
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+func (r *mysqlWebhookRepository) FindDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, webhookDeliverySelect+" WHERE d.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+// scanWebhookDelivery scans the delivery columns after any leading ones.
+func scanWebhookDelivery(row rowScanner, leading ...interface{}) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var payload string
+	err := row.Scan(append(leading, &d.ID, &d.EventID, &d.SubscriptionID, &d.SubscriptionName, &d.URL, &d.EventType, &payload,
+		&d.Status, &d.Attempt, &d.NextAttemptAt, &d.ResponseStatus, &d.ErrorMsg, &d.DeliveredAt,
+		&d.EventCreatedAt, &d.CreatedAt, &d.UpdatedAt)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	d.Payload = json.RawMessage(payload)
+	return &d, nil
+}
+
+func splitWebhookEvents(events string) []string {
+	list := []string{}
+	for _, event := range strings.Split(events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			list = append(list, event)
+		}
+	}
+	return list
+}
+
+// FabricWebhookPayload is the data of the fabric webhook events.
+type FabricWebhookPayload struct {
+	FabricID            int64  `json:"fabric_id"`
+	Code                string `json:"code"`
+	FromStage           string `json:"from_stage,omitempty"`
+	ToStage             string `json:"to_stage,omitempty"`
+	InventoryMovementID int64  `json:"inventory_movement_id,omitempty"`
+	CurrentRackID       int64  `json:"current_rack_id,omitempty"`
+	NewRackID           int64  `json:"new_rack_id,omitempty"`
+	QCResult            string `json:"qc_result,omitempty"`
+	UserID              int64  `json:"user_id,omitempty"`
+	OccurredAt          string `json:"occurred_at"`
+}
+
+// stageWebhookEvents names the events of a stage change. Every change is a
+// stage move. The first time a roll enters inventory it is also received, and
+// the first time it enters cutting WIP, where rolls are issued to the cutting
+// floor, it is also delivered; rolls coming back from QC, relaxation or
+// cutting are not received or delivered again. Call it before the entry of
+// the change is written.
+func stageWebhookEvents(ctx context.Context, tx *sql.Tx, fabricID int64, onStage, toStage string) ([]string, error) {
+	events := []string{domain.WebhookStageMoved}
+	if onStage == toStage {
+		return events, nil
+	}
+
+	var event string
+	switch toStage {
+	case string(domain.StageInventory):
+		event = domain.WebhookReceived
+	case string(domain.StageCuttingWIP):
+		event = domain.WebhookDelivered
+	default:
+		return events, nil
+	}
+
+	var entered bool
+	query := `SELECT EXISTS (SELECT 1 FROM inventory_entries e JOIN inventory_movements m ON m.id = e.inventory_movement_id WHERE m.fabric_id = ? AND m.deleted_at IS NULL AND e.to_stage = ?)`
+	if err := tx.QueryRowContext(ctx, query, fabricID, toStage).Scan(&entered); err != nil {
+		return nil, fmt.Errorf("failed to check earlier stage entries: %w", err)
+	}
+	if !entered {
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// enqueueWebhookEvents writes fabric events to the webhook outbox. Callers
+// pass their transaction so the events are only kept when the change is.
+func enqueueWebhookEvents(ctx context.Context, tx *sql.Tx, payload FabricWebhookPayload, events ...string) error {
+	if payload.Code == "" {
+		if err := tx.QueryRowContext(ctx, `SELECT code FROM fabrics WHERE id = ?`, payload.FabricID).Scan(&payload.Code); err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get fabric code: %w", err)
+		}
+	}
+	now := time.Now()
+	if payload.OccurredAt == "" {
+		payload.OccurredAt = now.Format(time.RFC3339)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	for _, event := range events {
+		query := `INSERT INTO webhook_events (event_type, payload, created_at) VALUES (?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, event, string(data), now); err != nil {
+			return fmt.Errorf("failed to insert webhook event: %w", err)
+		}
+	}
+	return nil
+}
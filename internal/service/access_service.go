@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/cache"
+	"github.com/rs/zerolog/log"
+)
+
+// AccessService loads the roles and permissions of users from the roles and
+// permissions tables. They are cached for ttl, so a role change made in the
+// web application takes effect within that time.
+type AccessService struct {
+	userRepo repository.UserRepository
+	store    cache.Store
+	ttl      time.Duration
+}
+
+// NewAccessService returns an access service. A nil store or a zero ttl
+// loads the access on every call.
+func NewAccessService(userRepo repository.UserRepository, store cache.Store, ttl time.Duration) *AccessService {
+	return &AccessService{userRepo: userRepo, store: store, ttl: ttl}
+}
+
+func accessCacheKey(userID int64) string {
+	return "access:" + strconv.FormatInt(userID, 10)
+}
+
+// Access returns the roles and permissions of a user.
+func (s *AccessService) Access(ctx context.Context, userID int64) (*domain.UserAccess, error) {
+	cached := s.store != nil && s.ttl > 0
+	if cached {
+		encoded, ok, err := s.store.Get(ctx, accessCacheKey(userID))
+		if err != nil {
+			log.Warn().Err(err).Int64("user_id", userID).Msg("Failed to read cached access")
+		}
+		var access domain.UserAccess
+		if ok && json.Unmarshal(encoded, &access) == nil {
+			return &access, nil
+		}
+	}
+
+	access, err := s.userRepo.FindAccess(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access: %w", err)
+	}
+	if cached {
+		encoded, err := json.Marshal(access)
+		if err == nil {
+			err = s.store.Put(ctx, accessCacheKey(userID), encoded, s.ttl)
+		}
+		if err != nil {
+			log.Warn().Err(err).Int64("user_id", userID).Msg("Failed to cache access")
+		}
+	}
+	return access, nil
+}
+
+// HasPermission reports whether the user holds the permission. Super admins
+// hold every permission.
+func (s *AccessService) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	access, err := s.Access(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return access.Can(permission), nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/pkg/cache"
+)
+
+func TestAccessService_HasPermission(t *testing.T) {
+	users := &permissionUserRepository{
+		permissions: map[int64][]string{1: {"fabric.qr-system.read"}},
+		roles:       map[int64][]string{1: {"operator"}, 2: {"superadmin"}},
+	}
+	svc := NewAccessService(users, cache.NewMemoryStore(), time.Minute)
+	ctx := context.Background()
+
+	cases := []struct {
+		userID     int64
+		permission string
+		want       bool
+	}{
+		{1, "fabric.qr-system.read", true},
+		{1, "fabric.qr-system.write", false},
+		{2, "setting.webhook.manage", true},
+		{3, "fabric.qr-system.read", false},
+	}
+	for _, tc := range cases {
+		got, err := svc.HasPermission(ctx, tc.userID, tc.permission)
+		if err != nil {
+			t.Fatalf("HasPermission returned error: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("HasPermission(%d, %q) = %v, want %v", tc.userID, tc.permission, got, tc.want)
+		}
+	}
+	if users.accessLoads != 3 {
+		t.Errorf("Expected the access of each user to be loaded once, got %d loads", users.accessLoads)
+	}
+}
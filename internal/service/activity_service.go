@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/dppi/dppierp-api/internal/authctx"
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Activity events, as written by the Laravel activity log.
+const (
+	ActivityCreated  = "created"
+	ActivityUpdated  = "updated"
+	ActivityDeleted  = "deleted"
+	ActivityRestored = "restored"
+)
+
+const (
+	defaultActivityLimit = 100
+	maxActivityLimit     = 500
+)
+
+// activityIgnoredFields change on every write and would only add noise to the
+// diff.
+var activityIgnoredFields = map[string]bool{"created_at": true, "updated_at": true}
+
+// ActivityInput describes a change to record. Old and New are the subject
+// before and after the change; either may be nil for created and deleted
+// subjects. CauserID defaults to the signed in user of ctx.
+type ActivityInput struct {
+	LogName     string
+	Event       string
+	Description string
+	SubjectType string
+	SubjectID   int64
+	Old         interface{}
+	New         interface{}
+	CauserID    int64
+}
+
+// ActivityService writes and reads the audit trail in activity_log. A nil
+// *ActivityService records nothing, so services can be used without one.
+type ActivityService struct {
+	repo repository.ActivityLogRepository
+}
+
+func NewActivityService(repo repository.ActivityLogRepository) *ActivityService {
+	return &ActivityService{repo: repo}
+}
+
+// Record writes an activity after the change has been saved. Failing to
+// write the audit trail is logged and does not fail the change itself.
+// Updates without any changed field are not recorded.
+func (s *ActivityService) Record(ctx context.Context, input ActivityInput) {
+	if s == nil {
+		return
+	}
+
+	properties, changed, err := ActivityProperties(input.Old, input.New)
+	if err != nil {
+		log.Error().Err(err).Str("subject_type", input.SubjectType).Int64("subject_id", input.SubjectID).Msg("Failed to diff activity")
+		return
+	}
+	if !changed && input.Event == ActivityUpdated {
+		return
+	}
+
+	activity := &domain.Activity{
+		LogName:     stringPtr(input.LogName),
+		Description: input.Description,
+		SubjectType: stringPtr(input.SubjectType),
+		Event:       stringPtr(input.Event),
+		Properties:  properties,
+		BatchUUID:   stringPtr(ActivityBatchFromContext(ctx)),
+	}
+	if activity.Description == "" {
+		activity.Description = input.Event
+	}
+	if input.SubjectID > 0 {
+		activity.SubjectID = &input.SubjectID
+	}
+
+	causerID := input.CauserID
+	if causerID == 0 {
+		causerID = authctx.UserID(ctx)
+	}
+	if causerID > 0 {
+		causerType := repository.SubjectUser
+		activity.CauserType, activity.CauserID = &causerType, &causerID
+	}
+
+	if err := s.repo.Create(ctx, activity); err != nil {
+		log.Error().Err(err).Str("subject_type", input.SubjectType).Int64("subject_id", input.SubjectID).Msg("Failed to record activity")
+	}
+}
+
+// List returns the audit trail, newest first.
+func (s *ActivityService) List(ctx context.Context, filter domain.ActivityFilter) ([]domain.Activity, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultActivityLimit
+	}
+	if filter.Limit > maxActivityLimit {
+		filter.Limit = maxActivityLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// ActivityProperties diffs old and new into {"old": {...}, "attributes":
+// {...}} holding only the fields that differ. With a nil old every field of
+// new is an attribute, and with a nil new every field of old is old. Both are
+// compared by their JSON fields.
+func ActivityProperties(old, new interface{}) (json.RawMessage, bool, error) {
+	oldFields, err := activityFields(old)
+	if err != nil {
+		return nil, false, err
+	}
+	newFields, err := activityFields(new)
+	if err != nil {
+		return nil, false, err
+	}
+
+	properties := map[string]map[string]interface{}{}
+	switch {
+	case oldFields == nil && newFields == nil:
+		return nil, false, nil
+	case oldFields == nil:
+		properties["attributes"] = newFields
+	case newFields == nil:
+		properties["old"] = oldFields
+	default:
+		changedOld, changedNew := map[string]interface{}{}, map[string]interface{}{}
+		for key, value := range newFields {
+			if previous, ok := oldFields[key]; !ok || !reflect.DeepEqual(previous, value) {
+				changedOld[key], changedNew[key] = oldFields[key], value
+			}
+		}
+		for key, previous := range oldFields {
+			if _, ok := newFields[key]; !ok {
+				changedOld[key], changedNew[key] = previous, nil
+			}
+		}
+		if len(changedNew) == 0 {
+			return nil, false, nil
+		}
+		properties["old"], properties["attributes"] = changedOld, changedNew
+	}
+
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode activity properties: %w", err)
+	}
+	return raw, true, nil
+}
+
+func activityFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode activity subject: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode activity subject: %w", err)
+	}
+	for key := range activityIgnoredFields {
+		delete(fields, key)
+	}
+	return fields, nil
+}
+
+type activityBatchKey struct{}
+
+// WithActivityBatch starts a batch: every activity recorded with the returned
+// context shares one batch_uuid, e.g. all rolls of one move.
+func WithActivityBatch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, activityBatchKey{}, newUUID())
+}
+
+// ActivityBatchFromContext returns the batch_uuid of ctx, if any.
+func ActivityBatchFromContext(ctx context.Context) string {
+	batch, _ := ctx.Value(activityBatchKey{}).(string)
+	return batch
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock ActivityLogRepository for testing
+type mockActivityLogRepository struct {
+	created []domain.Activity
+	filter  domain.ActivityFilter
+}
+
+func (m *mockActivityLogRepository) Create(ctx context.Context, activity *domain.Activity) error {
+	m.created = append(m.created, *activity)
+	return nil
+}
+
+func (m *mockActivityLogRepository) List(ctx context.Context, filter domain.ActivityFilter) ([]domain.Activity, error) {
+	m.filter = filter
+	return nil, nil
+}
+
+func decodeProperties(t *testing.T, raw json.RawMessage) map[string]map[string]interface{} {
+	t.Helper()
+	var properties map[string]map[string]interface{}
+	if err := json.Unmarshal(raw, &properties); err != nil {
+		t.Fatalf("Expected JSON properties, got %s", raw)
+	}
+	return properties
+}
+
+func TestActivityProperties_OnlyChangedFields(t *testing.T) {
+	blockType := "stock"
+	old := &domain.Block{ID: 1, Name: "B001"}
+	new := &domain.Block{ID: 1, Name: "B002", Type: &blockType}
+
+	raw, changed, err := ActivityProperties(old, new)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v", err)
+	}
+
+	properties := decodeProperties(t, raw)
+	if properties["old"]["name"] != "B001" || properties["attributes"]["name"] != "B002" {
+		t.Errorf("Expected the name change, got %s", raw)
+	}
+	if properties["attributes"]["type"] != "stock" {
+		t.Errorf("Expected the added type, got %s", raw)
+	}
+	if _, ok := properties["attributes"]["id"]; ok {
+		t.Errorf("Expected unchanged fields to be left out, got %s", raw)
+	}
+
+	if _, changed, _ := ActivityProperties(old, old); changed {
+		t.Error("Expected no change between equal subjects")
+	}
+
+	raw, _, _ = ActivityProperties(nil, new)
+	if properties := decodeProperties(t, raw); properties["old"] != nil || properties["attributes"]["name"] != "B002" {
+		t.Errorf("Expected only attributes for a created subject, got %s", raw)
+	}
+}
+
+func TestActivityRecord_CauserAndBatch(t *testing.T) {
+	repo := &mockActivityLogRepository{}
+	svc := NewActivityService(repo)
+
+	ctx := WithActivityBatch(context.Background())
+	for _, id := range []int64{1, 2} {
+		svc.Record(ctx, ActivityInput{
+			Event:       ActivityUpdated,
+			SubjectType: repository.SubjectFabric,
+			SubjectID:   id,
+			Old:         map[string]int64{"rack_id": 1},
+			New:         map[string]int64{"rack_id": 2},
+			CauserID:    9,
+		})
+	}
+	svc.Record(ctx, ActivityInput{Event: ActivityUpdated, Old: map[string]int{"a": 1}, New: map[string]int{"a": 1}})
+
+	if len(repo.created) != 2 {
+		t.Fatalf("Expected 2 activities without the unchanged update, got %d", len(repo.created))
+	}
+	first, second := repo.created[0], repo.created[1]
+	if first.BatchUUID == nil || second.BatchUUID == nil || *first.BatchUUID != *second.BatchUUID || len(*first.BatchUUID) != 36 {
+		t.Errorf("Expected both activities in one batch, got %v and %v", first.BatchUUID, second.BatchUUID)
+	}
+	if first.CauserID == nil || *first.CauserID != 9 || *first.CauserType != repository.SubjectUser {
+		t.Errorf("Expected user 9 as causer, got %+v", first)
+	}
+	if first.Description != ActivityUpdated {
+		t.Errorf("Expected the event as default description, got %s", first.Description)
+	}
+
+	var nilService *ActivityService
+	nilService.Record(ctx, ActivityInput{Event: ActivityCreated})
+}
+
+func TestMasterService_RecordsDelete(t *testing.T) {
+	activityRepo := &mockActivityLogRepository{}
+	repo := &mockMasterRepository{racks: map[int64]*domain.Rack{1: {ID: 1, Name: "R001"}}}
+	svc := NewMasterService(repo, NewActivityService(activityRepo), nil)
+
+	if err := svc.Delete(context.Background(), repository.MasterRacks, 1); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(activityRepo.created) != 1 {
+		t.Fatalf("Expected 1 activity, got %d", len(activityRepo.created))
+	}
+	activity := activityRepo.created[0]
+	if *activity.Event != ActivityDeleted || *activity.SubjectType != repository.SubjectRack || *activity.SubjectID != 1 {
+		t.Errorf("Unexpected activity %+v", activity)
+	}
+	if properties := decodeProperties(t, activity.Properties); properties["old"]["name"] != "R001" {
+		t.Errorf("Expected the deleted name, got %s", activity.Properties)
+	}
+}
+
+func TestActivityList_ClampsLimit(t *testing.T) {
+	repo := &mockActivityLogRepository{}
+	svc := NewActivityService(repo)
+
+	_, _ = svc.List(context.Background(), domain.ActivityFilter{})
+	if repo.filter.Limit != defaultActivityLimit {
+		t.Errorf("Expected default limit %d, got %d", defaultActivityLimit, repo.filter.Limit)
+	}
+	_, _ = svc.List(context.Background(), domain.ActivityFilter{Limit: 10000})
+	if repo.filter.Limit != maxActivityLimit {
+		t.Errorf("Expected limit %d, got %d", maxActivityLimit, repo.filter.Limit)
+	}
+}
@@ -1,26 +1,56 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dppi/dppierp-api/internal/domain"
 	"github.com/dppi/dppierp-api/internal/middleware"
 	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrEmailNotFound       = errors.New("email not found")
+	ErrInvalidResetToken   = errors.New("invalid token")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidOldPassword  = errors.New("invalid old password")
+)
+
+// Reasons stored with revoked refresh tokens.
+const (
+	RevokeReasonLogout          = "logout"
+	RevokeReasonReused          = "reused"
+	RevokeReasonPasswordChanged = "password_changed"
+)
+
 type AuthService struct {
 	userRepo       repository.UserRepository
+	tokenRepo      repository.RefreshTokenRepository
 	authMiddleware *middleware.AuthMiddleware
+	activity       *ActivityService
+	mailer         *EmailService
 }
 
-func NewAuthService(userRepo repository.UserRepository, authMiddleware *middleware.AuthMiddleware) *AuthService {
-	return &AuthService{
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.RefreshTokenRepository, authMiddleware *middleware.AuthMiddleware, activity *ActivityService, mailer *EmailService) *AuthService {
+	s := &AuthService{
 		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
 		authMiddleware: authMiddleware,
+		activity:       activity,
+		mailer:         mailer,
+	}
+	if mailer != nil {
+		mailer.ResolveSecrets(EmailModuleAuth, EmailActionResetPassword, s.resetPasswordSecrets)
 	}
+	return s
 }
 
 // Login authenticates a user
@@ -39,13 +69,13 @@ func (s *AuthService) Login(username, password string) (string, string, *domain.
 		return "", "", nil, errors.New("invalid credentials")
 	}
 
-	// Generate Token
-	accessToken, err := s.authMiddleware.GenerateToken(user.ID, user.Email, user.Name)
-	if err != nil {
+	// Every login starts a new refresh token family
+	stored := newRefreshToken(user.ID, newUUID())
+	if err := s.tokenRepo.Create(context.Background(), stored); err != nil {
 		return "", "", nil, err
 	}
 
-	refreshToken, err := s.authMiddleware.GenerateRefreshToken(user.ID)
+	accessToken, refreshToken, err := s.generateTokens(user, stored)
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -53,37 +83,88 @@ func (s *AuthService) Login(username, password string) (string, string, *domain.
 	return accessToken, refreshToken, user, nil
 }
 
-// RefreshToken validates the refresh token and returns a new access/refresh token pair
+// RefreshToken validates the refresh token and returns a new access/refresh
+// token pair. The refresh token is used up; presenting it again revokes its
+// whole family, since one of the two parties holding it is not the user.
 func (s *AuthService) RefreshToken(tokenString string) (string, string, error) {
-	userID, err := s.authMiddleware.ValidateRefreshToken(tokenString)
+	ctx := context.Background()
+	userID, tokenID, err := s.authMiddleware.ValidateRefreshToken(tokenString)
+	if err != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	stored, err := s.tokenRepo.FindByTokenID(ctx, tokenID)
 	if err != nil {
 		return "", "", err
 	}
+	if stored == nil || stored.UserID != userID || stored.RevokedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return "", "", s.revokeReused(ctx, stored)
+	}
 
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return "", "", err
 	}
 	if user == nil {
-		return "", "", errors.New("user not found")
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	next := newRefreshToken(user.ID, stored.FamilyID)
+	if err := s.tokenRepo.Rotate(ctx, stored, next); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenUsed) {
+			return "", "", s.revokeReused(ctx, stored)
+		}
+		return "", "", err
+	}
+
+	return s.generateTokens(user, next)
+}
+
+// revokeReused revokes the family of a refresh token that was presented after
+// it had been used.
+func (s *AuthService) revokeReused(ctx context.Context, token *domain.RefreshToken) error {
+	log.Warn().Int64("user_id", token.UserID).Str("family_id", token.FamilyID).Msg("Refresh token reused, revoking its session")
+	if err := s.tokenRepo.RevokeFamily(ctx, token.FamilyID, RevokeReasonReused); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func newRefreshToken(userID int64, familyID string) *domain.RefreshToken {
+	now := time.Now()
+	return &domain.RefreshToken{
+		UserID:    userID,
+		TokenID:   newUUID(),
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(middleware.RefreshTokenTTL),
+		CreatedAt: now,
 	}
+}
 
-	newAccessToken, err := s.authMiddleware.GenerateToken(user.ID, user.Email, user.Name)
+func (s *AuthService) generateTokens(user *domain.User, stored *domain.RefreshToken) (string, string, error) {
+	accessToken, err := s.authMiddleware.GenerateToken(user.ID, user.Email, user.Name, stored.FamilyID)
 	if err != nil {
 		return "", "", err
 	}
 
-	newRefreshToken, err := s.authMiddleware.GenerateRefreshToken(user.ID)
+	refreshToken, err := s.authMiddleware.GenerateRefreshToken(user.ID, stored.TokenID)
 	if err != nil {
 		return "", "", err
 	}
 
-	return newAccessToken, newRefreshToken, nil
+	return accessToken, refreshToken, nil
 }
 
-// Logout performs any necessary server-side logout operations
-func (s *AuthService) Logout() error {
-	return nil
+// Logout revokes the refresh tokens of the session. Access tokens issued for
+// it stay valid until they expire.
+func (s *AuthService) Logout(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return s.tokenRepo.RevokeFamily(context.Background(), sessionID, RevokeReasonLogout)
 }
 
 // ForgotPasswordRequest handles the request to send a password reset link
@@ -93,7 +174,7 @@ func (s *AuthService) ForgotPasswordRequest(email string) error {
 		return err
 	}
 	if user == nil {
-		return errors.New("email not found")
+		return ErrEmailNotFound
 	}
 
 	// Generate a random token
@@ -108,9 +189,47 @@ func (s *AuthService) ForgotPasswordRequest(email string) error {
 		return err
 	}
 
+	// Send the token by email
+	if s.mailer != nil {
+		_, err := s.mailer.Queue(context.Background(), EmailInput{
+			Module:  EmailModuleAuth,
+			Action:  EmailActionResetPassword,
+			DocType: repository.SubjectUser,
+			DocID:   user.ID,
+			To:      []string{user.Email},
+			Data: map[string]interface{}{
+				"name":  user.Name,
+				"email": user.Email,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue reset password email: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// resetPasswordSecrets looks the reset token up when the reset password email
+// is sent, so it is never stored in the email log.
+func (s *AuthService) resetPasswordSecrets(ctx context.Context, email *domain.EmailLog) (map[string]string, error) {
+	user, err := s.userRepo.FindByID(email.DocID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrEmailSecretGone
+	}
+	token, err := s.userRepo.GetResetToken(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, ErrEmailSecretGone
+	}
+	return map[string]string{"token": token}, nil
+}
+
 // ResetPassword resets the user's password using the token
 func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 	storedToken, err := s.userRepo.GetResetToken(email)
@@ -118,7 +237,7 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 		return err
 	}
 	if storedToken == "" || storedToken != token {
-		return errors.New("invalid token")
+		return ErrInvalidResetToken
 	}
 
 	user, err := s.userRepo.FindByEmail(email)
@@ -126,7 +245,7 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 		return err
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	hashedPassword, err := HashPassword(newPassword)
@@ -137,6 +256,10 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
 		return err
 	}
+	s.recordPassword(user.ID, "password reset")
+	if err := s.revokeSessions(user.ID); err != nil {
+		return err
+	}
 
 	// Delete used token
 	return s.userRepo.DeleteResetToken(email)
@@ -149,12 +272,12 @@ func (s *AuthService) ChangePassword(userID int64, oldPassword, newPassword stri
 		return err
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	// Verify old password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
-		return errors.New("invalid old password")
+		return ErrInvalidOldPassword
 	}
 
 	hashedPassword, err := HashPassword(newPassword)
@@ -162,7 +285,33 @@ func (s *AuthService) ChangePassword(userID int64, oldPassword, newPassword stri
 		return err
 	}
 
-	return s.userRepo.UpdatePassword(user.ID, hashedPassword)
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+	s.recordPassword(user.ID, "password changed")
+	return s.revokeSessions(user.ID)
+}
+
+// revokeSessions signs the user out of every session after a password change.
+func (s *AuthService) revokeSessions(userID int64) error {
+	if err := s.tokenRepo.RevokeUser(context.Background(), userID, RevokeReasonPasswordChanged); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// recordPassword logs a password change by the user themselves. The hash
+// itself never goes into the audit trail.
+func (s *AuthService) recordPassword(userID int64, description string) {
+	s.activity.Record(context.Background(), ActivityInput{
+		LogName:     "auth",
+		Event:       ActivityUpdated,
+		Description: description,
+		SubjectType: repository.SubjectUser,
+		SubjectID:   userID,
+		New:         map[string]string{"password": "[hidden]"},
+		CauserID:    userID,
+	})
 }
 
 // HashPassword is a utility to hash passwords
@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/dppi/dppierp-api/internal/domain"
 	"github.com/dppi/dppierp-api/internal/middleware"
+	"github.com/dppi/dppierp-api/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -21,6 +24,85 @@ func (m *mockUserRepository) FindByEmail(email string) (*domain.User, error) {
 	return nil, nil // Not found
 }
 
+// FindByUsername looks users up by the same key as FindByEmail so the
+// login tests can keep using email-shaped identifiers.
+func (m *mockUserRepository) FindByUsername(username string) (*domain.User, error) {
+	return m.FindByEmail(username)
+}
+
+func (m *mockUserRepository) FindByID(id int64) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepository) UpdatePassword(userID int64, newHash string) error { return nil }
+func (m *mockUserRepository) StoreResetToken(email, token string) error         { return nil }
+func (m *mockUserRepository) GetResetToken(email string) (string, error)        { return "", nil }
+func (m *mockUserRepository) DeleteResetToken(email string) error               { return nil }
+func (m *mockUserRepository) HasPermission(userID int64, permission string) (bool, error) {
+	return false, nil
+}
+func (m *mockUserRepository) FindAccess(userID int64) (*domain.UserAccess, error) {
+	return &domain.UserAccess{Roles: []string{}, Permissions: []string{}}, nil
+}
+
+// mockRefreshTokenRepository keeps the refresh tokens in memory by token id.
+type mockRefreshTokenRepository struct {
+	tokens map[string]*domain.RefreshToken
+}
+
+func newMockRefreshTokenRepository() *mockRefreshTokenRepository {
+	return &mockRefreshTokenRepository{tokens: map[string]*domain.RefreshToken{}}
+}
+
+func (m *mockRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	stored := *token
+	m.tokens[token.TokenID] = &stored
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) FindByTokenID(ctx context.Context, tokenID string) (*domain.RefreshToken, error) {
+	if token, ok := m.tokens[tokenID]; ok {
+		found := *token
+		return &found, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRefreshTokenRepository) Rotate(ctx context.Context, used, next *domain.RefreshToken) error {
+	stored := m.tokens[used.TokenID]
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		return repository.ErrRefreshTokenUsed
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	return m.Create(ctx, next)
+}
+
+func (m *mockRefreshTokenRepository) revoke(match func(*domain.RefreshToken) bool, reason string) {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			token.RevokedReason = &reason
+		}
+	}
+}
+
+func (m *mockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	m.revoke(func(token *domain.RefreshToken) bool { return token.FamilyID == familyID }, reason)
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeUser(ctx context.Context, userID int64, reason string) error {
+	m.revoke(func(token *domain.RefreshToken) bool { return token.UserID == userID }, reason)
+	return nil
+}
+
 func TestAuthService_Login(t *testing.T) {
 	// Setup
 	password := "password123"
@@ -40,7 +122,7 @@ func TestAuthService_Login(t *testing.T) {
 	}
 
 	authMiddleware := middleware.NewAuthMiddleware("secret-key-secret-key-secret-key-32")
-	authService := NewAuthService(mockRepo, authMiddleware)
+	authService := NewAuthService(mockRepo, newMockRefreshTokenRepository(), authMiddleware, nil, nil)
 
 	// Test Case 1: Success
 	token, refreshToken, user, err := authService.Login("test@example.com", password)
@@ -75,3 +157,71 @@ func TestAuthService_Login(t *testing.T) {
 		t.Errorf("Expected 'invalid credentials', got '%v'", err.Error())
 	}
 }
+
+func TestAuthService_RefreshTokenRotation(t *testing.T) {
+	password := "password123"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	mockRepo := &mockUserRepository{
+		users: map[string]*domain.User{
+			"test@example.com": {ID: 1, Name: "Test User", Email: "test@example.com", Password: string(hashedPassword)},
+		},
+	}
+	tokens := newMockRefreshTokenRepository()
+	authMiddleware := middleware.NewAuthMiddleware("secret-key-secret-key-secret-key-32")
+	authService := NewAuthService(mockRepo, tokens, authMiddleware, nil, nil)
+
+	accessToken, first, _, err := authService.Login("test@example.com", password)
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	claims, _ := authMiddleware.ValidateToken(accessToken)
+
+	_, second, err := authService.RefreshToken(first)
+	if err != nil {
+		t.Fatalf("RefreshToken returned error: %v", err)
+	}
+	if second == first {
+		t.Error("Expected the refresh token to rotate")
+	}
+
+	// Presenting the used token again revokes the session, including the
+	// token it was rotated to.
+	if _, _, err := authService.RefreshToken(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, _, err := authService.RefreshToken(second); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected the rotated token to be revoked, got %v", err)
+	}
+	for _, token := range tokens.tokens {
+		if token.FamilyID != claims.SessionID || token.RevokedReason == nil || *token.RevokedReason != RevokeReasonReused {
+			t.Errorf("Expected every token of session %s revoked as reused, got %+v", claims.SessionID, token)
+		}
+	}
+
+	// Logout revokes only the session it was called for.
+	accessToken, loggedOut, _, _ := authService.Login("test@example.com", password)
+	_, other, _, _ := authService.Login("test@example.com", password)
+	claims, _ = authMiddleware.ValidateToken(accessToken)
+	if err := authService.Logout(claims.SessionID); err != nil {
+		t.Fatalf("Logout returned error: %v", err)
+	}
+	if _, _, err := authService.RefreshToken(loggedOut); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected the logged out token to be rejected, got %v", err)
+	}
+	_, other, err = authService.RefreshToken(other)
+	if err != nil {
+		t.Fatalf("Expected the other session to survive the logout, got %v", err)
+	}
+
+	// A password change signs the user out everywhere.
+	if err := authService.ChangePassword(1, password, "password456"); err != nil {
+		t.Fatalf("ChangePassword returned error: %v", err)
+	}
+	if _, _, err := authService.RefreshToken(other); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected the token to be revoked by the password change, got %v", err)
+	}
+
+	if _, _, err := authService.RefreshToken("not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var (
+	ErrBuyerNotFound    = errors.New("buyer is not found")
+	ErrPartnerCodeTaken = errors.New("the code has already been taken")
+	ErrPartnerInUse     = errors.New("it is still used by orders or fabric rolls")
+)
+
+type BuyerService struct {
+	repo     repository.BuyerRepository
+	activity *ActivityService
+}
+
+func NewBuyerService(repo repository.BuyerRepository, activity *ActivityService) *BuyerService {
+	return &BuyerService{repo: repo, activity: activity}
+}
+
+func (s *BuyerService) List(ctx context.Context, filter repository.PartnerFilter) ([]domain.Buyer, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	return s.repo.List(ctx, filter)
+}
+
+func (s *BuyerService) Get(ctx context.Context, id int64) (*domain.Buyer, error) {
+	buyer, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == nil {
+		return nil, ErrBuyerNotFound
+	}
+	return buyer, nil
+}
+
+func (s *BuyerService) Create(ctx context.Context, buyer *domain.Buyer) error {
+	buyer.Code = strings.TrimSpace(buyer.Code)
+	buyer.Name = strings.TrimSpace(buyer.Name)
+	if err := s.checkCode(ctx, buyer.Code, 0); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, buyer); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityCreated, buyer.ID, nil, buyer)
+	return nil
+}
+
+// Update replaces the buyer details. The status only changes through ToggleStatus.
+func (s *BuyerService) Update(ctx context.Context, id int64, input *domain.Buyer) (*domain.Buyer, error) {
+	buyer, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ID = buyer.ID
+	input.Code = strings.TrimSpace(input.Code)
+	input.Name = strings.TrimSpace(input.Name)
+	input.Status = buyer.Status
+	input.CreatedAt = buyer.CreatedAt
+	if err := s.checkCode(ctx, input.Code, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, input); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, id, buyer, input)
+	return input, nil
+}
+
+// ToggleStatus switches the buyer between active (1) and inactive (0).
+func (s *BuyerService) ToggleStatus(ctx context.Context, id int64) (*domain.Buyer, error) {
+	buyer, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *buyer
+	buyer.Status = toggledStatus(buyer.Status)
+	if err := s.repo.SetStatus(ctx, id, buyer.Status); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, id, &old, buyer)
+	return buyer, nil
+}
+
+// Delete soft-deletes a buyer without orders.
+func (s *BuyerService) Delete(ctx context.Context, id int64) error {
+	buyer, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	orders, err := s.repo.CountOrders(ctx, id)
+	if err != nil {
+		return err
+	}
+	if orders > 0 {
+		return fmt.Errorf("%w (%d orders)", ErrPartnerInUse, orders)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityDeleted, id, buyer, nil)
+	return nil
+}
+
+func (s *BuyerService) record(ctx context.Context, event string, id int64, old, new *domain.Buyer) {
+	s.activity.Record(ctx, ActivityInput{LogName: "partner", Event: event, SubjectType: repository.SubjectBuyer, SubjectID: id, Old: old, New: new})
+}
+
+func (s *BuyerService) checkCode(ctx context.Context, code string, excludeID int64) error {
+	exists, err := s.repo.CodeExists(ctx, code, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrPartnerCodeTaken
+	}
+	return nil
+}
+
+func toggledStatus(status int) int {
+	if status == 1 {
+		return 0
+	}
+	return 1
+}
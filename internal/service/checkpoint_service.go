@@ -2,21 +2,67 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/dppi/dppierp-api/internal/authctx"
 	"github.com/dppi/dppierp-api/internal/domain"
 	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/rs/zerolog/log"
 )
 
+// ErrStageForbidden is matched by a StagePermissionError.
+var ErrStageForbidden = errors.New("not allowed to move rolls to this stage")
+
+var (
+	ErrInvalidStage  = errors.New("invalid stage")
+	ErrDestroyStage  = fmt.Errorf("rolls can only be moved to %s through an approved destroy request", domain.StageDestroy)
+	ErrNoMoveEntries = errors.New("entries field is required")
+	ErrRackNotFound  = errors.New("rack not found")
+	// Returned by the repository while moving rolls
+	ErrFabricNotFound = repository.ErrFabricNotFound
+	ErrRackEmpty      = repository.ErrRackEmpty
+)
+
+// StagePermissionError is returned when the user lacks the permission to move
+// rolls into a stage.
+type StagePermissionError struct {
+	Stage      string
+	Permission string
+}
+
+func (e *StagePermissionError) Error() string {
+	return fmt.Sprintf("moving rolls to %s needs the %s permission", e.Stage, e.Permission)
+}
+
+func (e *StagePermissionError) Is(target error) bool {
+	return target == ErrStageForbidden
+}
+
+// StagePermission names the permission to move rolls into a stage, such as
+// fabric.move.qc-fabric for qc_fabric.
+func StagePermission(stage string) string {
+	return "fabric.move." + strings.ReplaceAll(stage, "_", "-")
+}
+
 type CheckpointService struct {
 	fabricRepo *repository.FabricRepository
 	rackRepo   *repository.RackRepository
+	activity   *ActivityService
+	notifier   *NotificationService
+	stream     *MovementStream
+	access     *AccessService
 }
 
-func NewCheckpointService(fabricRepo *repository.FabricRepository, rackRepo *repository.RackRepository) *CheckpointService {
+func NewCheckpointService(fabricRepo *repository.FabricRepository, rackRepo *repository.RackRepository, activity *ActivityService, notifier *NotificationService, stream *MovementStream, access *AccessService) *CheckpointService {
 	return &CheckpointService{
 		fabricRepo: fabricRepo,
 		rackRepo:   rackRepo,
+		activity:   activity,
+		notifier:   notifier,
+		stream:     stream,
+		access:     access,
 	}
 }
 
@@ -39,7 +85,7 @@ func (s *CheckpointService) ScanQR(ctx context.Context, code string) (*ScanQRRes
 		return nil, fmt.Errorf("error finding fabric: %w", err)
 	}
 	if fabric == nil {
-		return nil, fmt.Errorf("QR code is not found")
+		return nil, ErrFabricNotFound
 	}
 
 	response := &ScanQRResponse{
@@ -74,15 +120,30 @@ type MoveRequest struct {
 	RelaxationBlockID *int64      `json:"relaxation_block_id,omitempty"`
 	RelaxationRackID  *int64      `json:"relaxation_rack_id,omitempty"`
 	Entries           []MoveEntry `json:"entries" binding:"required,dive"`
+	UserID            int64       `json:"-"`
 }
 
 func (s *CheckpointService) MoveStage(ctx context.Context, req *MoveRequest) error {
 	if !domain.IsValidStage(req.Stage) {
-		return fmt.Errorf("invalid stage: %s", req.Stage)
+		return fmt.Errorf("%w: %s", ErrInvalidStage, req.Stage)
+	}
+
+	// Destroying a roll goes through an approved destroy request instead.
+	if req.Stage == string(domain.StageDestroy) {
+		return ErrDestroyStage
 	}
 
 	if len(req.Entries) == 0 {
-		return fmt.Errorf("entries field is required")
+		return ErrNoMoveEntries
+	}
+
+	permission := StagePermission(req.Stage)
+	allowed, err := s.access.HasPermission(ctx, req.UserID, permission)
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+	if !allowed {
+		return &StagePermissionError{Stage: req.Stage, Permission: permission}
 	}
 
 	repoReq := &repository.MoveRequestData{
@@ -92,6 +153,7 @@ func (s *CheckpointService) MoveStage(ctx context.Context, req *MoveRequest) err
 		RelaxationBlockID: req.RelaxationBlockID,
 		RelaxationRackID:  req.RelaxationRackID,
 		Entries:           make([]repository.MoveEntryData, len(req.Entries)),
+		UserID:            req.UserID,
 	}
 
 	for i, entry := range req.Entries {
@@ -103,16 +165,170 @@ func (s *CheckpointService) MoveStage(ctx context.Context, req *MoveRequest) err
 		}
 	}
 
+	before, err := s.fabricSnapshots(ctx, req.Entries)
+	if err != nil {
+		return err
+	}
+
 	switch req.Stage {
 	case string(domain.StageInventory):
-		return s.fabricRepo.UpdateBlockRack(ctx, repoReq)
+		err = s.fabricRepo.UpdateBlockRack(ctx, repoReq)
 	case string(domain.StageRelaxation):
-		return s.fabricRepo.UpdateRelaxationBlockRack(ctx, repoReq)
+		err = s.fabricRepo.UpdateRelaxationBlockRack(ctx, repoReq)
 	case string(domain.StageQCFabric):
-		return s.fabricRepo.UpdateStageWithQC(ctx, repoReq)
+		err = s.fabricRepo.UpdateStageWithQC(ctx, repoReq)
 	default:
-		return s.fabricRepo.UpdateStage(ctx, repoReq)
+		err = s.fabricRepo.UpdateStage(ctx, repoReq)
 	}
+	if err != nil {
+		return err
+	}
+
+	after, err := s.fabricSnapshots(ctx, req.Entries)
+	if err != nil {
+		return err
+	}
+
+	eventType := MovementFabricMoved
+	if req.Stage == string(domain.StageQCFabric) {
+		eventType = MovementFabricQC
+	}
+
+	// All rolls of one move share a batch in the audit trail
+	ctx = WithActivityBatch(ctx)
+	var events []MovementEvent
+	for _, entry := range req.Entries {
+		old, new := before[entry.Code], after[entry.Code]
+		if old == nil || new == nil {
+			continue
+		}
+		events = append(events, new.event(eventType, entry.Code, old.Stage, req.UserID))
+		s.activity.Record(ctx, ActivityInput{
+			LogName:     "fabric",
+			Event:       ActivityUpdated,
+			Description: "moved to " + req.Stage,
+			SubjectType: repository.SubjectFabric,
+			SubjectID:   old.ID,
+			Old:         old,
+			New:         new,
+		})
+	}
+
+	s.stream.Publish(events...)
+	s.notifyMove(ctx, req, before, after)
+	return nil
+}
+
+// notifyMove tells the subscribed roles about rolls leaving relaxation and
+// rolls failing fabric QC in a move.
+func (s *CheckpointService) notifyMove(ctx context.Context, req *MoveRequest, before, after map[string]*fabricSnapshot) {
+	var relaxed, failed []string
+	for _, entry := range req.Entries {
+		old, new := before[entry.Code], after[entry.Code]
+		if old == nil || new == nil {
+			continue
+		}
+		if old.Stage == string(domain.StageRelaxation) && new.Stage != string(domain.StageRelaxation) {
+			relaxed = append(relaxed, entry.Code)
+		}
+		if req.Stage == string(domain.StageQCFabric) && new.QCResult != nil && strings.EqualFold(*new.QCResult, "fail") {
+			failed = append(failed, entry.Code)
+		}
+	}
+
+	if len(relaxed) > 0 {
+		s.notifier.Publish(ctx, NotificationMessage{
+			Module:  NotificationRelaxationFinished,
+			Message: fmt.Sprintf("%d roll(s) finished relaxation and moved to %s.", len(relaxed), req.Stage),
+			Data: map[string]interface{}{
+				"codes": strings.Join(relaxed, ", "),
+				"count": len(relaxed),
+				"stage": req.Stage,
+			},
+		})
+	}
+	if len(failed) > 0 {
+		s.notifier.Publish(ctx, NotificationMessage{
+			Module:  NotificationQCFailed,
+			Message: fmt.Sprintf("%d roll(s) failed fabric QC.", len(failed)),
+			Data: map[string]interface{}{
+				"source": "fabric",
+				"codes":  strings.Join(failed, ", "),
+				"count":  len(failed),
+			},
+		})
+	}
+}
+
+// fabricSnapshot holds the fields of a roll a move can change.
+type fabricSnapshot struct {
+	ID                int64   `json:"-"`
+	BuyerID           *int64  `json:"-"`
+	Buyer             string  `json:"-"`
+	Stage             string  `json:"stage"`
+	Yard              string  `json:"yard"`
+	BlockID           *int64  `json:"block_id"`
+	RackID            *int64  `json:"rack_id"`
+	RelaxationBlockID *int64  `json:"relaxation_block_id"`
+	RelaxationRackID  *int64  `json:"relaxation_rack_id"`
+	FinishDate        *string `json:"finish_date"`
+	QCResult          *string `json:"qc_result"`
+}
+
+func (s *CheckpointService) fabricSnapshots(ctx context.Context, entries []MoveEntry) (map[string]*fabricSnapshot, error) {
+	snapshots := map[string]*fabricSnapshot{}
+	for _, entry := range entries {
+		fabric, err := s.fabricRepo.FindByCodeWithInventory(ctx, entry.Code)
+		if err != nil {
+			return nil, fmt.Errorf("error finding fabric %s: %w", entry.Code, err)
+		}
+		if fabric == nil {
+			continue
+		}
+
+		snapshot := &fabricSnapshot{
+			ID:                fabric.ID,
+			BuyerID:           fabric.BuyerID,
+			Buyer:             fabric.Buyer,
+			Yard:              fabric.Yard,
+			BlockID:           fabric.BlockID,
+			RackID:            fabric.RackID,
+			RelaxationBlockID: fabric.RelaxationBlockID,
+			RelaxationRackID:  fabric.RelaxationRackID,
+			FinishDate:        fabric.FinishDate,
+			QCResult:          fabric.QCResult,
+		}
+		if fabric.Inventory != nil {
+			snapshot.Stage = fabric.Inventory.Stage
+		}
+		snapshots[entry.Code] = snapshot
+	}
+	return snapshots, nil
+}
+
+// event describes the roll after a committed change as a movement event.
+func (f *fabricSnapshot) event(eventType, code, fromStage string, userID int64) MovementEvent {
+	e := MovementEvent{
+		Type:              eventType,
+		Code:              code,
+		FabricID:          f.ID,
+		FromStage:         fromStage,
+		Stage:             f.Stage,
+		BlockID:           f.BlockID,
+		RackID:            f.RackID,
+		RelaxationBlockID: f.RelaxationBlockID,
+		RelaxationRackID:  f.RelaxationRackID,
+		BuyerID:           f.BuyerID,
+		QCResult:          f.QCResult,
+		UserID:            userID,
+	}
+	if f.Buyer != "-" {
+		e.Buyer = f.Buyer
+	}
+	if eventType != MovementFabricQC {
+		e.QCResult = nil
+	}
+	return e
 }
 
 type ScanRackResponse struct {
@@ -154,7 +370,7 @@ func (s *CheckpointService) ScanRack(ctx context.Context, code string) (*ScanRac
 		return nil, fmt.Errorf("error finding rack: %w", err)
 	}
 	if rack == nil {
-		return nil, fmt.Errorf("rack not found")
+		return nil, ErrRackNotFound
 	}
 
 	fabrics, err := s.fabricRepo.GetFabricsByRackID(ctx, rack.ID)
@@ -220,5 +436,62 @@ type RelocationRequest struct {
 }
 
 func (s *CheckpointService) Relocate(ctx context.Context, req *RelocationRequest) error {
-	return s.fabricRepo.RelocateFabricsWithLog(ctx, req.CurrentRackID, req.NewRackID)
+	fabricIDs, err := s.fabricRepo.RelocateFabricsWithLog(ctx, req.CurrentRackID, req.NewRackID, authctx.UserID(ctx))
+	if err != nil {
+		return err
+	}
+
+	ctx = WithActivityBatch(ctx)
+	for _, id := range fabricIDs {
+		s.activity.Record(ctx, ActivityInput{
+			LogName:     "fabric",
+			Event:       ActivityUpdated,
+			Description: "relocated",
+			SubjectType: repository.SubjectFabric,
+			SubjectID:   id,
+			Old:         map[string]int64{"rack_id": req.CurrentRackID},
+			New:         map[string]int64{"rack_id": req.NewRackID},
+		})
+	}
+
+	s.publishRelocation(ctx, req, fabricIDs)
+	return nil
+}
+
+// publishRelocation streams the relocated rolls as they are after the commit.
+func (s *CheckpointService) publishRelocation(ctx context.Context, req *RelocationRequest, fabricIDs []int64) {
+	if s.stream == nil {
+		return
+	}
+
+	relocated := map[int64]bool{}
+	for _, id := range fabricIDs {
+		relocated[id] = true
+	}
+	fabrics, err := s.fabricRepo.GetFabricsByRackID(ctx, req.NewRackID)
+	if err != nil {
+		log.Error().Err(err).Int64("rack_id", req.NewRackID).Msg("Failed to load relocated fabrics")
+		return
+	}
+
+	var entries []MoveEntry
+	for _, f := range fabrics {
+		if relocated[f.ID] {
+			entries = append(entries, MoveEntry{Code: f.Code})
+		}
+	}
+	snapshots, err := s.fabricSnapshots(ctx, entries)
+	if err != nil {
+		log.Error().Err(err).Int64("rack_id", req.NewRackID).Msg("Failed to load relocated fabrics")
+		return
+	}
+
+	userID := authctx.UserID(ctx)
+	var events []MovementEvent
+	for _, entry := range entries {
+		if snapshot := snapshots[entry.Code]; snapshot != nil {
+			events = append(events, snapshot.event(MovementFabricRelocated, entry.Code, snapshot.Stage, userID))
+		}
+	}
+	s.stream.Publish(events...)
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/dppi/dppierp-api/internal/domain"
@@ -133,3 +134,25 @@ func TestIsValidStage(t *testing.T) {
 		}
 	}
 }
+
+func TestMoveStage_RequiresStagePermission(t *testing.T) {
+	users := &permissionUserRepository{permissions: map[int64][]string{2: {"fabric.move.inventory"}}}
+	svc := NewCheckpointService(nil, nil, nil, nil, nil, NewAccessService(users, nil, 0))
+
+	err := svc.MoveStage(context.Background(), &MoveRequest{
+		Stage:   string(domain.StageQCFabric),
+		Entries: []MoveEntry{{Code: "F24120001", QCResult: "pass"}},
+		UserID:  2,
+	})
+	var forbidden *StagePermissionError
+	if !errors.As(err, &forbidden) || !errors.Is(err, ErrStageForbidden) {
+		t.Fatalf("Expected a StagePermissionError, got %v", err)
+	}
+	if forbidden.Permission != "fabric.move.qc-fabric" {
+		t.Errorf("Expected fabric.move.qc-fabric to be missing, got %s", forbidden.Permission)
+	}
+
+	if got := StagePermission(string(domain.StageReturnSupplier)); got != "fabric.move.return-supplier" {
+		t.Errorf("Expected fabric.move.return-supplier, got %s", got)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+type DefectReportService struct {
+	repo repository.DefectReportRepository
+}
+
+func NewDefectReportService(repo repository.DefectReportRepository) *DefectReportService {
+	return &DefectReportService{repo: repo}
+}
+
+// Pareto ranks the defect types found, with the share of all defects each type
+// and the types before it account for.
+func (s *DefectReportService) Pareto(ctx context.Context, filter domain.DefectFilter) ([]domain.DefectParetoItem, error) {
+	filter.Style = strings.TrimSpace(filter.Style)
+	items, err := s.repo.CountByDefectType(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return BuildPareto(items), nil
+}
+
+// Processes drills a defect type down to the order processes that caused it
+// and the operators working on them.
+func (s *DefectReportService) Processes(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) ([]domain.DefectProcessStat, error) {
+	filter.Style = strings.TrimSpace(filter.Style)
+	stats, err := s.repo.CountByProcess(ctx, defectTypeID, filter)
+	if err != nil {
+		return nil, err
+	}
+	operators, err := s.repo.CountByOperator(ctx, defectTypeID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats == nil {
+		stats = []domain.DefectProcessStat{}
+	}
+	for i := range stats {
+		stats[i].Operators = operators[stats[i].Key()]
+		if stats[i].Operators == nil {
+			stats[i].Operators = []domain.DefectOperator{}
+		}
+	}
+	return stats, nil
+}
+
+// BuildPareto fills in the percentages of defect counts sorted from most to
+// least frequent.
+func BuildPareto(items []domain.DefectParetoItem) []domain.DefectParetoItem {
+	total := 0
+	for _, item := range items {
+		total += item.Count
+	}
+
+	result := make([]domain.DefectParetoItem, 0, len(items))
+	cumulative := 0
+	for _, item := range items {
+		cumulative += item.Count
+		item.Percentage = round2(float64(item.Count) * 100 / float64(total))
+		item.CumulativePercentage = round2(float64(cumulative) * 100 / float64(total))
+		result = append(result, item)
+	}
+	return result
+}
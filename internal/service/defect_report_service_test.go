@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// Mock DefectReportRepository for testing
+type mockDefectReportRepository struct {
+	processes []domain.DefectProcessStat
+	operators map[domain.DefectProcessKey][]domain.DefectOperator
+}
+
+func (m *mockDefectReportRepository) CountByDefectType(ctx context.Context, filter domain.DefectFilter) ([]domain.DefectParetoItem, error) {
+	return nil, nil
+}
+
+func (m *mockDefectReportRepository) CountByProcess(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) ([]domain.DefectProcessStat, error) {
+	return m.processes, nil
+}
+
+func (m *mockDefectReportRepository) CountByOperator(ctx context.Context, defectTypeID int64, filter domain.DefectFilter) (map[domain.DefectProcessKey][]domain.DefectOperator, error) {
+	return m.operators, nil
+}
+
+func TestBuildPareto(t *testing.T) {
+	items := BuildPareto([]domain.DefectParetoItem{
+		{DefectTypeID: 1, Name: "Broken stitch", Count: 6},
+		{DefectTypeID: 2, Name: "Stain", Count: 3},
+		{DefectTypeID: 3, Name: "Open seam", Count: 1},
+	})
+
+	expected := []struct{ pct, cumulative float64 }{{60, 60}, {30, 90}, {10, 100}}
+	for i, e := range expected {
+		if items[i].Percentage != e.pct || items[i].CumulativePercentage != e.cumulative {
+			t.Errorf("Item %d: expected %v%% / %v%%, got %v%% / %v%%", i, e.pct, e.cumulative, items[i].Percentage, items[i].CumulativePercentage)
+		}
+	}
+
+	if empty := BuildPareto(nil); len(empty) != 0 {
+		t.Errorf("Expected no items, got %d", len(empty))
+	}
+}
+
+func TestDefectProcesses_AttachesOperators(t *testing.T) {
+	// Sleeve was recorded with a process but no order process
+	sleeveID := int64(7)
+	repo := &mockDefectReportRepository{
+		processes: []domain.DefectProcessStat{
+			{OrderProcessID: 11, Name: "Hem", Count: 4},
+			{OrderProcessID: 12, Name: "Collar", Count: 1},
+			{ProcessID: &sleeveID, Name: "Sleeve", Count: 2},
+		},
+		operators: map[domain.DefectProcessKey][]domain.DefectOperator{
+			{OrderProcessID: 11}: {{ManPowerID: 5, Nickname: "Ani", Count: 4}},
+			{ProcessID: 7}:       {{ManPowerID: 6, Nickname: "Budi", Count: 2}},
+		},
+	}
+	svc := NewDefectReportService(repo)
+
+	stats, err := svc.Processes(context.Background(), 1, domain.DefectFilter{})
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(stats[0].Operators) != 1 || stats[0].Operators[0].ManPowerID != 5 {
+		t.Errorf("Expected operator 5 on hem, got %+v", stats[0].Operators)
+	}
+	if stats[1].Operators == nil || len(stats[1].Operators) != 0 {
+		t.Errorf("Expected an empty operator list on collar, got %+v", stats[1].Operators)
+	}
+	if len(stats[2].Operators) != 1 || stats[2].Operators[0].ManPowerID != 6 {
+		t.Errorf("Expected operator 6 on sleeve, got %+v", stats[2].Operators)
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/storage"
+)
+
+// PermissionApproveDestroy allows a user to approve or reject destroy requests.
+const PermissionApproveDestroy = "fabric.destroy.approve"
+
+var (
+	ErrDestroyRequestNotFound   = errors.New("destroy request is not found")
+	ErrDestroyRequestNotPending = errors.New("destroy request has already been reviewed")
+	ErrDestroyForbidden         = errors.New("you are not allowed to review destroy requests")
+	ErrDestroySelfApproval      = errors.New("you cannot review your own destroy request")
+	ErrFabricDestroyed          = errors.New("roll is already destroyed")
+	ErrDestroyRequestPending    = errors.New("roll already has a pending destroy request")
+)
+
+type DestroyService struct {
+	destroyRepo repository.DestroyRepository
+	fabricRepo  *repository.FabricRepository
+	userRepo    repository.UserRepository
+	storage     *storage.LocalStorage
+	activity    *ActivityService
+	notifier    *NotificationService
+}
+
+func NewDestroyService(destroyRepo repository.DestroyRepository, fabricRepo *repository.FabricRepository, userRepo repository.UserRepository, store *storage.LocalStorage, activity *ActivityService, notifier *NotificationService) *DestroyService {
+	return &DestroyService{
+		destroyRepo: destroyRepo,
+		fabricRepo:  fabricRepo,
+		userRepo:    userRepo,
+		storage:     store,
+		activity:    activity,
+		notifier:    notifier,
+	}
+}
+
+type CreateDestroyRequest struct {
+	Code   string
+	Reason string
+	Photos []*multipart.FileHeader
+}
+
+// Create raises a destroy request for a roll. The roll stays on its current
+// stage until the request is approved.
+func (s *DestroyService) Create(ctx context.Context, userID int64, req *CreateDestroyRequest) (*domain.FabricDestroyRequest, error) {
+	fabric, err := s.fabricRepo.FindByCodeWithInventory(ctx, req.Code)
+	if err != nil {
+		return nil, fmt.Errorf("error finding fabric: %w", err)
+	}
+	if fabric == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFabricNotFound, req.Code)
+	}
+	if fabric.Inventory != nil && fabric.Inventory.Stage == string(domain.StageDestroy) {
+		return nil, fmt.Errorf("%w: %s", ErrFabricDestroyed, req.Code)
+	}
+
+	pending, err := s.destroyRepo.HasPending(ctx, fabric.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, fmt.Errorf("%w: %s", ErrDestroyRequestPending, req.Code)
+	}
+
+	paths, err := s.savePhotos(req.Photos)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.destroyRepo.Create(ctx, &domain.FabricDestroyRequest{
+		FabricID:    fabric.ID,
+		Reason:      req.Reason,
+		RequestedBy: userID,
+		Photos:      paths,
+	})
+	if err != nil {
+		s.deletePhotos(paths)
+		if errors.Is(err, repository.ErrDestroyRequestPending) {
+			return nil, fmt.Errorf("%w: %s", ErrDestroyRequestPending, req.Code)
+		}
+		return nil, err
+	}
+
+	created, err := s.destroyRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityCreated, id, nil, created)
+	s.notifier.Publish(ctx, NotificationMessage{
+		Module:  NotificationDestroyApproval,
+		Message: fmt.Sprintf("Destroy request for roll %s is waiting for approval.", created.FabricCode),
+		Data: map[string]interface{}{
+			"destroy_request_id": created.ID,
+			"fabric_code":        created.FabricCode,
+			"reason":             created.Reason,
+		},
+	})
+	return created, nil
+}
+
+func (s *DestroyService) List(ctx context.Context, status string) ([]domain.FabricDestroyRequest, error) {
+	return s.destroyRepo.List(ctx, status)
+}
+
+func (s *DestroyService) Get(ctx context.Context, id int64) (*domain.FabricDestroyRequest, error) {
+	req, err := s.destroyRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, ErrDestroyRequestNotFound
+	}
+	return req, nil
+}
+
+// Approve approves a pending request and moves the roll to the destroy stage.
+func (s *DestroyService) Approve(ctx context.Context, userID, id int64, notes string) error {
+	req, err := s.authorizeReview(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := mapDestroyRepoError(s.destroyRepo.Approve(ctx, id, userID, notes)); err != nil {
+		return err
+	}
+	s.recordReview(ctx, req)
+	return nil
+}
+
+// Reject rejects a pending request. The roll is left where it is.
+func (s *DestroyService) Reject(ctx context.Context, userID, id int64, notes string) error {
+	req, err := s.authorizeReview(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := mapDestroyRepoError(s.destroyRepo.Reject(ctx, id, userID, notes)); err != nil {
+		return err
+	}
+	s.recordReview(ctx, req)
+	return nil
+}
+
+// authorizeReview returns the pending request the user may review.
+func (s *DestroyService) authorizeReview(ctx context.Context, userID, id int64) (*domain.FabricDestroyRequest, error) {
+	req, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != domain.DestroyStatusPending {
+		return nil, ErrDestroyRequestNotPending
+	}
+
+	allowed, err := s.userRepo.HasPermission(userID, PermissionApproveDestroy)
+	if err != nil {
+		return nil, fmt.Errorf("error checking permission: %w", err)
+	}
+	if !allowed {
+		return nil, ErrDestroyForbidden
+	}
+	if req.RequestedBy == userID {
+		return nil, ErrDestroySelfApproval
+	}
+
+	return req, nil
+}
+
+// recordReview records the review of a request against its state before it.
+func (s *DestroyService) recordReview(ctx context.Context, before *domain.FabricDestroyRequest) {
+	if s.activity == nil {
+		return
+	}
+	after, err := s.destroyRepo.FindByID(ctx, before.ID)
+	if err != nil || after == nil {
+		return
+	}
+	s.record(ctx, ActivityUpdated, before.ID, before, after)
+}
+
+func (s *DestroyService) record(ctx context.Context, event string, id int64, old, new *domain.FabricDestroyRequest) {
+	s.activity.Record(ctx, ActivityInput{LogName: "fabric", Event: event, SubjectType: repository.SubjectDestroyRequest, SubjectID: id, Old: old, New: new})
+}
+
+func (s *DestroyService) savePhotos(photos []*multipart.FileHeader) ([]string, error) {
+	var paths []string
+	for _, photo := range photos {
+		f, err := photo.Open()
+		if err != nil {
+			s.deletePhotos(paths)
+			return nil, fmt.Errorf("failed to open photo %s: %w", photo.Filename, err)
+		}
+
+		path, err := s.storage.Save("Fabric/Destroy", photo.Filename, f)
+		f.Close()
+		if err != nil {
+			s.deletePhotos(paths)
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (s *DestroyService) deletePhotos(paths []string) {
+	for _, path := range paths {
+		_ = s.storage.Delete(path)
+	}
+}
+
+func mapDestroyRepoError(err error) error {
+	if errors.Is(err, repository.ErrDestroyRequestNotPending) {
+		return ErrDestroyRequestNotPending
+	}
+	return err
+}
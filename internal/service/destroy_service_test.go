@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock DestroyRepository for testing
+type mockDestroyRepository struct {
+	requests map[int64]*domain.FabricDestroyRequest
+	approved []int64
+	rejected []int64
+}
+
+func (m *mockDestroyRepository) Create(ctx context.Context, req *domain.FabricDestroyRequest) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockDestroyRepository) FindByID(ctx context.Context, id int64) (*domain.FabricDestroyRequest, error) {
+	return m.requests[id], nil
+}
+
+func (m *mockDestroyRepository) List(ctx context.Context, status string) ([]domain.FabricDestroyRequest, error) {
+	return nil, nil
+}
+
+func (m *mockDestroyRepository) HasPending(ctx context.Context, fabricID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockDestroyRepository) Approve(ctx context.Context, id, reviewerID int64, notes string) error {
+	m.approved = append(m.approved, id)
+	return nil
+}
+
+func (m *mockDestroyRepository) Reject(ctx context.Context, id, reviewerID int64, notes string) error {
+	m.rejected = append(m.rejected, id)
+	return nil
+}
+
+// permissionUserRepository grants permissions per user on top of mockUserRepository.
+type permissionUserRepository struct {
+	mockUserRepository
+	permissions map[int64][]string
+	roles       map[int64][]string
+	accessLoads int
+}
+
+func (m *permissionUserRepository) HasPermission(userID int64, permission string) (bool, error) {
+	for _, p := range m.permissions[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *permissionUserRepository) FindAccess(userID int64) (*domain.UserAccess, error) {
+	m.accessLoads++
+	return &domain.UserAccess{Roles: m.roles[userID], Permissions: m.permissions[userID]}, nil
+}
+
+func newDestroyServiceForTest() (*DestroyService, *mockDestroyRepository) {
+	destroyRepo := &mockDestroyRepository{
+		requests: map[int64]*domain.FabricDestroyRequest{
+			1: {ID: 1, FabricID: 10, Status: domain.DestroyStatusPending, RequestedBy: 5},
+			2: {ID: 2, FabricID: 11, Status: domain.DestroyStatusApproved, RequestedBy: 5},
+		},
+	}
+	userRepo := &permissionUserRepository{
+		permissions: map[int64][]string{
+			5: {PermissionApproveDestroy},
+			7: {PermissionApproveDestroy},
+		},
+	}
+	return NewDestroyService(destroyRepo, nil, userRepo, nil, nil, nil), destroyRepo
+}
+
+func TestDestroyService_Approve(t *testing.T) {
+	svc, repo := newDestroyServiceForTest()
+
+	if err := svc.Approve(context.Background(), 7, 1, ""); err != nil {
+		t.Fatalf("Expected approval to succeed, got %v", err)
+	}
+	if len(repo.approved) != 1 || repo.approved[0] != 1 {
+		t.Errorf("Expected request 1 to be approved, got %v", repo.approved)
+	}
+}
+
+func TestDestroyService_ReviewRules(t *testing.T) {
+	svc, repo := newDestroyServiceForTest()
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		userID   int64
+		id       int64
+		expected error
+	}{
+		{"missing permission", 9, 1, ErrDestroyForbidden},
+		{"own request", 5, 1, ErrDestroySelfApproval},
+		{"already reviewed", 7, 2, ErrDestroyRequestNotPending},
+		{"unknown request", 7, 99, ErrDestroyRequestNotFound},
+	}
+
+	for _, tc := range testCases {
+		if err := svc.Approve(ctx, tc.userID, tc.id, ""); !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+		if err := svc.Reject(ctx, tc.userID, tc.id, "damaged"); !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v on reject, got %v", tc.name, tc.expected, err)
+		}
+	}
+
+	if len(repo.approved) != 0 || len(repo.rejected) != 0 {
+		t.Errorf("Expected no request to be reviewed, got approved=%v rejected=%v", repo.approved, repo.rejected)
+	}
+}
+
+func TestMapDestroyRepoError(t *testing.T) {
+	if err := mapDestroyRepoError(repository.ErrDestroyRequestNotPending); !errors.Is(err, ErrDestroyRequestNotPending) {
+		t.Errorf("Expected ErrDestroyRequestNotPending, got %v", err)
+	}
+	if err := mapDestroyRepoError(nil); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestMoveStage_DestroyRequiresApproval(t *testing.T) {
+	svc := NewCheckpointService(nil, nil, nil, nil, nil, nil)
+
+	err := svc.MoveStage(context.Background(), &MoveRequest{
+		Stage:   string(domain.StageDestroy),
+		Entries: []MoveEntry{{Code: "F24120001"}},
+	})
+	if err == nil {
+		t.Fatal("Expected moving to destroy to be refused")
+	}
+}
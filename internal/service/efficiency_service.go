@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// maxEfficiencyDays caps the report range so the hourly breakdown stays small.
+const maxEfficiencyDays = 31
+
+var ErrEfficiencyRange = errors.New("the report range must be between 1 and 31 days")
+
+type EfficiencyService struct {
+	repo repository.EfficiencyRepository
+}
+
+func NewEfficiencyService(repo repository.EfficiencyRepository) *EfficiencyService {
+	return &EfficiencyService{repo: repo}
+}
+
+// Report returns the efficiency of every line request with output in the range.
+func (s *EfficiencyService) Report(ctx context.Context, filter domain.EfficiencyFilter) ([]domain.LineEfficiency, error) {
+	if !filter.To.After(filter.From) || filter.To.Sub(filter.From).Hours() > maxEfficiencyDays*24 {
+		return nil, ErrEfficiencyRange
+	}
+
+	targets, err := s.repo.GetRequestTargets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.repo.GetHourlyOutput(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := map[int64][]repository.HourlyOutput{}
+	for _, o := range output {
+		hours[o.RequestID] = append(hours[o.RequestID], o)
+	}
+
+	report := make([]domain.LineEfficiency, 0, len(targets))
+	for _, target := range targets {
+		report = append(report, BuildLineEfficiency(target, hours[target.RequestID]))
+	}
+	return report, nil
+}
+
+// BuildLineEfficiency totals the hourly output of a request per day and over
+// the whole range. An hour counts as worked when any piece was checked in it.
+// The target per hour is the output of the whole line at the target SMV,
+// which is what target_time_used (target_qty x target_smv) is planned on.
+//
+//	efficiency   = output x SMV / (manpower x worked minutes)
+//	productivity = output / manpower
+func BuildLineEfficiency(line domain.LineEfficiency, hours []repository.HourlyOutput) domain.LineEfficiency {
+	if line.ManTotal > 0 && line.TargetSMV > 0 {
+		line.TargetPerHour = round2(float64(line.ManTotal) * 60 / line.TargetSMV)
+	}
+
+	line.Days = []domain.EfficiencyPeriod{}
+	var total domain.EfficiencyPeriod
+	for _, h := range hours {
+		day := h.Hour.Format("2006-01-02")
+		if n := len(line.Days); n == 0 || line.Days[n-1].Period != day {
+			line.Days = append(line.Days, domain.EfficiencyPeriod{Period: day})
+		}
+		current := &line.Days[len(line.Days)-1]
+
+		hour := domain.EfficiencyPeriod{Period: h.Hour.Format("15:04"), Output: h.Output, WorkedHours: 1}
+		current.Hours = append(current.Hours, measurePeriod(line, hour))
+		current.Output += h.Output
+		current.WorkedHours++
+		total.Output += h.Output
+		total.WorkedHours++
+	}
+
+	for i := range line.Days {
+		breakdown := line.Days[i].Hours
+		line.Days[i] = measurePeriod(line, line.Days[i])
+		line.Days[i].Hours = breakdown
+	}
+	line.EfficiencyPeriod = measurePeriod(line, total)
+	return line
+}
+
+// measurePeriod fills in the target, variance, efficiency and productivity of
+// a period from its output and worked hours.
+func measurePeriod(line domain.LineEfficiency, p domain.EfficiencyPeriod) domain.EfficiencyPeriod {
+	p.Target = round2(line.TargetPerHour * float64(p.WorkedHours))
+	p.Variance = round2(float64(p.Output) - p.Target)
+	if line.ManTotal > 0 {
+		p.Productivity = round2(float64(p.Output) / float64(line.ManTotal))
+		if p.WorkedHours > 0 {
+			availableMinutes := float64(line.ManTotal * p.WorkedHours * 60)
+			p.EfficiencyPct = round2(float64(p.Output) * line.TargetSMV * 100 / availableMinutes)
+		}
+	}
+	return p
+}
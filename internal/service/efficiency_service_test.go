@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock EfficiencyRepository for testing
+type mockEfficiencyRepository struct{}
+
+func (m *mockEfficiencyRepository) GetRequestTargets(ctx context.Context, filter domain.EfficiencyFilter) ([]domain.LineEfficiency, error) {
+	return nil, nil
+}
+
+func (m *mockEfficiencyRepository) GetHourlyOutput(ctx context.Context, filter domain.EfficiencyFilter) ([]repository.HourlyOutput, error) {
+	return nil, nil
+}
+
+func TestBuildLineEfficiency(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 9, day, hour, 0, 0, 0, time.Local)
+	}
+	line := domain.LineEfficiency{RequestID: 1, ManTotal: 20, TargetSMV: 10, TargetQty: 500}
+	hours := []repository.HourlyOutput{
+		{RequestID: 1, Hour: at(1, 8), Output: 120},
+		{RequestID: 1, Hour: at(1, 9), Output: 90},
+		{RequestID: 1, Hour: at(2, 8), Output: 60},
+	}
+
+	result := BuildLineEfficiency(line, hours)
+
+	if result.TargetPerHour != 120 {
+		t.Errorf("Expected 120 pieces per hour, got %v", result.TargetPerHour)
+	}
+	if len(result.Days) != 2 || len(result.Days[0].Hours) != 2 {
+		t.Fatalf("Expected 2 days with 2 hours on the first, got %+v", result.Days)
+	}
+
+	first := result.Days[0]
+	if first.Period != "2026-09-01" || first.Output != 210 || first.WorkedHours != 2 || first.Target != 240 || first.Variance != -30 {
+		t.Errorf("Unexpected first day %+v", first)
+	}
+	if first.EfficiencyPct != 87.5 || first.Productivity != 10.5 {
+		t.Errorf("Expected 87.5%% efficiency and 10.5 pieces per person, got %v and %v", first.EfficiencyPct, first.Productivity)
+	}
+	if h := first.Hours[0]; h.Period != "08:00" || h.EfficiencyPct != 100 || h.Variance != 0 {
+		t.Errorf("Expected first hour on target, got %+v", h)
+	}
+
+	if result.Output != 270 || result.WorkedHours != 3 || result.Target != 360 || result.EfficiencyPct != 75 {
+		t.Errorf("Unexpected totals %+v", result.EfficiencyPeriod)
+	}
+}
+
+func TestBuildLineEfficiency_WithoutTargets(t *testing.T) {
+	result := BuildLineEfficiency(domain.LineEfficiency{}, []repository.HourlyOutput{
+		{Hour: time.Date(2026, 9, 1, 8, 0, 0, 0, time.Local), Output: 10},
+	})
+	if result.TargetPerHour != 0 || result.EfficiencyPct != 0 || result.Variance != 10 {
+		t.Errorf("Expected output without targets to be reported as is, got %+v", result.EfficiencyPeriod)
+	}
+}
+
+func TestEfficiencyReport_Range(t *testing.T) {
+	svc := NewEfficiencyService(&mockEfficiencyRepository{})
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)
+
+	if _, err := svc.Report(context.Background(), domain.EfficiencyFilter{From: from, To: from.AddDate(0, 2, 0)}); !errors.Is(err, ErrEfficiencyRange) {
+		t.Errorf("Expected ErrEfficiencyRange for two months, got %v", err)
+	}
+	if _, err := svc.Report(context.Background(), domain.EfficiencyFilter{From: from, To: from}); !errors.Is(err, ErrEfficiencyRange) {
+		t.Errorf("Expected ErrEfficiencyRange for an empty range, got %v", err)
+	}
+	if _, err := svc.Report(context.Background(), domain.EfficiencyFilter{From: from, To: from.AddDate(0, 0, 31)}); err != nil {
+		t.Errorf("Expected 31 days to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/mailer"
+	"github.com/rs/zerolog/log"
+)
+
+// Email module actions. Each has a body template below and may have a
+// setting_email_configs row with the same module_name and action.
+const (
+	EmailModuleAuth          = "auth"
+	EmailActionResetPassword = "forgot-password"
+)
+
+const (
+	emailBatchSize = 50
+	// emailSendingTimeout leaves room for a slow SMTP server. An email still
+	// marked sending after that was left behind by a stopped instance; it is
+	// sent again, so its recipients may get it twice.
+	emailSendingTimeout = 10 * time.Minute
+)
+
+var (
+	ErrUnknownEmailTemplate = errors.New("email template is not found")
+	ErrNoEmailRecipients    = errors.New("email has no recipients")
+	ErrEmailSecretGone      = errors.New("email secret no longer exists")
+)
+
+type emailTemplate struct {
+	subject string
+	body    string
+	// private emails only go to the recipients they were queued for; the
+	// to, cc and bcc of setting_email_configs are not added.
+	private bool
+	// secrets are data keys kept out of data_body. The body stores a
+	// placeholder and the value is looked up again when the email is sent.
+	secrets []string
+}
+
+// emailTemplates holds the default subject and the body of each module action,
+// keyed by "module/action". Templates use text/template syntax.
+var emailTemplates = map[string]emailTemplate{
+	EmailModuleAuth + "/" + EmailActionResetPassword: {
+		private: true,
+		secrets: []string{"token"},
+		subject: "{{.app_name}} password reset",
+		body: `Hello {{.name}},
+
+We received a request to reset the password of your {{.app_name}} account.
+{{if .app_url}}
+Open the link below to choose a new password:
+{{.app_url}}/reset-password?email={{urlquery .email}}&token={{.token}}
+{{else}}
+Use this token to choose a new password:
+{{.token}}
+{{end}}
+If you did not ask for a password reset, you can ignore this email.
+{{if .supported_by}}
+Supported by {{.supported_by}}{{if .supported_link}} ({{.supported_link}}){{end}}
+{{end}}`,
+	},
+}
+
+// EmailSettings configures the outbox.
+type EmailSettings struct {
+	AppName       string
+	From          string
+	AppURL        string
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// EmailInput is an email to queue. DocType and DocID name the record the
+// email is about.
+type EmailInput struct {
+	Module  string
+	Action  string
+	DocType string
+	DocID   int64
+	DocURL  string
+	To      []string
+	Data    map[string]interface{}
+}
+
+// EmailSecretResolver returns the secrets of a queued email when it is sent.
+// It returns ErrEmailSecretGone when they no longer exist, e.g. a reset token
+// that was used since.
+type EmailSecretResolver func(ctx context.Context, email *domain.EmailLog) (map[string]string, error)
+
+// EmailService queues templated emails in setting_email_logs and delivers
+// them with retries.
+type EmailService struct {
+	repo      repository.EmailRepository
+	sender    mailer.Sender
+	settings  EmailSettings
+	resolvers map[string]EmailSecretResolver
+	wake      chan struct{}
+}
+
+func NewEmailService(repo repository.EmailRepository, sender mailer.Sender, settings EmailSettings) *EmailService {
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = 1
+	}
+	return &EmailService{
+		repo:      repo,
+		sender:    sender,
+		settings:  settings,
+		resolvers: map[string]EmailSecretResolver{},
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// ResolveSecrets sets how the secrets of a module action are looked up when
+// its emails are sent. It must be called before the outbox runs.
+func (s *EmailService) ResolveSecrets(module, action string, resolver EmailSecretResolver) {
+	s.resolvers[module+"/"+action] = resolver
+}
+
+func emailSecretPlaceholder(key string) string {
+	return "[secret:" + key + "]"
+}
+
+// Queue renders the email and stores it in the outbox. The worker picks it up
+// right away when it is running.
+func (s *EmailService) Queue(ctx context.Context, input EmailInput) (*domain.EmailLog, error) {
+	tmpl, ok := emailTemplates[input.Module+"/"+input.Action]
+	if !ok {
+		return nil, ErrUnknownEmailTemplate
+	}
+	config, err := s.repo.FindConfig(ctx, input.Module, input.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"app_name":       s.settings.AppName,
+		"app_url":        s.settings.AppURL,
+		"supported_by":   "",
+		"supported_link": "",
+	}
+	subjectTemplate := tmpl.subject
+	var cc, bcc []string
+	to := append([]string{}, input.To...)
+	if config != nil {
+		if config.AppName != nil && *config.AppName != "" {
+			data["app_name"] = *config.AppName
+		}
+		if config.SupportedBy != nil {
+			data["supported_by"] = *config.SupportedBy
+		}
+		if config.SupportedLink != nil {
+			data["supported_link"] = *config.SupportedLink
+		}
+		if config.Subject != nil && strings.TrimSpace(*config.Subject) != "" {
+			subjectTemplate = *config.Subject
+		}
+		if !tmpl.private {
+			to = append(to, SplitAddresses(config.To)...)
+			cc = SplitAddresses(config.Cc)
+			bcc = SplitAddresses(config.Bcc)
+		}
+	}
+	for key, value := range input.Data {
+		data[key] = value
+	}
+	for _, key := range tmpl.secrets {
+		data[key] = emailSecretPlaceholder(key)
+	}
+	if len(to) == 0 {
+		return nil, ErrNoEmailRecipients
+	}
+
+	subject, err := RenderEmail(subjectTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := RenderEmail(tmpl.body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := strings.Join(append(append(append([]string{}, to...), cc...), bcc...), ", ")
+	appName := fmt.Sprint(data["app_name"])
+	email := &domain.EmailLog{
+		AppName:    stringPtr(appName),
+		ModuleName: stringPtr(input.Module),
+		ActionName: stringPtr(input.Action),
+		DocType:    input.DocType,
+		DocID:      input.DocID,
+		Subject:    strings.TrimSpace(subject),
+		To:         strings.Join(to, ", "),
+		Cc:         stringPtr(strings.Join(cc, ", ")),
+		Bcc:        stringPtr(strings.Join(bcc, ", ")),
+		Recipients: &recipients,
+		DocURL:     stringPtr(input.DocURL),
+		DataBody:   body,
+	}
+	if err := s.repo.CreateLog(ctx, email); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return email, nil
+}
+
+// ProcessOutbox sends the emails that are due and returns how many went out.
+func (s *EmailService) ProcessOutbox(ctx context.Context) (int, error) {
+	now := time.Now()
+	emails, err := s.repo.Pending(ctx, now.Add(-emailSendingTimeout), emailBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range emails {
+		email := &emails[i]
+		if !EmailDue(email, now, s.settings) {
+			continue
+		}
+		claimed, err := s.repo.Claim(ctx, email)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if s.deliver(ctx, email) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// deliver makes one attempt and stores its outcome. Permanent failures and
+// the last allowed attempt mark the email failed; other failures wait for a
+// retry.
+func (s *EmailService) deliver(ctx context.Context, email *domain.EmailLog) bool {
+	from := s.settings.From
+	if config, err := s.repo.FindConfig(ctx, deref(email.ModuleName), deref(email.ActionName)); err == nil && config != nil && config.From != nil && *config.From != "" {
+		from = *config.From
+	}
+
+	msg := &mailer.Message{
+		From:    from,
+		To:      SplitAddresses(&email.To),
+		Cc:      SplitAddresses(email.Cc),
+		Bcc:     SplitAddresses(email.Bcc),
+		Subject: email.Subject,
+	}
+
+	email.Attempt++
+	now := time.Now()
+	body, err := s.body(ctx, email)
+	if err == nil {
+		msg.Body = body
+		err = s.sender.Send(ctx, msg)
+	}
+	if err == nil {
+		email.Status = domain.EmailStatusSent
+		email.SendTime = &now
+	} else {
+		line := fmt.Sprintf("[attempt %d at %s] %v", email.Attempt, now.Format(time.RFC3339), err)
+		if email.ErrorMsg != nil && *email.ErrorMsg != "" {
+			line = *email.ErrorMsg + "\n" + line
+		}
+		email.ErrorMsg = &line
+		email.Status = domain.EmailStatusRetry
+		if mailer.IsPermanent(err) || email.Attempt >= s.settings.MaxAttempts {
+			email.Status = domain.EmailStatusFailed
+		}
+		log.Warn().Err(err).Int64("email_id", email.ID).Int("attempt", email.Attempt).Str("status", email.Status).Msg("Failed to send email")
+	}
+
+	if err := s.repo.UpdateDelivery(ctx, email); err != nil {
+		log.Error().Err(err).Int64("email_id", email.ID).Msg("Failed to store email delivery")
+	}
+	return email.Status == domain.EmailStatusSent
+}
+
+// body fills the secrets of the template into the stored body. Secrets that
+// cannot be looked up fail the email for good.
+func (s *EmailService) body(ctx context.Context, email *domain.EmailLog) (string, error) {
+	key := deref(email.ModuleName) + "/" + deref(email.ActionName)
+	tmpl := emailTemplates[key]
+	if len(tmpl.secrets) == 0 {
+		return email.DataBody, nil
+	}
+	resolver, ok := s.resolvers[key]
+	if !ok {
+		return "", &mailer.PermanentError{Err: fmt.Errorf("no secret resolver for %s", key)}
+	}
+	secrets, err := resolver(ctx, email)
+	if errors.Is(err, ErrEmailSecretGone) {
+		return "", &mailer.PermanentError{Err: err}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	body := email.DataBody
+	for _, key := range tmpl.secrets {
+		body = strings.ReplaceAll(body, emailSecretPlaceholder(key), secrets[key])
+	}
+	return body, nil
+}
+
+// RunOutbox sends due emails every interval, and right after an email is
+// queued, until ctx is cancelled.
+func (s *EmailService) RunOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessOutbox(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to process email outbox")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// EmailDue reports whether a pending email should be attempted now. Retries
+// wait RetryDelay after the first failure, doubling with every further one.
+func EmailDue(email *domain.EmailLog, now time.Time, settings EmailSettings) bool {
+	if email.Status != domain.EmailStatusRetry || email.UpdatedAt == nil {
+		return true
+	}
+	return !now.Before(email.UpdatedAt.Add(RetryBackoff(email.Attempt, settings.RetryDelay, settings.MaxRetryDelay)))
+}
+
+// RetryBackoff is the wait after the given number of failed attempts.
+func RetryBackoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+// RenderEmail executes a text/template with the email data.
+func RenderEmail(text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("email").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid email template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render email: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// SplitAddresses splits a comma or semicolon separated address list.
+func SplitAddresses(list *string) []string {
+	if list == nil {
+		return nil
+	}
+	var addresses []string
+	for _, address := range strings.FieldsFunc(*list, func(r rune) bool { return r == ',' || r == ';' }) {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/pkg/mailer"
+)
+
+type mockEmailRepository struct {
+	config *domain.EmailConfig
+	emails []*domain.EmailLog
+}
+
+func (m *mockEmailRepository) FindConfig(ctx context.Context, module, action string) (*domain.EmailConfig, error) {
+	return m.config, nil
+}
+
+func (m *mockEmailRepository) CreateLog(ctx context.Context, e *domain.EmailLog) error {
+	e.ID = int64(len(m.emails) + 1)
+	e.Status = domain.EmailStatusQueued
+	m.emails = append(m.emails, e)
+	return nil
+}
+
+func (m *mockEmailRepository) Pending(ctx context.Context, staleBefore time.Time, limit int) ([]domain.EmailLog, error) {
+	var pending []domain.EmailLog
+	for _, e := range m.emails {
+		if e.Status == domain.EmailStatusQueued || e.Status == domain.EmailStatusRetry {
+			pending = append(pending, *e)
+		}
+	}
+	return pending, nil
+}
+
+func (m *mockEmailRepository) Claim(ctx context.Context, e *domain.EmailLog) (bool, error) {
+	e.Status = domain.EmailStatusSending
+	return true, nil
+}
+
+func (m *mockEmailRepository) UpdateDelivery(ctx context.Context, e *domain.EmailLog) error {
+	now := time.Now()
+	e.UpdatedAt = &now
+	*m.emails[e.ID-1] = *e
+	return nil
+}
+
+// fakeSender fails with the queued errors before it starts delivering.
+type fakeSender struct {
+	errs []error
+	sent []*mailer.Message
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg *mailer.Message) error {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// resolveToken hands out the reset token of every queued reset email.
+func resolveToken(svc *EmailService, token string) {
+	svc.ResolveSecrets(EmailModuleAuth, EmailActionResetPassword, func(ctx context.Context, email *domain.EmailLog) (map[string]string, error) {
+		return map[string]string{"token": token}, nil
+	})
+}
+
+func TestEmailQueue_RendersTemplateWithConfig(t *testing.T) {
+	from, cc, subject, supportedBy := "IT <it@dppi.test>", "it@dppi.test; audit@dppi.test", "Reset password for {{.name}}", "DPPI IT"
+	repo := &mockEmailRepository{config: &domain.EmailConfig{From: &from, Cc: &cc, Subject: &subject, SupportedBy: &supportedBy}}
+	sender := &fakeSender{}
+	svc := NewEmailService(repo, sender, EmailSettings{AppName: "DPPI ERP", From: "noreply@dppi.test", AppURL: "https://erp.dppi.test", MaxAttempts: 3})
+	resolveToken(svc, "abc123")
+
+	email, err := svc.Queue(context.Background(), EmailInput{
+		Module:  EmailModuleAuth,
+		Action:  EmailActionResetPassword,
+		DocType: "App\\Models\\User",
+		DocID:   7,
+		To:      []string{"budi@dppi.test"},
+		Data:    map[string]interface{}{"name": "Budi", "email": "budi+1@dppi.test", "token": "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("Queue returned error: %v", err)
+	}
+	if email.Subject != "Reset password for Budi" {
+		t.Errorf("Expected subject from config, got %q", email.Subject)
+	}
+	if !strings.Contains(email.DataBody, "https://erp.dppi.test/reset-password?email=budi%2B1%40dppi.test&token=[secret:token]") {
+		t.Errorf("Expected reset link without the token in body, got:\n%s", email.DataBody)
+	}
+	if !strings.Contains(email.DataBody, "Supported by DPPI IT") || strings.Contains(email.DataBody, "<no value>") {
+		t.Errorf("Unexpected body:\n%s", email.DataBody)
+	}
+	// The token is only for the user; the configured cc is left out
+	if *email.Recipients != "budi@dppi.test" {
+		t.Errorf("Unexpected recipients: %s", *email.Recipients)
+	}
+
+	if sent, err := svc.ProcessOutbox(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("ProcessOutbox = %d, %v", sent, err)
+	}
+	if msg := sender.sent[0]; msg.From != from || len(msg.Cc) != 0 || msg.To[0] != "budi@dppi.test" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if msg := sender.sent[0]; !strings.Contains(msg.Body, "&token=abc123") {
+		t.Errorf("Expected the token in the sent body, got:\n%s", msg.Body)
+	}
+	if stored := repo.emails[0]; stored.Status != domain.EmailStatusSent || stored.Attempt != 1 || stored.SendTime == nil {
+		t.Errorf("Expected sent email after one attempt, got %+v", stored)
+	}
+	if stored := repo.emails[0]; strings.Contains(stored.DataBody, "abc123") {
+		t.Errorf("Expected the token to stay out of the email log, got:\n%s", stored.DataBody)
+	}
+
+	if _, err := svc.Queue(context.Background(), EmailInput{Module: "unknown", Action: "x"}); !errors.Is(err, ErrUnknownEmailTemplate) {
+		t.Errorf("Expected ErrUnknownEmailTemplate, got %v", err)
+	}
+}
+
+func TestEmailOutbox_RetriesWithBackoff(t *testing.T) {
+	repo := &mockEmailRepository{}
+	sender := &fakeSender{errs: []error{errors.New("connection refused"), errors.New("451 try again")}}
+	svc := NewEmailService(repo, sender, EmailSettings{From: "noreply@dppi.test", MaxAttempts: 5, RetryDelay: time.Hour, MaxRetryDelay: 4 * time.Hour})
+	resolveToken(svc, "abc123")
+
+	if _, err := svc.Queue(context.Background(), EmailInput{Module: EmailModuleAuth, Action: EmailActionResetPassword, To: []string{"a@dppi.test"}}); err != nil {
+		t.Fatalf("Queue returned error: %v", err)
+	}
+
+	svc.ProcessOutbox(context.Background())
+	email := repo.emails[0]
+	if email.Status != domain.EmailStatusRetry || email.Attempt != 1 || !strings.Contains(*email.ErrorMsg, "[attempt 1 at") {
+		t.Fatalf("Expected retry after first failure, got %+v", email)
+	}
+
+	// Not due again until the backoff has passed
+	svc.ProcessOutbox(context.Background())
+	if email.Attempt != 1 {
+		t.Fatalf("Expected no attempt before the backoff, got %d attempts", email.Attempt)
+	}
+
+	past := time.Now().Add(-2 * time.Hour)
+	email.UpdatedAt = &past
+	svc.ProcessOutbox(context.Background())
+	if email.Attempt != 2 || strings.Count(*email.ErrorMsg, "[attempt") != 2 {
+		t.Fatalf("Expected second attempt to be recorded, got %+v", email)
+	}
+
+	past = time.Now().Add(-90 * time.Minute)
+	email.UpdatedAt = &past
+	if EmailDue(email, time.Now(), svc.settings) {
+		t.Error("Expected the second retry to wait twice as long")
+	}
+	past = time.Now().Add(-3 * time.Hour)
+	email.UpdatedAt = &past
+	svc.ProcessOutbox(context.Background())
+	if email.Status != domain.EmailStatusSent || email.Attempt != 3 {
+		t.Errorf("Expected sent on third attempt, got %+v", email)
+	}
+}
+
+func TestEmailOutbox_PermanentFailure(t *testing.T) {
+	repo := &mockEmailRepository{}
+	sender := &fakeSender{errs: []error{&mailer.PermanentError{Err: errors.New("550 mailbox unavailable")}}}
+	svc := NewEmailService(repo, sender, EmailSettings{From: "noreply@dppi.test", MaxAttempts: 5, RetryDelay: time.Minute})
+	resolveToken(svc, "abc123")
+
+	svc.Queue(context.Background(), EmailInput{Module: EmailModuleAuth, Action: EmailActionResetPassword, To: []string{"gone@dppi.test"}})
+	svc.ProcessOutbox(context.Background())
+
+	if email := repo.emails[0]; email.Status != domain.EmailStatusFailed || email.Attempt != 1 {
+		t.Errorf("Expected failed after a permanent error, got %+v", email)
+	}
+}
+
+func TestEmailOutbox_FailsWhenSecretIsGone(t *testing.T) {
+	repo := &mockEmailRepository{}
+	sender := &fakeSender{}
+	svc := NewEmailService(repo, sender, EmailSettings{From: "noreply@dppi.test", MaxAttempts: 5, RetryDelay: time.Minute})
+	svc.ResolveSecrets(EmailModuleAuth, EmailActionResetPassword, func(ctx context.Context, email *domain.EmailLog) (map[string]string, error) {
+		return nil, ErrEmailSecretGone
+	})
+
+	svc.Queue(context.Background(), EmailInput{Module: EmailModuleAuth, Action: EmailActionResetPassword, To: []string{"a@dppi.test"}})
+	svc.ProcessOutbox(context.Background())
+
+	if email := repo.emails[0]; email.Status != domain.EmailStatusFailed || len(sender.sent) != 0 {
+		t.Errorf("Expected failed without sending once the token is gone, got %+v", email)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := RetryBackoff(tt.attempts, time.Minute, 10*time.Minute); got != tt.want {
+			t.Errorf("RetryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// PermissionViewExceptions allows a user to browse captured exceptions.
+const PermissionViewExceptions = "setting.exception.read"
+
+// exceptionReferencePrefix marks the opaque reference handed to clients in
+// place of the internal error message.
+const exceptionReferencePrefix = "EXC-"
+
+const (
+	defaultExceptionLimit = 50
+	maxExceptionLimit     = 200
+)
+
+var (
+	ErrExceptionNotFound  = errors.New("exception is not found")
+	ErrExceptionForbidden = errors.New("you are not allowed to view exceptions")
+)
+
+type ExceptionService struct {
+	repo     repository.ExceptionRepository
+	userRepo repository.UserRepository
+}
+
+func NewExceptionService(repo repository.ExceptionRepository, userRepo repository.UserRepository) *ExceptionService {
+	return &ExceptionService{repo: repo, userRepo: userRepo}
+}
+
+// Record stores a captured exception and returns the reference to show the
+// client. Values are cut to the column sizes of the exceptions table.
+func (s *ExceptionService) Record(ctx context.Context, e *domain.Exception) (string, error) {
+	e.URL = truncate(e.URL, 255)
+	e.Method = truncate(e.Method, 10)
+	e.ExceptionClass = truncate(e.ExceptionClass, 255)
+	e.Message = truncate(e.Message, 500)
+	e.File = truncate(e.File, 255)
+	if e.IPAddress != nil {
+		ip := truncate(*e.IPAddress, 45)
+		e.IPAddress = &ip
+	}
+	if e.CallerFile != nil {
+		file := truncate(*e.CallerFile, 255)
+		e.CallerFile = &file
+	}
+	if e.CallerFunction != nil {
+		function := truncate(*e.CallerFunction, 255)
+		e.CallerFunction = &function
+	}
+	if e.StatusCode == 0 {
+		e.StatusCode = 500
+	}
+
+	if err := s.repo.Create(ctx, e); err != nil {
+		return "", err
+	}
+	return ExceptionReference(e.ID), nil
+}
+
+func (s *ExceptionService) List(ctx context.Context, userID int64, filter domain.ExceptionFilter) ([]domain.Exception, error) {
+	if err := s.authorize(userID); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultExceptionLimit
+	}
+	if filter.Limit > maxExceptionLimit {
+		filter.Limit = maxExceptionLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// Get returns one exception, including its stack trace, by id or reference.
+func (s *ExceptionService) Get(ctx context.Context, userID int64, reference string) (*domain.Exception, error) {
+	if err := s.authorize(userID); err != nil {
+		return nil, err
+	}
+
+	id, ok := ParseExceptionReference(reference)
+	if !ok {
+		return nil, ErrExceptionNotFound
+	}
+	e, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrExceptionNotFound
+	}
+	return e, nil
+}
+
+func (s *ExceptionService) authorize(userID int64) error {
+	allowed, err := s.userRepo.HasPermission(userID, PermissionViewExceptions)
+	if err != nil {
+		return fmt.Errorf("error checking permission: %w", err)
+	}
+	if !allowed {
+		return ErrExceptionForbidden
+	}
+	return nil
+}
+
+// ExceptionReference is the reference clients quote for a stored exception.
+func ExceptionReference(id int64) string {
+	return exceptionReferencePrefix + strconv.FormatInt(id, 10)
+}
+
+// ParseExceptionReference accepts either a reference such as "EXC-42" or a
+// bare id.
+func ParseExceptionReference(reference string) (int64, bool) {
+	raw := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(reference)), exceptionReferencePrefix)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// truncate cuts s to at most n characters without splitting a rune.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n])
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type mockExceptionRepository struct {
+	exceptions []domain.Exception
+}
+
+func (m *mockExceptionRepository) Create(ctx context.Context, e *domain.Exception) error {
+	e.ID = int64(len(m.exceptions) + 1)
+	m.exceptions = append(m.exceptions, *e)
+	return nil
+}
+
+func (m *mockExceptionRepository) List(ctx context.Context, filter domain.ExceptionFilter) ([]domain.Exception, error) {
+	return m.exceptions, nil
+}
+
+func (m *mockExceptionRepository) FindByID(ctx context.Context, id int64) (*domain.Exception, error) {
+	for i := range m.exceptions {
+		if m.exceptions[i].ID == id {
+			return &m.exceptions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestExceptionRecord_TruncatesToColumns(t *testing.T) {
+	repo := &mockExceptionRepository{}
+	svc := NewExceptionService(repo, &permissionUserRepository{})
+
+	reference, err := svc.Record(context.Background(), &domain.Exception{
+		URL:     "/overview",
+		Method:  "GET",
+		Message: strings.Repeat("é", 600),
+	})
+	if err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if reference != "EXC-1" {
+		t.Errorf("Expected reference EXC-1, got %q", reference)
+	}
+
+	stored := repo.exceptions[0]
+	if n := len([]rune(stored.Message)); n != 500 {
+		t.Errorf("Expected message cut to 500 characters, got %d", n)
+	}
+	if stored.StatusCode != 500 {
+		t.Errorf("Expected default status 500, got %d", stored.StatusCode)
+	}
+}
+
+func TestExceptionGet_RequiresPermissionAndAcceptsReference(t *testing.T) {
+	repo := &mockExceptionRepository{exceptions: []domain.Exception{{ID: 42, Message: "boom"}}}
+	users := &permissionUserRepository{permissions: map[int64][]string{1: {PermissionViewExceptions}}}
+	svc := NewExceptionService(repo, users)
+
+	if _, err := svc.Get(context.Background(), 2, "EXC-42"); !errors.Is(err, ErrExceptionForbidden) {
+		t.Errorf("Expected ErrExceptionForbidden, got %v", err)
+	}
+
+	for _, reference := range []string{"EXC-42", "exc-42", "42"} {
+		e, err := svc.Get(context.Background(), 1, reference)
+		if err != nil || e.Message != "boom" {
+			t.Errorf("Get(%q) = %+v, %v", reference, e, err)
+		}
+	}
+
+	for _, reference := range []string{"EXC-7", "abc", "EXC-"} {
+		if _, err := svc.Get(context.Background(), 1, reference); !errors.Is(err, ErrExceptionNotFound) {
+			t.Errorf("Get(%q): expected ErrExceptionNotFound, got %v", reference, err)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/authctx"
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var (
+	ErrInvalidGarmentCode    = errors.New("the QR code must look like <bundle>-<piece>")
+	ErrNoActiveRequest       = errors.New("the line has no approved request")
+	ErrQRSystemNotFound      = errors.New("QR code is not found")
+	ErrGarmentOrderMismatch  = errors.New("the QR code belongs to another order than the line is running")
+	ErrGarmentAlreadyChecked = errors.New("the piece has already been checked")
+	ErrDefectTypeNotFound    = errors.New("defect type is not found")
+	ErrOrderProcessNotFound  = errors.New("the process is not part of the order")
+	ErrGarmentItemNotFound   = errors.New("garment piece is not found")
+	ErrGarmentPacked         = errors.New("the piece has already been packed")
+	ErrGarmentInRework       = errors.New("the piece is already in rework")
+	ErrGarmentNotInRework    = errors.New("the piece is not in rework")
+)
+
+// GarmentDefectInput is a defect reported by the line QC.
+type GarmentDefectInput struct {
+	DefectTypeID   int64
+	OrderProcessID int64
+}
+
+// GarmentScanInput is a piece checked by the line QC.
+type GarmentScanInput struct {
+	LineID  int64
+	QRCode  string
+	Result  string
+	Defects []GarmentDefectInput
+}
+
+type GarmentQCService struct {
+	repo     repository.GarmentQCRepository
+	activity *ActivityService
+	notifier *NotificationService
+	stream   *MovementStream
+}
+
+func NewGarmentQCService(repo repository.GarmentQCRepository, activity *ActivityService, notifier *NotificationService, stream *MovementStream) *GarmentQCService {
+	return &GarmentQCService{repo: repo, activity: activity, notifier: notifier, stream: stream}
+}
+
+// Scan records the QC result of a piece against the line's approved request.
+func (s *GarmentQCService) Scan(ctx context.Context, input GarmentScanInput) (*domain.RequestItem, error) {
+	qrCode := strings.TrimSpace(input.QRCode)
+	bundle, err := parseGarmentCode(qrCode)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.repo.FindActiveRequest(ctx, input.LineID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrNoActiveRequest
+	}
+
+	qr, err := s.repo.FindQRSystemByCode(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
+	if qr == nil {
+		return nil, ErrQRSystemNotFound
+	}
+	if qr.OrderID != request.OrderID {
+		return nil, ErrGarmentOrderMismatch
+	}
+
+	existing, err := s.repo.FindItemByCode(ctx, qrCode)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrGarmentAlreadyChecked
+	}
+
+	item := &domain.RequestItem{
+		QRCode:     qrCode,
+		RequestID:  request.ID,
+		OrderID:    request.OrderID,
+		QRSystemID: qr.ID,
+	}
+	if err := s.applyResult(ctx, item, input.Result, input.Defects); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateItem(ctx, item); err != nil {
+		if errors.Is(err, repository.ErrItemAlreadyChecked) {
+			return nil, ErrGarmentAlreadyChecked
+		}
+		return nil, err
+	}
+	s.record(ctx, ActivityCreated, item.ID, nil, item)
+	s.publish(ctx, item, "", request.BuyerID)
+	if item.Result == domain.GarmentResultFail {
+		s.notifier.Publish(ctx, NotificationMessage{
+			Module:  NotificationQCFailed,
+			Message: fmt.Sprintf("Piece %s failed QC.", item.QRCode),
+			Data: map[string]interface{}{
+				"source":     "garment",
+				"item_id":    item.ID,
+				"qr_code":    item.QRCode,
+				"request_id": item.RequestID,
+				"order_id":   item.OrderID,
+			},
+		})
+	}
+	return item, nil
+}
+
+// SendToRework sends a checked piece back to the sewing process, for example
+// when finishing finds a defect QC missed.
+func (s *GarmentQCService) SendToRework(ctx context.Context, itemID int64, defects []GarmentDefectInput) (*domain.RequestItem, error) {
+	item, err := s.findItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.IsRework && item.Stage == domain.GarmentStageProcess {
+		return nil, ErrGarmentInRework
+	}
+	buyerID, err := s.requestBuyer(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *item
+	if err := s.applyResult(ctx, item, domain.GarmentResultDefect, defects); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateItemStage(ctx, item); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, item.ID, &old, item)
+	s.publish(ctx, item, old.Stage, buyerID)
+	return item, nil
+}
+
+// ReturnFromRework records the QC result of a repaired piece. A piece that
+// still has defects starts another trip through rework.
+func (s *GarmentQCService) ReturnFromRework(ctx context.Context, itemID int64, result string, defects []GarmentDefectInput) (*domain.RequestItem, error) {
+	item, err := s.findItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if !item.IsRework || item.Stage != domain.GarmentStageProcess {
+		return nil, ErrGarmentNotInRework
+	}
+	buyerID, err := s.requestBuyer(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *item
+	if err := s.applyResult(ctx, item, result, defects); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateItemStage(ctx, item); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, item.ID, &old, item)
+	s.publish(ctx, item, old.Stage, buyerID)
+	return item, nil
+}
+
+// ReworkReport returns the rework rate and turnaround per line and per
+// process. Rates are based on the pieces checked in the same range.
+func (s *GarmentQCService) ReworkReport(ctx context.Context, filter domain.ReworkFilter) (*domain.ReworkReport, error) {
+	checked, err := s.repo.GetCheckedByLine(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.GetReworkEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return BuildReworkReport(checked, events), nil
+}
+
+// Summary returns the line's approved request with the pieces checked so far.
+func (s *GarmentQCService) Summary(ctx context.Context, lineID int64) (*domain.LineQCSummary, error) {
+	request, err := s.repo.FindActiveRequest(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrNoActiveRequest
+	}
+
+	summary, err := s.repo.GetRequestCounts(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary.Request = request
+	return summary, nil
+}
+
+func (s *GarmentQCService) record(ctx context.Context, event string, id int64, old, new *domain.RequestItem) {
+	s.activity.Record(ctx, ActivityInput{LogName: "garment", Event: event, SubjectType: repository.SubjectRequestItem, SubjectID: id, Old: old, New: new})
+}
+
+func (s *GarmentQCService) publish(ctx context.Context, item *domain.RequestItem, fromStage string, buyerID *int64) {
+	result := item.Result
+	s.stream.Publish(MovementEvent{
+		Type:      MovementGarmentQC,
+		Code:      item.QRCode,
+		ItemID:    item.ID,
+		FromStage: fromStage,
+		Stage:     item.Stage,
+		BuyerID:   buyerID,
+		OrderID:   item.OrderID,
+		QCResult:  &result,
+		UserID:    authctx.UserID(ctx),
+	})
+}
+
+// requestBuyer returns the buyer of the request a piece was checked for, so
+// rework moves reach the same buyer filters as the scan.
+func (s *GarmentQCService) requestBuyer(ctx context.Context, item *domain.RequestItem) (*int64, error) {
+	request, err := s.repo.FindRequest(ctx, item.RequestID)
+	if err != nil || request == nil {
+		return nil, err
+	}
+	return request.BuyerID, nil
+}
+
+func (s *GarmentQCService) findItem(ctx context.Context, id int64) (*domain.RequestItem, error) {
+	item, err := s.repo.FindItemByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrGarmentItemNotFound
+	}
+	if item.Stage == domain.GarmentStagePacking {
+		return nil, ErrGarmentPacked
+	}
+	return item, nil
+}
+
+// applyResult sets the result and the stage that follows from it. Passed and
+// rejected pieces move on to finishing, pieces with defects go back to the
+// sewing process for rework.
+func (s *GarmentQCService) applyResult(ctx context.Context, item *domain.RequestItem, result string, defects []GarmentDefectInput) error {
+	item.Result = result
+	item.ResultedAt = time.Now()
+	item.Stage = domain.GarmentStageFinishing
+	item.IsCNCM = result == domain.GarmentResultFail
+	item.IsRework = false
+	item.Defects = nil
+
+	if result == domain.GarmentResultDefect {
+		item.IsRework = true
+		item.Stage = domain.GarmentStageProcess
+
+		var err error
+		if item.Defects, err = s.resolveDefects(ctx, item.OrderID, defects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GarmentQCService) resolveDefects(ctx context.Context, orderID int64, inputs []GarmentDefectInput) ([]domain.RequestDefect, error) {
+	defects := make([]domain.RequestDefect, 0, len(inputs))
+	for _, input := range inputs {
+		exists, err := s.repo.DefectTypeExists(ctx, input.DefectTypeID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", ErrDefectTypeNotFound, input.DefectTypeID)
+		}
+
+		process, err := s.repo.FindOrderProcess(ctx, orderID, input.OrderProcessID)
+		if err != nil {
+			return nil, err
+		}
+		if process == nil {
+			return nil, fmt.Errorf("%w: %d", ErrOrderProcessNotFound, input.OrderProcessID)
+		}
+
+		defects = append(defects, domain.RequestDefect{
+			DefectTypeID:   input.DefectTypeID,
+			OrderProcessID: process.ID,
+			ProcessID:      process.ProcessID,
+			ProcessName:    process.Name,
+		})
+	}
+	return defects, nil
+}
+
+// parseGarmentCode returns the bundle code of a piece code like "223-4".
+func parseGarmentCode(code string) (string, error) {
+	i := strings.LastIndex(code, "-")
+	if i <= 0 {
+		return "", ErrInvalidGarmentCode
+	}
+	piece, err := strconv.Atoi(code[i+1:])
+	if err != nil || piece < 1 {
+		return "", ErrInvalidGarmentCode
+	}
+	return code[:i], nil
+}
+
+// BuildReworkReport aggregates rework trips per line and per process. A piece
+// counts once towards the rework rate however often it was repaired, while
+// every returned trip counts towards the turnaround.
+func BuildReworkReport(checked []domain.ReworkStat, events []repository.ReworkEvent) *domain.ReworkReport {
+	report := &domain.ReworkReport{ByLine: []domain.ReworkStat{}, ByProcess: []domain.ReworkStat{}}
+
+	lines := newReworkTally()
+	totalChecked := 0
+	for _, c := range checked {
+		lines.get(c.ID, c.Name).Checked = c.Checked
+		totalChecked += c.Checked
+	}
+
+	processes := newReworkTally()
+	for _, e := range events {
+		lines.add(lines.get(e.LineID, e.LineName), e)
+		for _, p := range e.Processes {
+			stat := processes.get(p.ID, p.Name)
+			stat.Checked = totalChecked
+			processes.add(stat, e)
+		}
+	}
+
+	report.ByLine = lines.stats()
+	report.ByProcess = processes.stats()
+	sort.SliceStable(report.ByProcess, func(i, j int) bool {
+		return report.ByProcess[i].Reworked > report.ByProcess[j].Reworked
+	})
+	return report
+}
+
+type reworkTally struct {
+	order   []int64
+	byID    map[int64]*domain.ReworkStat
+	items   map[int64]map[int64]bool
+	minutes map[int64]float64
+}
+
+func newReworkTally() *reworkTally {
+	return &reworkTally{
+		byID:    map[int64]*domain.ReworkStat{},
+		items:   map[int64]map[int64]bool{},
+		minutes: map[int64]float64{},
+	}
+}
+
+func (t *reworkTally) get(id int64, name string) *domain.ReworkStat {
+	stat, ok := t.byID[id]
+	if !ok {
+		stat = &domain.ReworkStat{ID: id, Name: name}
+		t.byID[id] = stat
+		t.items[id] = map[int64]bool{}
+		t.order = append(t.order, id)
+	}
+	return stat
+}
+
+func (t *reworkTally) add(stat *domain.ReworkStat, e repository.ReworkEvent) {
+	if !t.items[stat.ID][e.ItemID] {
+		t.items[stat.ID][e.ItemID] = true
+		stat.Reworked++
+	}
+	if e.ReturnedAt == nil {
+		stat.Open++
+		return
+	}
+	stat.Returned++
+	t.minutes[stat.ID] += e.ReturnedAt.Sub(e.ReworkAt).Minutes()
+}
+
+func (t *reworkTally) stats() []domain.ReworkStat {
+	stats := make([]domain.ReworkStat, 0, len(t.order))
+	for _, id := range t.order {
+		stat := *t.byID[id]
+		if stat.Checked > 0 {
+			stat.ReworkRate = round2(float64(stat.Reworked) * 100 / float64(stat.Checked))
+		}
+		if stat.Returned > 0 {
+			stat.AvgTurnaroundMinutes = round2(t.minutes[id] / float64(stat.Returned))
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
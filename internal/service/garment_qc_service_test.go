@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock GarmentQCRepository for testing
+type mockGarmentQCRepository struct {
+	request   *domain.LineRequest
+	qr        *domain.QRSystem
+	existing  *domain.RequestItem
+	processes map[int64]*domain.OrderProcess
+	created   *domain.RequestItem
+	updated   *domain.RequestItem
+	createErr error
+}
+
+func (m *mockGarmentQCRepository) FindActiveRequest(ctx context.Context, lineID int64) (*domain.LineRequest, error) {
+	return m.request, nil
+}
+
+func (m *mockGarmentQCRepository) FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error) {
+	if m.request == nil || m.request.ID != id {
+		return nil, nil
+	}
+	return m.request, nil
+}
+
+func (m *mockGarmentQCRepository) FindQRSystemByCode(ctx context.Context, code string) (*domain.QRSystem, error) {
+	if m.qr == nil || m.qr.Code != code {
+		return nil, nil
+	}
+	return m.qr, nil
+}
+
+func (m *mockGarmentQCRepository) FindItemByCode(ctx context.Context, qrCode string) (*domain.RequestItem, error) {
+	return m.existing, nil
+}
+
+func (m *mockGarmentQCRepository) FindItemByID(ctx context.Context, id int64) (*domain.RequestItem, error) {
+	if m.existing == nil || m.existing.ID != id {
+		return nil, nil
+	}
+	item := *m.existing
+	return &item, nil
+}
+
+func (m *mockGarmentQCRepository) DefectTypeExists(ctx context.Context, id int64) (bool, error) {
+	return id == 1, nil
+}
+
+func (m *mockGarmentQCRepository) FindOrderProcess(ctx context.Context, orderID, id int64) (*domain.OrderProcess, error) {
+	p := m.processes[id]
+	if p == nil || p.OrderID != orderID {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (m *mockGarmentQCRepository) CreateItem(ctx context.Context, item *domain.RequestItem) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	item.ID = 100
+	m.created = item
+	return nil
+}
+
+func (m *mockGarmentQCRepository) UpdateItemStage(ctx context.Context, item *domain.RequestItem) error {
+	m.updated = item
+	return nil
+}
+
+func (m *mockGarmentQCRepository) GetCheckedByLine(ctx context.Context, filter domain.ReworkFilter) ([]domain.ReworkStat, error) {
+	return nil, nil
+}
+
+func (m *mockGarmentQCRepository) GetReworkEvents(ctx context.Context, filter domain.ReworkFilter) ([]repository.ReworkEvent, error) {
+	return nil, nil
+}
+
+func (m *mockGarmentQCRepository) GetRequestCounts(ctx context.Context, requestID int64) (*domain.LineQCSummary, error) {
+	return &domain.LineQCSummary{}, nil
+}
+
+func newGarmentQCRepo() *mockGarmentQCRepository {
+	processID := int64(7)
+	return &mockGarmentQCRepository{
+		request: &domain.LineRequest{ID: 5, OrderID: 2, LineID: 1},
+		qr:      &domain.QRSystem{ID: 223, Code: "223", OrderID: 2},
+		processes: map[int64]*domain.OrderProcess{
+			11: {ID: 11, OrderID: 2, ProcessID: &processID},
+			12: {ID: 12, OrderID: 3},
+		},
+	}
+}
+
+func TestGarmentScan_Results(t *testing.T) {
+	testCases := []struct {
+		result string
+		stage  string
+		cncm   bool
+		rework bool
+	}{
+		{domain.GarmentResultPass, domain.GarmentStageFinishing, false, false},
+		{domain.GarmentResultFail, domain.GarmentStageFinishing, true, false},
+		{domain.GarmentResultDefect, domain.GarmentStageProcess, false, true},
+	}
+
+	for _, tc := range testCases {
+		repo := newGarmentQCRepo()
+		svc := NewGarmentQCService(repo, nil, nil, nil)
+
+		input := GarmentScanInput{LineID: 1, QRCode: " 223-4 ", Result: tc.result}
+		if tc.result == domain.GarmentResultDefect {
+			input.Defects = []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}}
+		}
+
+		item, err := svc.Scan(context.Background(), input)
+		if err != nil {
+			t.Fatalf("%s: expected success, got %v", tc.result, err)
+		}
+		if item.QRCode != "223-4" || item.RequestID != 5 || item.QRSystemID != 223 {
+			t.Errorf("%s: unexpected item %+v", tc.result, item)
+		}
+		if item.Stage != tc.stage || item.IsCNCM != tc.cncm || item.IsRework != tc.rework {
+			t.Errorf("%s: expected stage %s cncm %v rework %v, got %+v", tc.result, tc.stage, tc.cncm, tc.rework, item)
+		}
+	}
+}
+
+func TestGarmentScan_DefectProcess(t *testing.T) {
+	repo := newGarmentQCRepo()
+	svc := NewGarmentQCService(repo, nil, nil, nil)
+
+	item, err := svc.Scan(context.Background(), GarmentScanInput{
+		LineID: 1, QRCode: "223-1", Result: domain.GarmentResultDefect,
+		Defects: []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}},
+	})
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(item.Defects) != 1 || item.Defects[0].ProcessID == nil || *item.Defects[0].ProcessID != 7 {
+		t.Errorf("Expected defect linked to process 7, got %+v", item.Defects)
+	}
+
+	_, err = svc.Scan(context.Background(), GarmentScanInput{
+		LineID: 1, QRCode: "223-2", Result: domain.GarmentResultDefect,
+		Defects: []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 12}},
+	})
+	if !errors.Is(err, ErrOrderProcessNotFound) {
+		t.Errorf("Expected ErrOrderProcessNotFound for a process of another order, got %v", err)
+	}
+
+	_, err = svc.Scan(context.Background(), GarmentScanInput{
+		LineID: 1, QRCode: "223-3", Result: domain.GarmentResultDefect,
+		Defects: []GarmentDefectInput{{DefectTypeID: 9, OrderProcessID: 11}},
+	})
+	if !errors.Is(err, ErrDefectTypeNotFound) {
+		t.Errorf("Expected ErrDefectTypeNotFound, got %v", err)
+	}
+}
+
+func TestGarmentScan_Rejected(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		setup    func(*mockGarmentQCRepository)
+		expected error
+	}{
+		{"invalid code", "223", nil, ErrInvalidGarmentCode},
+		{"invalid piece", "223-x", nil, ErrInvalidGarmentCode},
+		{"no active request", "223-1", func(m *mockGarmentQCRepository) { m.request = nil }, ErrNoActiveRequest},
+		{"unknown bundle", "999-1", nil, ErrQRSystemNotFound},
+		{"other order", "223-1", func(m *mockGarmentQCRepository) { m.qr.OrderID = 3 }, ErrGarmentOrderMismatch},
+		{"already checked", "223-1", func(m *mockGarmentQCRepository) { m.existing = &domain.RequestItem{ID: 1} }, ErrGarmentAlreadyChecked},
+		{"checked concurrently", "223-1", func(m *mockGarmentQCRepository) { m.createErr = repository.ErrItemAlreadyChecked }, ErrGarmentAlreadyChecked},
+	}
+
+	for _, tc := range testCases {
+		repo := newGarmentQCRepo()
+		if tc.setup != nil {
+			tc.setup(repo)
+		}
+		svc := NewGarmentQCService(repo, nil, nil, nil)
+
+		_, err := svc.Scan(context.Background(), GarmentScanInput{LineID: 1, QRCode: tc.code, Result: domain.GarmentResultPass})
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+		if repo.created != nil {
+			t.Errorf("%s: expected no item to be created", tc.name)
+		}
+	}
+}
+
+func TestGarmentRework_SendAndReturn(t *testing.T) {
+	repo := newGarmentQCRepo()
+	repo.existing = &domain.RequestItem{ID: 3, OrderID: 2, Stage: domain.GarmentStageFinishing, Result: domain.GarmentResultPass}
+	svc := NewGarmentQCService(repo, nil, nil, nil)
+
+	item, err := svc.SendToRework(context.Background(), 3, []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}})
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if !item.IsRework || item.Stage != domain.GarmentStageProcess || len(item.Defects) != 1 {
+		t.Errorf("Expected piece in rework with one defect, got %+v", item)
+	}
+
+	if _, err := svc.ReturnFromRework(context.Background(), 3, domain.GarmentResultPass, nil); !errors.Is(err, ErrGarmentNotInRework) {
+		t.Errorf("Expected ErrGarmentNotInRework before the piece is stored in rework, got %v", err)
+	}
+
+	repo.existing = repo.updated
+	if _, err := svc.SendToRework(context.Background(), 3, []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}}); !errors.Is(err, ErrGarmentInRework) {
+		t.Errorf("Expected ErrGarmentInRework, got %v", err)
+	}
+
+	item, err = svc.ReturnFromRework(context.Background(), 3, domain.GarmentResultPass, nil)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if item.IsRework || item.Stage != domain.GarmentStageFinishing || item.Result != domain.GarmentResultPass || len(item.Defects) != 0 {
+		t.Errorf("Expected repaired piece to move on to finishing, got %+v", item)
+	}
+}
+
+func TestGarmentRework_PublishesBuyer(t *testing.T) {
+	buyerID := int64(9)
+	repo := newGarmentQCRepo()
+	repo.request.BuyerID = &buyerID
+	repo.existing = &domain.RequestItem{ID: 3, RequestID: 5, OrderID: 2, Stage: domain.GarmentStageFinishing, Result: domain.GarmentResultPass}
+	stream := NewMovementStream(10)
+	sub, _, _ := stream.Subscribe(0, MovementFilter{BuyerID: &buyerID})
+	defer stream.Unsubscribe(sub)
+	svc := NewGarmentQCService(repo, nil, nil, stream)
+
+	if _, err := svc.SendToRework(context.Background(), 3, []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}}); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	repo.existing = repo.updated
+	if _, err := svc.ReturnFromRework(context.Background(), 3, domain.GarmentResultPass, nil); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+
+	for _, stage := range []string{domain.GarmentStageProcess, domain.GarmentStageFinishing} {
+		select {
+		case e := <-sub.C:
+			if e.Stage != stage || e.BuyerID == nil || *e.BuyerID != buyerID {
+				t.Errorf("Expected a move to %s for buyer 9, got %+v", stage, e)
+			}
+		default:
+			t.Fatalf("Expected the move to %s to reach the buyer filter", stage)
+		}
+	}
+}
+
+func TestGarmentRework_PackedPiece(t *testing.T) {
+	repo := newGarmentQCRepo()
+	repo.existing = &domain.RequestItem{ID: 3, OrderID: 2, Stage: domain.GarmentStagePacking}
+	svc := NewGarmentQCService(repo, nil, nil, nil)
+
+	_, err := svc.SendToRework(context.Background(), 3, []GarmentDefectInput{{DefectTypeID: 1, OrderProcessID: 11}})
+	if !errors.Is(err, ErrGarmentPacked) {
+		t.Errorf("Expected ErrGarmentPacked, got %v", err)
+	}
+	if _, err := svc.SendToRework(context.Background(), 4, nil); !errors.Is(err, ErrGarmentItemNotFound) {
+		t.Errorf("Expected ErrGarmentItemNotFound, got %v", err)
+	}
+}
+
+func TestBuildReworkReport(t *testing.T) {
+	start := time.Date(2026, 9, 1, 8, 0, 0, 0, time.Local)
+	back := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	hem := repository.ReworkProcess{ID: 11, Name: "Hem"}
+	collar := repository.ReworkProcess{ID: 12, Name: "Collar"}
+
+	checked := []domain.ReworkStat{
+		{ID: 1, Name: "Line 1", Checked: 40},
+		{ID: 2, Name: "Line 2", Checked: 10},
+	}
+	events := []repository.ReworkEvent{
+		{ItemID: 100, LineID: 1, ReworkAt: start, ReturnedAt: back(30), Processes: []repository.ReworkProcess{hem}},
+		{ItemID: 100, LineID: 1, ReworkAt: start, ReturnedAt: back(10), Processes: []repository.ReworkProcess{hem}},
+		{ItemID: 101, LineID: 1, ReworkAt: start, Processes: []repository.ReworkProcess{hem, collar}},
+		{ItemID: 200, LineID: 2, ReworkAt: start, ReturnedAt: back(60), Processes: []repository.ReworkProcess{collar}},
+	}
+
+	report := BuildReworkReport(checked, events)
+
+	if len(report.ByLine) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(report.ByLine))
+	}
+	line1 := report.ByLine[0]
+	if line1.Reworked != 2 || line1.Returned != 2 || line1.Open != 1 {
+		t.Errorf("Expected line 1 with 2 pieces, 2 returned trips and 1 open, got %+v", line1)
+	}
+	if line1.ReworkRate != 5 || line1.AvgTurnaroundMinutes != 20 {
+		t.Errorf("Expected line 1 rate 5%% and 20 minutes, got %v%% and %v", line1.ReworkRate, line1.AvgTurnaroundMinutes)
+	}
+	if line2 := report.ByLine[1]; line2.ReworkRate != 10 || line2.AvgTurnaroundMinutes != 60 {
+		t.Errorf("Expected line 2 rate 10%% and 60 minutes, got %+v", line2)
+	}
+
+	if len(report.ByProcess) != 2 || report.ByProcess[0].ID != hem.ID {
+		t.Fatalf("Expected hem to cause most rework, got %+v", report.ByProcess)
+	}
+	if p := report.ByProcess[0]; p.Reworked != 2 || p.Checked != 50 || p.ReworkRate != 4 {
+		t.Errorf("Expected hem to rework 2 of 50 pieces, got %+v", p)
+	}
+}
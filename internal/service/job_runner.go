@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrUnknownJob       = errors.New("job is not registered")
+	ErrJobBatchNotFound = errors.New("job batch is not found")
+	ErrEmptyJobBatch    = errors.New("job batch has no jobs")
+)
+
+// JobHandler runs a job with the data it was dispatched with. Returning an
+// error retries the job until it runs out of tries.
+type JobHandler func(ctx context.Context, data json.RawMessage) error
+
+// JobInput is a job to dispatch.
+type JobInput struct {
+	Name        string
+	DisplayName string
+	Data        interface{}
+	// MaxTries overrides the runner's default when positive.
+	MaxTries int
+}
+
+// JobSettings configures the job runner. RetryAfter has to be longer than
+// Timeout, or a job that is still running is picked up a second time.
+type JobSettings struct {
+	Queue        string
+	Workers      int
+	MaxTries     int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+	RetryAfter   time.Duration
+	PollInterval time.Duration
+}
+
+// JobRunner is an in-process worker pool on the jobs table. Failed jobs are
+// retried with backoff and end up in failed_jobs; batches are tracked in
+// job_batches.
+type JobRunner struct {
+	repo     repository.JobRepository
+	settings JobSettings
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	wake     chan struct{}
+}
+
+func NewJobRunner(repo repository.JobRepository, settings JobSettings) *JobRunner {
+	if settings.Queue == "" {
+		settings.Queue = "default"
+	}
+	if settings.MaxTries <= 0 {
+		settings.MaxTries = 1
+	}
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = 3 * time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = time.Minute
+	}
+	if settings.RetryAfter <= settings.Timeout {
+		settings.RetryAfter = settings.Timeout + 30*time.Second
+	}
+	return &JobRunner{
+		repo:     repo,
+		settings: settings,
+		handlers: map[string]JobHandler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler of a job name.
+func (r *JobRunner) Register(name string, handler JobHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = handler
+}
+
+func (r *JobRunner) handler(name string) JobHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[name]
+}
+
+// Dispatch queues a job and returns its uuid.
+func (r *JobRunner) Dispatch(ctx context.Context, input JobInput) (string, error) {
+	job, payload, err := r.newJob(input, "")
+	if err != nil {
+		return "", err
+	}
+	if err := r.repo.Push(ctx, job); err != nil {
+		return "", err
+	}
+	r.notify()
+	return payload.UUID, nil
+}
+
+// DispatchBatch queues the jobs as one batch, so their progress can be
+// followed with Batch.
+func (r *JobRunner) DispatchBatch(ctx context.Context, name string, inputs []JobInput) (*domain.JobBatch, error) {
+	if len(inputs) == 0 {
+		return nil, ErrEmptyJobBatch
+	}
+	id := newUUID()
+	jobs := make([]*domain.Job, 0, len(inputs))
+	for _, input := range inputs {
+		job, _, err := r.newJob(input, id)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	batch := &domain.JobBatch{
+		ID:           id,
+		Name:         name,
+		TotalJobs:    len(jobs),
+		PendingJobs:  len(jobs),
+		FailedJobIDs: []string{},
+		CreatedAt:    time.Now(),
+	}
+	if err := r.repo.CreateBatch(ctx, batch, jobs); err != nil {
+		return nil, err
+	}
+	r.notify()
+	return batch, nil
+}
+
+func (r *JobRunner) newJob(input JobInput, batchID string) (*domain.Job, *domain.JobPayload, error) {
+	if r.handler(input.Name) == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownJob, input.Name)
+	}
+	data, err := json.Marshal(input.Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode job data: %w", err)
+	}
+	payload := &domain.JobPayload{
+		UUID:        newUUID(),
+		DisplayName: input.DisplayName,
+		Job:         input.Name,
+		MaxTries:    input.MaxTries,
+		Timeout:     int(r.settings.Timeout.Seconds()),
+		BatchID:     batchID,
+		Data:        data,
+	}
+	if payload.DisplayName == "" {
+		payload.DisplayName = input.Name
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	now := time.Now().Unix()
+	return &domain.Job{Queue: r.settings.Queue, Payload: string(encoded), AvailableAt: now, CreatedAt: now}, payload, nil
+}
+
+// Batch returns the progress of a batch.
+func (r *JobRunner) Batch(ctx context.Context, id string) (*domain.JobBatch, error) {
+	batch, err := r.repo.FindBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrJobBatchNotFound
+	}
+	batch.ProcessedJobs = batch.TotalJobs - batch.PendingJobs
+	if batch.TotalJobs > 0 {
+		batch.Progress = batch.ProcessedJobs * 100 / batch.TotalJobs
+	}
+	return batch, nil
+}
+
+// Run starts the workers and blocks until ctx is cancelled and the jobs they
+// are running have finished. Running jobs are not cancelled with ctx; they
+// get their own timeout instead.
+func (r *JobRunner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < r.settings.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			r.work(ctx, worker)
+		}(i + 1)
+	}
+	log.Info().Int("workers", r.settings.Workers).Str("queue", r.settings.Queue).Msg("Started job runner")
+	wg.Wait()
+	log.Info().Msg("Stopped job runner")
+}
+
+func (r *JobRunner) work(ctx context.Context, worker int) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		ran, err := r.RunNext(ctx)
+		if err != nil {
+			log.Error().Err(err).Int("worker", worker).Msg("Failed to run job")
+		}
+		if ran && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.settings.PollInterval):
+		case <-r.wake:
+		}
+	}
+}
+
+// RunNext reserves and runs the next available job. It reports whether there
+// was one.
+func (r *JobRunner) RunNext(ctx context.Context) (bool, error) {
+	job, err := r.repo.Reserve(ctx, r.settings.Queue, time.Now(), r.settings.RetryAfter)
+	if err != nil || job == nil {
+		return false, err
+	}
+	// The job finishes, and is stored, even when the runner is stopping
+	jobCtx := context.WithoutCancel(ctx)
+
+	var payload domain.JobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		payload = domain.JobPayload{UUID: newUUID()}
+		return true, r.repo.Fail(jobCtx, job, &payload, fmt.Sprintf("invalid job payload: %v", err))
+	}
+	if payload.UUID == "" {
+		payload.UUID = newUUID()
+	}
+	logger := log.With().Int64("job_id", job.ID).Str("job", payload.Job).Int("attempt", job.Attempts).Logger()
+
+	maxTries := r.settings.MaxTries
+	if payload.MaxTries > 0 {
+		maxTries = payload.MaxTries
+	}
+	// A job reserved again after its worker died has used its attempts
+	// without failing; running it once more could crash this worker too.
+	if job.Attempts > maxTries {
+		logger.Error().Msg("Job attempted too many times")
+		return true, r.repo.Fail(jobCtx, job, &payload, fmt.Sprintf("%s has been attempted too many times", payload.Job))
+	}
+
+	if payload.BatchID != "" {
+		batch, err := r.repo.FindBatch(jobCtx, payload.BatchID)
+		if err != nil {
+			return true, err
+		}
+		if batch != nil && batch.CancelledAt != nil {
+			logger.Info().Str("batch_id", payload.BatchID).Msg("Skipped job of cancelled batch")
+			return true, r.repo.Complete(jobCtx, job, payload.BatchID)
+		}
+	}
+
+	handler := r.handler(payload.Job)
+	if handler == nil {
+		return true, r.repo.Fail(jobCtx, job, &payload, fmt.Sprintf("%v: %s", ErrUnknownJob, payload.Job))
+	}
+
+	started := time.Now()
+	runErr := r.call(jobCtx, handler, payload.Data)
+	if runErr == nil {
+		logger.Info().Dur("duration", time.Since(started)).Msg("Processed job")
+		return true, r.repo.Complete(jobCtx, job, payload.BatchID)
+	}
+
+	if job.Attempts >= maxTries {
+		logger.Error().Err(runErr).Msg("Job failed")
+		return true, r.repo.Fail(jobCtx, job, &payload, runErr.Error())
+	}
+
+	delay := RetryBackoff(job.Attempts, r.settings.Backoff, r.settings.MaxBackoff)
+	logger.Warn().Err(runErr).Dur("retry_in", delay).Msg("Job failed, retrying")
+	return true, r.repo.Release(jobCtx, job, time.Now().Add(delay))
+}
+
+// call runs the handler within the job timeout and turns a panic into an
+// error with its stack trace.
+func (r *JobRunner) call(ctx context.Context, handler JobHandler, data json.RawMessage) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.settings.Timeout)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v\n%s", recovered, debug.Stack())
+		}
+	}()
+	return handler(ctx, data)
+}
+
+func (r *JobRunner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// mockJobRepository keeps the queue in memory. Released jobs become available
+// at the given time, so tests move the clock by editing AvailableAt.
+type mockJobRepository struct {
+	mu      sync.Mutex
+	jobs    []*domain.Job
+	failed  map[string]string
+	batches map[string]*domain.JobBatch
+}
+
+func newMockJobRepository() *mockJobRepository {
+	return &mockJobRepository{failed: map[string]string{}, batches: map[string]*domain.JobBatch{}}
+}
+
+func (m *mockJobRepository) Push(ctx context.Context, job *domain.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = int64(len(m.jobs) + 1)
+	m.jobs = append(m.jobs, job)
+	return nil
+}
+
+func (m *mockJobRepository) CreateBatch(ctx context.Context, batch *domain.JobBatch, jobs []*domain.Job) error {
+	m.mu.Lock()
+	stored := *batch
+	m.batches[batch.ID] = &stored
+	m.mu.Unlock()
+	for _, job := range jobs {
+		m.Push(ctx, job)
+	}
+	return nil
+}
+
+func (m *mockJobRepository) Reserve(ctx context.Context, queue string, now time.Time, retryAfter time.Duration) (*domain.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.Queue == queue && job.ReservedAt == nil && job.AvailableAt <= now.Unix() {
+			reservedAt := now.Unix()
+			job.ReservedAt = &reservedAt
+			job.Attempts++
+			reserved := *job
+			return &reserved, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockJobRepository) remove(id int64) {
+	for i, job := range m.jobs {
+		if job.ID == id {
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *mockJobRepository) Complete(ctx context.Context, job *domain.Job, batchID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(job.ID)
+	if batch := m.batches[batchID]; batch != nil {
+		batch.PendingJobs--
+	}
+	return nil
+}
+
+func (m *mockJobRepository) Release(ctx context.Context, job *domain.Job, availableAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stored := range m.jobs {
+		if stored.ID == job.ID {
+			stored.ReservedAt = nil
+			stored.AvailableAt = availableAt.Unix()
+		}
+	}
+	return nil
+}
+
+func (m *mockJobRepository) Fail(ctx context.Context, job *domain.Job, payload *domain.JobPayload, exception string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(job.ID)
+	m.failed[payload.UUID] = exception
+	if batch := m.batches[payload.BatchID]; batch != nil {
+		batch.FailedJobs++
+		batch.FailedJobIDs = append(batch.FailedJobIDs, payload.UUID)
+	}
+	return nil
+}
+
+func (m *mockJobRepository) FindBatch(ctx context.Context, id string) (*domain.JobBatch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if batch := m.batches[id]; batch != nil {
+		found := *batch
+		return &found, nil
+	}
+	return nil, nil
+}
+
+func (m *mockJobRepository) makeAvailable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		job.AvailableAt = 0
+	}
+}
+
+func newJobRunnerForTest(repo *mockJobRepository) *JobRunner {
+	return NewJobRunner(repo, JobSettings{Queue: "api", Workers: 2, MaxTries: 3, Backoff: time.Minute, MaxBackoff: time.Hour, PollInterval: 10 * time.Millisecond})
+}
+
+func TestJobRunner_RetriesThenFails(t *testing.T) {
+	repo := newMockJobRepository()
+	runner := newJobRunnerForTest(repo)
+	calls := 0
+	runner.Register("flaky", func(ctx context.Context, data json.RawMessage) error {
+		calls++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected the job to run with a timeout")
+		}
+		return errors.New("supplier table is locked")
+	})
+
+	uuid, err := runner.Dispatch(context.Background(), JobInput{Name: "flaky", Data: map[string]string{"period": "2025-01"}})
+	if err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+
+	if ran, err := runner.RunNext(context.Background()); !ran || err != nil {
+		t.Fatalf("RunNext = %v, %v", ran, err)
+	}
+	job := repo.jobs[0]
+	if job.ReservedAt != nil || job.AvailableAt < time.Now().Add(50*time.Second).Unix() {
+		t.Fatalf("Expected the job to be released for about a minute, got %+v", job)
+	}
+	if ran, _ := runner.RunNext(context.Background()); ran {
+		t.Fatal("Expected no job before the backoff has passed")
+	}
+
+	for i := 0; i < 2; i++ {
+		repo.makeAvailable()
+		runner.RunNext(context.Background())
+	}
+	if calls != 3 || len(repo.jobs) != 0 {
+		t.Fatalf("Expected 3 tries and an empty queue, got %d tries and %d jobs", calls, len(repo.jobs))
+	}
+	if repo.failed[uuid] != "supplier table is locked" {
+		t.Errorf("Expected the job in failed_jobs, got %v", repo.failed)
+	}
+
+	if _, err := runner.Dispatch(context.Background(), JobInput{Name: "missing"}); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Expected ErrUnknownJob, got %v", err)
+	}
+}
+
+// A job whose worker died mid-run is reserved again with another attempt
+// counted. Once that exceeds the tries it fails without running.
+func TestJobRunner_FailsJobAttemptedTooManyTimes(t *testing.T) {
+	repo := newMockJobRepository()
+	runner := newJobRunnerForTest(repo)
+	calls := 0
+	runner.Register("crash", func(ctx context.Context, data json.RawMessage) error {
+		calls++
+		return nil
+	})
+
+	uuid, _ := runner.Dispatch(context.Background(), JobInput{Name: "crash"})
+	repo.jobs[0].Attempts = 3
+
+	if ran, err := runner.RunNext(context.Background()); !ran || err != nil {
+		t.Fatalf("RunNext = %v, %v", ran, err)
+	}
+	if calls != 0 || len(repo.jobs) != 0 {
+		t.Fatalf("Expected the job to fail without running, got %d runs and %d jobs", calls, len(repo.jobs))
+	}
+	if repo.failed[uuid] != "crash has been attempted too many times" {
+		t.Errorf("Unexpected failure: %q", repo.failed[uuid])
+	}
+}
+
+func TestJobRunner_TracksBatch(t *testing.T) {
+	repo := newMockJobRepository()
+	runner := newJobRunnerForTest(repo)
+	runner.Register("compute", func(ctx context.Context, data json.RawMessage) error {
+		var input struct {
+			Period string `json:"period"`
+		}
+		json.Unmarshal(data, &input)
+		if input.Period == "2025-02" {
+			panic("division by zero")
+		}
+		return nil
+	})
+
+	batch, err := runner.DispatchBatch(context.Background(), "Recompute", []JobInput{
+		{Name: "compute", Data: map[string]string{"period": "2025-01"}},
+		{Name: "compute", Data: map[string]string{"period": "2025-02"}, MaxTries: 1},
+		{Name: "compute", Data: map[string]string{"period": "2025-03"}},
+	})
+	if err != nil {
+		t.Fatalf("DispatchBatch returned error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		runner.RunNext(context.Background())
+	}
+
+	got, err := runner.Batch(context.Background(), batch.ID)
+	if err != nil {
+		t.Fatalf("Batch returned error: %v", err)
+	}
+	if got.TotalJobs != 3 || got.PendingJobs != 1 || got.FailedJobs != 1 || got.ProcessedJobs != 2 || got.Progress != 66 {
+		t.Errorf("Unexpected batch progress: %+v", got)
+	}
+	for _, exception := range repo.failed {
+		if !strings.HasPrefix(exception, "panic: division by zero") {
+			t.Errorf("Expected the panic to be stored, got %q", exception)
+		}
+	}
+
+	if _, err := runner.Batch(context.Background(), "missing"); !errors.Is(err, ErrJobBatchNotFound) {
+		t.Errorf("Expected ErrJobBatchNotFound, got %v", err)
+	}
+}
+
+func TestJobRunner_FinishesRunningJobOnShutdown(t *testing.T) {
+	repo := newMockJobRepository()
+	runner := newJobRunnerForTest(repo)
+	started, finished := make(chan struct{}), make(chan struct{})
+	runner.Register("slow", func(ctx context.Context, data json.RawMessage) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		if ctx.Err() != nil {
+			t.Error("Expected the job context to survive the shutdown")
+		}
+		close(finished)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	runner.Dispatch(context.Background(), JobInput{Name: "slow"})
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return after the shutdown")
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Expected Run to wait for the running job")
+	}
+	if len(repo.jobs) != 0 {
+		t.Errorf("Expected the finished job to be removed, %d left", len(repo.jobs))
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/cache"
+	"github.com/rs/zerolog/log"
+)
+
+// MasterList is an encoded master data list with the validators clients use
+// for conditional requests.
+type MasterList struct {
+	ETag         string          `json:"etag"`
+	LastModified time.Time       `json:"last_modified"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// MasterCache keeps the encoded master data lists until they are written to
+// or ttl passes. Writes made by the web application are not seen until then.
+type MasterCache struct {
+	store cache.Store
+	ttl   time.Duration
+
+	// loading makes concurrent misses wait for a single load instead of
+	// scanning the table once each.
+	loading sync.Mutex
+	mu      sync.Mutex
+	// generations counts the invalidations of each table, so a load that
+	// raced with a write does not store what it read before the write.
+	generations map[repository.MasterTable]int
+}
+
+func NewMasterCache(store cache.Store, ttl time.Duration) *MasterCache {
+	return &MasterCache{store: store, ttl: ttl, generations: map[repository.MasterTable]int{}}
+}
+
+func masterCacheKey(table repository.MasterTable) string {
+	return "master:" + string(table)
+}
+
+// List returns the cached list of table, calling load on a miss. A nil cache
+// always calls load. Cache errors are logged and the list is loaded instead.
+func (c *MasterCache) List(ctx context.Context, table repository.MasterTable, load func() (interface{}, error)) (*MasterList, error) {
+	if c == nil {
+		return newMasterList(load)
+	}
+	if list := c.get(ctx, table); list != nil {
+		return list, nil
+	}
+
+	c.loading.Lock()
+	defer c.loading.Unlock()
+	if list := c.get(ctx, table); list != nil {
+		return list, nil
+	}
+
+	generation := c.generation(table)
+	list, err := newMasterList(load)
+	if err != nil {
+		return nil, err
+	}
+	if c.generation(table) != generation {
+		return list, nil
+	}
+	encoded, err := json.Marshal(list)
+	if err == nil {
+		err = c.store.Put(ctx, masterCacheKey(table), encoded, c.ttl)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("table", string(table)).Msg("Failed to cache master data")
+	}
+	return list, nil
+}
+
+func (c *MasterCache) get(ctx context.Context, table repository.MasterTable) *MasterList {
+	encoded, ok, err := c.store.Get(ctx, masterCacheKey(table))
+	if err != nil {
+		log.Warn().Err(err).Str("table", string(table)).Msg("Failed to read cached master data")
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var list MasterList
+	if err := json.Unmarshal(encoded, &list); err != nil {
+		return nil
+	}
+	return &list
+}
+
+// Forget drops the cached list of table after it was written to.
+func (c *MasterCache) Forget(ctx context.Context, table repository.MasterTable) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.generations[table]++
+	c.mu.Unlock()
+
+	if err := c.store.Forget(ctx, masterCacheKey(table)); err != nil {
+		log.Error().Err(err).Str("table", string(table)).Msg("Failed to invalidate cached master data")
+	}
+}
+
+func (c *MasterCache) generation(table repository.MasterTable) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[table]
+}
+
+func newMasterList(load func() (interface{}, error)) (*MasterList, error) {
+	rows, err := load()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode master data: %w", err)
+	}
+	return &MasterList{
+		ETag:         MasterETag(data),
+		LastModified: time.Now().UTC().Truncate(time.Second),
+		Data:         data,
+	}, nil
+}
+
+// MasterETag returns the strong entity tag of an encoded list.
+func MasterETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
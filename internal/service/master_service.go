@@ -1,30 +1,498 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/dppi/dppierp-api/internal/domain"
 	"github.com/dppi/dppierp-api/internal/repository"
 )
 
+var (
+	ErrMasterNotFound   = errors.New("master data is not found")
+	ErrMasterNameTaken  = errors.New("the name has already been taken")
+	ErrMasterInUse      = errors.New("master data is still in use")
+	ErrMasterNotDeleted = errors.New("master data is not deleted")
+	ErrDefectKeyTaken   = errors.New("the key has already been taken")
+	ErrStageMovement    = errors.New("movement type belongs to a stage and cannot be renamed or deleted")
+)
+
 type MasterService struct {
-	repo repository.MasterRepository
+	repo     repository.MasterRepository
+	activity *ActivityService
+	cache    *MasterCache
+}
+
+func NewMasterService(repo repository.MasterRepository, activity *ActivityService, cache *MasterCache) *MasterService {
+	return &MasterService{repo: repo, activity: activity, cache: cache}
+}
+
+// List returns the active rows of a master table, cached until the table is
+// written to through this service.
+func (s *MasterService) List(ctx context.Context, table repository.MasterTable) (*MasterList, error) {
+	return s.cache.List(ctx, table, func() (interface{}, error) {
+		switch table {
+		case repository.MasterBlocks:
+			return s.repo.GetAllBlocks()
+		case repository.MasterRacks:
+			return s.repo.GetAllRacks()
+		case repository.MasterRelaxationBlocks:
+			return s.repo.GetAllRelaxationBlocks()
+		case repository.MasterRelaxationRacks:
+			return s.repo.GetAllRelaxationRacks()
+		case repository.MasterUnits:
+			return s.repo.GetAllUnits()
+		case repository.MasterDefectTypes:
+			return s.repo.GetAllDefectTypes()
+		case repository.MasterMovementTypes:
+			return s.repo.GetAllMovementTypes()
+		}
+		return nil, fmt.Errorf("unknown master table %s", table)
+	})
 }
 
-func NewMasterService(repo repository.MasterRepository) *MasterService {
-	return &MasterService{repo: repo}
+func (s *MasterService) CreateBlock(ctx context.Context, block *domain.Block) error {
+	block.Name = strings.TrimSpace(block.Name)
+	if err := s.checkName(repository.MasterBlocks, block.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateBlock(block); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterBlocks, ActivityCreated, block.ID, nil, block)
+	return nil
 }
 
-func (s *MasterService) GetAllBlocks() ([]domain.Block, error) {
-	return s.repo.GetAllBlocks()
+func (s *MasterService) UpdateBlock(ctx context.Context, id int64, input *domain.Block) (*domain.Block, error) {
+	block, err := s.repo.FindBlockByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find block: %w", err)
+	}
+	if block == nil || block.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *block
+
+	block.Name = strings.TrimSpace(input.Name)
+	block.Type = input.Type
+	if err := s.checkName(repository.MasterBlocks, block.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateBlock(block); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterBlocks, ActivityUpdated, id, &old, block)
+	return block, nil
 }
 
-func (s *MasterService) GetAllRacks() ([]domain.Rack, error) {
-	return s.repo.GetAllRacks()
+func (s *MasterService) CreateRack(ctx context.Context, rack *domain.Rack) error {
+	rack.Name = strings.TrimSpace(rack.Name)
+	if err := s.checkName(repository.MasterRacks, rack.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateRack(rack); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterRacks, ActivityCreated, rack.ID, nil, rack)
+	return nil
 }
 
-func (s *MasterService) GetAllRelaxationBlocks() ([]domain.RelaxationBlock, error) {
-	return s.repo.GetAllRelaxationBlocks()
+func (s *MasterService) UpdateRack(ctx context.Context, id int64, input *domain.Rack) (*domain.Rack, error) {
+	rack, err := s.repo.FindRackByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rack: %w", err)
+	}
+	if rack == nil || rack.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *rack
+
+	rack.Name = strings.TrimSpace(input.Name)
+	rack.Type = input.Type
+	rack.Capacity = input.Capacity
+	if err := s.checkName(repository.MasterRacks, rack.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRack(rack); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterRacks, ActivityUpdated, id, &old, rack)
+	return rack, nil
+}
+
+func (s *MasterService) CreateRelaxationBlock(ctx context.Context, block *domain.RelaxationBlock) error {
+	block.Name = strings.TrimSpace(block.Name)
+	if err := s.checkName(repository.MasterRelaxationBlocks, block.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateRelaxationBlock(block); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterRelaxationBlocks, ActivityCreated, block.ID, nil, block)
+	return nil
+}
+
+func (s *MasterService) UpdateRelaxationBlock(ctx context.Context, id int64, input *domain.RelaxationBlock) (*domain.RelaxationBlock, error) {
+	block, err := s.repo.FindRelaxationBlockByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relaxation block: %w", err)
+	}
+	if block == nil || block.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *block
+
+	block.Name = strings.TrimSpace(input.Name)
+	if err := s.checkName(repository.MasterRelaxationBlocks, block.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRelaxationBlock(block); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterRelaxationBlocks, ActivityUpdated, id, &old, block)
+	return block, nil
+}
+
+func (s *MasterService) CreateRelaxationRack(ctx context.Context, rack *domain.RelaxationRack) error {
+	rack.Name = strings.TrimSpace(rack.Name)
+	if err := s.checkName(repository.MasterRelaxationRacks, rack.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateRelaxationRack(rack); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterRelaxationRacks, ActivityCreated, rack.ID, nil, rack)
+	return nil
+}
+
+func (s *MasterService) UpdateRelaxationRack(ctx context.Context, id int64, input *domain.RelaxationRack) (*domain.RelaxationRack, error) {
+	rack, err := s.repo.FindRelaxationRackByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relaxation rack: %w", err)
+	}
+	if rack == nil || rack.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *rack
+
+	rack.Name = strings.TrimSpace(input.Name)
+	if err := s.checkName(repository.MasterRelaxationRacks, rack.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRelaxationRack(rack); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterRelaxationRacks, ActivityUpdated, id, &old, rack)
+	return rack, nil
+}
+
+func (s *MasterService) CreateUnit(ctx context.Context, unit *domain.Unit) error {
+	unit.Name = strings.TrimSpace(unit.Name)
+	if err := s.checkName(repository.MasterUnits, unit.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateUnit(unit); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterUnits, ActivityCreated, unit.ID, nil, unit)
+	return nil
+}
+
+func (s *MasterService) UpdateUnit(ctx context.Context, id int64, input *domain.Unit) (*domain.Unit, error) {
+	unit, err := s.repo.FindUnitByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unit: %w", err)
+	}
+	if unit == nil || unit.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *unit
+
+	unit.Name = strings.TrimSpace(input.Name)
+	if err := s.checkName(repository.MasterUnits, unit.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateUnit(unit); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterUnits, ActivityUpdated, id, &old, unit)
+	return unit, nil
+}
+
+// CreateDefectType stores a defect type. Without a key, one is derived from
+// the name ("Broken stitch" becomes "broken-stitch").
+func (s *MasterService) CreateDefectType(ctx context.Context, defectType *domain.DefectType) error {
+	if err := s.prepareDefectType(defectType, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateDefectType(defectType); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterDefectTypes, ActivityCreated, defectType.ID, nil, defectType)
+	return nil
+}
+
+func (s *MasterService) UpdateDefectType(ctx context.Context, id int64, input *domain.DefectType) (*domain.DefectType, error) {
+	defectType, err := s.repo.FindDefectTypeByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find defect type: %w", err)
+	}
+	if defectType == nil || defectType.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *defectType
+
+	defectType.Name = input.Name
+	defectType.Key = input.Key
+	if err := s.prepareDefectType(defectType, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateDefectType(defectType); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterDefectTypes, ActivityUpdated, id, &old, defectType)
+	return defectType, nil
+}
+
+func (s *MasterService) prepareDefectType(defectType *domain.DefectType, excludeID int64) error {
+	defectType.Name = strings.TrimSpace(defectType.Name)
+	key := ""
+	if defectType.Key != nil {
+		key = strings.TrimSpace(*defectType.Key)
+	}
+	if key == "" {
+		key = slugify(defectType.Name)
+	}
+	defectType.Key = &key
+
+	if err := s.checkName(repository.MasterDefectTypes, defectType.Name, excludeID); err != nil {
+		return err
+	}
+	exists, err := s.repo.DefectKeyExists(key, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to check key: %w", err)
+	}
+	if exists {
+		return ErrDefectKeyTaken
+	}
+	return nil
+}
+
+func (s *MasterService) CreateMovementType(ctx context.Context, movementType *domain.MovementType) error {
+	movementType.Name = strings.TrimSpace(movementType.Name)
+	if err := s.checkName(repository.MasterMovementTypes, movementType.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateMovementType(movementType); err != nil {
+		return err
+	}
+	s.record(ctx, repository.MasterMovementTypes, ActivityCreated, movementType.ID, nil, movementType)
+	return nil
+}
+
+// UpdateMovementType renames a movement type. The rows backing a stage are
+// looked up by name when moving rolls, so they cannot be renamed.
+func (s *MasterService) UpdateMovementType(ctx context.Context, id int64, input *domain.MovementType) (*domain.MovementType, error) {
+	movementType, err := s.repo.FindMovementTypeByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find movement type: %w", err)
+	}
+	if movementType == nil || movementType.DeletedAt != nil {
+		return nil, ErrMasterNotFound
+	}
+	old := *movementType
+
+	name := strings.TrimSpace(input.Name)
+	if name == movementType.Name {
+		return movementType, nil
+	}
+	if domain.IsValidStage(movementType.Name) {
+		return nil, ErrStageMovement
+	}
+
+	movementType.Name = name
+	if err := s.checkName(repository.MasterMovementTypes, movementType.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMovementType(movementType); err != nil {
+		return nil, err
+	}
+	s.record(ctx, repository.MasterMovementTypes, ActivityUpdated, id, &old, movementType)
+	return movementType, nil
+}
+
+// CheckStageMovementTypes makes sure every stage has an active movement_types
+// row. Moving a roll falls back to movement type 1 when the stage's row is
+// missing, which would silently record the movement as inventory.
+func (s *MasterService) CheckStageMovementTypes() error {
+	types, err := s.repo.GetAllMovementTypes()
+	if err != nil {
+		return fmt.Errorf("failed to get movement types: %w", err)
+	}
+
+	names := make(map[string]bool, len(types))
+	for _, mt := range types {
+		names[mt.Name] = true
+	}
+
+	var missing []string
+	for _, stage := range domain.GetAllStages() {
+		if !names[stage.Name] {
+			missing = append(missing, stage.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("movement types are missing for stages: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Delete soft-deletes a master row. It is refused while fabric rolls or
+// other records still reference it, and for the movement types of a stage.
+func (s *MasterService) Delete(ctx context.Context, table repository.MasterTable, id int64) error {
+	name, deletedAt, err := s.find(table, id)
+	if err != nil {
+		return err
+	}
+	if deletedAt != nil {
+		return ErrMasterNotFound
+	}
+	if table == repository.MasterMovementTypes && domain.IsValidStage(name) {
+		return ErrStageMovement
+	}
+
+	usage, err := s.repo.CountUsage(table, id)
+	if err != nil {
+		return fmt.Errorf("failed to count usage: %w", err)
+	}
+	if usage > 0 {
+		return fmt.Errorf("%w (%d records)", ErrMasterInUse, usage)
+	}
+
+	if err := s.repo.SoftDelete(table, id); err != nil {
+		return err
+	}
+	s.record(ctx, table, ActivityDeleted, id, map[string]interface{}{"name": name}, nil)
+	return nil
+}
+
+// Restore brings back a soft-deleted master row, unless its name has been
+// reused by an active row in the meantime.
+func (s *MasterService) Restore(ctx context.Context, table repository.MasterTable, id int64) error {
+	name, deletedAt, err := s.find(table, id)
+	if err != nil {
+		return err
+	}
+	if deletedAt == nil {
+		return ErrMasterNotDeleted
+	}
+	if err := s.checkName(table, name, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Restore(table, id); err != nil {
+		return err
+	}
+	s.record(ctx, table, ActivityRestored, id, nil, map[string]interface{}{"name": name})
+	return nil
+}
+
+// record drops the cached list of the written table and logs the activity.
+func (s *MasterService) record(ctx context.Context, table repository.MasterTable, event string, id int64, old, new interface{}) {
+	s.cache.Forget(ctx, table)
+	s.activity.Record(ctx, ActivityInput{
+		LogName:     "master",
+		Event:       event,
+		SubjectType: repository.MasterSubjects[table],
+		SubjectID:   id,
+		Old:         old,
+		New:         new,
+	})
+}
+
+func (s *MasterService) checkName(table repository.MasterTable, name string, excludeID int64) error {
+	exists, err := s.repo.NameExists(table, name, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to check name: %w", err)
+	}
+	if exists {
+		return ErrMasterNameTaken
+	}
+	return nil
+}
+
+// find returns the name and deleted_at of a master row, including trashed rows.
+func (s *MasterService) find(table repository.MasterTable, id int64) (string, *time.Time, error) {
+	var (
+		name      string
+		deletedAt *time.Time
+		found     bool
+		err       error
+	)
+
+	switch table {
+	case repository.MasterBlocks:
+		var b *domain.Block
+		if b, err = s.repo.FindBlockByID(id); b != nil {
+			name, deletedAt, found = b.Name, b.DeletedAt, true
+		}
+	case repository.MasterRacks:
+		var r *domain.Rack
+		if r, err = s.repo.FindRackByID(id); r != nil {
+			name, deletedAt, found = r.Name, r.DeletedAt, true
+		}
+	case repository.MasterRelaxationBlocks:
+		var b *domain.RelaxationBlock
+		if b, err = s.repo.FindRelaxationBlockByID(id); b != nil {
+			name, deletedAt, found = b.Name, b.DeletedAt, true
+		}
+	case repository.MasterRelaxationRacks:
+		var r *domain.RelaxationRack
+		if r, err = s.repo.FindRelaxationRackByID(id); r != nil {
+			name, deletedAt, found = r.Name, r.DeletedAt, true
+		}
+	case repository.MasterUnits:
+		var u *domain.Unit
+		if u, err = s.repo.FindUnitByID(id); u != nil {
+			name, deletedAt, found = u.Name, u.DeletedAt, true
+		}
+	case repository.MasterDefectTypes:
+		var d *domain.DefectType
+		if d, err = s.repo.FindDefectTypeByID(id); d != nil {
+			name, deletedAt, found = d.Name, d.DeletedAt, true
+		}
+	case repository.MasterMovementTypes:
+		var mt *domain.MovementType
+		if mt, err = s.repo.FindMovementTypeByID(id); mt != nil {
+			name, deletedAt, found = mt.Name, mt.DeletedAt, true
+		}
+	default:
+		return "", nil, fmt.Errorf("unknown master table %s", table)
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find %s: %w", table, err)
+	}
+	if !found {
+		return "", nil, ErrMasterNotFound
+	}
+	return name, deletedAt, nil
 }
 
-func (s *MasterService) GetAllRelaxationRacks() ([]domain.RelaxationRack, error) {
-	return s.repo.GetAllRelaxationRacks()
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
 }
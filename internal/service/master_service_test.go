@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/cache"
+)
+
+// Mock MasterRepository for testing
+type mockMasterRepository struct {
+	blocks        map[int64]*domain.Block
+	racks         map[int64]*domain.Rack
+	movementTypes map[int64]*domain.MovementType
+	rolls         int
+	taken         map[string]bool
+	takenKeys     map[string]bool
+	deleted       []int64
+	restored      []int64
+	createdID     int64
+	listed        int
+}
+
+func (m *mockMasterRepository) GetAllBlocks() ([]domain.Block, error) {
+	m.listed++
+	var blocks []domain.Block
+	for _, b := range m.blocks {
+		blocks = append(blocks, *b)
+	}
+	return blocks, nil
+}
+
+func (m *mockMasterRepository) GetAllRacks() ([]domain.Rack, error) { return nil, nil }
+func (m *mockMasterRepository) GetAllRelaxationBlocks() ([]domain.RelaxationBlock, error) {
+	return nil, nil
+}
+func (m *mockMasterRepository) GetAllRelaxationRacks() ([]domain.RelaxationRack, error) {
+	return nil, nil
+}
+
+func (m *mockMasterRepository) GetAllUnits() ([]domain.Unit, error) { return nil, nil }
+func (m *mockMasterRepository) GetAllDefectTypes() ([]domain.DefectType, error) {
+	return nil, nil
+}
+
+func (m *mockMasterRepository) GetAllMovementTypes() ([]domain.MovementType, error) {
+	var types []domain.MovementType
+	for _, mt := range m.movementTypes {
+		types = append(types, *mt)
+	}
+	return types, nil
+}
+
+func (m *mockMasterRepository) FindBlockByID(id int64) (*domain.Block, error) {
+	return m.blocks[id], nil
+}
+
+func (m *mockMasterRepository) FindRackByID(id int64) (*domain.Rack, error) {
+	return m.racks[id], nil
+}
+
+func (m *mockMasterRepository) FindRelaxationBlockByID(id int64) (*domain.RelaxationBlock, error) {
+	return nil, nil
+}
+
+func (m *mockMasterRepository) FindRelaxationRackByID(id int64) (*domain.RelaxationRack, error) {
+	return nil, nil
+}
+
+func (m *mockMasterRepository) FindUnitByID(id int64) (*domain.Unit, error) { return nil, nil }
+func (m *mockMasterRepository) FindDefectTypeByID(id int64) (*domain.DefectType, error) {
+	return nil, nil
+}
+
+func (m *mockMasterRepository) FindMovementTypeByID(id int64) (*domain.MovementType, error) {
+	return m.movementTypes[id], nil
+}
+
+func (m *mockMasterRepository) CreateBlock(block *domain.Block) error {
+	block.ID = m.createdID
+	return nil
+}
+
+func (m *mockMasterRepository) UpdateBlock(block *domain.Block) error { return nil }
+
+func (m *mockMasterRepository) CreateRack(rack *domain.Rack) error {
+	rack.ID = m.createdID
+	return nil
+}
+
+func (m *mockMasterRepository) UpdateRack(rack *domain.Rack) error { return nil }
+func (m *mockMasterRepository) CreateRelaxationBlock(block *domain.RelaxationBlock) error {
+	return nil
+}
+func (m *mockMasterRepository) UpdateRelaxationBlock(block *domain.RelaxationBlock) error {
+	return nil
+}
+func (m *mockMasterRepository) CreateRelaxationRack(rack *domain.RelaxationRack) error { return nil }
+func (m *mockMasterRepository) UpdateRelaxationRack(rack *domain.RelaxationRack) error { return nil }
+
+func (m *mockMasterRepository) CreateUnit(unit *domain.Unit) error { return nil }
+func (m *mockMasterRepository) UpdateUnit(unit *domain.Unit) error { return nil }
+func (m *mockMasterRepository) CreateDefectType(defectType *domain.DefectType) error {
+	defectType.ID = m.createdID
+	return nil
+}
+func (m *mockMasterRepository) UpdateDefectType(defectType *domain.DefectType) error { return nil }
+func (m *mockMasterRepository) CreateMovementType(movementType *domain.MovementType) error {
+	return nil
+}
+func (m *mockMasterRepository) UpdateMovementType(movementType *domain.MovementType) error {
+	return nil
+}
+
+func (m *mockMasterRepository) NameExists(table repository.MasterTable, name string, excludeID int64) (bool, error) {
+	return m.taken[name], nil
+}
+
+func (m *mockMasterRepository) DefectKeyExists(key string, excludeID int64) (bool, error) {
+	return m.takenKeys[key], nil
+}
+
+func (m *mockMasterRepository) CountUsage(table repository.MasterTable, id int64) (int, error) {
+	return m.rolls, nil
+}
+
+func (m *mockMasterRepository) SoftDelete(table repository.MasterTable, id int64) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *mockMasterRepository) Restore(table repository.MasterTable, id int64) error {
+	m.restored = append(m.restored, id)
+	return nil
+}
+
+func TestMasterService_CreateRack(t *testing.T) {
+	repo := &mockMasterRepository{taken: map[string]bool{"R001": true}, createdID: 7}
+	svc := NewMasterService(repo, nil, nil)
+
+	if err := svc.CreateRack(context.Background(), &domain.Rack{Name: " R001 "}); !errors.Is(err, ErrMasterNameTaken) {
+		t.Errorf("Expected ErrMasterNameTaken, got %v", err)
+	}
+
+	rack := &domain.Rack{Name: " R002 ", Capacity: 20}
+	if err := svc.CreateRack(context.Background(), rack); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if rack.ID != 7 || rack.Name != "R002" {
+		t.Errorf("Expected rack 7 named R002, got %d %q", rack.ID, rack.Name)
+	}
+}
+
+func TestMasterService_UpdateDeletedBlock(t *testing.T) {
+	now := time.Now()
+	repo := &mockMasterRepository{blocks: map[int64]*domain.Block{
+		1: {ID: 1, Name: "B001", DeletedAt: &now},
+	}}
+	svc := NewMasterService(repo, nil, nil)
+
+	if _, err := svc.UpdateBlock(context.Background(), 1, &domain.Block{Name: "B002"}); !errors.Is(err, ErrMasterNotFound) {
+		t.Errorf("Expected ErrMasterNotFound for a deleted block, got %v", err)
+	}
+	if _, err := svc.UpdateBlock(context.Background(), 2, &domain.Block{Name: "B002"}); !errors.Is(err, ErrMasterNotFound) {
+		t.Errorf("Expected ErrMasterNotFound for a missing block, got %v", err)
+	}
+}
+
+func TestMasterService_DeleteRefusedWhileHoldingRolls(t *testing.T) {
+	repo := &mockMasterRepository{
+		racks: map[int64]*domain.Rack{1: {ID: 1, Name: "R001"}},
+		rolls: 3,
+	}
+	svc := NewMasterService(repo, nil, nil)
+
+	if err := svc.Delete(context.Background(), repository.MasterRacks, 1); !errors.Is(err, ErrMasterInUse) {
+		t.Errorf("Expected ErrMasterInUse, got %v", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Error("Expected rack not to be deleted")
+	}
+
+	repo.rolls = 0
+	if err := svc.Delete(context.Background(), repository.MasterRacks, 1); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(repo.deleted) != 1 {
+		t.Error("Expected rack to be deleted")
+	}
+}
+
+func TestMasterService_Restore(t *testing.T) {
+	now := time.Now()
+	repo := &mockMasterRepository{
+		blocks: map[int64]*domain.Block{
+			1: {ID: 1, Name: "B001"},
+			2: {ID: 2, Name: "B002", DeletedAt: &now},
+		},
+		taken: map[string]bool{"B002": true},
+	}
+	svc := NewMasterService(repo, nil, nil)
+
+	if err := svc.Restore(context.Background(), repository.MasterBlocks, 1); !errors.Is(err, ErrMasterNotDeleted) {
+		t.Errorf("Expected ErrMasterNotDeleted, got %v", err)
+	}
+	if err := svc.Restore(context.Background(), repository.MasterBlocks, 2); !errors.Is(err, ErrMasterNameTaken) {
+		t.Errorf("Expected ErrMasterNameTaken when the name was reused, got %v", err)
+	}
+
+	repo.taken = nil
+	if err := svc.Restore(context.Background(), repository.MasterBlocks, 2); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(repo.restored) != 1 || repo.restored[0] != 2 {
+		t.Errorf("Expected block 2 to be restored, got %v", repo.restored)
+	}
+}
+
+func TestMasterService_CreateDefectTypeDerivesKey(t *testing.T) {
+	repo := &mockMasterRepository{takenKeys: map[string]bool{"open-seam": true}, createdID: 5}
+	svc := NewMasterService(repo, nil, nil)
+
+	defectType := &domain.DefectType{Name: " Skipped  Stitch! "}
+	if err := svc.CreateDefectType(context.Background(), defectType); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if defectType.Key == nil || *defectType.Key != "skipped-stitch" {
+		t.Errorf("Expected key skipped-stitch, got %v", defectType.Key)
+	}
+
+	if err := svc.CreateDefectType(context.Background(), &domain.DefectType{Name: "Open Seam"}); !errors.Is(err, ErrDefectKeyTaken) {
+		t.Errorf("Expected ErrDefectKeyTaken, got %v", err)
+	}
+}
+
+func TestMasterService_StageMovementTypesAreProtected(t *testing.T) {
+	repo := &mockMasterRepository{movementTypes: map[int64]*domain.MovementType{
+		1:  {ID: 1, Name: "inventory"},
+		10: {ID: 10, Name: "sample"},
+	}}
+	svc := NewMasterService(repo, nil, nil)
+
+	if _, err := svc.UpdateMovementType(context.Background(), 1, &domain.MovementType{Name: "stock"}); !errors.Is(err, ErrStageMovement) {
+		t.Errorf("Expected ErrStageMovement on rename, got %v", err)
+	}
+	if err := svc.Delete(context.Background(), repository.MasterMovementTypes, 1); !errors.Is(err, ErrStageMovement) {
+		t.Errorf("Expected ErrStageMovement on delete, got %v", err)
+	}
+	if _, err := svc.UpdateMovementType(context.Background(), 10, &domain.MovementType{Name: "sampling"}); err != nil {
+		t.Errorf("Expected other movement types to be renamed, got %v", err)
+	}
+}
+
+func TestMasterService_CheckStageMovementTypes(t *testing.T) {
+	types := map[int64]*domain.MovementType{}
+	for _, stage := range domain.GetAllStages() {
+		types[int64(stage.ID)] = &domain.MovementType{ID: int64(stage.ID), Name: stage.Name}
+	}
+	repo := &mockMasterRepository{movementTypes: types}
+	svc := NewMasterService(repo, nil, nil)
+
+	if err := svc.CheckStageMovementTypes(); err != nil {
+		t.Fatalf("Expected all stages to be covered, got %v", err)
+	}
+
+	delete(types, 9)
+	err := svc.CheckStageMovementTypes()
+	if err == nil || !strings.Contains(err.Error(), "qc_fabric") {
+		t.Errorf("Expected missing qc_fabric to be reported, got %v", err)
+	}
+}
+
+func TestMasterService_ListIsCachedUntilWritten(t *testing.T) {
+	repo := &mockMasterRepository{blocks: map[int64]*domain.Block{1: {ID: 1, Name: "B001"}}, createdID: 2}
+	svc := NewMasterService(repo, nil, NewMasterCache(cache.NewMemoryStore(), time.Minute))
+	ctx := context.Background()
+
+	first, err := svc.List(ctx, repository.MasterBlocks)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	second, _ := svc.List(ctx, repository.MasterBlocks)
+	if repo.listed != 1 || second.ETag != first.ETag || !strings.Contains(string(second.Data), "B001") {
+		t.Fatalf("Expected the second list from the cache, got %d loads and %+v", repo.listed, second)
+	}
+
+	block := &domain.Block{Name: "B002"}
+	if err := svc.CreateBlock(ctx, block); err != nil {
+		t.Fatalf("CreateBlock returned error: %v", err)
+	}
+	repo.blocks[block.ID] = block
+	third, _ := svc.List(ctx, repository.MasterBlocks)
+	if repo.listed != 2 || third.ETag == first.ETag {
+		t.Errorf("Expected the list to be loaded again with a new ETag, got %d loads", repo.listed)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Movement event types.
+const (
+	MovementFabricMoved     = "fabric.moved"
+	MovementFabricRelocated = "fabric.relocated"
+	MovementFabricQC        = "fabric.qc"
+	MovementGarmentQC       = "garment.qc"
+)
+
+const (
+	defaultMovementBuffer = 1000
+	// movementSubscriberBuffer is how many events a slow client may fall
+	// behind before it is dropped. It then reconnects and resumes from its
+	// last event ID.
+	movementSubscriberBuffer = 256
+)
+
+// MovementEvent is a committed change of where a roll or piece is.
+type MovementEvent struct {
+	ID        uint64 `json:"id"`
+	Type      string `json:"type"`
+	Code      string `json:"code"`
+	FabricID  int64  `json:"fabric_id,omitempty"`
+	ItemID    int64  `json:"item_id,omitempty"`
+	FromStage string `json:"from_stage,omitempty"`
+	Stage     string `json:"stage,omitempty"`
+	BlockID   *int64 `json:"block_id,omitempty"`
+	RackID    *int64 `json:"rack_id,omitempty"`
+	// Relaxation racks are a separate master; block filters do not apply.
+	RelaxationBlockID *int64    `json:"relaxation_block_id,omitempty"`
+	RelaxationRackID  *int64    `json:"relaxation_rack_id,omitempty"`
+	BuyerID           *int64    `json:"buyer_id,omitempty"`
+	Buyer             string    `json:"buyer,omitempty"`
+	OrderID           int64     `json:"order_id,omitempty"`
+	QCResult          *string   `json:"qc_result,omitempty"`
+	UserID            int64     `json:"user_id,omitempty"`
+	OccurredAt        time.Time `json:"occurred_at"`
+}
+
+// MovementFilter narrows down a stream. Empty fields are not filtered on.
+type MovementFilter struct {
+	Stage   string
+	BlockID *int64
+	BuyerID *int64
+}
+
+func (f MovementFilter) Matches(e MovementEvent) bool {
+	if f.Stage != "" && e.Stage != f.Stage {
+		return false
+	}
+	if f.BlockID != nil && (e.BlockID == nil || *e.BlockID != *f.BlockID) {
+		return false
+	}
+	if f.BuyerID != nil && (e.BuyerID == nil || *e.BuyerID != *f.BuyerID) {
+		return false
+	}
+	return true
+}
+
+// MovementSubscription receives the matching events published after it was
+// opened. C is closed when the subscriber falls too far behind or the stream
+// is closed.
+type MovementSubscription struct {
+	C      <-chan MovementEvent
+	c      chan MovementEvent
+	filter MovementFilter
+}
+
+// MovementStream fans out movement events to connected dashboards and keeps
+// the latest ones so reconnecting clients can catch up. It lives in memory,
+// so every API instance streams the moves it committed itself.
+type MovementStream struct {
+	mu          sync.Mutex
+	lastID      uint64
+	buffer      []MovementEvent
+	size        int
+	subscribers map[*MovementSubscription]struct{}
+	closed      bool
+}
+
+func NewMovementStream(size int) *MovementStream {
+	if size <= 0 {
+		size = defaultMovementBuffer
+	}
+	return &MovementStream{
+		// Event IDs keep growing across restarts, so an ID from before a
+		// restart is recognised as older than anything buffered.
+		lastID:      uint64(time.Now().UnixMicro()),
+		size:        size,
+		subscribers: map[*MovementSubscription]struct{}{},
+	}
+}
+
+// Publish assigns IDs to the events and sends them to the subscribers. Call
+// it only after the change is committed.
+func (s *MovementStream) Publish(events ...MovementEvent) {
+	if s == nil || len(events) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, e := range events {
+		s.lastID++
+		e.ID = s.lastID
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = now
+		}
+
+		s.buffer = append(s.buffer, e)
+		if len(s.buffer) > s.size {
+			s.buffer = s.buffer[len(s.buffer)-s.size:]
+		}
+
+		for sub := range s.subscribers {
+			if !sub.filter.Matches(e) {
+				continue
+			}
+			select {
+			case sub.c <- e:
+			default:
+				delete(s.subscribers, sub)
+				close(sub.c)
+			}
+		}
+	}
+}
+
+// Subscribe opens a subscription. With a lastEventID it also returns the
+// buffered matching events after it; complete is false when events after it
+// have already left the buffer and the client has to reload instead.
+func (s *MovementStream) Subscribe(lastEventID uint64, filter MovementFilter) (sub *MovementSubscription, replay []MovementEvent, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := make(chan MovementEvent, movementSubscriberBuffer)
+	sub = &MovementSubscription{C: c, c: c, filter: filter}
+	if s.closed {
+		close(c)
+		return sub, nil, true
+	}
+	s.subscribers[sub] = struct{}{}
+
+	complete = true
+	if lastEventID == 0 {
+		return sub, nil, complete
+	}
+	if lastEventID > s.lastID {
+		return sub, nil, false
+	}
+	if len(s.buffer) > 0 && s.buffer[0].ID > lastEventID+1 {
+		complete = false
+	} else if len(s.buffer) == 0 && lastEventID < s.lastID {
+		complete = false
+	}
+	for _, e := range s.buffer {
+		if e.ID > lastEventID && filter.Matches(e) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, complete
+}
+
+// Close ends every subscription, so open streams return while the server
+// shuts down. Later subscriptions are closed right away.
+func (s *MovementStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.c)
+	}
+}
+
+// Unsubscribe closes a subscription. It is safe to call more than once.
+func (s *MovementStream) Unsubscribe(sub *MovementSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestMovementStream_FiltersLiveEvents(t *testing.T) {
+	stream := NewMovementStream(10)
+	block := int64(3)
+	sub, replay, complete := stream.Subscribe(0, MovementFilter{Stage: "inventory", BlockID: &block})
+	defer stream.Unsubscribe(sub)
+	if len(replay) != 0 || !complete {
+		t.Fatalf("Expected a fresh subscription without replay, got %d events (complete=%v)", len(replay), complete)
+	}
+
+	other := int64(4)
+	stream.Publish(
+		MovementEvent{Type: MovementFabricMoved, Code: "F-1", Stage: "inventory", BlockID: &block},
+		MovementEvent{Type: MovementFabricMoved, Code: "F-2", Stage: "inventory", BlockID: &other},
+		MovementEvent{Type: MovementFabricMoved, Code: "F-3", Stage: "relaxation", BlockID: &block},
+	)
+
+	select {
+	case e := <-sub.C:
+		if e.Code != "F-1" || e.ID == 0 || e.OccurredAt.IsZero() {
+			t.Errorf("Unexpected event: %+v", e)
+		}
+	default:
+		t.Fatal("Expected the matching event to be delivered")
+	}
+	if len(sub.C) != 0 {
+		t.Errorf("Expected other blocks and stages to be filtered out, %d left", len(sub.C))
+	}
+}
+
+func TestMovementStream_ResumesFromLastEventID(t *testing.T) {
+	stream := NewMovementStream(3)
+	for _, code := range []string{"F-1", "F-2", "F-3"} {
+		stream.Publish(MovementEvent{Type: MovementFabricMoved, Code: code})
+	}
+	first, _, _ := stream.Subscribe(0, MovementFilter{})
+	stream.Unsubscribe(first)
+
+	sub, replay, complete := stream.Subscribe(stream.buffer[0].ID, MovementFilter{})
+	stream.Unsubscribe(sub)
+	if !complete || len(replay) != 2 || replay[0].Code != "F-2" || replay[1].Code != "F-3" {
+		t.Fatalf("Expected F-2 and F-3 to be replayed, got %+v (complete=%v)", replay, complete)
+	}
+
+	// F-1 leaves the buffer, so a client that last saw the event before it has a gap
+	gapFrom := stream.buffer[0].ID - 1
+	stream.Publish(MovementEvent{Type: MovementFabricMoved, Code: "F-4"})
+	sub, replay, complete = stream.Subscribe(gapFrom, MovementFilter{})
+	stream.Unsubscribe(sub)
+	if complete || len(replay) != 3 {
+		t.Errorf("Expected an incomplete replay of 3 events, got %d (complete=%v)", len(replay), complete)
+	}
+
+	// An ID from the future, e.g. from another server, cannot be resumed
+	sub, _, complete = stream.Subscribe(stream.lastID+100, MovementFilter{})
+	stream.Unsubscribe(sub)
+	if complete {
+		t.Error("Expected an unknown event ID to require a reload")
+	}
+}
+
+func TestMovementStream_DropsSlowSubscriber(t *testing.T) {
+	stream := NewMovementStream(0)
+	sub, _, _ := stream.Subscribe(0, MovementFilter{})
+
+	for i := 0; i <= movementSubscriberBuffer; i++ {
+		stream.Publish(MovementEvent{Type: MovementFabricMoved})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != movementSubscriberBuffer {
+		t.Errorf("Expected %d buffered events before the channel closed, got %d", movementSubscriberBuffer, received)
+	}
+	stream.Unsubscribe(sub)
+
+	var nilStream *MovementStream
+	nilStream.Publish(MovementEvent{Type: MovementFabricMoved})
+}
+
+func TestMovementStream_Close(t *testing.T) {
+	stream := NewMovementStream(0)
+	sub, _, _ := stream.Subscribe(0, MovementFilter{})
+
+	stream.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("Expected the subscription to be closed")
+	}
+	stream.Unsubscribe(sub)
+
+	late, _, _ := stream.Subscribe(0, MovementFilter{})
+	if _, ok := <-late.C; ok {
+		t.Error("Expected a subscription after Close to be closed")
+	}
+	stream.Unsubscribe(late)
+	stream.Publish(MovementEvent{Type: MovementFabricMoved})
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/authctx"
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// Notification modules, matched against setting_notifications.module to find
+// the recipients.
+const (
+	NotificationRelaxationFinished = "fabric.relaxation-finished"
+	NotificationQCFailed           = "qc.failed"
+	NotificationDestroyApproval    = "fabric.destroy-approval"
+)
+
+// notificationTypes maps a module to the type stored with its notifications.
+var notificationTypes = map[string]string{
+	NotificationRelaxationFinished: "App\\Notifications\\RelaxationFinished",
+	NotificationQCFailed:           "App\\Notifications\\QcFailed",
+	NotificationDestroyApproval:    "App\\Notifications\\DestroyApprovalNeeded",
+}
+
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 100
+)
+
+var ErrNotificationNotFound = errors.New("notification is not found")
+
+// NotificationList is a page of a user's notifications with their unread count.
+type NotificationList struct {
+	Notifications []domain.Notification `json:"notifications"`
+	UnreadCount   int                   `json:"unread_count"`
+}
+
+// NotificationMessage is an event to notify about. Message is used when the
+// matching rule has no body of its own; rule bodies may refer to Data values
+// as {key}.
+type NotificationMessage struct {
+	Module  string
+	Message string
+	Data    map[string]interface{}
+}
+
+type NotificationService struct {
+	repo repository.NotificationRepository
+}
+
+func NewNotificationService(repo repository.NotificationRepository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+func (s *NotificationService) List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) (*NotificationList, error) {
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	notifications, err := s.repo.List(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationList{Notifications: notifications, UnreadCount: unread}, nil
+}
+
+func (s *NotificationService) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID int64, id string) (*domain.Notification, error) {
+	if _, err := s.find(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkRead(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.find(ctx, userID, id)
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	return s.repo.MarkAllRead(ctx, userID)
+}
+
+func (s *NotificationService) Delete(ctx context.Context, userID int64, id string) error {
+	deleted, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *NotificationService) find(ctx context.Context, userID int64, id string) (*domain.Notification, error) {
+	n, err := s.repo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, ErrNotificationNotFound
+	}
+	return n, nil
+}
+
+// Publish notifies the users that the setting_notifications rules of the
+// module resolve to. The user whose request raised the event is left out.
+// Like the audit trail it is best effort: failures are logged and never fail
+// the change that triggered them.
+func (s *NotificationService) Publish(ctx context.Context, msg NotificationMessage) {
+	if s == nil {
+		return
+	}
+
+	recipients, err := s.repo.Recipients(ctx, msg.Module, repository.NotificationChannelDatabase)
+	if err != nil {
+		log.Error().Err(err).Str("module", msg.Module).Msg("Failed to resolve notification recipients")
+		return
+	}
+
+	notifications, err := BuildNotifications(msg, recipients, authctx.UserID(ctx))
+	if err != nil {
+		log.Error().Err(err).Str("module", msg.Module).Msg("Failed to build notifications")
+		return
+	}
+	if len(notifications) == 0 {
+		return
+	}
+
+	if err := s.repo.Create(ctx, notifications); err != nil {
+		log.Error().Err(err).Str("module", msg.Module).Msg("Failed to publish notifications")
+	}
+}
+
+// BuildNotifications turns resolved recipients into one notification per
+// user. When a user is matched by several rules the first rule wins.
+func BuildNotifications(msg NotificationMessage, recipients []domain.NotificationRecipient, actorID int64) ([]domain.Notification, error) {
+	notificationType, ok := notificationTypes[msg.Module]
+	if !ok {
+		return nil, fmt.Errorf("unknown notification module: %s", msg.Module)
+	}
+
+	seen := map[int64]bool{}
+	var notifications []domain.Notification
+	for _, recipient := range recipients {
+		if recipient.UserID == actorID || seen[recipient.UserID] {
+			continue
+		}
+		seen[recipient.UserID] = true
+
+		message := msg.Message
+		if body := strings.TrimSpace(recipient.Body); body != "" {
+			message = RenderNotificationBody(body, msg.Data)
+		}
+		data := map[string]interface{}{}
+		for key, value := range msg.Data {
+			data[key] = value
+		}
+		data["message"] = message
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, domain.Notification{
+			ID:     newUUID(),
+			Type:   notificationType,
+			Data:   raw,
+			UserID: recipient.UserID,
+		})
+	}
+	return notifications, nil
+}
+
+// RenderNotificationBody replaces {key} in a rule body with the matching data
+// value. Unknown keys are left as they are.
+func RenderNotificationBody(body string, data map[string]interface{}) string {
+	for key, value := range data {
+		body = strings.ReplaceAll(body, "{"+key+"}", fmt.Sprint(value))
+	}
+	return body
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+type mockNotificationRepository struct {
+	recipients    []domain.NotificationRecipient
+	notifications []domain.Notification
+}
+
+func (m *mockNotificationRepository) List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]domain.Notification, error) {
+	var result []domain.Notification
+	for _, n := range m.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockNotificationRepository) CountUnread(ctx context.Context, userID int64) (int, error) {
+	unread, _ := m.List(ctx, userID, true, 0, 0)
+	return len(unread), nil
+}
+
+func (m *mockNotificationRepository) FindByID(ctx context.Context, userID int64, id string) (*domain.Notification, error) {
+	for i := range m.notifications {
+		if m.notifications[i].ID == id && m.notifications[i].UserID == userID {
+			return &m.notifications[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockNotificationRepository) MarkRead(ctx context.Context, userID int64, id string) error {
+	return nil
+}
+
+func (m *mockNotificationRepository) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockNotificationRepository) Delete(ctx context.Context, userID int64, id string) (bool, error) {
+	n, _ := m.FindByID(ctx, userID, id)
+	return n != nil, nil
+}
+
+func (m *mockNotificationRepository) Create(ctx context.Context, notifications []domain.Notification) error {
+	m.notifications = append(m.notifications, notifications...)
+	return nil
+}
+
+func (m *mockNotificationRepository) Recipients(ctx context.Context, module, channel string) ([]domain.NotificationRecipient, error) {
+	return m.recipients, nil
+}
+
+func TestBuildNotifications_OnePerUserWithRuleBody(t *testing.T) {
+	msg := NotificationMessage{
+		Module:  NotificationDestroyApproval,
+		Message: "Destroy request is waiting for approval.",
+		Data:    map[string]interface{}{"fabric_code": "F-001"},
+	}
+	recipients := []domain.NotificationRecipient{
+		{UserID: 2, Body: "Please review the destroy request for {fabric_code}."},
+		{UserID: 3},
+		{UserID: 2, Body: "ignored, user 2 is already notified"},
+		{UserID: 7, Body: "the actor is left out"},
+	}
+
+	notifications, err := BuildNotifications(msg, recipients, 7)
+	if err != nil {
+		t.Fatalf("BuildNotifications returned error: %v", err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(notifications))
+	}
+
+	messages := map[int64]string{}
+	for _, n := range notifications {
+		if n.Type != "App\\Notifications\\DestroyApprovalNeeded" || n.ID == "" {
+			t.Errorf("Unexpected notification: %+v", n)
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal(n.Data, &data); err != nil {
+			t.Fatalf("Failed to decode data: %v", err)
+		}
+		if data["fabric_code"] != "F-001" {
+			t.Errorf("Expected event data to be kept, got %v", data)
+		}
+		messages[n.UserID], _ = data["message"].(string)
+	}
+	if messages[2] != "Please review the destroy request for F-001." {
+		t.Errorf("Expected rendered rule body, got %q", messages[2])
+	}
+	if messages[3] != msg.Message {
+		t.Errorf("Expected default message, got %q", messages[3])
+	}
+
+	if _, err := BuildNotifications(NotificationMessage{Module: "unknown"}, recipients, 0); err == nil {
+		t.Error("Expected error for unknown module")
+	}
+}
+
+func TestNotificationPublishAndList(t *testing.T) {
+	repo := &mockNotificationRepository{recipients: []domain.NotificationRecipient{{UserID: 1}, {UserID: 2}}}
+	svc := NewNotificationService(repo)
+
+	svc.Publish(context.Background(), NotificationMessage{Module: NotificationQCFailed, Message: "Piece failed QC."})
+	if len(repo.notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(repo.notifications))
+	}
+
+	list, err := svc.List(context.Background(), 1, false, 0, 0)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(list.Notifications) != 1 || list.UnreadCount != 1 {
+		t.Errorf("Expected 1 unread notification, got %+v", list)
+	}
+
+	if _, err := svc.MarkRead(context.Background(), 2, list.Notifications[0].ID); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("Expected another user's notification to be not found, got %v", err)
+	}
+	if err := svc.Delete(context.Background(), 1, "missing"); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("Expected ErrNotificationNotFound, got %v", err)
+	}
+
+	var nilService *NotificationService
+	nilService.Publish(context.Background(), NotificationMessage{Module: NotificationQCFailed})
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// defaultNumberFormat is used for numberings without a format.
+const defaultNumberFormat = "{PREFIX}{SEQ:6}"
+
+var (
+	ErrNumberingNotFound   = errors.New("numbering is not found")
+	ErrInvalidNumberFormat = errors.New("invalid numbering format")
+)
+
+var numberTokenPattern = regexp.MustCompile(`\{([A-Z]+)(?::(\d+))?\}`)
+
+type NumberingService struct {
+	repo     repository.NumberingRepository
+	activity *ActivityService
+}
+
+func NewNumberingService(repo repository.NumberingRepository, activity *ActivityService) *NumberingService {
+	return &NumberingService{repo: repo, activity: activity}
+}
+
+func (s *NumberingService) List(ctx context.Context) ([]domain.Numbering, error) {
+	return s.repo.List(ctx)
+}
+
+// Next allocates the next number of a module, e.g. ("fabric", "incoming").
+func (s *NumberingService) Next(ctx context.Context, module, forKey string) (string, error) {
+	code, err := s.repo.Allocate(ctx, module, forKey, allocateNumber)
+	if err != nil {
+		return "", mapNumberingError(err)
+	}
+	s.activity.Record(ctx, ActivityInput{
+		LogName:     "setting",
+		Event:       ActivityUpdated,
+		Description: "allocated number",
+		SubjectType: repository.SubjectNumbering,
+		New:         map[string]string{"module": module, "for": forKey, "number": code},
+	})
+	return code, nil
+}
+
+// Preview renders the number the next allocation would return, without
+// allocating it.
+func (s *NumberingService) Preview(ctx context.Context, id int64) (*domain.NumberingPreview, error) {
+	n, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, ErrNumberingNotFound
+	}
+
+	_, next, err := allocateNumber(n, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &domain.NumberingPreview{Format: numberFormat(n), Next: next, Example: n.Example}, nil
+}
+
+// PreviewFormat renders the first number of an unsaved numbering, to fill in
+// its example.
+func (s *NumberingService) PreviewFormat(format, prefix string) (*domain.NumberingPreview, error) {
+	n := &domain.Numbering{Format: &format, Prefix: &prefix, IsIncrement: true}
+	_, next, err := allocateNumber(n, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &domain.NumberingPreview{Format: numberFormat(n), Next: next, Example: &next}, nil
+}
+
+// allocateNumber picks the next sequence of a numbering and renders it.
+func allocateNumber(n *domain.Numbering, now time.Time) (int64, string, error) {
+	sequence := NextSequence(n, now)
+	code, err := RenderNumber(numberFormat(n), stringOr(n.Prefix, ""), sequence, now)
+	if err != nil {
+		return 0, "", err
+	}
+	return sequence, code, nil
+}
+
+// NextSequence returns the sequence of the next number. The sequence starts
+// over when the reset period of the clause has changed since the last
+// allocation. Numberings that do not increment keep their sequence.
+func NextSequence(n *domain.Numbering, now time.Time) int64 {
+	if !n.IsIncrement {
+		return n.Sequence
+	}
+	if n.LastAllocatedAt != nil && resetPeriod(stringOr(n.Clause, ""), *n.LastAllocatedAt) != resetPeriod(stringOr(n.Clause, ""), now) {
+		return 1
+	}
+	return n.Sequence + 1
+}
+
+func resetPeriod(clause string, t time.Time) string {
+	switch strings.ToLower(clause) {
+	case domain.NumberingResetDaily:
+		return t.Format("2006-01-02")
+	case domain.NumberingResetMonthly:
+		return t.Format("2006-01")
+	case domain.NumberingResetYearly:
+		return t.Format("2006")
+	default:
+		return ""
+	}
+}
+
+// RenderNumber fills in a format such as "{PREFIX}{YY}{MM}{SEQ:4}". Supported
+// tokens are PREFIX, YYYY, YY, MM, DD and SEQ, where SEQ:n pads the sequence
+// with zeros to n digits.
+func RenderNumber(format, prefix string, sequence int64, t time.Time) (string, error) {
+	var renderErr error
+	hasSequence := false
+
+	code := numberTokenPattern.ReplaceAllStringFunc(format, func(token string) string {
+		parts := numberTokenPattern.FindStringSubmatch(token)
+		name, width := parts[1], parts[2]
+		if width != "" && name != "SEQ" {
+			renderErr = fmt.Errorf("%w: only SEQ takes a width, got %s", ErrInvalidNumberFormat, token)
+			return token
+		}
+
+		switch name {
+		case "PREFIX":
+			return prefix
+		case "YYYY":
+			return t.Format("2006")
+		case "YY":
+			return t.Format("06")
+		case "MM":
+			return t.Format("01")
+		case "DD":
+			return t.Format("02")
+		case "SEQ":
+			hasSequence = true
+			if width == "" {
+				return strconv.FormatInt(sequence, 10)
+			}
+			n, _ := strconv.Atoi(width)
+			return fmt.Sprintf("%0*d", n, sequence)
+		}
+		renderErr = fmt.Errorf("%w: unknown token %s", ErrInvalidNumberFormat, token)
+		return token
+	})
+
+	if renderErr != nil {
+		return "", renderErr
+	}
+	if !hasSequence {
+		return "", fmt.Errorf("%w: the format needs a {SEQ} token", ErrInvalidNumberFormat)
+	}
+	return code, nil
+}
+
+func numberFormat(n *domain.Numbering) string {
+	return stringOr(n.Format, defaultNumberFormat)
+}
+
+func mapNumberingError(err error) error {
+	if errors.Is(err, repository.ErrNumberingNotFound) {
+		return ErrNumberingNotFound
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock NumberingRepository for testing. Allocate runs the allocator against
+// the stored numbering like the MySQL repository does under its row lock.
+type mockNumberingRepository struct {
+	numberings map[string]*domain.Numbering
+	now        time.Time
+}
+
+func (m *mockNumberingRepository) List(ctx context.Context) ([]domain.Numbering, error) {
+	return nil, nil
+}
+
+func (m *mockNumberingRepository) FindByID(ctx context.Context, id int64) (*domain.Numbering, error) {
+	for _, n := range m.numberings {
+		if n.ID == id {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockNumberingRepository) Allocate(ctx context.Context, module, forKey string, allocate repository.NumberingAllocator) (string, error) {
+	n, ok := m.numberings[module+"/"+forKey]
+	if !ok {
+		return "", repository.ErrNumberingNotFound
+	}
+	sequence, code, err := allocate(n, m.now)
+	if err != nil {
+		return "", err
+	}
+	n.Sequence = sequence
+	n.LastAllocatedAt = &m.now
+	n.UpdatedAt = &m.now
+	return code, nil
+}
+
+func TestRenderNumber(t *testing.T) {
+	at := time.Date(2026, 3, 7, 10, 0, 0, 0, time.Local)
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"{PREFIX}{YY}{MM}{SEQ:4}", "FI26030042"},
+		{"{PREFIX}/{YYYY}/{DD}/{SEQ}", "FI/2026/07/42"},
+		{"{SEQ:1}", "42"},
+	}
+
+	for _, tc := range testCases {
+		got, err := RenderNumber(tc.format, "FI", 42, at)
+		if err != nil {
+			t.Fatalf("%s: expected success, got %v", tc.format, err)
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.format, tc.expected, got)
+		}
+	}
+
+	for _, format := range []string{"{PREFIX}{WEEK}{SEQ}", "{PREFIX}{YY}", "{MM:2}{SEQ}"} {
+		if _, err := RenderNumber(format, "FI", 1, at); !errors.Is(err, ErrInvalidNumberFormat) {
+			t.Errorf("%s: expected ErrInvalidNumberFormat, got %v", format, err)
+		}
+	}
+}
+
+func TestNextSequence(t *testing.T) {
+	last := time.Date(2026, 3, 31, 23, 0, 0, 0, time.Local)
+	now := time.Date(2026, 4, 1, 8, 0, 0, 0, time.Local)
+	clause := func(c string) *string { return &c }
+
+	testCases := []struct {
+		name     string
+		n        domain.Numbering
+		expected int64
+	}{
+		{"never resets", domain.Numbering{Sequence: 9, IsIncrement: true, Clause: clause(domain.NumberingResetNever), LastAllocatedAt: &last}, 10},
+		{"monthly reset", domain.Numbering{Sequence: 9, IsIncrement: true, Clause: clause(domain.NumberingResetMonthly), LastAllocatedAt: &last}, 1},
+		{"monthly reset after an edit", domain.Numbering{Sequence: 9, IsIncrement: true, Clause: clause(domain.NumberingResetMonthly), LastAllocatedAt: &last, UpdatedAt: &now}, 1},
+		{"yearly within year", domain.Numbering{Sequence: 9, IsIncrement: true, Clause: clause(domain.NumberingResetYearly), LastAllocatedAt: &last}, 10},
+		{"first allocation", domain.Numbering{IsIncrement: true, Clause: clause(domain.NumberingResetDaily)}, 1},
+		{"not incrementing", domain.Numbering{Sequence: 9, LastAllocatedAt: &last}, 9},
+	}
+
+	for _, tc := range testCases {
+		if got := NextSequence(&tc.n, now); got != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestNext_AllocatesWithoutGaps(t *testing.T) {
+	format, prefix := "{PREFIX}{YY}{SEQ:3}", "INC"
+	repo := &mockNumberingRepository{
+		numberings: map[string]*domain.Numbering{
+			"fabric/incoming": {ID: 1, Format: &format, Prefix: &prefix, IsIncrement: true, IsActive: true},
+		},
+		now: time.Date(2026, 5, 2, 9, 0, 0, 0, time.Local),
+	}
+	svc := NewNumberingService(repo, nil)
+
+	for _, expected := range []string{"INC26001", "INC26002", "INC26003"} {
+		code, err := svc.Next(context.Background(), "fabric", "incoming")
+		if err != nil {
+			t.Fatalf("Expected success, got %v", err)
+		}
+		if code != expected {
+			t.Errorf("Expected %s, got %s", expected, code)
+		}
+	}
+
+	preview, err := svc.Preview(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if expected := "INC" + time.Now().Format("06") + "004"; preview.Next != expected {
+		t.Errorf("Expected preview %s, got %s", expected, preview.Next)
+	}
+	if repo.numberings["fabric/incoming"].Sequence != 3 {
+		t.Error("Expected preview not to allocate a number")
+	}
+
+	if _, err := svc.Next(context.Background(), "fabric", "outgoing"); !errors.Is(err, ErrNumberingNotFound) {
+		t.Errorf("Expected ErrNumberingNotFound, got %v", err)
+	}
+}
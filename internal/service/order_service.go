@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var ErrOrderNotFound = errors.New("order is not found")
+
+type OrderService struct {
+	repo repository.OrderRepository
+}
+
+func NewOrderService(repo repository.OrderRepository) *OrderService {
+	return &OrderService{repo: repo}
+}
+
+func (s *OrderService) List(ctx context.Context, filter repository.OrderFilter) ([]domain.Order, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	filter.Status = strings.TrimSpace(filter.Status)
+	return s.repo.List(ctx, filter)
+}
+
+// Get returns the order with its manpower plan, process list and fabric
+// reconciliation.
+func (s *OrderService) Get(ctx context.Context, id int64) (*domain.Order, error) {
+	order, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Detail, err = s.repo.GetDetail(ctx, id); err != nil {
+		return nil, err
+	}
+	if order.Processes, err = s.repo.GetProcesses(ctx, id); err != nil {
+		return nil, err
+	}
+	if order.Fabric, err = s.reconcile(ctx, id); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *OrderService) GetFabricReconciliation(ctx context.Context, id int64) (*domain.FabricReconciliation, error) {
+	if _, err := s.find(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.reconcile(ctx, id)
+}
+
+func (s *OrderService) find(ctx context.Context, id int64) (*domain.Order, error) {
+	order, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// reconcile lists every check point stage, including the empty ones, so the
+// received yards can be compared with where they are now at a glance. Rolls
+// without a stage yet, or on a stage that no longer exists, come last.
+func (s *OrderService) reconcile(ctx context.Context, id int64) (*domain.FabricReconciliation, error) {
+	rec, err := s.repo.GetFabricReceived(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	byStage, err := s.repo.GetFabricByStage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]domain.StageYards{}
+	for _, st := range byStage {
+		total := totals[st.Stage]
+		total.Rolls += st.Rolls
+		total.Yards += st.Yards
+		totals[st.Stage] = total
+	}
+
+	stages := domain.GetAllStages()
+	rec.Stages = make([]domain.StageYards, 0, len(stages)+1)
+	for _, stage := range stages {
+		total := totals[stage.Name]
+		total.Stage = stage.Name
+		rec.Stages = append(rec.Stages, total)
+		delete(totals, stage.Name)
+	}
+
+	unassigned := domain.StageYards{Stage: domain.StageUnassigned}
+	for _, total := range totals {
+		unassigned.Rolls += total.Rolls
+		unassigned.Yards += total.Yards
+	}
+	if unassigned.Rolls > 0 {
+		rec.Stages = append(rec.Stages, unassigned)
+	}
+	return rec, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock OrderRepository for testing
+type mockOrderRepository struct {
+	order    *domain.Order
+	received *domain.FabricReconciliation
+	byStage  []domain.StageYards
+}
+
+func (m *mockOrderRepository) List(ctx context.Context, filter repository.OrderFilter) ([]domain.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderRepository) FindByID(ctx context.Context, id int64) (*domain.Order, error) {
+	return m.order, nil
+}
+
+func (m *mockOrderRepository) GetDetail(ctx context.Context, orderID int64) (*domain.OrderDetail, error) {
+	return &domain.OrderDetail{ManTotal: 30}, nil
+}
+
+func (m *mockOrderRepository) GetProcesses(ctx context.Context, orderID int64) ([]domain.OrderProcess, error) {
+	return nil, nil
+}
+
+func (m *mockOrderRepository) GetFabricReceived(ctx context.Context, orderID int64) (*domain.FabricReconciliation, error) {
+	rec := *m.received
+	return &rec, nil
+}
+
+func (m *mockOrderRepository) GetFabricByStage(ctx context.Context, orderID int64) ([]domain.StageYards, error) {
+	return m.byStage, nil
+}
+
+func TestOrderGet_ReconcilesEveryStage(t *testing.T) {
+	repo := &mockOrderRepository{
+		order:    &domain.Order{ID: 1, Code: "ORD-1"},
+		received: &domain.FabricReconciliation{Incomings: 2, RollsReceived: 4, YardsReceived: 400},
+		byStage: []domain.StageYards{
+			{Stage: string(domain.StageRelaxation), Rolls: 2, Yards: 210},
+			{Stage: string(domain.StageCuttingWIP), Rolls: 1, Yards: 90},
+			{Stage: domain.StageUnassigned, Rolls: 1, Yards: 60},
+			{Stage: "OLD STAGE", Rolls: 1, Yards: 40},
+		},
+	}
+	svc := NewOrderService(repo)
+
+	order, err := svc.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if order.Detail == nil || order.Detail.ManTotal != 30 {
+		t.Errorf("Expected manpower detail to be loaded")
+	}
+
+	stages := order.Fabric.Stages
+	if len(stages) != len(domain.GetAllStages())+1 {
+		t.Fatalf("Expected every stage plus unassigned, got %d entries", len(stages))
+	}
+	if stages[0].Stage != string(domain.StageInventory) || stages[0].Rolls != 0 {
+		t.Errorf("Expected empty inventory stage first, got %+v", stages[0])
+	}
+
+	var yards float64
+	for _, st := range stages {
+		yards += st.Yards
+		if st.Stage == string(domain.StageRelaxation) && st.Yards != 210 {
+			t.Errorf("Expected 210 yards on relaxation, got %v", st.Yards)
+		}
+	}
+	if yards != order.Fabric.YardsReceived {
+		t.Errorf("Expected stage yards to add up to %v, got %v", order.Fabric.YardsReceived, yards)
+	}
+
+	last := stages[len(stages)-1]
+	if last.Stage != domain.StageUnassigned || last.Rolls != 2 || last.Yards != 100 {
+		t.Errorf("Expected unknown stages to be folded into unassigned, got %+v", last)
+	}
+}
+
+// A roll without an inventory row and a roll with an empty stage both come
+// back as unassigned; neither may be dropped from the reconciliation.
+func TestOrderGet_KeepsEveryUnassignedRoll(t *testing.T) {
+	repo := &mockOrderRepository{
+		order:    &domain.Order{ID: 1, Code: "ORD-1"},
+		received: &domain.FabricReconciliation{Incomings: 1, RollsReceived: 3, YardsReceived: 250},
+		byStage: []domain.StageYards{
+			{Stage: string(domain.StageRelaxation), Rolls: 1, Yards: 100},
+			{Stage: domain.StageUnassigned, Rolls: 1, Yards: 70},
+			{Stage: domain.StageUnassigned, Rolls: 1, Yards: 80},
+		},
+	}
+
+	order, err := NewOrderService(repo).Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	last := order.Fabric.Stages[len(order.Fabric.Stages)-1]
+	if last.Stage != domain.StageUnassigned || last.Rolls != 2 || last.Yards != 150 {
+		t.Errorf("Expected both unassigned rolls to be counted, got %+v", last)
+	}
+}
+
+func TestOrderGet_NotFound(t *testing.T) {
+	svc := NewOrderService(&mockOrderRepository{})
+	if _, err := svc.Get(context.Background(), 1); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var (
+	ErrPackingGateNotFound = errors.New("packing gate is not found")
+	ErrGarmentNotChecked   = errors.New("the piece has not been checked by QC")
+	ErrGarmentNotPassed    = errors.New("the piece did not pass QC")
+)
+
+type PackingService struct {
+	repo        repository.PackingRepository
+	garmentRepo repository.GarmentQCRepository
+	activity    *ActivityService
+}
+
+func NewPackingService(repo repository.PackingRepository, garmentRepo repository.GarmentQCRepository, activity *ActivityService) *PackingService {
+	return &PackingService{repo: repo, garmentRepo: garmentRepo, activity: activity}
+}
+
+func (s *PackingService) ListGates(ctx context.Context) ([]domain.PackingGate, error) {
+	return s.repo.ListGates(ctx)
+}
+
+// Scan packs a piece that passed QC at a gate. Packing over the bundle's
+// tolerance is allowed but comes back with a warning.
+func (s *PackingService) Scan(ctx context.Context, packingID int64, qrCode string) (*domain.PackingScanResult, error) {
+	qrCode = strings.TrimSpace(qrCode)
+	bundle, err := parseGarmentCode(qrCode)
+	if err != nil {
+		return nil, err
+	}
+
+	gate, err := s.repo.FindGate(ctx, packingID)
+	if err != nil {
+		return nil, err
+	}
+	if gate == nil {
+		return nil, ErrPackingGateNotFound
+	}
+
+	item, err := s.garmentRepo.FindItemByCode(ctx, qrCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPackable(item); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Pack(ctx, item.ID, gate.ID); err != nil {
+		if errors.Is(err, repository.ErrItemNotPackable) {
+			return nil, s.notPackable(ctx, qrCode)
+		}
+		return nil, err
+	}
+	old := *item
+	item.Stage = domain.GarmentStagePacking
+	item.PackingID = &gate.ID
+	s.activity.Record(ctx, ActivityInput{
+		LogName:     "garment",
+		Event:       ActivityUpdated,
+		Description: "packed",
+		SubjectType: repository.SubjectRequestItem,
+		SubjectID:   item.ID,
+		Old:         &old,
+		New:         item,
+	})
+
+	summary, err := s.summary(ctx, bundle, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.PackingScanResult{Item: item, Summary: summary}
+	if summary.OverTolerance {
+		result.Warning = fmt.Sprintf("Bundle %s is packed over tolerance: %d packed, at most %s allowed.",
+			summary.Code, summary.Packed, formatAmount(summary.MaxAmount))
+	}
+	return result, nil
+}
+
+// Summary returns the packed quantity of a bundle and where each piece was packed.
+func (s *PackingService) Summary(ctx context.Context, code string) (*domain.PackingSummary, error) {
+	return s.summary(ctx, strings.TrimSpace(code), true)
+}
+
+func (s *PackingService) summary(ctx context.Context, code string, withPieces bool) (*domain.PackingSummary, error) {
+	qr, err := s.garmentRepo.FindQRSystemByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if qr == nil {
+		return nil, ErrQRSystemNotFound
+	}
+
+	packed, err := s.repo.CountPacked(ctx, qr.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary := BuildPackingSummary(qr, packed)
+
+	if withPieces {
+		if summary.Pieces, err = s.repo.ListPacked(ctx, qr.ID); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// notPackable tells why a piece that changed since it was read could not be
+// packed.
+func (s *PackingService) notPackable(ctx context.Context, qrCode string) error {
+	item, err := s.garmentRepo.FindItemByCode(ctx, qrCode)
+	if err != nil {
+		return err
+	}
+	if err := checkPackable(item); err != nil {
+		return err
+	}
+	return ErrGarmentPacked
+}
+
+// checkPackable refuses pieces that QC has not passed, including pieces that
+// are still being reworked.
+func checkPackable(item *domain.RequestItem) error {
+	switch {
+	case item == nil:
+		return ErrGarmentNotChecked
+	case item.Stage == domain.GarmentStagePacking:
+		return ErrGarmentPacked
+	case item.IsRework:
+		return ErrGarmentInRework
+	case item.Result != domain.GarmentResultPass:
+		return ErrGarmentNotPassed
+	}
+	return nil
+}
+
+// BuildPackingSummary compares the packed quantity with the bundle amount.
+// The tolerance is a percentage of the amount that may be packed on top.
+func BuildPackingSummary(qr *domain.QRSystem, packed int) *domain.PackingSummary {
+	maxAmount := qr.Amount
+	if qr.AmountTolerance != nil {
+		maxAmount = qr.Amount * (1 + *qr.AmountTolerance/100)
+	}
+
+	return &domain.PackingSummary{
+		QRSystemID:      qr.ID,
+		Code:            qr.Code,
+		OrderID:         qr.OrderID,
+		Color:           qr.Color,
+		Size:            qr.Size,
+		Amount:          qr.Amount,
+		AmountTolerance: qr.AmountTolerance,
+		MaxAmount:       round2(maxAmount),
+		Packed:          packed,
+		Remaining:       round2(math.Max(0, qr.Amount-float64(packed))),
+		OverTolerance:   float64(packed) > maxAmount,
+	}
+}
+
+func formatAmount(v float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock PackingRepository for testing
+type mockPackingRepository struct {
+	packed   int
+	packedID int64
+	// onPack runs before packing; an error refuses the piece.
+	onPack func() error
+}
+
+func (m *mockPackingRepository) ListGates(ctx context.Context) ([]domain.PackingGate, error) {
+	return nil, nil
+}
+
+func (m *mockPackingRepository) FindGate(ctx context.Context, id int64) (*domain.PackingGate, error) {
+	if id != 1 {
+		return nil, nil
+	}
+	return &domain.PackingGate{ID: 1, Gate: "Gate 1"}, nil
+}
+
+func (m *mockPackingRepository) Pack(ctx context.Context, itemID, packingID int64) error {
+	if m.onPack != nil {
+		if err := m.onPack(); err != nil {
+			return err
+		}
+	}
+	m.packedID = itemID
+	m.packed++
+	return nil
+}
+
+func (m *mockPackingRepository) CountPacked(ctx context.Context, qrSystemID int64) (int, error) {
+	return m.packed, nil
+}
+
+func (m *mockPackingRepository) ListPacked(ctx context.Context, qrSystemID int64) ([]domain.PackedPiece, error) {
+	return nil, nil
+}
+
+func TestPackingScan(t *testing.T) {
+	tolerance := 10.0
+	garmentRepo := newGarmentQCRepo()
+	garmentRepo.qr.Amount = 10
+	garmentRepo.qr.AmountTolerance = &tolerance
+	garmentRepo.existing = &domain.RequestItem{ID: 3, QRCode: "223-3", Result: domain.GarmentResultPass, Stage: domain.GarmentStageFinishing}
+	repo := &mockPackingRepository{packed: 9}
+	svc := NewPackingService(repo, garmentRepo, nil)
+
+	result, err := svc.Scan(context.Background(), 1, "223-3")
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if repo.packedID != 3 || result.Item.Stage != domain.GarmentStagePacking || *result.Item.PackingID != 1 {
+		t.Errorf("Expected piece 3 packed at gate 1, got %+v", result.Item)
+	}
+	if result.Summary.Packed != 10 || result.Summary.Remaining != 0 || result.Warning != "" {
+		t.Errorf("Expected bundle complete without warning, got %+v %q", result.Summary, result.Warning)
+	}
+
+	repo.packed = 11
+	garmentRepo.existing.Stage = domain.GarmentStageFinishing
+	result, err = svc.Scan(context.Background(), 1, "223-3")
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if !result.Summary.OverTolerance || result.Warning == "" {
+		t.Errorf("Expected a warning for 12 packed against at most 11, got %+v", result.Summary)
+	}
+}
+
+func TestPackingScan_Refused(t *testing.T) {
+	testCases := []struct {
+		name     string
+		gateID   int64
+		item     *domain.RequestItem
+		expected error
+	}{
+		{"unknown gate", 2, &domain.RequestItem{Result: domain.GarmentResultPass}, ErrPackingGateNotFound},
+		{"not checked", 1, nil, ErrGarmentNotChecked},
+		{"failed", 1, &domain.RequestItem{Result: domain.GarmentResultFail, IsCNCM: true}, ErrGarmentNotPassed},
+		{"in rework", 1, &domain.RequestItem{Result: domain.GarmentResultDefect, IsRework: true, Stage: domain.GarmentStageProcess}, ErrGarmentInRework},
+		{"already packed", 1, &domain.RequestItem{Result: domain.GarmentResultPass, Stage: domain.GarmentStagePacking}, ErrGarmentPacked},
+	}
+
+	for _, tc := range testCases {
+		garmentRepo := newGarmentQCRepo()
+		garmentRepo.existing = tc.item
+		repo := &mockPackingRepository{}
+		svc := NewPackingService(repo, garmentRepo, nil)
+
+		_, err := svc.Scan(context.Background(), tc.gateID, "223-1")
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+		if repo.packed != 0 {
+			t.Errorf("%s: expected nothing to be packed", tc.name)
+		}
+	}
+}
+
+// A piece sent to rework between the read and the write is not packed.
+func TestPackingScan_SentToReworkMeanwhile(t *testing.T) {
+	garmentRepo := newGarmentQCRepo()
+	garmentRepo.existing = &domain.RequestItem{ID: 3, QRCode: "223-3", Result: domain.GarmentResultPass, Stage: domain.GarmentStageFinishing}
+	repo := &mockPackingRepository{onPack: func() error {
+		garmentRepo.existing.IsRework = true
+		return repository.ErrItemNotPackable
+	}}
+	svc := NewPackingService(repo, garmentRepo, nil)
+
+	if _, err := svc.Scan(context.Background(), 1, "223-3"); !errors.Is(err, ErrGarmentInRework) {
+		t.Errorf("Expected ErrGarmentInRework, got %v", err)
+	}
+	if repo.packed != 0 {
+		t.Errorf("Expected nothing packed, got %d", repo.packed)
+	}
+}
+
+func TestBuildPackingSummary_WithoutTolerance(t *testing.T) {
+	summary := BuildPackingSummary(&domain.QRSystem{Code: "001", Amount: 6}, 4)
+	if summary.MaxAmount != 6 || summary.Remaining != 2 || summary.OverTolerance {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if over := BuildPackingSummary(&domain.QRSystem{Amount: 6}, 7); !over.OverTolerance || over.Remaining != 0 {
+		t.Errorf("Expected 7 of 6 to be over tolerance, got %+v", over)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var (
+	ErrRequestNotFound        = errors.New("request is not found")
+	ErrRequestProcessNotFound = errors.New("the process is not part of the request, copy the order processes first")
+	ErrManPowerNotFound       = errors.New("operator is not found")
+	ErrManPowerInactive       = errors.New("operator is not active")
+	ErrSkillMismatch          = errors.New("the operator's class does not match the process")
+	ErrRequestProcessChanged  = errors.New("the assignment has been changed in the meantime, reload and try again")
+)
+
+type RequestProcessService struct {
+	repo     repository.RequestProcessRepository
+	activity *ActivityService
+}
+
+func NewRequestProcessService(repo repository.RequestProcessRepository, activity *ActivityService) *RequestProcessService {
+	return &RequestProcessService{repo: repo, activity: activity}
+}
+
+// List returns the current operator of every process of the request.
+func (s *RequestProcessService) List(ctx context.Context, requestID int64) ([]domain.RequestProcess, error) {
+	if _, err := s.findRequest(ctx, requestID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, requestID, false)
+}
+
+// History returns every assignment of the request, replaced ones included.
+func (s *RequestProcessService) History(ctx context.Context, requestID int64) ([]domain.RequestProcess, error) {
+	if _, err := s.findRequest(ctx, requestID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, requestID, true)
+}
+
+// CopyFromOrder adds the processes of the request's order that are missing,
+// so it can be run again after the order's process list changed.
+func (s *RequestProcessService) CopyFromOrder(ctx context.Context, requestID int64) ([]domain.RequestProcess, error) {
+	request, err := s.findRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := s.repo.CopyFromOrder(ctx, request.ID, request.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if copied > 0 {
+		s.activity.Record(ctx, ActivityInput{
+			LogName:     "production",
+			Event:       ActivityUpdated,
+			Description: "copied order processes",
+			SubjectType: repository.SubjectRequest,
+			SubjectID:   request.ID,
+			New:         map[string]interface{}{"order_id": request.OrderID, "copied_processes": copied},
+		})
+	}
+	return s.repo.List(ctx, requestID, false)
+}
+
+// Assign puts an operator on a process of the request. The operator must be
+// active and, when the process asks for a class, be of that class.
+func (s *RequestProcessService) Assign(ctx context.Context, userID, requestID, orderProcessID, manPowerID int64) (*domain.RequestProcess, error) {
+	if _, err := s.findRequest(ctx, requestID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.FindCurrent(ctx, requestID, orderProcessID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrRequestProcessNotFound
+	}
+	if current.ManPower != nil && current.ManPower.ID == manPowerID {
+		return current, nil
+	}
+
+	manPower, err := s.repo.FindManPower(ctx, manPowerID)
+	if err != nil {
+		return nil, err
+	}
+	if manPower == nil {
+		return nil, ErrManPowerNotFound
+	}
+	if !manPower.IsActive {
+		return nil, ErrManPowerInactive
+	}
+	if err := checkSkill(current, manPower); err != nil {
+		return nil, err
+	}
+
+	assigned, err := s.repo.Reassign(ctx, current, manPowerID, userID)
+	if errors.Is(err, repository.ErrRequestProcessChanged) {
+		return nil, ErrRequestProcessChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Reassigning replaces the row, so the new row is logged against the old one
+	s.activity.Record(ctx, ActivityInput{
+		LogName:     "production",
+		Event:       ActivityUpdated,
+		Description: "assigned operator",
+		SubjectType: repository.SubjectRequestProcess,
+		SubjectID:   assigned.ID,
+		Old:         current,
+		New:         assigned,
+		CauserID:    userID,
+	})
+	return assigned, nil
+}
+
+func (s *RequestProcessService) findRequest(ctx context.Context, id int64) (*domain.LineRequest, error) {
+	request, err := s.repo.FindRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrRequestNotFound
+	}
+	return request, nil
+}
+
+func checkSkill(process *domain.RequestProcess, manPower *domain.ManPower) error {
+	if process.ClassID == nil {
+		return nil
+	}
+	if manPower.ClassID != nil && *manPower.ClassID == *process.ClassID {
+		return nil
+	}
+	return fmt.Errorf("%w: %s needs %s, %s is %s", ErrSkillMismatch,
+		process.ProcessName, stringOr(process.ClassName, "another class"),
+		manPower.Nickname, stringOr(manPower.ClassName, "not classified"))
+}
+
+func stringOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
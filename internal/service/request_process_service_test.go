@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// Mock RequestProcessRepository for testing
+type mockRequestProcessRepository struct {
+	request    *domain.LineRequest
+	current    *domain.RequestProcess
+	manPowers  map[int64]*domain.ManPower
+	reassigned int64
+}
+
+func (m *mockRequestProcessRepository) FindRequest(ctx context.Context, id int64) (*domain.LineRequest, error) {
+	return m.request, nil
+}
+
+func (m *mockRequestProcessRepository) List(ctx context.Context, requestID int64, withDeleted bool) ([]domain.RequestProcess, error) {
+	return nil, nil
+}
+
+func (m *mockRequestProcessRepository) FindCurrent(ctx context.Context, requestID, orderProcessID int64) (*domain.RequestProcess, error) {
+	if m.current == nil || m.current.OrderProcessID != orderProcessID {
+		return nil, nil
+	}
+	return m.current, nil
+}
+
+func (m *mockRequestProcessRepository) CopyFromOrder(ctx context.Context, requestID, orderID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRequestProcessRepository) FindManPower(ctx context.Context, id int64) (*domain.ManPower, error) {
+	return m.manPowers[id], nil
+}
+
+func (m *mockRequestProcessRepository) Reassign(ctx context.Context, current *domain.RequestProcess, manPowerID, userID int64) (*domain.RequestProcess, error) {
+	m.reassigned = manPowerID
+	assigned := *current
+	assigned.ManPower = m.manPowers[manPowerID]
+	assigned.AssignedBy = &userID
+	return &assigned, nil
+}
+
+func newRequestProcessRepo() *mockRequestProcessRepository {
+	operator, helper := int64(1), int64(2)
+	operatorName := "Operator"
+	return &mockRequestProcessRepository{
+		request: &domain.LineRequest{ID: 22, OrderID: 3},
+		current: &domain.RequestProcess{ID: 6, RequestID: 22, OrderProcessID: 11, ProcessName: "Join shoulder", ClassID: &operator, ClassName: &operatorName},
+		manPowers: map[int64]*domain.ManPower{
+			5: {ID: 5, Nickname: "Ani", ClassID: &operator, IsActive: true},
+			6: {ID: 6, Nickname: "Budi", ClassID: &helper, IsActive: true},
+			7: {ID: 7, Nickname: "Citra", ClassID: &operator},
+		},
+	}
+}
+
+func TestAssignOperator(t *testing.T) {
+	repo := newRequestProcessRepo()
+	svc := NewRequestProcessService(repo, nil)
+
+	assigned, err := svc.Assign(context.Background(), 9, 22, 11, 5)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if repo.reassigned != 5 || assigned.ManPower.ID != 5 || *assigned.AssignedBy != 9 {
+		t.Errorf("Expected operator 5 assigned by user 9, got %+v", assigned)
+	}
+
+	repo.reassigned = 0
+	repo.current = assigned
+	if _, err := svc.Assign(context.Background(), 9, 22, 11, 5); err != nil || repo.reassigned != 0 {
+		t.Errorf("Expected assigning the same operator again to be a no-op, got %v", err)
+	}
+}
+
+func TestAssignOperator_Rejected(t *testing.T) {
+	testCases := []struct {
+		name           string
+		orderProcessID int64
+		manPowerID     int64
+		setup          func(*mockRequestProcessRepository)
+		expected       error
+	}{
+		{"unknown request", 11, 5, func(m *mockRequestProcessRepository) { m.request = nil }, ErrRequestNotFound},
+		{"process not copied", 12, 5, nil, ErrRequestProcessNotFound},
+		{"unknown operator", 11, 8, nil, ErrManPowerNotFound},
+		{"inactive operator", 11, 7, nil, ErrManPowerInactive},
+		{"class mismatch", 11, 6, nil, ErrSkillMismatch},
+	}
+
+	for _, tc := range testCases {
+		repo := newRequestProcessRepo()
+		if tc.setup != nil {
+			tc.setup(repo)
+		}
+		svc := NewRequestProcessService(repo, nil)
+
+		_, err := svc.Assign(context.Background(), 9, 22, tc.orderProcessID, tc.manPowerID)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+		if repo.reassigned != 0 {
+			t.Errorf("%s: expected no reassignment", tc.name)
+		}
+	}
+}
+
+func TestAssignOperator_ProcessWithoutClass(t *testing.T) {
+	repo := newRequestProcessRepo()
+	repo.current.ClassID = nil
+	svc := NewRequestProcessService(repo, nil)
+
+	if _, err := svc.Assign(context.Background(), 9, 22, 11, 6); err != nil {
+		t.Errorf("Expected any active operator on a process without class, got %v", err)
+	}
+}
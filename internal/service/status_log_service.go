@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var ErrUnknownDocument = errors.New("unknown document type")
+
+// statusLogDocuments maps the document names used in URLs to the loggable
+// types their status changes are stored under.
+var statusLogDocuments = map[string]string{
+	"fabrics":          repository.LoggableFabric,
+	"destroy-requests": repository.LoggableFabricDestroyRequest,
+	"request-items":    repository.LoggableRequestItem,
+}
+
+type StatusLogService struct {
+	repo repository.StatusLogRepository
+}
+
+func NewStatusLogService(repo repository.StatusLogRepository) *StatusLogService {
+	return &StatusLogService{repo: repo}
+}
+
+// History returns the status changes of a document, e.g. ("fabrics", 12).
+func (s *StatusLogService) History(ctx context.Context, document string, id int64) ([]domain.StatusLog, error) {
+	loggableType, ok := statusLogDocuments[document]
+	if !ok {
+		return nil, ErrUnknownDocument
+	}
+	return s.repo.History(ctx, loggableType, id)
+}
+
+// StatusLogDocuments lists the document names History accepts.
+func StatusLogDocuments() []string {
+	documents := make([]string, 0, len(statusLogDocuments))
+	for document := range statusLogDocuments {
+		documents = append(documents, document)
+	}
+	sort.Strings(documents)
+	return documents
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock StatusLogRepository for testing
+type mockStatusLogRepository struct {
+	loggableType string
+	loggableID   int64
+}
+
+func (m *mockStatusLogRepository) Log(ctx context.Context, entry repository.StatusLogEntry) error {
+	return nil
+}
+
+func (m *mockStatusLogRepository) History(ctx context.Context, loggableType string, loggableID int64) ([]domain.StatusLog, error) {
+	m.loggableType, m.loggableID = loggableType, loggableID
+	return []domain.StatusLog{{LoggableType: loggableType, LoggableID: loggableID}}, nil
+}
+
+func TestStatusLogHistory_MapsDocumentToLoggableType(t *testing.T) {
+	repo := &mockStatusLogRepository{}
+	svc := NewStatusLogService(repo)
+
+	logs, err := svc.History(context.Background(), "destroy-requests", 7)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(logs) != 1 || repo.loggableType != repository.LoggableFabricDestroyRequest || repo.loggableID != 7 {
+		t.Errorf("Expected history of destroy request 7, got %s %d", repo.loggableType, repo.loggableID)
+	}
+
+	if _, err := svc.History(context.Background(), "invoices", 7); !errors.Is(err, ErrUnknownDocument) {
+		t.Errorf("Expected ErrUnknownDocument, got %v", err)
+	}
+}
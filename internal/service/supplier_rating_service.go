@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+const (
+	ratingPeriodLayout = "2006-01"
+
+	// acceptableYsdPoints is the usual 4-point system acceptance limit
+	// (points per 100 square yards). Rolls at the limit score 50 for defects.
+	acceptableYsdPoints = 40.0
+
+	// maxRecomputePeriods caps how many months one recompute request covers.
+	maxRecomputePeriods = 24
+)
+
+// JobComputeSupplierRatings recomputes the ratings of one period.
+const JobComputeSupplierRatings = "supplier-ratings.compute"
+
+var ErrRatingPeriodRange = fmt.Errorf("the period range must run forward and cover at most %d months", maxRecomputePeriods)
+
+type SupplierRatingService struct {
+	repo     repository.SupplierRatingRepository
+	leadDays int
+	activity *ActivityService
+	jobs     *JobRunner
+}
+
+// NewSupplierRatingService registers the recompute job with jobs when it is
+// given.
+func NewSupplierRatingService(repo repository.SupplierRatingRepository, leadDays int, activity *ActivityService, jobs *JobRunner) *SupplierRatingService {
+	s := &SupplierRatingService{repo: repo, leadDays: leadDays, activity: activity, jobs: jobs}
+	if jobs != nil {
+		jobs.Register(JobComputeSupplierRatings, s.computePeriodJob)
+	}
+	return s
+}
+
+type computeRatingsJob struct {
+	Period string `json:"period"`
+}
+
+func (s *SupplierRatingService) computePeriodJob(ctx context.Context, data json.RawMessage) error {
+	var job computeRatingsJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return fmt.Errorf("invalid job data: %w", err)
+	}
+	_, err := s.ComputePeriod(ctx, job.Period)
+	return err
+}
+
+// Recompute queues one job per month from fromPeriod to toPeriod, e.g. after
+// inspections were corrected, and returns the batch to follow them with.
+func (s *SupplierRatingService) Recompute(ctx context.Context, fromPeriod, toPeriod string) (*domain.JobBatch, error) {
+	periods, err := RatingPeriods(fromPeriod, toPeriod)
+	if err != nil {
+		return nil, err
+	}
+	if s.jobs == nil {
+		return nil, errors.New("background jobs are not available")
+	}
+
+	inputs := make([]JobInput, 0, len(periods))
+	for _, period := range periods {
+		inputs = append(inputs, JobInput{
+			Name:        JobComputeSupplierRatings,
+			DisplayName: "Compute supplier ratings " + period,
+			Data:        computeRatingsJob{Period: period},
+		})
+	}
+	return s.jobs.DispatchBatch(ctx, fmt.Sprintf("Recompute supplier ratings %s to %s", fromPeriod, toPeriod), inputs)
+}
+
+// RatingPeriods lists the months from fromPeriod to toPeriod, both included.
+func RatingPeriods(fromPeriod, toPeriod string) ([]string, error) {
+	from, err := time.Parse(ratingPeriodLayout, fromPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid period %q, expected YYYY-MM", fromPeriod)
+	}
+	to, err := time.Parse(ratingPeriodLayout, toPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid period %q, expected YYYY-MM", toPeriod)
+	}
+
+	var periods []string
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		if len(periods) == maxRecomputePeriods {
+			return nil, ErrRatingPeriodRange
+		}
+		periods = append(periods, month.Format(ratingPeriodLayout))
+	}
+	if len(periods) == 0 {
+		return nil, ErrRatingPeriodRange
+	}
+	return periods, nil
+}
+
+// ComputePeriod derives quality and delivery scores for every supplier with
+// activity in the given month and stores them in supplier_ratings. The price
+// score is entered by purchasing and is kept as is.
+func (s *SupplierRatingService) ComputePeriod(ctx context.Context, period string) ([]domain.SupplierRating, error) {
+	from, err := time.ParseInLocation(ratingPeriodLayout, period, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid period %q, expected YYYY-MM", period)
+	}
+	to := from.AddDate(0, 1, 0)
+	ctx = WithActivityBatch(ctx)
+
+	qualityStats, err := s.repo.GetQualityStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	deliveryStats, err := s.repo.GetDeliveryStats(ctx, from, to, s.leadDays)
+	if err != nil {
+		return nil, err
+	}
+
+	quality := map[int64]repository.SupplierQualityStats{}
+	delivery := map[int64]repository.SupplierDeliveryStats{}
+	var supplierIDs []int64
+	for _, q := range qualityStats {
+		quality[q.SupplierID] = q
+		supplierIDs = append(supplierIDs, q.SupplierID)
+	}
+	for _, d := range deliveryStats {
+		if _, ok := quality[d.SupplierID]; !ok {
+			supplierIDs = append(supplierIDs, d.SupplierID)
+		}
+		delivery[d.SupplierID] = d
+	}
+
+	var ratings []domain.SupplierRating
+	for _, supplierID := range supplierIDs {
+		q, hasQuality := quality[supplierID]
+		d, hasDelivery := delivery[supplierID]
+
+		rating, err := s.repo.FindByPeriod(ctx, supplierID, period)
+		if err != nil {
+			return nil, err
+		}
+		var old *domain.SupplierRating
+		if rating == nil {
+			p := period
+			rating = &domain.SupplierRating{SupplierID: supplierID, Period: &p}
+		} else {
+			previous := *rating
+			old = &previous
+		}
+
+		remarks := fmt.Sprintf("Computed from %d inspections, %d QC results and %d deliveries.", q.Inspections, q.Controls, d.Deliveries)
+		rating.Remarks = &remarks
+
+		var scores []int
+		if hasQuality {
+			rating.RatingQuality = QualityScore(q)
+			scores = append(scores, rating.RatingQuality)
+		}
+		if hasDelivery {
+			rating.RatingDelivery = DeliveryScore(d)
+			scores = append(scores, rating.RatingDelivery)
+		}
+		if rating.RatingPrice > 0 {
+			scores = append(scores, rating.RatingPrice)
+		}
+		rating.RatingOverall = averageScore(scores...)
+
+		if err := s.repo.Save(ctx, rating); err != nil {
+			return nil, err
+		}
+		event := ActivityUpdated
+		if old == nil {
+			event = ActivityCreated
+		}
+		s.activity.Record(ctx, ActivityInput{
+			LogName:     "partner",
+			Event:       event,
+			Description: "computed rating " + period,
+			SubjectType: repository.SubjectSupplierRating,
+			SubjectID:   rating.ID,
+			Old:         old,
+			New:         rating,
+		})
+		ratings = append(ratings, *rating)
+	}
+
+	return ratings, nil
+}
+
+func (s *SupplierRatingService) GetByPeriod(ctx context.Context, period string) ([]domain.SupplierRating, error) {
+	if _, err := time.Parse(ratingPeriodLayout, period); err != nil {
+		return nil, fmt.Errorf("invalid period %q, expected YYYY-MM", period)
+	}
+	return s.repo.ListByPeriod(ctx, period)
+}
+
+// GetTrend returns the monthly ratings of a supplier between two periods.
+// Without a range it covers the last twelve months.
+func (s *SupplierRatingService) GetTrend(ctx context.Context, supplierID int64, fromPeriod, toPeriod string) ([]domain.SupplierRating, error) {
+	now := time.Now()
+	if toPeriod == "" {
+		toPeriod = now.Format(ratingPeriodLayout)
+	}
+	if fromPeriod == "" {
+		fromPeriod = now.AddDate(0, -11, 0).Format(ratingPeriodLayout)
+	}
+	for _, p := range []string{fromPeriod, toPeriod} {
+		if _, err := time.Parse(ratingPeriodLayout, p); err != nil {
+			return nil, fmt.Errorf("invalid period %q, expected YYYY-MM", p)
+		}
+	}
+	return s.repo.ListBySupplier(ctx, supplierID, fromPeriod, toPeriod)
+}
+
+// RunSchedule recomputes the current and previous month on every tick until
+// ctx is cancelled. The previous month is included so late inspections and
+// deliveries still land in the right period.
+func (s *SupplierRatingService) RunSchedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		for _, period := range []string{now.AddDate(0, -1, 0).Format(ratingPeriodLayout), now.Format(ratingPeriodLayout)} {
+			ratings, err := s.ComputePeriod(ctx, period)
+			if err != nil {
+				log.Error().Err(err).Str("period", period).Msg("Failed to compute supplier ratings")
+				continue
+			}
+			log.Info().Str("period", period).Int("suppliers", len(ratings)).Msg("Computed supplier ratings")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// QualityScore combines the inspection pass rate, the average defect points
+// and the QC pass rate. Only the parts with data are averaged.
+func QualityScore(q repository.SupplierQualityStats) int {
+	var scores []int
+	if q.Inspections > 0 {
+		scores = append(scores, percentage(q.InspectionsPassed, q.Inspections))
+
+		defectScore := 100 - q.AvgYsdPoints*50/acceptableYsdPoints
+		scores = append(scores, clampScore(defectScore))
+	}
+	if q.Controls > 0 {
+		scores = append(scores, percentage(q.ControlsPassed, q.Controls))
+	}
+	return averageScore(scores...)
+}
+
+// DeliveryScore is the share of deliveries that arrived within the lead time.
+func DeliveryScore(d repository.SupplierDeliveryStats) int {
+	if d.Deliveries == 0 {
+		return 0
+	}
+	return percentage(d.OnTime, d.Deliveries)
+}
+
+func percentage(part, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return clampScore(float64(part) * 100 / float64(total))
+}
+
+func averageScore(scores ...int) int {
+	if len(scores) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, score := range scores {
+		sum += score
+	}
+	return clampScore(float64(sum) / float64(len(scores)))
+}
+
+func clampScore(v float64) int {
+	return int(math.Round(math.Max(0, math.Min(100, v))))
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock SupplierRatingRepository for testing
+type mockSupplierRatingRepository struct {
+	quality  []repository.SupplierQualityStats
+	delivery []repository.SupplierDeliveryStats
+	existing map[int64]*domain.SupplierRating
+	saved    []domain.SupplierRating
+}
+
+func (m *mockSupplierRatingRepository) GetQualityStats(ctx context.Context, from, to time.Time) ([]repository.SupplierQualityStats, error) {
+	return m.quality, nil
+}
+
+func (m *mockSupplierRatingRepository) GetDeliveryStats(ctx context.Context, from, to time.Time, leadDays int) ([]repository.SupplierDeliveryStats, error) {
+	return m.delivery, nil
+}
+
+func (m *mockSupplierRatingRepository) FindByPeriod(ctx context.Context, supplierID int64, period string) (*domain.SupplierRating, error) {
+	return m.existing[supplierID], nil
+}
+
+func (m *mockSupplierRatingRepository) Save(ctx context.Context, rating *domain.SupplierRating) error {
+	m.saved = append(m.saved, *rating)
+	return nil
+}
+
+func (m *mockSupplierRatingRepository) ListByPeriod(ctx context.Context, period string) ([]domain.SupplierRating, error) {
+	return nil, nil
+}
+
+func (m *mockSupplierRatingRepository) ListBySupplier(ctx context.Context, supplierID int64, fromPeriod, toPeriod string) ([]domain.SupplierRating, error) {
+	return nil, nil
+}
+
+func TestQualityScore(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stats    repository.SupplierQualityStats
+		expected int
+	}{
+		{"no data", repository.SupplierQualityStats{}, 0},
+		{"all passed without defects", repository.SupplierQualityStats{Inspections: 4, InspectionsPassed: 4, Controls: 2, ControlsPassed: 2}, 100},
+		{"defects at acceptance limit", repository.SupplierQualityStats{Inspections: 2, InspectionsPassed: 2, AvgYsdPoints: 40}, 75},
+		{"only QC results", repository.SupplierQualityStats{Controls: 4, ControlsPassed: 3}, 75},
+		{"defects far above limit", repository.SupplierQualityStats{Inspections: 1, AvgYsdPoints: 200, Controls: 1}, 0},
+	}
+
+	for _, tc := range testCases {
+		if got := QualityScore(tc.stats); got != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestDeliveryScore(t *testing.T) {
+	if got := DeliveryScore(repository.SupplierDeliveryStats{Deliveries: 3, OnTime: 2}); got != 67 {
+		t.Errorf("Expected 67, got %d", got)
+	}
+	if got := DeliveryScore(repository.SupplierDeliveryStats{}); got != 0 {
+		t.Errorf("Expected 0 without deliveries, got %d", got)
+	}
+}
+
+func TestComputePeriod_KeepsPriceScore(t *testing.T) {
+	period := "2026-09"
+	repo := &mockSupplierRatingRepository{
+		quality: []repository.SupplierQualityStats{
+			{SupplierID: 1, Controls: 2, ControlsPassed: 2},
+		},
+		delivery: []repository.SupplierDeliveryStats{
+			{SupplierID: 1, Deliveries: 2, OnTime: 1},
+			{SupplierID: 2, Deliveries: 1, OnTime: 1},
+		},
+		existing: map[int64]*domain.SupplierRating{
+			1: {ID: 10, SupplierID: 1, Period: &period, RatingPrice: 70},
+		},
+	}
+	svc := NewSupplierRatingService(repo, 30, nil, nil)
+
+	ratings, err := svc.ComputePeriod(context.Background(), period)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if len(ratings) != 2 || len(repo.saved) != 2 {
+		t.Fatalf("Expected 2 ratings to be saved, got %d", len(repo.saved))
+	}
+
+	first := ratings[0]
+	if first.ID != 10 || first.RatingPrice != 70 {
+		t.Errorf("Expected existing rating 10 with price 70 to be updated, got id %d price %d", first.ID, first.RatingPrice)
+	}
+	if first.RatingQuality != 100 || first.RatingDelivery != 50 || first.RatingOverall != 73 {
+		t.Errorf("Unexpected scores: quality %d delivery %d overall %d", first.RatingQuality, first.RatingDelivery, first.RatingOverall)
+	}
+
+	second := ratings[1]
+	if second.SupplierID != 2 || second.RatingQuality != 0 || second.RatingOverall != 100 {
+		t.Errorf("Expected supplier 2 to be rated on delivery only, got %+v", second)
+	}
+	if second.Period == nil || *second.Period != period {
+		t.Errorf("Expected period %s on new rating", period)
+	}
+}
+
+func TestComputePeriod_InvalidPeriod(t *testing.T) {
+	svc := NewSupplierRatingService(&mockSupplierRatingRepository{}, 30, nil, nil)
+	if _, err := svc.ComputePeriod(context.Background(), "2026-13"); err == nil {
+		t.Error("Expected error for invalid period")
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/dppi/dppierp-api/pkg/storage"
+)
+
+var (
+	ErrSupplierNotFound          = errors.New("supplier is not found")
+	ErrSupplierCategoryNotFound  = errors.New("supplier category is not found")
+	ErrSupplierCategoryNameTaken = errors.New("the category name has already been taken")
+	ErrSupplierCategoryInUse     = errors.New("supplier category still has suppliers")
+	ErrSupplierDocumentNotFound  = errors.New("supplier document is not found")
+)
+
+type SupplierService struct {
+	repo     repository.SupplierRepository
+	storage  *storage.LocalStorage
+	activity *ActivityService
+}
+
+func NewSupplierService(repo repository.SupplierRepository, store *storage.LocalStorage, activity *ActivityService) *SupplierService {
+	return &SupplierService{repo: repo, storage: store, activity: activity}
+}
+
+func (s *SupplierService) List(ctx context.Context, filter repository.PartnerFilter) ([]domain.Supplier, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	return s.repo.List(ctx, filter)
+}
+
+// Get returns the supplier together with its documents.
+func (s *SupplierService) Get(ctx context.Context, id int64) (*domain.Supplier, error) {
+	supplier, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier.Documents, err = s.repo.ListDocuments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func (s *SupplierService) Create(ctx context.Context, supplier *domain.Supplier) error {
+	if err := s.prepare(ctx, supplier, 0); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, supplier); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityCreated, repository.SubjectSupplier, supplier.ID, nil, supplier)
+	return s.reload(ctx, supplier)
+}
+
+// Update replaces the supplier details. The status only changes through ToggleStatus.
+func (s *SupplierService) Update(ctx context.Context, id int64, input *domain.Supplier) (*domain.Supplier, error) {
+	supplier, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ID = id
+	input.Status = supplier.Status
+	if err := s.prepare(ctx, input, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, input); err != nil {
+		return nil, err
+	}
+	if err := s.reload(ctx, input); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, repository.SubjectSupplier, id, supplier, input)
+	return input, nil
+}
+
+// ToggleStatus switches the supplier between active (1) and inactive (0).
+func (s *SupplierService) ToggleStatus(ctx context.Context, id int64) (*domain.Supplier, error) {
+	supplier, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *supplier
+	supplier.Status = toggledStatus(supplier.Status)
+	if err := s.repo.SetStatus(ctx, id, supplier.Status); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, repository.SubjectSupplier, id, &old, supplier)
+	return supplier, nil
+}
+
+// Delete soft-deletes a supplier that is not referenced by fabric rolls or orders.
+func (s *SupplierService) Delete(ctx context.Context, id int64) error {
+	supplier, err := s.find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.repo.CountUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if usage > 0 {
+		return fmt.Errorf("%w (%d records)", ErrPartnerInUse, usage)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityDeleted, repository.SubjectSupplier, id, supplier, nil)
+	return nil
+}
+
+func (s *SupplierService) ListCategories(ctx context.Context) ([]domain.SupplierCategory, error) {
+	return s.repo.ListCategories(ctx)
+}
+
+func (s *SupplierService) CreateCategory(ctx context.Context, category *domain.SupplierCategory) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if err := s.checkCategoryName(ctx, category.Name, 0); err != nil {
+		return err
+	}
+	if err := s.repo.CreateCategory(ctx, category); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityCreated, repository.SubjectSupplierCategory, category.ID, nil, category)
+	return nil
+}
+
+func (s *SupplierService) UpdateCategory(ctx context.Context, id int64, input *domain.SupplierCategory) (*domain.SupplierCategory, error) {
+	category, err := s.findCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *category
+	category.Name = strings.TrimSpace(input.Name)
+	category.Description = input.Description
+	if err := s.checkCategoryName(ctx, category.Name, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, repository.SubjectSupplierCategory, id, &old, category)
+	return category, nil
+}
+
+// DeleteCategory soft-deletes a category without active suppliers.
+func (s *SupplierService) DeleteCategory(ctx context.Context, id int64) error {
+	category, err := s.findCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	suppliers, err := s.repo.CountCategorySuppliers(ctx, id)
+	if err != nil {
+		return err
+	}
+	if suppliers > 0 {
+		return fmt.Errorf("%w (%d suppliers)", ErrSupplierCategoryInUse, suppliers)
+	}
+	if err := s.repo.DeleteCategory(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityDeleted, repository.SubjectSupplierCategory, id, category, nil)
+	return nil
+}
+
+type UploadSupplierDocument struct {
+	DocumentName string
+	DocumentType string
+	File         *multipart.FileHeader
+}
+
+// UploadDocument stores the file on local disk and records it against the supplier.
+func (s *SupplierService) UploadDocument(ctx context.Context, supplierID int64, req *UploadSupplierDocument) (*domain.SupplierDocument, error) {
+	if _, err := s.find(ctx, supplierID); err != nil {
+		return nil, err
+	}
+
+	f, err := req.File.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document %s: %w", req.File.Filename, err)
+	}
+	path, err := s.storage.Save("Supplier/Documents", req.File.Filename, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.DocumentName)
+	if name == "" {
+		name = req.File.Filename
+	}
+	document := &domain.SupplierDocument{
+		SupplierID:   supplierID,
+		DocumentName: &name,
+		DocumentType: optionalString(req.DocumentType),
+		FilePath:     &path,
+	}
+	if err := s.repo.CreateDocument(ctx, document); err != nil {
+		_ = s.storage.Delete(path)
+		return nil, err
+	}
+	s.record(ctx, ActivityCreated, repository.SubjectSupplierDocument, document.ID, nil, document)
+	return document, nil
+}
+
+// DeleteDocument removes the document record and its file.
+func (s *SupplierService) DeleteDocument(ctx context.Context, supplierID, id int64) error {
+	document, err := s.repo.FindDocumentByID(ctx, supplierID, id)
+	if err != nil {
+		return err
+	}
+	if document == nil {
+		return ErrSupplierDocumentNotFound
+	}
+
+	if err := s.repo.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityDeleted, repository.SubjectSupplierDocument, id, document, nil)
+	if document.FilePath != nil {
+		_ = s.storage.Delete(*document.FilePath)
+	}
+	return nil
+}
+
+func (s *SupplierService) find(ctx context.Context, id int64) (*domain.Supplier, error) {
+	supplier, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier == nil {
+		return nil, ErrSupplierNotFound
+	}
+	return supplier, nil
+}
+
+func (s *SupplierService) findCategory(ctx context.Context, id int64) (*domain.SupplierCategory, error) {
+	category, err := s.repo.FindCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrSupplierCategoryNotFound
+	}
+	return category, nil
+}
+
+func (s *SupplierService) prepare(ctx context.Context, supplier *domain.Supplier, excludeID int64) error {
+	supplier.Code = strings.TrimSpace(supplier.Code)
+	supplier.Name = strings.TrimSpace(supplier.Name)
+
+	if supplier.SupplierCategoryID != nil {
+		if _, err := s.findCategory(ctx, *supplier.SupplierCategoryID); err != nil {
+			return err
+		}
+	}
+
+	exists, err := s.repo.CodeExists(ctx, supplier.Code, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrPartnerCodeTaken
+	}
+	return nil
+}
+
+// reload refreshes the joined and stored fields after a write.
+func (s *SupplierService) reload(ctx context.Context, supplier *domain.Supplier) error {
+	stored, err := s.repo.FindByID(ctx, supplier.ID)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		*supplier = *stored
+	}
+	return nil
+}
+
+func (s *SupplierService) checkCategoryName(ctx context.Context, name string, excludeID int64) error {
+	exists, err := s.repo.CategoryNameExists(ctx, name, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrSupplierCategoryNameTaken
+	}
+	return nil
+}
+
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (s *SupplierService) record(ctx context.Context, event, subjectType string, id int64, old, new interface{}) {
+	s.activity.Record(ctx, ActivityInput{LogName: "partner", Event: event, SubjectType: subjectType, SubjectID: id, Old: old, New: new})
+}
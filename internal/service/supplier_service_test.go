@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+// Mock SupplierRepository for testing
+type mockSupplierRepository struct {
+	suppliers  map[int64]*domain.Supplier
+	categories map[int64]*domain.SupplierCategory
+	takenCodes map[string]bool
+	usage      int
+	statuses   map[int64]int
+	deleted    []int64
+	updated    *domain.Supplier
+}
+
+func (m *mockSupplierRepository) List(ctx context.Context, filter repository.PartnerFilter) ([]domain.Supplier, error) {
+	return nil, nil
+}
+
+func (m *mockSupplierRepository) FindByID(ctx context.Context, id int64) (*domain.Supplier, error) {
+	if s, ok := m.suppliers[id]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *mockSupplierRepository) Create(ctx context.Context, supplier *domain.Supplier) error {
+	supplier.ID = int64(len(m.suppliers) + 1)
+	m.suppliers[supplier.ID] = supplier
+	return nil
+}
+
+func (m *mockSupplierRepository) Update(ctx context.Context, supplier *domain.Supplier) error {
+	m.updated = supplier
+	return nil
+}
+
+func (m *mockSupplierRepository) CodeExists(ctx context.Context, code string, excludeID int64) (bool, error) {
+	return m.takenCodes[code], nil
+}
+
+func (m *mockSupplierRepository) SetStatus(ctx context.Context, id int64, status int) error {
+	if m.statuses == nil {
+		m.statuses = map[int64]int{}
+	}
+	m.statuses[id] = status
+	return nil
+}
+
+func (m *mockSupplierRepository) CountUsage(ctx context.Context, id int64) (int, error) {
+	return m.usage, nil
+}
+
+func (m *mockSupplierRepository) Delete(ctx context.Context, id int64) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *mockSupplierRepository) ListCategories(ctx context.Context) ([]domain.SupplierCategory, error) {
+	return nil, nil
+}
+
+func (m *mockSupplierRepository) FindCategoryByID(ctx context.Context, id int64) (*domain.SupplierCategory, error) {
+	return m.categories[id], nil
+}
+
+func (m *mockSupplierRepository) CreateCategory(ctx context.Context, category *domain.SupplierCategory) error {
+	return nil
+}
+
+func (m *mockSupplierRepository) UpdateCategory(ctx context.Context, category *domain.SupplierCategory) error {
+	return nil
+}
+
+func (m *mockSupplierRepository) CategoryNameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockSupplierRepository) CountCategorySuppliers(ctx context.Context, id int64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockSupplierRepository) DeleteCategory(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *mockSupplierRepository) ListDocuments(ctx context.Context, supplierID int64) ([]domain.SupplierDocument, error) {
+	return nil, nil
+}
+
+func (m *mockSupplierRepository) FindDocumentByID(ctx context.Context, supplierID, id int64) (*domain.SupplierDocument, error) {
+	return nil, nil
+}
+
+func (m *mockSupplierRepository) CreateDocument(ctx context.Context, document *domain.SupplierDocument) error {
+	return nil
+}
+
+func (m *mockSupplierRepository) DeleteDocument(ctx context.Context, id int64) error {
+	return nil
+}
+
+func TestSupplierService_CreateValidatesCodeAndCategory(t *testing.T) {
+	repo := &mockSupplierRepository{
+		suppliers:  map[int64]*domain.Supplier{},
+		categories: map[int64]*domain.SupplierCategory{1: {ID: 1, Name: "Fabric"}},
+		takenCodes: map[string]bool{"SUP-01": true},
+	}
+	svc := NewSupplierService(repo, nil, nil)
+	ctx := context.Background()
+
+	if err := svc.Create(ctx, &domain.Supplier{Code: " SUP-01 ", Name: "Taken"}); !errors.Is(err, ErrPartnerCodeTaken) {
+		t.Errorf("Expected ErrPartnerCodeTaken, got %v", err)
+	}
+
+	missing := int64(9)
+	if err := svc.Create(ctx, &domain.Supplier{Code: "SUP-02", Name: "Other", SupplierCategoryID: &missing}); !errors.Is(err, ErrSupplierCategoryNotFound) {
+		t.Errorf("Expected ErrSupplierCategoryNotFound, got %v", err)
+	}
+
+	category := int64(1)
+	supplier := &domain.Supplier{Code: "SUP-02", Name: " Mill ", SupplierCategoryID: &category, Status: 1}
+	if err := svc.Create(ctx, supplier); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if supplier.ID == 0 || supplier.Name != "Mill" {
+		t.Errorf("Expected a stored supplier named Mill, got %+v", supplier)
+	}
+}
+
+func TestSupplierService_UpdateKeepsStatus(t *testing.T) {
+	repo := &mockSupplierRepository{
+		suppliers: map[int64]*domain.Supplier{1: {ID: 1, Code: "SUP-01", Name: "Mill", Status: 0}},
+	}
+	svc := NewSupplierService(repo, nil, nil)
+
+	if _, err := svc.Update(context.Background(), 1, &domain.Supplier{Code: "SUP-01", Name: "Mill Co", Status: 1}); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if repo.updated == nil || repo.updated.Status != 0 {
+		t.Errorf("Expected the inactive status to be kept, got %+v", repo.updated)
+	}
+}
+
+func TestSupplierService_ToggleStatusAndDelete(t *testing.T) {
+	repo := &mockSupplierRepository{
+		suppliers: map[int64]*domain.Supplier{1: {ID: 1, Code: "SUP-01", Name: "Mill", Status: 1}},
+		usage:     2,
+	}
+	svc := NewSupplierService(repo, nil, nil)
+	ctx := context.Background()
+
+	supplier, err := svc.ToggleStatus(ctx, 1)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if supplier.Status != 0 || repo.statuses[1] != 0 {
+		t.Errorf("Expected supplier to be deactivated, got %d", supplier.Status)
+	}
+
+	if err := svc.Delete(ctx, 1); !errors.Is(err, ErrPartnerInUse) {
+		t.Errorf("Expected ErrPartnerInUse, got %v", err)
+	}
+	if err := svc.Delete(ctx, 2); !errors.Is(err, ErrSupplierNotFound) {
+		t.Errorf("Expected ErrSupplierNotFound, got %v", err)
+	}
+
+	repo.usage = 0
+	if err := svc.Delete(ctx, 1); err != nil || len(repo.deleted) != 1 {
+		t.Errorf("Expected supplier to be deleted, got %v", err)
+	}
+}
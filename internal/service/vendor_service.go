@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+)
+
+var ErrVendorNotFound = errors.New("vendor is not found")
+
+type VendorService struct {
+	repo     repository.VendorRepository
+	activity *ActivityService
+}
+
+func NewVendorService(repo repository.VendorRepository, activity *ActivityService) *VendorService {
+	return &VendorService{repo: repo, activity: activity}
+}
+
+func (s *VendorService) List(ctx context.Context, filter repository.PartnerFilter) ([]domain.Vendor, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	return s.repo.List(ctx, filter)
+}
+
+func (s *VendorService) Get(ctx context.Context, id int64) (*domain.Vendor, error) {
+	vendor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, ErrVendorNotFound
+	}
+	return vendor, nil
+}
+
+func (s *VendorService) Create(ctx context.Context, vendor *domain.Vendor) error {
+	vendor.Code = strings.TrimSpace(vendor.Code)
+	vendor.Name = strings.TrimSpace(vendor.Name)
+	if err := s.checkCode(ctx, vendor.Code, 0); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, vendor); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityCreated, vendor.ID, nil, vendor)
+	return nil
+}
+
+// Update replaces the vendor details. The status only changes through ToggleStatus.
+func (s *VendorService) Update(ctx context.Context, id int64, input *domain.Vendor) (*domain.Vendor, error) {
+	vendor, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	input.ID = vendor.ID
+	input.Code = strings.TrimSpace(input.Code)
+	input.Name = strings.TrimSpace(input.Name)
+	input.Status = vendor.Status
+	input.CreatedAt = vendor.CreatedAt
+	if err := s.checkCode(ctx, input.Code, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, input); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, id, vendor, input)
+	return input, nil
+}
+
+// ToggleStatus switches the vendor between active (1) and inactive (0).
+func (s *VendorService) ToggleStatus(ctx context.Context, id int64) (*domain.Vendor, error) {
+	vendor, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *vendor
+	vendor.Status = toggledStatus(vendor.Status)
+	if err := s.repo.SetStatus(ctx, id, vendor.Status); err != nil {
+		return nil, err
+	}
+	s.record(ctx, ActivityUpdated, id, &old, vendor)
+	return vendor, nil
+}
+
+// Delete soft-deletes a vendor without orders.
+func (s *VendorService) Delete(ctx context.Context, id int64) error {
+	vendor, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	orders, err := s.repo.CountOrders(ctx, id)
+	if err != nil {
+		return err
+	}
+	if orders > 0 {
+		return fmt.Errorf("%w (%d orders)", ErrPartnerInUse, orders)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, ActivityDeleted, id, vendor, nil)
+	return nil
+}
+
+func (s *VendorService) record(ctx context.Context, event string, id int64, old, new *domain.Vendor) {
+	s.activity.Record(ctx, ActivityInput{LogName: "partner", Event: event, SubjectType: repository.SubjectVendor, SubjectID: id, Old: old, New: new})
+}
+
+func (s *VendorService) checkCode(ctx context.Context, code string, excludeID int64) error {
+	exists, err := s.repo.CodeExists(ctx, code, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrPartnerCodeTaken
+	}
+	return nil
+}
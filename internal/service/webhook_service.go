@@ -0,0 +1,459 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+	"github.com/dppi/dppierp-api/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// PermissionManageWebhooks allows a user to manage webhook subscriptions and
+// deliveries.
+const PermissionManageWebhooks = "setting.webhook.manage"
+
+// Headers sent with every webhook delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderID        = "X-Webhook-Id"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	webhookBatchSize = 100
+	// webhookSendingTimeout is far above the default request timeout, so a
+	// delivery still claimed after it can only belong to a worker that
+	// stopped mid-request. It is posted again with the same delivery id.
+	webhookSendingTimeout = 5 * time.Minute
+	// webhookResponseLimit caps how much of a failed response is kept.
+	webhookResponseLimit = 500
+
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 200
+)
+
+var (
+	ErrWebhookForbidden          = errors.New("you are not allowed to manage webhooks")
+	ErrWebhookNotFound           = errors.New("webhook subscription is not found")
+	ErrWebhookDeliveryNotFound   = errors.New("webhook delivery is not found")
+	ErrWebhookDeliveryInProgress = errors.New("webhook delivery is being sent")
+)
+
+// WebhookSettings configures the delivery worker.
+type WebhookSettings struct {
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	Timeout       time.Duration
+}
+
+// WebhookService manages webhook subscriptions and delivers the events of the
+// webhook outbox to them.
+type WebhookService struct {
+	repo     repository.WebhookRepository
+	access   *AccessService
+	activity *ActivityService
+	client   *http.Client
+	settings WebhookSettings
+	wake     chan struct{}
+}
+
+func NewWebhookService(repo repository.WebhookRepository, access *AccessService, activity *ActivityService, settings WebhookSettings) *WebhookService {
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = 1
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+	return &WebhookService{
+		repo:     repo,
+		access:   access,
+		activity: activity,
+		client:   &http.Client{Timeout: settings.Timeout},
+		settings: settings,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, userID int64) ([]domain.WebhookSubscription, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSubscriptions(ctx)
+}
+
+// CreateSubscription stores a subscription with a new signing secret, which
+// is returned this one time.
+func (s *WebhookService) CreateSubscription(ctx context.Context, userID int64, sub *domain.WebhookSubscription) error {
+	if err := s.authorize(ctx, userID); err != nil {
+		return err
+	}
+
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		return err
+	}
+	sub.Secret = secret
+	sub.CreatedBy = &userID
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return err
+	}
+	s.recordSubscription(ctx, ActivityCreated, sub.ID, nil, sub)
+	return nil
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, userID, id int64, input *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.FindSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrWebhookNotFound
+	}
+	old := *sub
+	sub.Name, sub.URL, sub.Events, sub.IsActive = input.Name, input.URL, input.Events, input.IsActive
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	s.recordSubscription(ctx, ActivityUpdated, id, &old, sub)
+	return sub, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, userID, id int64) error {
+	if err := s.authorize(ctx, userID); err != nil {
+		return err
+	}
+
+	sub, err := s.repo.FindSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return ErrWebhookNotFound
+	}
+	deleted, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	s.recordSubscription(ctx, ActivityDeleted, id, sub, nil)
+	return nil
+}
+
+// recordSubscription logs a subscription change. The signing secret is left
+// out of the audit trail.
+func (s *WebhookService) recordSubscription(ctx context.Context, event string, id int64, old, new *domain.WebhookSubscription) {
+	input := ActivityInput{LogName: "webhook", Event: event, SubjectType: repository.SubjectWebhookSubscription, SubjectID: id}
+	if old != nil {
+		withoutSecret := *old
+		withoutSecret.Secret = ""
+		input.Old = &withoutSecret
+	}
+	if new != nil {
+		withoutSecret := *new
+		withoutSecret.Secret = ""
+		input.New = &withoutSecret
+	}
+	s.activity.Record(ctx, input)
+}
+
+// Deliveries lists deliveries, newest first. Filtering on the dead status
+// gives the dead-letter list.
+func (s *WebhookService) Deliveries(ctx context.Context, userID int64, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultWebhookDeliveryLimit
+	}
+	if filter.Limit > maxWebhookDeliveryLimit {
+		filter.Limit = maxWebhookDeliveryLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListDeliveries(ctx, filter)
+}
+
+// Delivery returns one delivery with the event payload.
+func (s *WebhookService) Delivery(ctx context.Context, userID, id int64) (*domain.WebhookDelivery, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.findDelivery(ctx, id)
+}
+
+// Redeliver queues a delivery to be sent again right away with a fresh set of
+// attempts, typically to replay a dead letter once the receiver is fixed.
+func (s *WebhookService) Redeliver(ctx context.Context, userID, id int64) (*domain.WebhookDelivery, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.findDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == domain.WebhookDeliverySending {
+		return nil, ErrWebhookDeliveryInProgress
+	}
+
+	old := *delivery
+	now := time.Now()
+	delivery.Status = domain.WebhookDeliveryPending
+	delivery.Attempt = 0
+	delivery.NextAttemptAt = &now
+	delivery.DeliveredAt = nil
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	s.activity.Record(ctx, ActivityInput{
+		LogName:     "webhook",
+		Event:       ActivityUpdated,
+		Description: "redelivered",
+		SubjectType: repository.SubjectWebhookDelivery,
+		SubjectID:   delivery.ID,
+		Old:         &old,
+		New:         delivery,
+	})
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) findDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	delivery, err := s.repo.FindDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// ProcessOutbox fans new outbox events out to the subscriptions and sends the
+// deliveries that are due. It returns how many were delivered.
+func (s *WebhookService) ProcessOutbox(ctx context.Context) (int, error) {
+	if err := s.dispatch(ctx); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	deliveries, err := s.repo.DueDeliveries(ctx, now, now.Add(-webhookSendingTimeout), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		claimed, err := s.repo.ClaimDelivery(ctx, delivery)
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			continue
+		}
+		if s.deliver(ctx, delivery) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// dispatch creates the deliveries of the events waiting in the outbox.
+// Events nobody subscribes to are marked dispatched without deliveries.
+func (s *WebhookService) dispatch(ctx context.Context) error {
+	events, err := s.repo.PendingEvents(ctx, webhookBatchSize)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	subs, err := s.repo.ActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := s.repo.DispatchEvent(ctx, event.ID, WebhookSubscribers(subs, event.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt and stores its outcome. A delivery that used its
+// last attempt is dead; other failures wait for a retry.
+func (s *WebhookService) deliver(ctx context.Context, delivery *domain.WebhookDelivery) bool {
+	delivery.Attempt++
+	now := time.Now()
+	status, err := s.send(ctx, delivery, now)
+	delivery.ResponseStatus = nil
+	if status > 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	if err == nil {
+		delivery.Status = domain.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else {
+		line := fmt.Sprintf("[attempt %d at %s] %v", delivery.Attempt, now.Format(time.RFC3339), err)
+		if delivery.ErrorMsg != nil && *delivery.ErrorMsg != "" {
+			line = *delivery.ErrorMsg + "\n" + line
+		}
+		delivery.ErrorMsg = &line
+
+		if delivery.Attempt >= s.settings.MaxAttempts {
+			delivery.Status = domain.WebhookDeliveryDead
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(RetryBackoff(delivery.Attempt, s.settings.RetryDelay, s.settings.MaxRetryDelay))
+			delivery.Status = domain.WebhookDeliveryRetry
+			delivery.NextAttemptAt = &next
+		}
+		log.Warn().Err(err).Int64("delivery_id", delivery.ID).Int("attempt", delivery.Attempt).Str("status", delivery.Status).Msg("Failed to deliver webhook")
+	}
+
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Error().Err(err).Int64("delivery_id", delivery.ID).Msg("Failed to store webhook delivery")
+	}
+	return delivery.Status == domain.WebhookDeliveryDelivered
+}
+
+// send posts the signed event and returns the response status. Any status
+// outside 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, delivery *domain.WebhookDelivery, now time.Time) (int, error) {
+	body, err := WebhookBody(delivery)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dppierp-webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderID, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(delivery.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+// RunOutbox processes the webhook outbox every interval, and right after a
+// redelivery is requested, until ctx is cancelled.
+func (s *WebhookService) RunOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessOutbox(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to process webhook outbox")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *WebhookService) authorize(ctx context.Context, userID int64) error {
+	allowed, err := s.access.HasPermission(ctx, userID, PermissionManageWebhooks)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrWebhookForbidden
+	}
+	return nil
+}
+
+// WebhookSubscribers returns the ids of the subscriptions to the event type.
+func WebhookSubscribers(subs []domain.WebhookSubscription, eventType string) []int64 {
+	var ids []int64
+	for _, sub := range subs {
+		for _, event := range sub.Events {
+			if event == eventType {
+				ids = append(ids, sub.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// WebhookBody is the JSON posted for a delivery.
+func WebhookBody(delivery *domain.WebhookDelivery) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		ID        int64           `json:"id"`
+		Type      string          `json:"type"`
+		CreatedAt time.Time       `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}{delivery.EventID, delivery.EventType, delivery.EventCreatedAt, delivery.Payload})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook body: %w", err)
+	}
+	return body, nil
+}
+
+// SignWebhook signs a delivery body. Receivers recompute it with their secret
+// and reject old timestamps to stop replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookSecret returns a random signing secret.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dppi/dppierp-api/internal/domain"
+)
+
+// mockWebhookRepository keeps events and deliveries in memory. Every
+// delivery is due as soon as its next attempt time has passed.
+type mockWebhookRepository struct {
+	subscriptions []domain.WebhookSubscription
+	events        []domain.WebhookEvent
+	dispatched    map[int64]bool
+	deliveries    []*domain.WebhookDelivery
+}
+
+func (m *mockWebhookRepository) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return m.subscriptions, nil
+}
+
+func (m *mockWebhookRepository) FindSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == id {
+			return &m.subscriptions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	sub.ID = int64(len(m.subscriptions) + 1)
+	m.subscriptions = append(m.subscriptions, *sub)
+	return nil
+}
+
+func (m *mockWebhookRepository) UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	return false, nil
+}
+
+func (m *mockWebhookRepository) ActiveSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	var active []domain.WebhookSubscription
+	for _, sub := range m.subscriptions {
+		if sub.IsActive {
+			active = append(active, sub)
+		}
+	}
+	return active, nil
+}
+
+func (m *mockWebhookRepository) PendingEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+	var pending []domain.WebhookEvent
+	for _, e := range m.events {
+		if !m.dispatched[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (m *mockWebhookRepository) DispatchEvent(ctx context.Context, eventID int64, subscriptionIDs []int64) error {
+	var event domain.WebhookEvent
+	for _, e := range m.events {
+		if e.ID == eventID {
+			event = e
+		}
+	}
+	now := time.Now()
+	for _, subID := range subscriptionIDs {
+		sub, _ := m.FindSubscription(ctx, subID)
+		m.deliveries = append(m.deliveries, &domain.WebhookDelivery{
+			ID:             int64(len(m.deliveries) + 1),
+			EventID:        eventID,
+			SubscriptionID: subID,
+			URL:            sub.URL,
+			EventType:      event.Type,
+			Payload:        event.Payload,
+			EventCreatedAt: event.CreatedAt,
+			Status:         domain.WebhookDeliveryPending,
+			NextAttemptAt:  &now,
+		})
+	}
+	m.dispatched[eventID] = true
+	return nil
+}
+
+func (m *mockWebhookRepository) DueDeliveries(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var due []domain.WebhookDelivery
+	for _, d := range m.deliveries {
+		if (d.Status == domain.WebhookDeliveryPending || d.Status == domain.WebhookDeliveryRetry) && !d.NextAttemptAt.After(now) {
+			delivery := *d
+			sub, _ := m.FindSubscription(ctx, d.SubscriptionID)
+			delivery.Secret = sub.Secret
+			due = append(due, delivery)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockWebhookRepository) ClaimDelivery(ctx context.Context, d *domain.WebhookDelivery) (bool, error) {
+	d.Status = domain.WebhookDeliverySending
+	return true, nil
+}
+
+func (m *mockWebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	stored := *d
+	stored.Secret = ""
+	*m.deliveries[d.ID-1] = stored
+	return nil
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) FindDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	if id < 1 || int(id) > len(m.deliveries) {
+		return nil, nil
+	}
+	d := *m.deliveries[id-1]
+	return &d, nil
+}
+
+// webhookReceiver records the requests it gets and answers with the queued
+// status codes, then 200.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+	w.Write([]byte("receiver says " + http.StatusText(status)))
+}
+
+func newWebhookServiceForTest(t *testing.T, receiver *webhookReceiver, maxAttempts int) (*WebhookService, *mockWebhookRepository) {
+	t.Helper()
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	repo := &mockWebhookRepository{
+		subscriptions: []domain.WebhookSubscription{
+			{ID: 1, URL: server.URL, Secret: "whsec_test", Events: []string{domain.WebhookStageMoved, domain.WebhookQCResult}, IsActive: true},
+			{ID: 2, URL: server.URL, Secret: "whsec_other", Events: []string{domain.WebhookRelocated}, IsActive: true},
+		},
+		dispatched: map[int64]bool{},
+	}
+	userRepo := &permissionUserRepository{permissions: map[int64][]string{1: {PermissionManageWebhooks}}}
+	svc := NewWebhookService(repo, NewAccessService(userRepo, nil, 0), nil, WebhookSettings{MaxAttempts: maxAttempts, RetryDelay: time.Minute, MaxRetryDelay: time.Hour})
+	return svc, repo
+}
+
+func TestWebhookOutbox_DeliversSignedEvent(t *testing.T) {
+	receiver := &webhookReceiver{}
+	svc, repo := newWebhookServiceForTest(t, receiver, 3)
+	repo.events = []domain.WebhookEvent{
+		{ID: 10, Type: domain.WebhookStageMoved, Payload: json.RawMessage(`{"fabric_id":7,"code":"F-7","to_stage":"inventory"}`), CreatedAt: time.Now()},
+		{ID: 11, Type: domain.WebhookDelivered, Payload: json.RawMessage(`{"fabric_id":7}`), CreatedAt: time.Now()},
+	}
+
+	delivered, err := svc.ProcessOutbox(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("ProcessOutbox = %d, %v", delivered, err)
+	}
+	if !repo.dispatched[11] || len(repo.deliveries) != 1 {
+		t.Fatalf("Expected the unsubscribed event to be dispatched without deliveries, got %d deliveries", len(repo.deliveries))
+	}
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if req.Header.Get(WebhookHeaderEvent) != domain.WebhookStageMoved || req.Header.Get(WebhookHeaderID) != "10" {
+		t.Errorf("Unexpected headers: %v", req.Header)
+	}
+	if want := SignWebhook("whsec_test", req.Header.Get(WebhookHeaderTimestamp), body); req.Header.Get(WebhookHeaderSignature) != want {
+		t.Errorf("Expected signature %s, got %s", want, req.Header.Get(WebhookHeaderSignature))
+	}
+	var sent struct {
+		ID   int64           `json:"id"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil || sent.ID != 10 || !strings.Contains(string(sent.Data), `"code":"F-7"`) {
+		t.Errorf("Unexpected body %s (%v)", body, err)
+	}
+
+	if d := repo.deliveries[0]; d.Status != domain.WebhookDeliveryDelivered || d.Attempt != 1 || d.DeliveredAt == nil || *d.ResponseStatus != http.StatusOK {
+		t.Errorf("Expected delivered after one attempt, got %+v", d)
+	}
+}
+
+func TestWebhookOutbox_RetriesThenDeadLetters(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}}
+	svc, repo := newWebhookServiceForTest(t, receiver, 2)
+	repo.events = []domain.WebhookEvent{{ID: 1, Type: domain.WebhookQCResult, Payload: json.RawMessage(`{}`), CreatedAt: time.Now()}}
+
+	svc.ProcessOutbox(context.Background())
+	d := repo.deliveries[0]
+	if d.Status != domain.WebhookDeliveryRetry || d.NextAttemptAt == nil || time.Until(*d.NextAttemptAt) < 50*time.Second {
+		t.Fatalf("Expected a retry in about a minute, got %+v", d)
+	}
+
+	// Not due before the backoff has passed
+	svc.ProcessOutbox(context.Background())
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected no request before the backoff, got %d", len(receiver.requests))
+	}
+
+	past := time.Now().Add(-time.Second)
+	d.NextAttemptAt = &past
+	svc.ProcessOutbox(context.Background())
+	if d.Status != domain.WebhookDeliveryDead || d.Attempt != 2 || strings.Count(*d.ErrorMsg, "[attempt") != 2 {
+		t.Fatalf("Expected a dead letter after two attempts, got %+v", d)
+	}
+	if !strings.Contains(*d.ErrorMsg, "HTTP 502: receiver says Bad Gateway") {
+		t.Errorf("Expected the response to be kept, got %s", *d.ErrorMsg)
+	}
+
+	if _, err := svc.Redeliver(context.Background(), 2, d.ID); !errors.Is(err, ErrWebhookForbidden) {
+		t.Errorf("Expected ErrWebhookForbidden, got %v", err)
+	}
+	if _, err := svc.Redeliver(context.Background(), 1, d.ID); err != nil {
+		t.Fatalf("Redeliver returned error: %v", err)
+	}
+	if d.Status != domain.WebhookDeliveryPending || d.Attempt != 0 {
+		t.Fatalf("Expected a pending delivery after redelivery, got %+v", d)
+	}
+
+	// The receiver still fails once more, then accepts the retry
+	svc.ProcessOutbox(context.Background())
+	d.NextAttemptAt = &past
+	svc.ProcessOutbox(context.Background())
+	if d.Status != domain.WebhookDeliveryDelivered || d.Attempt != 2 {
+		t.Errorf("Expected delivered after redelivery, got %+v", d)
+	}
+}
+
+func TestWebhookService_CreateSubscriptionGeneratesSecret(t *testing.T) {
+	svc, _ := newWebhookServiceForTest(t, &webhookReceiver{}, 1)
+	activities := &mockActivityLogRepository{}
+	svc.activity = NewActivityService(activities)
+
+	sub := &domain.WebhookSubscription{Name: "ERP", URL: "https://erp.dppi.test/hooks", Events: []string{domain.WebhookReceived}, IsActive: true}
+	if err := svc.CreateSubscription(context.Background(), 1, sub); err != nil {
+		t.Fatalf("CreateSubscription returned error: %v", err)
+	}
+	if !strings.HasPrefix(sub.Secret, "whsec_") || len(sub.Secret) != len("whsec_")+64 || *sub.CreatedBy != 1 {
+		t.Errorf("Unexpected subscription: %+v", sub)
+	}
+	if len(activities.created) != 1 || *activities.created[0].Event != ActivityCreated || *activities.created[0].SubjectID != sub.ID {
+		t.Fatalf("Expected the created subscription to be logged, got %+v", activities.created)
+	}
+	if properties := string(activities.created[0].Properties); !strings.Contains(properties, `"url":"https://erp.dppi.test/hooks"`) || strings.Contains(properties, "whsec_") {
+		t.Errorf("Expected the subscription without its secret, got %s", properties)
+	}
+	if err := svc.CreateSubscription(context.Background(), 2, sub); !errors.Is(err, ErrWebhookForbidden) {
+		t.Errorf("Expected ErrWebhookForbidden, got %v", err)
+	}
+}
+
+func TestWebhookSubscribers(t *testing.T) {
+	subs := []domain.WebhookSubscription{
+		{ID: 1, Events: []string{domain.WebhookStageMoved, domain.WebhookReceived}},
+		{ID: 2, Events: []string{domain.WebhookReceived}},
+		{ID: 3, Events: []string{domain.WebhookRelocated}},
+	}
+	if got := WebhookSubscribers(subs, domain.WebhookReceived); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Unexpected subscribers: %v", got)
+	}
+	if got := WebhookSubscribers(subs, domain.WebhookQCResult); len(got) != 0 {
+		t.Errorf("Expected no subscribers, got %v", got)
+	}
+}
@@ -0,0 +1,48 @@
+-- Two-step fabric destroy (write-off) workflow.
+-- A roll only moves to the `destroy` stage once its request is approved.
+
+CREATE TABLE IF NOT EXISTS `fabric_destroy_requests` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `fabric_id` bigint(20) unsigned NOT NULL,
+  `reason` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+  `requested_by` bigint(20) unsigned NOT NULL,
+  `reviewed_by` bigint(20) unsigned DEFAULT NULL,
+  `reviewed_at` timestamp NULL DEFAULT NULL,
+  `review_notes` text COLLATE utf8mb4_unicode_ci,
+  `inventory_movement_id` bigint(20) unsigned DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fabric_destroy_requests_fabric_id_foreign` (`fabric_id`),
+  KEY `fabric_destroy_requests_status_index` (`status`),
+  CONSTRAINT `fabric_destroy_requests_fabric_id_foreign` FOREIGN KEY (`fabric_id`) REFERENCES `fabrics` (`id`),
+  CONSTRAINT `fabric_destroy_requests_requested_by_foreign` FOREIGN KEY (`requested_by`) REFERENCES `users` (`id`),
+  CONSTRAINT `fabric_destroy_requests_reviewed_by_foreign` FOREIGN KEY (`reviewed_by`) REFERENCES `users` (`id`),
+  CONSTRAINT `fabric_destroy_requests_inventory_movement_id_foreign` FOREIGN KEY (`inventory_movement_id`) REFERENCES `inventory_movements` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `fabric_destroy_photos` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `fabric_destroy_request_id` bigint(20) unsigned NOT NULL,
+  `file_path` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fabric_destroy_photos_request_id_foreign` (`fabric_destroy_request_id`),
+  CONSTRAINT `fabric_destroy_photos_request_id_foreign` FOREIGN KEY (`fabric_destroy_request_id`) REFERENCES `fabric_destroy_requests` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO `permissions` (`name`, `guard_name`, `created_at`, `updated_at`)
+SELECT 'fabric.destroy.approve', 'web', NOW(), NOW()
+FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM `permissions` WHERE `name` = 'fabric.destroy.approve' AND `guard_name` = 'web');
+
+INSERT INTO `role_has_permissions` (`permission_id`, `role_id`)
+SELECT p.id, r.id
+FROM `permissions` p
+JOIN `roles` r ON r.name = 'superadmin'
+WHERE p.name = 'fabric.destroy.approve'
+  AND NOT EXISTS (SELECT 1 FROM `role_has_permissions` rhp WHERE rhp.permission_id = p.id AND rhp.role_id = r.id);
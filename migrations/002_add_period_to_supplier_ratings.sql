@@ -0,0 +1,6 @@
+-- Supplier scorecards are computed per calendar month ("YYYY-MM").
+-- Rows without a period are ratings entered manually in the web application.
+
+ALTER TABLE `supplier_ratings`
+  ADD COLUMN `period` char(7) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `supplier_id`,
+  ADD KEY `supplier_ratings_supplier_id_period_index` (`supplier_id`, `period`);
@@ -0,0 +1,7 @@
+-- Operators are reassigned by soft-deleting the current request_processes row
+-- and inserting a new one, so the deleted rows form the assignment history.
+-- assigned_by records the supervisor behind each assignment.
+
+ALTER TABLE `request_processes`
+  ADD COLUMN `assigned_by` bigint(20) unsigned DEFAULT NULL AFTER `man_power_id`,
+  ADD CONSTRAINT `request_processes_assigned_by_foreign` FOREIGN KEY (`assigned_by`) REFERENCES `users` (`id`);
@@ -0,0 +1,61 @@
+-- Outgoing webhooks for inventory events.
+-- Events are written to `webhook_events` in the same transaction as the
+-- change they describe. A worker fans each event out to the matching
+-- subscriptions as `webhook_deliveries` and sends them with retries;
+-- deliveries that run out of attempts stay behind with status `dead`.
+
+CREATE TABLE IF NOT EXISTS `webhook_subscriptions` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `url` varchar(500) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `secret` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `events` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `is_active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_by` bigint(20) unsigned DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `webhook_subscriptions_created_by_foreign` FOREIGN KEY (`created_by`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `webhook_events` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `event_type` varchar(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `payload` longtext COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `dispatched_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhook_events_dispatched_at_index` (`dispatched_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `webhook_event_id` bigint(20) unsigned NOT NULL,
+  `webhook_subscription_id` bigint(20) unsigned NOT NULL,
+  `status` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+  `attempt` int(11) NOT NULL DEFAULT 0,
+  `next_attempt_at` timestamp NULL DEFAULT NULL,
+  `response_status` int(11) DEFAULT NULL,
+  `error_msg` text COLLATE utf8mb4_unicode_ci,
+  `delivered_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `webhook_deliveries_event_subscription_unique` (`webhook_event_id`, `webhook_subscription_id`),
+  KEY `webhook_deliveries_status_next_attempt_at_index` (`status`, `next_attempt_at`),
+  CONSTRAINT `webhook_deliveries_webhook_event_id_foreign` FOREIGN KEY (`webhook_event_id`) REFERENCES `webhook_events` (`id`),
+  CONSTRAINT `webhook_deliveries_webhook_subscription_id_foreign` FOREIGN KEY (`webhook_subscription_id`) REFERENCES `webhook_subscriptions` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO `permissions` (`name`, `guard_name`, `created_at`, `updated_at`)
+SELECT 'setting.webhook.manage', 'web', NOW(), NOW()
+FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM `permissions` WHERE `name` = 'setting.webhook.manage' AND `guard_name` = 'web');
+
+INSERT INTO `role_has_permissions` (`permission_id`, `role_id`)
+SELECT p.id, r.id
+FROM `permissions` p
+JOIN `roles` r ON r.name = 'superadmin'
+WHERE p.name = 'setting.webhook.manage'
+  AND NOT EXISTS (SELECT 1 FROM `role_has_permissions` rhp WHERE rhp.permission_id = p.id AND rhp.role_id = r.id);
@@ -0,0 +1,25 @@
+-- Permissions for the API route groups that have no counterpart in the web
+-- application yet. The superadmin role gets all of them.
+
+INSERT INTO `permissions` (`name`, `guard_name`, `created_at`, `updated_at`)
+SELECT n.name, 'web', NOW(), NOW()
+FROM (
+  SELECT 'sewing.qc.read' AS name
+  UNION ALL SELECT 'sewing.qc.write'
+  UNION ALL SELECT 'sewing.packing.read'
+  UNION ALL SELECT 'sewing.packing.write'
+  UNION ALL SELECT 'setting.status-log.read'
+  UNION ALL SELECT 'setting.activity-log.read'
+  UNION ALL SELECT 'setting.exception.read'
+) n
+WHERE NOT EXISTS (SELECT 1 FROM `permissions` p WHERE p.name = n.name AND p.guard_name = 'web');
+
+INSERT INTO `role_has_permissions` (`permission_id`, `role_id`)
+SELECT p.id, r.id
+FROM `permissions` p
+JOIN `roles` r ON r.name = 'superadmin'
+WHERE p.name IN (
+    'sewing.qc.read', 'sewing.qc.write', 'sewing.packing.read', 'sewing.packing.write',
+    'setting.status-log.read', 'setting.activity-log.read', 'setting.exception.read'
+  )
+  AND NOT EXISTS (SELECT 1 FROM `role_has_permissions` rhp WHERE rhp.permission_id = p.id AND rhp.role_id = r.id);
@@ -0,0 +1,37 @@
+-- Permissions to move rolls into each stage at the check point. Rolls reach
+-- destroy only through an approved destroy request (fabric.destroy.approve).
+-- Roles that could use the check point keep the everyday stages; setting QC
+-- results and returning rolls to the supplier are left to superadmin until
+-- they are given to the QC and warehouse lead roles.
+
+INSERT INTO `permissions` (`name`, `guard_name`, `created_at`, `updated_at`)
+SELECT n.name, 'web', NOW(), NOW()
+FROM (
+  SELECT 'fabric.move.inventory' AS name
+  UNION ALL SELECT 'fabric.move.relaxation'
+  UNION ALL SELECT 'fabric.move.cutting-wip'
+  UNION ALL SELECT 'fabric.move.stock-fabric'
+  UNION ALL SELECT 'fabric.move.cncm'
+  UNION ALL SELECT 'fabric.move.washing'
+  UNION ALL SELECT 'fabric.move.return-supplier'
+  UNION ALL SELECT 'fabric.move.qc-fabric'
+) n
+WHERE NOT EXISTS (SELECT 1 FROM `permissions` p WHERE p.name = n.name AND p.guard_name = 'web');
+
+INSERT INTO `role_has_permissions` (`permission_id`, `role_id`)
+SELECT p.id, r.id
+FROM `permissions` p
+JOIN `roles` r ON r.name = 'superadmin'
+WHERE p.name LIKE 'fabric.move.%'
+  AND NOT EXISTS (SELECT 1 FROM `role_has_permissions` rhp WHERE rhp.permission_id = p.id AND rhp.role_id = r.id);
+
+INSERT INTO `role_has_permissions` (`permission_id`, `role_id`)
+SELECT p.id, rhp.role_id
+FROM `permissions` p
+CROSS JOIN `role_has_permissions` rhp
+JOIN `permissions` qr ON qr.id = rhp.permission_id AND qr.name = 'fabric.qr-system.write'
+WHERE p.name IN (
+    'fabric.move.inventory', 'fabric.move.relaxation', 'fabric.move.cutting-wip',
+    'fabric.move.stock-fabric', 'fabric.move.cncm', 'fabric.move.washing'
+  )
+  AND NOT EXISTS (SELECT 1 FROM `role_has_permissions` x WHERE x.permission_id = p.id AND x.role_id = rhp.role_id);
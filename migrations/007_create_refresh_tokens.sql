@@ -0,0 +1,22 @@
+-- Refresh tokens handed out by the API. Every refresh marks the presented
+-- token used and stores its successor under the same family_id; a used token
+-- presented again revokes the whole family. Logout revokes the family of the
+-- session and a password change revokes every family of the user.
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) unsigned NOT NULL,
+  `token_id` char(36) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `family_id` char(36) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `expires_at` timestamp NULL DEFAULT NULL,
+  `used_at` timestamp NULL DEFAULT NULL,
+  `revoked_at` timestamp NULL DEFAULT NULL,
+  `revoked_reason` varchar(50) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `refresh_tokens_token_id_unique` (`token_id`),
+  KEY `refresh_tokens_family_id_index` (`family_id`),
+  KEY `refresh_tokens_user_id_revoked_at_index` (`user_id`, `revoked_at`),
+  CONSTRAINT `refresh_tokens_user_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,9 @@
+-- The reset clause of a numbering compares the period of the last allocation
+-- with the current one. updated_at also changes when the numbering is edited,
+-- so the allocation time gets its own column. Existing rows start from their
+-- updated_at, which was the allocation time until now.
+
+ALTER TABLE `setting_numberings`
+  ADD COLUMN `last_allocated_at` timestamp NULL DEFAULT NULL AFTER `sequence`;
+
+UPDATE `setting_numberings` SET `last_allocated_at` = `updated_at` WHERE `last_allocated_at` IS NULL;
@@ -0,0 +1,13 @@
+-- A supplier has one computed scorecard per period, so concurrent runs of the
+-- rating job update the same row. Duplicates stored so far are removed first,
+-- keeping the latest. Manual ratings have no period and are not affected.
+
+DELETE `older` FROM `supplier_ratings` `older`
+  JOIN `supplier_ratings` `newer`
+    ON `newer`.`supplier_id` = `older`.`supplier_id`
+   AND `newer`.`period` = `older`.`period`
+   AND `newer`.`id` > `older`.`id`;
+
+ALTER TABLE `supplier_ratings`
+  ADD UNIQUE KEY `supplier_ratings_supplier_id_period_unique` (`supplier_id`, `period`),
+  DROP KEY `supplier_ratings_supplier_id_period_index`;
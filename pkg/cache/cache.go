@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store keeps values for a while. A missing or expired key is reported as
+// not found rather than as an error.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Forget(ctx context.Context, key string) error
+}
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore keeps values in the process. Every instance of the API has its
+// own copy, so a Forget is not seen by the other instances.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: map[string]memoryItem{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	item, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok || !time.Now().Before(item.expiresAt) {
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = memoryItem{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Forget(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+// DatabaseStore keeps values in the cache table the web application uses, so
+// every instance of the API shares them. Keys are prefixed to stay apart from
+// the web application's entries.
+type DatabaseStore struct {
+	db     *sql.DB
+	prefix string
+}
+
+func NewDatabaseStore(db *sql.DB, prefix string) *DatabaseStore {
+	return &DatabaseStore{db: db, prefix: prefix}
+}
+
+func (s *DatabaseStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value string
+	var expiration int64
+	err := s.db.QueryRowContext(ctx, "SELECT value, expiration FROM cache WHERE `key` = ?", s.prefix+key).Scan(&value, &expiration)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache: %w", err)
+	}
+	if expiration <= time.Now().Unix() {
+		return nil, false, nil
+	}
+	return []byte(value), true, nil
+}
+
+func (s *DatabaseStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	query := "INSERT INTO cache (`key`, value, expiration) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value), expiration = VALUES(expiration)"
+	if _, err := s.db.ExecContext(ctx, query, s.prefix+key, string(value), time.Now().Add(ttl).Unix()); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+func (s *DatabaseStore) Forget(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM cache WHERE `key` = ?", s.prefix+key); err != nil {
+		return fmt.Errorf("failed to forget cache: %w", err)
+	}
+	return nil
+}
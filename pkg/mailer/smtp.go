@@ -0,0 +1,214 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a single email. Bcc addresses receive the message but are not
+// written to its headers.
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	Subject string
+	Body    string
+	HTML    bool
+}
+
+// Recipients returns every address the message is delivered to.
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPSender delivers messages to an SMTP server. It upgrades to TLS when the
+// server offers STARTTLS and authenticates when a username is set, so it
+// also works against a plain local SMTP server during development and tests.
+type SMTPSender struct {
+	host     string
+	addr     string
+	username string
+	password string
+	timeout  time.Duration
+}
+
+func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
+	return &SMTPSender{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: username,
+		password: password,
+		timeout:  30 * time.Second,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return &PermanentError{Err: errors.New("message has no recipients")}
+	}
+	data, err := BuildMessage(msg, time.Now())
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return classify(err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return classify(err)
+		}
+	}
+	if s.username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+				return classify(err)
+			}
+		}
+	}
+
+	if err := client.Mail(addressOnly(msg.From)); err != nil {
+		return classify(err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(addressOnly(rcpt)); err != nil {
+			return classify(err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return classify(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return classify(err)
+	}
+	if err := w.Close(); err != nil {
+		return classify(err)
+	}
+	return classify(client.Quit())
+}
+
+// PermanentError is a failure that retrying will not fix, such as a rejected
+// recipient.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err should not be retried.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// classify marks 5xx SMTP replies as permanent; everything else, such as
+// 4xx replies and network errors, may succeed later.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// BuildMessage renders the headers and a quoted-printable body of msg.
+func BuildMessage(msg *Message, date time.Time) ([]byte, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", msg.From, err)
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	writeHeader("From", from.String())
+	if len(msg.To) > 0 {
+		writeHeader("To", strings.Join(msg.To, ", "))
+	}
+	if len(msg.Cc) > 0 {
+		writeHeader("Cc", strings.Join(msg.Cc, ", "))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", date.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID(from.Address))
+	writeHeader("MIME-Version", "1.0")
+	contentType := "text/plain"
+	if msg.HTML {
+		contentType = "text/html"
+	}
+	writeHeader("Content-Type", contentType+"; charset=UTF-8")
+	writeHeader("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// addressOnly strips the display name, as SMTP envelopes take bare addresses.
+func addressOnly(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
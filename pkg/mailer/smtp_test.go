@@ -0,0 +1,175 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal SMTP stand-in that records what it receives.
+// Recipients starting with "blocked" are rejected permanently and those
+// starting with "busy" temporarily.
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu         sync.Mutex
+	from       string
+	recipients []string
+	data       string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			rcpt := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			switch {
+			case strings.HasPrefix(rcpt, "blocked"):
+				reply("550 mailbox unavailable")
+			case strings.HasPrefix(rcpt, "busy"):
+				reply("451 try again later")
+			default:
+				s.mu.Lock()
+				s.recipients = append(s.recipients, rcpt)
+				s.mu.Unlock()
+				reply("250 OK")
+			}
+		case command == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sender := NewSMTPSender("127.0.0.1", server.port(), "", "")
+
+	err := sender.Send(context.Background(), &Message{
+		From:    "DPPI ERP <noreply@dppi.test>",
+		To:      []string{"user@dppi.test"},
+		Bcc:     []string{"audit@dppi.test"},
+		Subject: "Reset your password",
+		Body:    "Your token is abc123.",
+	})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.from != "noreply@dppi.test" {
+		t.Errorf("Expected envelope sender noreply@dppi.test, got %q", server.from)
+	}
+	if strings.Join(server.recipients, ",") != "user@dppi.test,audit@dppi.test" {
+		t.Errorf("Unexpected recipients: %v", server.recipients)
+	}
+	if !strings.Contains(server.data, "Subject: Reset your password") || !strings.Contains(server.data, "Your token is abc123.") {
+		t.Errorf("Unexpected message data: %s", server.data)
+	}
+	if strings.Contains(server.data, "audit@dppi.test") {
+		t.Error("Expected bcc address to stay out of the headers")
+	}
+}
+
+func TestSMTPSender_ClassifiesFailures(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sender := NewSMTPSender("127.0.0.1", server.port(), "", "")
+	msg := func(to string) *Message {
+		return &Message{From: "noreply@dppi.test", To: []string{to}, Subject: "Hi", Body: "Hi"}
+	}
+
+	if err := sender.Send(context.Background(), msg("blocked@dppi.test")); err == nil || !IsPermanent(err) {
+		t.Errorf("Expected permanent error for 550, got %v", err)
+	}
+	if err := sender.Send(context.Background(), msg("busy@dppi.test")); err == nil || IsPermanent(err) {
+		t.Errorf("Expected temporary error for 451, got %v", err)
+	}
+
+	closed := NewSMTPSender("127.0.0.1", 1, "", "")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := closed.Send(ctx, msg("user@dppi.test")); err == nil || IsPermanent(err) {
+		t.Errorf("Expected temporary error for a refused connection, got %v", err)
+	}
+}
+
+func TestBuildMessage_EncodesSubject(t *testing.T) {
+	data, err := BuildMessage(&Message{From: "noreply@dppi.test", To: []string{"a@dppi.test"}, Subject: "Kata sandi baru ✓", Body: "ok"}, time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("BuildMessage returned error: %v", err)
+	}
+	if !strings.Contains(string(data), "Subject: =?utf-8?q?") {
+		t.Errorf("Expected encoded subject, got %s", data)
+	}
+	if _, err := BuildMessage(&Message{From: "not an address"}, time.Now()); err == nil {
+		t.Error("Expected error for invalid from address")
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage stores uploaded files on the local disk below a root directory.
+// Paths handed back to callers are relative to that root so they can be saved
+// in the database the same way the web application stores its file paths.
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Save writes r to a new file inside dir, keeping the extension of filename,
+// and returns the relative path of the stored file.
+func (s *LocalStorage) Save(dir, filename string, r io.Reader) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	relPath := path.Join(dir, hex.EncodeToString(b)+ext)
+	fullPath := filepath.Join(s.root, filepath.FromSlash(relPath))
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(fullPath)
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return relPath, nil
+}
+
+// Delete removes a file previously returned by Save. Missing files are ignored.
+func (s *LocalStorage) Delete(relPath string) error {
+	err := os.Remove(filepath.Join(s.root, filepath.FromSlash(relPath)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}